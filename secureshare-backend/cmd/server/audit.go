@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"secureshare-backend/internal/service"
+)
+
+const auditUsage = `uso: server audit verify [-json]
+
+Recalcula a cadeia de hashes do log de auditoria e aponta o primeiro evento
+alterado, removido ou fora de ordem. Sai com status 1 se a cadeia estiver
+quebrada. Anote lastSeq/lastHash fora do banco: remover os últimos eventos
+não quebra a cadeia, mas muda o fim dela.
+
+Só DATABASE_URL é necessária.`
+
+// runAuditCommand implementa o subcomando "server audit"
+func runAuditCommand(args []string) {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, auditUsage)
+		os.Exit(2)
+	}
+	flags := flag.NewFlagSet("audit verify", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "imprime o resultado em JSON")
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, auditUsage) }
+	flags.Parse(args[1:])
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		fatal("DATABASE_URL não definida")
+	}
+
+	ctx := context.Background()
+	store, migrator, closeStore, err := openStore(ctx, databaseURL)
+	if err != nil {
+		fatal("Falha ao abrir o banco de dados", "err", err)
+	}
+	defer closeStore()
+	checkSchema(ctx, migrator, false)
+
+	result, err := service.NewAuditService(store).Verify(ctx)
+	if err != nil {
+		fatal("Falha na verificação", "err", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			fatal("Falha ao escrever resultado", "err", err)
+		}
+	} else {
+		printAuditVerification(result)
+	}
+	if !result.OK() {
+		os.Exit(1)
+	}
+}
+
+func printAuditVerification(v *service.AuditVerification) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Eventos verificados:\t%d\n", v.Checked)
+	fmt.Fprintf(w, "Último evento íntegro:\t%d\n", v.LastSeq)
+	fmt.Fprintf(w, "Hash do último evento:\t%s\n", v.LastHash)
+	if v.OK() {
+		fmt.Fprintf(w, "Resultado:\tcadeia íntegra\n")
+	} else {
+		fmt.Fprintf(w, "Resultado:\tQUEBRADA no evento %d: %s\n", v.BrokenAt, v.Problem)
+	}
+	w.Flush()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"secureshare-backend/internal/config"
+	"secureshare-backend/internal/service"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// runGCCommand implementa o subcomando "server gc": roda o coletor de
+// arquivos órfãos uma vez, fora da fila de jobs
+func runGCCommand(args []string) {
+	var cfg config.Config
+	if err := config.Load(&cfg); err != nil {
+		fatal("Falha ao carregar configuração", "err", err)
+	}
+
+	flags := flag.NewFlagSet("gc", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "só lista os órfãos, sem removê-los")
+	report := flags.Bool("report", false, "imprime o relatório completo (JSON) em vez do resumo")
+	grace := flags.Duration("grace", cfg.BlobGCGrace, "objetos mais novos que isto nunca são coletados")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "uso: server gc [-dry-run] [-report] [-grace 24h]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	ctx := context.Background()
+	store, migrator, closeStore, err := openStore(ctx, cfg.DatabaseURL)
+	if err != nil {
+		fatal("Falha ao conectar ao banco de dados", "err", err)
+	}
+	defer closeStore()
+	checkSchema(ctx, migrator, false)
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.AWSRegion))
+	if err != nil {
+		fatal("Falha ao carregar configuração AWS SDK", "err", err)
+	}
+	s3Service := service.NewS3Service(s3.NewFromConfig(awsCfg), cfg.AWSBucketName)
+
+	collector := service.NewOrphanCollector(store, s3Service, *grace)
+	result, err := collector.Collect(ctx, time.Now(), *dryRun)
+	if err != nil {
+		slog.Error("Coleta interrompida", "err", err)
+	}
+
+	if *report {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(result); encErr != nil {
+			fatal("Falha ao escrever relatório", "err", encErr)
+		}
+	} else {
+		printGCSummary(result)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func printGCSummary(r *service.GCReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Objetos verificados:\t%d\n", r.Scanned)
+	fmt.Fprintf(w, "Referenciados:\t%d\n", r.Referenced)
+	fmt.Fprintf(w, "Dentro da carência (%s):\t%d\n", r.Grace, r.TooRecent)
+	fmt.Fprintf(w, "Órfãos:\t%d (%d bytes)\n", len(r.Orphans), r.OrphanBytes)
+	if r.DryRun {
+		fmt.Fprintf(w, "Removidos:\t0 (dry-run)\n")
+	} else {
+		fmt.Fprintf(w, "Removidos:\t%d\n", r.Deleted)
+	}
+	w.Flush()
+}
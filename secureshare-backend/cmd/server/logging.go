@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"secureshare-backend/internal/logging"
+)
+
+// setupLogging instala o logger JSON (internal/logging) como padrão do
+// processo, no nível de LOG_LEVEL (debug, info, warn ou error; padrão:
+// info). O pacote log passa a escrever pelo mesmo logger.
+func setupLogging() {
+	level, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	slog.SetDefault(logging.New(os.Stderr, level))
+	if err != nil {
+		slog.Warn("Usando o nível info", "err", err)
+	}
+}
+
+// fatal registra o erro e encerra o processo (o log.Fatal do slog)
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -3,20 +3,26 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"secureshare-backend/internal/api"
 	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/auth/oidc"
 	"secureshare-backend/internal/config"
+	"secureshare-backend/internal/grpcapi"
+	"secureshare-backend/internal/notify"
 	"secureshare-backend/internal/repository"
 	"secureshare-backend/internal/service"
 
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 
 	// --- IMPORTS DO AWS SDK ---
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
@@ -24,72 +30,140 @@ import (
 )
 
 func main() {
-	// Carregar .env
+	// Carregar .env (que pode definir LOG_LEVEL)
 	err := godotenv.Load()
+	setupLogging()
 	if err != nil {
-		log.Printf("Aviso: Não foi possível carregar o arquivo .env: %v.", err)
+		slog.Warn("Não foi possível carregar o arquivo .env", "err", err)
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrateCommand(os.Args[2:])
+			return
+		case "worker":
+			runWorkerCommand()
+			return
+		case "gc":
+			runGCCommand(os.Args[2:])
+			return
+		case "audit":
+			runAuditCommand(os.Args[2:])
+			return
+		}
 	}
 
 	// 1. Carregar Configuração
 	var cfg config.Config
 	if err := config.Load(&cfg); err != nil {
-		log.Fatalf("Falha ao carregar configuração: %v", err)
+		fatal("Falha ao carregar configuração", "err", err)
 	}
 
 	// Contexto de inicialização
 	initCtx, cancelInit := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelInit()
 
-	// 2. Inicializar Repositório (PostgreSQL)
-	store, err := repository.NewPostgresStore(initCtx, cfg.DatabaseURL)
+	// 2. Inicializar Repositório (PostgreSQL, ou SQLite com DATABASE_URL=sqlite://...)
+	store, migrator, closeStore, err := openStore(initCtx, cfg.DatabaseURL)
 	if err != nil {
-		log.Fatalf("Falha ao conectar ao banco de dados: %v", err)
+		fatal("Falha ao conectar ao banco de dados", "err", err)
 	}
-	defer store.Close()
-	log.Println("Conectado ao PostgreSQL!")
+	defer closeStore()
+	slog.Info("Conectado ao banco de dados")
 
-	// 3. Rodar Migrations
-	migrationSQL, err := os.ReadFile("./migrations/001_init.sql")
-	if err != nil {
-		log.Fatalf("Falha ao ler arquivo de migração: %v", err)
-	}
-	if err := store.RunMigrations(initCtx, string(migrationSQL)); err != nil {
-		log.Printf("Aviso ao rodar migrações: %v. (Continuando...)", err)
-	} else {
-		log.Println("Migrações do banco de dados aplicadas com sucesso.")
-	}
+	// 3. Conferir o schema (ver internal/migrate). Bancos PostgreSQL criados
+	// antes do schema_migrations são adotados: os scripts up são idempotentes.
+	checkSchema(initCtx, migrator, cfg.AutoMigrate)
 
 	// 4. Inicializar Cliente S3 e Serviço
 	// O LoadDefaultConfig irá carregar automaticamente as credenciais
 	// do .env (porque o godotenv as colocou no ambiente)
 	awsCfg, err := awsconfig.LoadDefaultConfig(initCtx, awsconfig.WithRegion(cfg.AWSRegion))
 	if err != nil {
-		log.Fatalf("Falha ao carregar configuração AWS SDK: %v", err)
+		fatal("Falha ao carregar configuração AWS SDK", "err", err)
 	}
 
 	s3Client := s3.NewFromConfig(awsCfg)
 	s3Service := service.NewS3Service(s3Client, cfg.AWSBucketName)
-	log.Println("Serviço S3 inicializado.")
+	slog.Info("Serviço S3 inicializado")
 
 	// 5. Inicializar Camada de Autenticação
 	tokenService, err := auth.NewTokenService(cfg.JWTSecret)
 	if err != nil {
-		log.Fatalf("Falha ao iniciar TokenService: %v", err)
+		fatal("Falha ao iniciar TokenService", "err", err)
 	}
 
 	// 6. Inicializar Camada de Serviço
 	userService := service.NewUserService(store, tokenService)
-	transferService := service.NewTransferService(store)
+	transferService := service.NewTransferService(store, s3Service, service.UploadLimits{
+		MaxFileSize: cfg.MaxFileSize,
+		UserQuota:   cfg.UserQuotaBytes,
+		OrgQuota:    cfg.OrgQuotaBytes,
+	})
+	accountService := service.NewAccountService(store, userService)
+	keyBackupService := service.NewKeyBackupService(store)
+	deviceService := service.NewDeviceService(store)
+	apiKeyService := service.NewAPIKeyService(store, store)
+	auditService := service.NewAuditService(store)
+	eventHub := service.NewEventHub(store)
+	webhookService := newWebhookService(cfg, store)
+	idempotencyService := service.NewIdempotencyService(store)
+	emailService := newEmailService(cfg, store) // nil sem SMTP_HOST
+
+	// Fila de jobs (ou em processos "server worker")
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	workers := &sync.WaitGroup{}
+	if cfg.RunWorkers {
+		workers = startWorkers(workerCtx, cfg, store, s3Service)
+	} else {
+		slog.Info("RUN_WORKERS desabilitado: a fila de jobs fica com 'server worker'")
+	}
+
+	// Notificações em tempo real (GET /v1/events)
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+	go eventHub.Run(eventsCtx)
+
+	// Login SSO via OIDC (opcional)
+	var ssoService *service.SSOService
+	if cfg.OIDCIssuerURL != "" {
+		provider, err := oidc.NewProvider(initCtx, oidc.Config{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+		}, nil)
+		if err != nil {
+			fatal("Falha ao configurar provedor OIDC", "err", err)
+		}
+		ssoService = service.NewSSOService(provider, store, tokenService, cfg.OIDCUsernameClaim, cfg.OIDCAutoProvision)
+		slog.Info("Login SSO habilitado", "issuer", cfg.OIDCIssuerURL)
+	}
 
 	// 7. Inicializar Camada de API (Handlers e Rotas)
 	// (Passe o novo s3Service)
 	handler := api.NewHandler(
 		userService,
 		transferService,
+		accountService,
+		keyBackupService,
+		deviceService,
+		apiKeyService,
+		auditService,
+		eventHub,
+		webhookService,
+		idempotencyService,
+		emailService,
+		ssoService,
 		tokenService,
 		store,
 		s3Service, // <-- PASSE O NOVO SERVIÇO
 	)
+	handler.SetSSOPostLoginRedirect(cfg.OIDCPostLoginRedirect)
+	handler.SetAdmins(cfg.AdminUsernames)
 
 	// 8. Configurar Servidor HTTP
 	srv := &http.Server{
@@ -103,22 +177,102 @@ func main() {
 	// 9. Iniciar Servidor
 	// (O resto do código de graceful shutdown permanece o mesmo)
 	go func() {
-		log.Printf("Servidor iniciado em http://localhost:%d/v1", cfg.ServerPort)
+		slog.Info("Servidor iniciado", "addr", fmt.Sprintf("http://localhost:%d/v1", cfg.ServerPort))
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Erro ao iniciar servidor: %v", err)
+			fatal("Erro ao iniciar servidor", "err", err)
 		}
 	}()
 
+	// 10. API gRPC (opcional), sobre os mesmos serviços
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			fatal("Erro ao abrir a porta gRPC", "err", err)
+		}
+		grpcServer = grpcapi.NewServer(
+			userService,
+			transferService,
+			deviceService,
+			apiKeyService,
+			auditService,
+			eventHub,
+			s3Service,
+		).NewGRPCServer()
+		go func() {
+			slog.Info("API gRPC iniciada", "port", cfg.GRPCPort)
+			if err := grpcServer.Serve(lis); err != nil {
+				fatal("Erro ao iniciar servidor gRPC", "err", err)
+			}
+		}()
+	}
+
+	// 11. Métricas Prometheus (opcional), em listener próprio
+	metricsServer := startMetricsServer(cfg.MetricsPort, store)
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Recebido sinal de desligamento, encerrando servidor...")
+	slog.Info("Recebido sinal de desligamento, encerrando servidor")
+	stopWorkers()
+	// Encerra as conexões de /v1/events, que senão segurariam o Shutdown
+	stopEvents()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if grpcServer != nil {
+		// Os streams de eventos já foram encerrados por stopEvents
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Erro no graceful shutdown: %v", err)
+		fatal("Erro no graceful shutdown", "err", err)
+	}
+	stopMetricsServer(ctx, metricsServer)
+	// Espera os jobs em andamento antes do closeStore adiado acima
+	workers.Wait()
+	slog.Info("Servidor encerrado")
+}
+
+// newWebhookService cria o serviço de webhooks; destinos em redes privadas
+// só com WEBHOOK_ALLOW_PRIVATE
+func newWebhookService(cfg config.Config, store repository.Store) *service.WebhookService {
+	webhooks := service.NewWebhookService(store, nil)
+	if cfg.WebhookAllowPrivate {
+		slog.Warn("WEBHOOK_ALLOW_PRIVATE ativo: webhooks podem apontar para a rede interna")
+		webhooks.AllowPrivateNetworks()
+	}
+	return webhooks
+}
+
+// newEmailService cria o serviço de e-mail, ou retorna nil se o SMTP não
+// estiver configurado
+func newEmailService(cfg config.Config, store repository.Store) *service.EmailService {
+	if cfg.SMTPHost == "" {
+		return nil
+	}
+	notifier, err := notify.NewSMTPNotifier(notify.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+	if err != nil {
+		fatal("Configuração SMTP inválida", "err", err)
+	}
+	tokenService, err := auth.NewTokenService(cfg.JWTSecret)
+	if err != nil {
+		fatal("Falha ao iniciar TokenService", "err", err)
 	}
-	log.Println("Servidor encerrado.")
+	return service.NewEmailService(store, notifier, tokenService, cfg.EmailVerifyURL)
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"secureshare-backend/internal/metrics"
+	"secureshare-backend/internal/repository"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// startMetricsServer registra as métricas do banco e da fila de jobs e sobe
+// o listener de administração com /metrics em METRICS_PORT. Retorna nil se
+// a porta não estiver configurada.
+func startMetricsServer(port int, store repository.Store) *http.Server {
+	if port == 0 {
+		return nil
+	}
+
+	switch s := store.(type) {
+	case *repository.PostgresStore:
+		metrics.Registry.MustRegister(metrics.NewPoolCollector(s.Pool()))
+	case *repository.SQLiteStore:
+		metrics.Registry.MustRegister(collectors.NewDBStatsCollector(s.DB(), "sqlite"))
+	}
+	metrics.Registry.MustRegister(metrics.NewJobQueueCollector(store))
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", port),
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+	go func() {
+		slog.Info("Métricas disponíveis", "addr", fmt.Sprintf("http://localhost:%d/metrics", port))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Erro ao iniciar o listener de métricas", "err", err)
+		}
+	}()
+	return srv
+}
+
+// stopMetricsServer encerra o listener de métricas, se houver
+func stopMetricsServer(ctx context.Context, srv *http.Server) {
+	if srv == nil {
+		return
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("Erro ao encerrar o listener de métricas", "err", err)
+	}
+}
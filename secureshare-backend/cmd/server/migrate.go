@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"secureshare-backend/internal/migrate"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/migrations"
+)
+
+const migrateUsage = `uso: server migrate <comando>
+
+comandos:
+  up          aplica todas as migrações pendentes
+  down        reverte a última migração aplicada
+  to <versão> migra (para cima ou para baixo) até a versão indicada; 0 reverte tudo
+  status      lista as migrações e se estão aplicadas
+  force <v>   marca <v> como versão atual e limpa o estado sujo, sem executar SQL
+
+Só DATABASE_URL é necessária. Com SQLite não há estado sujo, nem force.`
+
+// schemaMigrator é o que os dois backends (PostgreSQL e SQLite) oferecem
+type schemaMigrator interface {
+	Up(ctx context.Context) error
+	Down(ctx context.Context) error
+	To(ctx context.Context, target int64) error
+	Status(ctx context.Context) ([]migrate.Status, error)
+	Latest() int64
+}
+
+// openStore abre o store indicado por DATABASE_URL (sqlite://... ou uma
+// URL do PostgreSQL) e o migrador correspondente
+func openStore(ctx context.Context, databaseURL string) (repository.Store, schemaMigrator, func(), error) {
+	if repository.IsSQLiteURL(databaseURL) {
+		store, err := repository.NewSQLiteStore(ctx, databaseURL)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		migrator, err := migrate.NewSQLite(store.DB(), migrations.SQLiteFS)
+		if err != nil {
+			store.Close()
+			return nil, nil, nil, err
+		}
+		return store, migrator, store.Close, nil
+	}
+
+	store, err := repository.NewPostgresStore(ctx, databaseURL)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	migrator, err := migrate.New(store.Pool(), migrations.FS)
+	if err != nil {
+		store.Close()
+		return nil, nil, nil, err
+	}
+	return store, migrator, store.Close, nil
+}
+
+// runMigrateCommand implementa o subcomando "server migrate"
+func runMigrateCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		fatal("DATABASE_URL não definida")
+	}
+
+	ctx := context.Background()
+	_, migrator, closeStore, err := openStore(ctx, databaseURL)
+	if err != nil {
+		fatal("Falha ao abrir o banco de dados", "err", err)
+	}
+	defer closeStore()
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "to", "force":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			os.Exit(2)
+		}
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil || version < 0 {
+			fatal("Versão inválida", "version", args[1])
+		}
+		if args[0] == "to" {
+			err = migrator.To(ctx, version)
+			break
+		}
+		pg, ok := migrator.(*migrate.Migrator)
+		if !ok {
+			fatal("Comando não suportado com SQLite", "command", args[0])
+		}
+		err = pg.Force(ctx, version)
+	case "status":
+		err = printMigrationStatus(ctx, migrator)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fatal("Erro na migração", "err", err)
+	}
+}
+
+func printMigrationStatus(ctx context.Context, migrator schemaMigrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSÃO\tNOME\tESTADO\tAPLICADA EM")
+	for _, st := range statuses {
+		state, appliedAt := "pendente", "-"
+		if st.Applied {
+			state = "aplicada"
+			appliedAt = st.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		if st.Dirty {
+			state = "SUJA"
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
+
+// checkSchema roda no start do servidor: recusa um schema sujo e aplica (ou
+// exige) as migrações pendentes
+func checkSchema(ctx context.Context, migrator schemaMigrator, autoMigrate bool) {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		fatal("Falha ao ler o estado do schema", "err", err)
+	}
+
+	pending := 0
+	for _, st := range statuses {
+		if st.Dirty {
+			fatal("Schema do banco inválido", "version", st.Version, "err", migrate.ErrDirty)
+		}
+		if !st.Applied {
+			pending++
+		}
+	}
+	if pending == 0 {
+		slog.Info("Schema atualizado", "version", migrator.Latest())
+		return
+	}
+
+	if !autoMigrate {
+		fatal("Migrações pendentes e AUTO_MIGRATE desabilitado; rode 'server migrate up'", "pending", pending)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		fatal("Falha ao aplicar migrações", "err", err)
+	}
+	slog.Info("Schema migrado", "version", migrator.Latest())
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"secureshare-backend/internal/config"
+	"secureshare-backend/internal/jobs"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// startWorkers inicia a fila de jobs em background. O WaitGroup retornado
+// termina quando ela para (após ctx ser cancelado).
+func startWorkers(ctx context.Context, cfg config.Config, store repository.Store, s3Service *service.S3Service) *sync.WaitGroup {
+	runner := jobs.NewRunner(store)
+	runner.Register(service.JobKindBlobDeletion, service.BlobDeletionJob(s3Service), jobs.Options{})
+	collector := service.NewOrphanCollector(store, s3Service, cfg.BlobGCGrace)
+	runner.Register(service.JobKindOrphanGC, collector.RunJob, jobs.Options{MaxAttempts: 3})
+	if cfg.BlobGCSchedule != "" {
+		if err := runner.Schedule(service.JobKindOrphanGC, cfg.BlobGCSchedule, service.JobKindOrphanGC, nil); err != nil {
+			fatal("BLOB_GC_SCHEDULE inválido", "err", err)
+		}
+	}
+	webhooks := newWebhookService(cfg, store)
+	runner.Register(service.JobKindWebhookDelivery, webhooks.RunDeliveryJob, jobs.Options{MaxAttempts: service.WebhookMaxAttempts})
+	if emails := newEmailService(cfg, store); emails != nil {
+		runner.Register(service.JobKindEmailVerification, emails.RunVerificationJob, jobs.Options{MaxAttempts: 5})
+		runner.Register(service.JobKindEmailTransferReceived, emails.RunTransferReceivedJob, jobs.Options{MaxAttempts: 5})
+	}
+	runner.Register(service.JobKindPruneEvents, service.PruneEventsJob(store, cfg.EventsRetention), jobs.Options{MaxAttempts: 3})
+	if err := runner.Schedule(service.JobKindPruneEvents, "@hourly", service.JobKindPruneEvents, nil); err != nil {
+		fatal("Falha ao agendar job", "kind", service.JobKindPruneEvents, "err", err)
+	}
+	runner.Register(service.JobKindPruneIdempotency, service.PruneIdempotencyJob(store), jobs.Options{MaxAttempts: 3})
+	if err := runner.Schedule(service.JobKindPruneIdempotency, "@hourly", service.JobKindPruneIdempotency, nil); err != nil {
+		fatal("Falha ao agendar job", "kind", service.JobKindPruneIdempotency, "err", err)
+	}
+	slog.Info("Fila de jobs iniciada", "kinds", runner.Kinds())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		runner.Run(ctx)
+	}()
+	return &wg
+}
+
+// runWorkerCommand implementa o subcomando "server worker": só os workers,
+// sem a API HTTP. Usa a mesma configuração do servidor.
+func runWorkerCommand() {
+	var cfg config.Config
+	if err := config.Load(&cfg); err != nil {
+		fatal("Falha ao carregar configuração", "err", err)
+	}
+
+	initCtx, cancelInit := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelInit()
+
+	store, migrator, closeStore, err := openStore(initCtx, cfg.DatabaseURL)
+	if err != nil {
+		fatal("Falha ao conectar ao banco de dados", "err", err)
+	}
+	defer closeStore()
+	checkSchema(initCtx, migrator, cfg.AutoMigrate)
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(initCtx, awsconfig.WithRegion(cfg.AWSRegion))
+	if err != nil {
+		fatal("Falha ao carregar configuração AWS SDK", "err", err)
+	}
+	s3Service := service.NewS3Service(s3.NewFromConfig(awsCfg), cfg.AWSBucketName)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	workers := startWorkers(ctx, cfg, store, s3Service)
+	slog.Info("Worker iniciado")
+	metricsServer := startMetricsServer(cfg.MetricsPort, store)
+
+	<-ctx.Done()
+	slog.Info("Recebido sinal de desligamento, aguardando os jobs em andamento")
+	workers.Wait()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stopMetricsServer(shutdownCtx, metricsServer)
+	slog.Info("Worker encerrado")
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+)
+
+func TestAPIKeyScopes(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	tokens, err := auth.NewTokenService("segredo-de-teste-com-32-bytes!!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := service.NewUserService(store, tokens)
+	keys := service.NewAPIKeyService(store, store)
+	h := &Handler{userService: users, apiKeyService: keys, validate: newValidator()}
+	routes := h.Routes()
+
+	alice := newTestUser(t, store, "alice")
+	bot, err := users.CreateServiceAccount(ctx, alice, "alice-bot", "pk-bot", "pks-bot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey := func(scope string, allowedIPs ...string) string {
+		raw, _, err := keys.CreateAPIKey(ctx, bot, service.CreateAPIKeyRequest{Name: scope, Scopes: []string{scope}, AllowedIPs: allowedIPs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	usersKey := newKey(auth.ScopeUsersRead)
+	transfersKey := newKey(auth.ScopeTransfersRead)
+	// httptest.NewRequest usa 192.0.2.1 como origem
+	otherNetworkKey := newKey(auth.ScopeUsersRead, "10.0.0.0/8")
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		status int
+		code   apperr.Code
+	}{
+		{"escopo certo", http.MethodGet, "/v1/users", usersKey, http.StatusOK, ""},
+		{"sem o escopo", http.MethodGet, "/v1/users", transfersKey, http.StatusForbidden, apperr.CodeMissingScope},
+		{"rota só de sessão", http.MethodGet, "/v1/devices", usersKey, http.StatusForbidden, apperr.CodeSessionRequired},
+		{"IP fora da lista", http.MethodGet, "/v1/users", otherNetworkKey, http.StatusUnauthorized, apperr.CodeAPIKeyIPDenied},
+		{"chave inválida", http.MethodGet, "/v1/users", "ssk_invalida", http.StatusUnauthorized, apperr.CodeInvalidAPIKey},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			req.Header.Set("Authorization", "Bearer "+c.key)
+			rec := httptest.NewRecorder()
+			routes.ServeHTTP(rec, req)
+
+			if rec.Code != c.status {
+				t.Fatalf("status %d, esperado %d: %s", rec.Code, c.status, rec.Body)
+			}
+			if c.code == "" {
+				return
+			}
+			var problem Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil || problem.Code != string(c.code) {
+				t.Fatalf("código %q, esperado %q (%v)", problem.Code, c.code, err)
+			}
+		})
+	}
+}
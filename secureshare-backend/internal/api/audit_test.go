@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+)
+
+func TestAdminAuditRoute(t *testing.T) {
+	store := repository.NewInMemoryStore()
+	h := &Handler{auditService: service.NewAuditService(store)}
+	h.SetAdmins([]string{"root"})
+
+	for _, target := range []string{"user:alice", "user:bob", "user:alice"} {
+		h.auditService.Record(context.Background(), &models.AuditEvent{Type: service.AuditLoginFailed, Target: target})
+	}
+	route := h.RequireAdmin(http.HandlerFunc(h.handleListAuditEvents))
+
+	get := func(user *models.User, apiKey *models.APIKey, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/audit"+query, nil)
+		ctx := context.WithValue(req.Context(), userContextKey, user)
+		if apiKey != nil {
+			ctx = context.WithValue(ctx, apiKeyContextKey, apiKey)
+		}
+		rec := httptest.NewRecorder()
+		route.ServeHTTP(rec, req.WithContext(ctx))
+		return rec
+	}
+	root := &models.User{ID: uuid.New(), Username: "root", Kind: models.UserKindHuman}
+	alice := &models.User{ID: uuid.New(), Username: "alice", Kind: models.UserKindHuman}
+
+	if rec := get(alice, nil, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("usuário comum: esperava 403, obteve %d", rec.Code)
+	}
+	if rec := get(root, &models.APIKey{ID: uuid.New()}, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("API key: esperava 403, obteve %d", rec.Code)
+	}
+	if rec := get(root, nil, "?since=ontem"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("since inválido: esperava 400, obteve %d", rec.Code)
+	}
+
+	rec := get(root, nil, "?target=user:alice&limit=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin: esperava 200, obteve %d: %s", rec.Code, rec.Body)
+	}
+	var page AuditEventsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Events) != 1 || page.Events[0].Seq != 3 || page.NextBefore != 3 {
+		t.Fatalf("primeira página inesperada: %+v", page)
+	}
+
+	rec = get(root, nil, "?target=user:alice&limit=1&before=3")
+	page = AuditEventsResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Events) != 1 || page.Events[0].Seq != 1 || page.NextBefore != 0 {
+		t.Fatalf("última página inesperada: %+v", page)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/service"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Limites do corpo JSON das requisições, por rota (ver decodeJSON)
+const (
+	// maxJSONBody vale para a maioria das rotas: credenciais, chaves
+	// públicas, configurações
+	maxJSONBody = 16 << 10
+	// maxKeyBackupBody cabe o maior backup aceito (keybackup.MaxCiphertextLen)
+	// e os parâmetros do KDF
+	maxKeyBackupBody = 128 << 10
+	// maxTransferBody cabe uma SKB por dispositivo do destinatário
+	maxTransferBody = 256 << 10
+)
+
+// decodeJSON decodifica o corpo da requisição em dst, lendo no máximo limit
+// bytes. Campos desconhecidos e dados após o objeto são recusados. Em caso
+// de erro, já responde (413 ou 400) e retorna false.
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, dst any, limit int64) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		// Um único objeto JSON: "{...}{...}" ou "{...} lixo" são recusados
+		if _, extra := dec.Token(); extra != io.EOF {
+			err = errTrailingData
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	var maxErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &maxErr):
+		h.respondWithError(w, r, http.StatusRequestEntityTooLarge, apperr.CodeBodyTooLarge,
+			fmt.Sprintf("Corpo da requisição maior que %d bytes", maxErr.Limit))
+	case errors.Is(err, io.EOF):
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidJSON, "Corpo da requisição vazio")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// O encoding/json não exporta um tipo para este erro
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		h.respondWithProblem(w, r, http.StatusBadRequest, apperr.CodeUnknownField, "Campo desconhecido: "+field,
+			[]FieldError{{Field: field, Code: "unknown", Message: fieldRuleMessage(requestLocale(r), "unknown", "")}})
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		h.respondWithProblem(w, r, http.StatusBadRequest, apperr.CodeInvalidJSON, "Payload JSON inválido: tipo incorreto em "+typeErr.Field,
+			[]FieldError{{Field: typeErr.Field, Code: "type", Message: fieldRuleMessage(requestLocale(r), "type", typeErr.Type.String())}})
+	default:
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidJSON, "Payload JSON inválido")
+	}
+	return false
+}
+
+var errTrailingData = errors.New("dados após o objeto JSON")
+
+// newValidator cria o validador dos corpos, que reporta os campos pelo nome
+// no JSON. Regras próprias: upload_key (chave gerada por
+// /transfers/upload-url).
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(jsonFieldName)
+	v.RegisterValidation("upload_key", func(fl validator.FieldLevel) bool {
+		return service.IsUploadKey(fl.Field().String())
+	})
+	return v
+}
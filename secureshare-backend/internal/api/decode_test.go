@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"secureshare-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	h := &Handler{}
+	type body struct {
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+	}
+
+	cases := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{"válido", `{"name":"a","size":1}`, http.StatusOK, "", ""},
+		{"espaços ao final", "{\"name\":\"a\"}\n", http.StatusOK, "", ""},
+		{"vazio", ``, http.StatusBadRequest, "INVALID_JSON", ""},
+		{"sintaxe", `{"name":`, http.StatusBadRequest, "INVALID_JSON", ""},
+		{"campo desconhecido", `{"nome":"a"}`, http.StatusBadRequest, "UNKNOWN_FIELD", "nome"},
+		{"tipo incorreto", `{"size":"grande"}`, http.StatusBadRequest, "INVALID_JSON", "size"},
+		{"dois objetos", `{"name":"a"}{"name":"b"}`, http.StatusBadRequest, "INVALID_JSON", ""},
+		{"lixo após o objeto", `{"name":"a"} x`, http.StatusBadRequest, "INVALID_JSON", ""},
+		{"acima do limite", `{"name":"` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			var dst body
+			if ok := h.decodeJSON(rec, req, &dst, 64); ok != (tc.status == http.StatusOK) {
+				t.Fatalf("decodeJSON = %v, resposta %d %s", ok, rec.Code, rec.Body)
+			}
+			if tc.status == http.StatusOK {
+				return
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tc.status || problem.Code != tc.code {
+				t.Fatalf("resposta %d %+v, esperado %d %s", rec.Code, problem, tc.status, tc.code)
+			}
+			if tc.field != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != tc.field) {
+				t.Fatalf("erros de campo %+v, esperado %s", problem.Errors, tc.field)
+			}
+		})
+	}
+}
+
+func TestCreateTransferValidation(t *testing.T) {
+	// Os corpos recusados não chegam ao serviço (transferService nil)
+	h := &Handler{validate: newValidator()}
+	alice := &models.User{ID: uuid.New(), Username: "alice"}
+	link := "uploads/" + alice.ID.String() + "/" + uuid.NewString()
+
+	cases := []struct {
+		name   string
+		body   string
+		code   string
+		fields []string
+	}{
+		{"campos ausentes", `{}`, "VALIDATION_FAILED", []string{"destUser", "linkToEncFile", "sig"}},
+		{"base64 inválido", `{"destUser":"bob","linkToEncFile":"` + link + `","skb":"não é base64","sig":"c2ln!"}`,
+			"VALIDATION_FAILED", []string{"skb", "sig"}},
+		{"chave de objeto inválida", `{"destUser":"bob","linkToEncFile":"uploads/../segredo","skb":"c2ti","sig":"c2ln"}`,
+			"VALIDATION_FAILED", []string{"linkToEncFile"}},
+		{"SKB por dispositivo inválida", `{"destUser":"bob","linkToEncFile":"` + link + `","sig":"c2ln","skbs":{"x":"c2tis"}}`,
+			"VALIDATION_FAILED", []string{"skbs[x]", "skbs[x]"}},
+		{"sem SKB", `{"destUser":"bob","linkToEncFile":"` + link + `","sig":"c2ln"}`, "EMPTY_SKB", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/transfers", strings.NewReader(tc.body))
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, alice))
+			rec := httptest.NewRecorder()
+			h.handleCreateTransfer(rec, req)
+
+			var problem Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusBadRequest || problem.Code != tc.code || len(problem.Errors) != len(tc.fields) {
+				t.Fatalf("resposta %d %+v", rec.Code, problem)
+			}
+			for i, fe := range problem.Errors {
+				if fe.Field != tc.fields[i] {
+					t.Errorf("campo %d: %s, esperado %s", i, fe.Field, tc.fields[i])
+				}
+			}
+		})
+	}
+}
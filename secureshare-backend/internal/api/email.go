@@ -0,0 +1,123 @@
+package api
+
+import (
+	"net/http"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/i18n"
+	"secureshare-backend/internal/models"
+)
+
+// === Handlers de E-mail ===
+
+type (
+	// EmailSettingsResponse é o e-mail de aviso do usuário autenticado
+	EmailSettingsResponse struct {
+		Email    string `json:"email"` // vazio: sem e-mail
+		Verified bool   `json:"verified"`
+		Locale   string `json:"locale"`
+	}
+
+	// EmailSettingsRequest (PUT /users/me/email)
+	EmailSettingsRequest struct {
+		Email  string `json:"email" validate:"required"`
+		Locale string `json:"locale,omitempty"` // vazio mantém o atual
+	}
+
+	// VerifyEmailRequest (POST /users/verify-email)
+	VerifyEmailRequest struct {
+		Token string `json:"token" validate:"required"`
+	}
+)
+
+func emailSettings(user *models.User) EmailSettingsResponse {
+	locale := user.Locale
+	if locale == "" {
+		locale = i18n.DefaultLocale
+	}
+	return EmailSettingsResponse{
+		Email:    user.Email,
+		Verified: user.Email != "" && user.EmailVerifiedAt != nil,
+		Locale:   locale,
+	}
+}
+
+// handleGetEmail (GET /users/me/email)
+func (h *Handler) handleGetEmail(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, emailSettings(user))
+}
+
+// handlePutEmail (PUT /users/me/email)
+// Cadastra ou troca o e-mail e envia o pedido de confirmação; responde 202,
+// já que os avisos só começam depois da confirmação.
+func (h *Handler) handlePutEmail(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	var req EmailSettingsRequest
+
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.respondWithValidationError(w, r, err)
+		return
+	}
+
+	updated, err := h.emailService.SetEmail(r.Context(), user, req.Email, req.Locale)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusAccepted, emailSettings(updated))
+}
+
+// handleDeleteEmail (DELETE /users/me/email)
+func (h *Handler) handleDeleteEmail(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	if err := h.emailService.RemoveEmail(r.Context(), user); err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleVerifyEmail (POST /users/verify-email)
+// Público: o token enviado por e-mail já identifica o usuário.
+func (h *Handler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.respondWithValidationError(w, r, err)
+		return
+	}
+
+	user, err := h.emailService.VerifyEmail(r.Context(), req.Token)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, emailSettings(user))
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/i18n"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+)
+
+// problemContentType é o tipo das respostas de erro (RFC 9457)
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix forma o "type" do Problem a partir do código
+const problemTypePrefix = "urn:secureshare:problem:"
+
+type (
+	// Problem é o corpo de todas as respostas de erro (RFC 9457). Code é
+	// estável (ver internal/apperr) e Title vem no idioma de
+	// Accept-Language; Detail, específico da ocorrência, vem sempre, mas
+	// só as mensagens de validação são traduzidas (as dos serviços são em
+	// pt-BR).
+	Problem struct {
+		Type      string       `json:"type"`
+		Title     string       `json:"title"`
+		Status    int          `json:"status"`
+		Detail    string       `json:"detail,omitempty"`
+		Instance  string       `json:"instance,omitempty"`
+		Code      string       `json:"code"`
+		RequestID string       `json:"requestId,omitempty"`
+		Errors    []FieldError `json:"errors,omitempty"`
+	}
+
+	// FieldError é um campo recusado pela validação do corpo. Code é a
+	// regra violada (ex: "required", "min").
+	FieldError struct {
+		Field   string `json:"field"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+)
+
+// statusForError é o único ponto que traduz erros de stores e serviços em
+// status HTTP. Decide pela categoria (ver internal/apperr), nunca pelo texto.
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, apperr.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, apperr.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, apperr.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apperr.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperr.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperr.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, apperr.ErrQuotaExceeded):
+		// 507, como no WebDAV: o pedido é válido, mas não há espaço na cota
+		return http.StatusInsufficientStorage
+	case errors.Is(err, apperr.ErrUnprocessable):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondWithAppError responde com o status e o código do erro. Erros sem
+// categoria viram 500; os serviços já os reescrevem como "erro interno ...",
+// sem detalhes do store.
+func (h *Handler) respondWithAppError(w http.ResponseWriter, r *http.Request, err error) {
+	h.respondWithError(w, r, statusForError(err), apperr.CodeOf(err), err.Error())
+}
+
+// respondWithError responde com um Problem. message é o Detail.
+func (h *Handler) respondWithError(w http.ResponseWriter, r *http.Request, status int, code apperr.Code, message string) {
+	h.respondWithProblem(w, r, status, code, message, nil)
+}
+
+// respondWithValidationError responde 400 ao erro de h.validate.Struct,
+// com um FieldError por campo recusado
+func (h *Handler) respondWithValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	locale := requestLocale(r)
+	var fields []FieldError
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		fields = make([]FieldError, 0, len(invalid))
+		for _, fe := range invalid {
+			fields = append(fields, FieldError{
+				Field:   fe.Field(),
+				Code:    fe.Tag(),
+				Message: fieldMessage(locale, fe),
+			})
+		}
+	}
+	h.respondWithProblem(w, r, http.StatusBadRequest, apperr.CodeValidation, validationDetail(locale, fields), fields)
+}
+
+// validationDetail resume os campos recusados no idioma (ex: "Dados
+// inválidos: password deve ter no mínimo 8 caracteres"). O texto do
+// validator não é usado: expõe nomes de structs Go e não é traduzido.
+func validationDetail(locale string, fields []FieldError) string {
+	detail := problemTitle(locale, apperr.CodeValidation)
+	if len(fields) == 0 {
+		return detail
+	}
+	parts := make([]string, 0, len(fields))
+	for _, fe := range fields {
+		parts = append(parts, fe.Field+" "+fe.Message)
+	}
+	return detail + ": " + strings.Join(parts, "; ")
+}
+
+func (h *Handler) respondWithProblem(w http.ResponseWriter, r *http.Request, status int, code apperr.Code, message string, fields []FieldError) {
+	locale := requestLocale(r)
+	problem := Problem{
+		Type:      problemTypePrefix + string(code),
+		Title:     problemTitle(locale, code),
+		Status:    status,
+		Detail:    message,
+		Instance:  r.URL.Path,
+		Code:      string(code),
+		RequestID: middleware.GetReqID(r.Context()),
+		Errors:    fields,
+	}
+
+	w.Header().Set("Content-Language", locale)
+	writeJSON(w, status, problemContentType, problem)
+}
+
+// requestLocale escolhe o idioma das mensagens pelo Accept-Language
+func requestLocale(r *http.Request) string {
+	return i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// jsonFieldName faz o validator reportar os campos pelo nome no JSON
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
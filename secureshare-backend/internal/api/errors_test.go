@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+)
+
+func TestStatusForError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{apperr.Validation("SKB vazia"), http.StatusBadRequest},
+		{apperr.Unauthorized("credenciais inválidas"), http.StatusUnauthorized},
+		{apperr.Forbidden("senha atual incorreta"), http.StatusForbidden},
+		{apperr.NotFound("usuário não encontrado"), http.StatusNotFound},
+		{apperr.Conflict("usuário 'x' já existe"), http.StatusConflict},
+		{apperr.TooLarge("arquivo maior que 5 GiB"), http.StatusRequestEntityTooLarge},
+		{apperr.QuotaExceeded("cota excedida"), http.StatusInsufficientStorage},
+		{apperr.Unprocessable("chave reutilizada"), http.StatusUnprocessableEntity},
+		// O status não depende do texto: mensagem "de conflito" sem categoria é 500
+		{errors.New("usuário 'x' já existe"), http.StatusInternalServerError},
+		// Categorias sobrevivem a wrapping com %w
+		{fmt.Errorf("contexto: %w", apperr.NotFound("x")), http.StatusNotFound},
+		{apperr.Wrap(apperr.ErrValidation, errors.New("causa"), "backup inválido"), http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		if got := statusForError(tc.err); got != tc.want {
+			t.Errorf("statusForError(%q) = %d, esperado %d", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	cases := []struct {
+		err  error
+		want apperr.Code
+	}{
+		{apperr.New(apperr.ErrConflict, apperr.CodeUserExists, "usuário '%s' já existe", "x"), apperr.CodeUserExists},
+		// Sem código próprio, vale o padrão da categoria
+		{apperr.NotFound("x"), apperr.CodeNotFound},
+		{errors.New("falha no banco"), apperr.CodeInternal},
+		{fmt.Errorf("contexto: %w", apperr.New(apperr.ErrNotFound, apperr.CodeDestUserNotFound, "x")), apperr.CodeDestUserNotFound},
+		// O código do erro externo prevalece sobre o da causa
+		{apperr.WrapCode(apperr.ErrValidation, apperr.CodeInvalidKeyFormat, apperr.New(apperr.ErrValidation, apperr.CodeInvalidKeyBackup, "x"), "y"), apperr.CodeInvalidKeyFormat},
+		{apperr.Wrap(apperr.ErrValidation, apperr.New(apperr.ErrValidation, apperr.CodeInvalidKeyBackup, "x"), "y"), apperr.CodeInvalidKeyBackup},
+	}
+	for _, tc := range cases {
+		if got := apperr.CodeOf(tc.err); got != tc.want {
+			t.Errorf("CodeOf(%q) = %s, esperado %s", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	store := repository.NewInMemoryStore()
+	newTestUser(t, store, "alice")
+	h := &Handler{userService: service.NewUserService(store, nil), validate: newValidator()}
+	routes := h.Routes()
+
+	do := func(method, path, body, lang string) (*httptest.ResponseRecorder, Problem) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Accept-Language", lang)
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+			t.Fatalf("%s %s: Content-Type %q", method, path, ct)
+		}
+		var problem Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("%s %s: corpo inválido: %v", method, path, err)
+		}
+		return rec, problem
+	}
+
+	register := `{"username":"alice","password":"senha-longa","publicKey":"pk","publicKeySign":"pks"}`
+	rec, problem := do(http.MethodPost, "/v1/users/register", register, "")
+	if rec.Code != http.StatusConflict || problem.Code != "USER_EXISTS" || problem.Status != http.StatusConflict ||
+		problem.Type != "urn:secureshare:problem:USER_EXISTS" || problem.Title != "Usuário já existe" ||
+		problem.Detail != "usuário 'alice' já existe" || problem.Instance != "/v1/users/register" {
+		t.Fatalf("conflito: %d %+v", rec.Code, problem)
+	}
+	if problem.RequestID == "" || rec.Header().Get("X-Request-Id") != problem.RequestID {
+		t.Fatalf("requestId %q, header %q", problem.RequestID, rec.Header().Get("X-Request-Id"))
+	}
+
+	// Em inglês, o título é traduzido; a mensagem do serviço vem como está
+	rec, problem = do(http.MethodPost, "/v1/users/register", register, "en-US,en;q=0.9")
+	if problem.Title != "User already exists" || problem.Detail != "usuário 'alice' já existe" || rec.Header().Get("Content-Language") != "en" {
+		t.Fatalf("em inglês: %+v %v", problem, rec.Header())
+	}
+
+	rec, problem = do(http.MethodPost, "/v1/users/register", `{"username":"bob","password":"curta"}`, "en")
+	want := map[string]string{"password": "min", "publicKey": "required", "publicKeySign": "required"}
+	if rec.Code != http.StatusBadRequest || problem.Code != "VALIDATION_FAILED" || len(problem.Errors) != len(want) {
+		t.Fatalf("validação: %d %+v", rec.Code, problem)
+	}
+	for _, fe := range problem.Errors {
+		if want[fe.Field] != fe.Code || fe.Message == "" {
+			t.Errorf("campo inesperado: %+v", fe)
+		}
+	}
+	// O detail é traduzido e não vaza o texto do validator
+	if !strings.HasPrefix(problem.Detail, "Invalid data: ") || !strings.Contains(problem.Detail, "publicKey is required") ||
+		strings.Contains(problem.Detail, "RegisterRequest") || strings.Contains(problem.Detail, "Key:") {
+		t.Errorf("detail da validação: %q", problem.Detail)
+	}
+
+	if rec, problem = do(http.MethodPost, "/v1/users/register", `{`, ""); rec.Code != http.StatusBadRequest || problem.Code != "INVALID_JSON" {
+		t.Fatalf("JSON inválido: %d %+v", rec.Code, problem)
+	}
+	if rec, problem = do(http.MethodGet, "/v1/nada", "", ""); rec.Code != http.StatusNotFound || problem.Code != "ROUTE_NOT_FOUND" {
+		t.Fatalf("rota inexistente: %d %+v", rec.Code, problem)
+	}
+	if rec, problem = do(http.MethodGet, "/v1/users/register", "", ""); rec.Code != http.StatusMethodNotAllowed || problem.Code != "METHOD_NOT_ALLOWED" {
+		t.Fatalf("método errado: %d %+v", rec.Code, problem)
+	}
+}
+
+// TestProblemTitlesCoverCodes garante que todo código declarado em
+// internal/apperr tem título em todos os idiomas
+func TestProblemTitlesCoverCodes(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../apperr/codes.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	declared := 0
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || len(spec.Values) != 1 {
+			return true
+		}
+		lit, ok := spec.Values[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		declared++
+		code := apperr.Code(strings.Trim(lit.Value, `"`))
+		for _, locale := range []string{"pt-BR", "en"} {
+			if problemTitles[code][locale] == "" {
+				t.Errorf("%s sem título em %s", code, locale)
+			}
+		}
+		return true
+	})
+	if declared != len(problemTitles) {
+		t.Errorf("%d códigos declarados, %d títulos", declared, len(problemTitles))
+	}
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/service"
+
+	"golang.org/x/net/websocket"
+)
+
+// eventsKeepAlive é o intervalo entre comentários (SSE) ou pings
+// (WebSocket) numa conexão ociosa, abaixo do timeout comum de proxies. A
+// cada keepalive a credencial do stream é conferida de novo.
+var eventsKeepAlive = 25 * time.Second
+
+// eventsWriteTimeout limita cada escrita: um cliente que não lê é
+// desconectado em vez de prender a conexão
+const eventsWriteTimeout = 10 * time.Second
+
+// eventsQueryToken aceita o token em ?access_token= nas rotas /v1/events,
+// já que EventSource e WebSocket do navegador não enviam o header
+// Authorization. O token é movido para o header antes do log de acesso,
+// para não aparecer na URL registrada.
+func eventsQueryToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v1/events") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		query := r.URL.Query()
+		token := query.Get("access_token")
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		query.Del("access_token")
+		r.URL.RawQuery = query.Encode()
+		r.RequestURI = r.URL.RequestURI()
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// lastEventID lê o ID do último evento recebido pelo cliente: o header
+// Last-Event-ID (reconexão automática do EventSource) ou ?lastEventId=
+func lastEventID(r *http.Request) (int64, error) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("lastEventId")
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("Last-Event-ID inválido")
+	}
+	return id, nil
+}
+
+// credentialExpiry retorna um canal que dispara quando a credencial da
+// requisição expira (exp do JWT ou ExpiresAt da API key); nil se ela não
+// expira
+func credentialExpiry(r *http.Request) (<-chan time.Time, func() bool) {
+	var expiresAt time.Time
+	if key, ok := r.Context().Value(apiKeyContextKey).(*models.APIKey); ok {
+		if key.ExpiresAt != nil {
+			expiresAt = *key.ExpiresAt
+		}
+	} else if session, ok := r.Context().Value(sessionContextKey).(service.Session); ok {
+		expiresAt = session.ExpiresAt
+	}
+	if expiresAt.IsZero() {
+		return nil, func() bool { return false }
+	}
+	timer := time.NewTimer(time.Until(expiresAt))
+	return timer.C, timer.Stop
+}
+
+// streamAuthorized confere de novo a credencial de um stream aberto: depois
+// do AuthMiddleware a sessão pode ter sido revogada, a API key revogada ou
+// a conta removida
+func (h *Handler) streamAuthorized(r *http.Request, user *models.User) bool {
+	current, _, _, err := h.authenticate(r)
+	if err != nil {
+		logging.FromContext(r.Context()).Info("Stream de eventos encerrado: credencial recusada", "code", apperr.CodeOf(err))
+		return false
+	}
+	return current.ID == user.ID
+}
+
+// handleEvents (GET /events) entrega os eventos do usuário por Server-Sent
+// Events: primeiro os guardados depois do Last-Event-ID, depois os novos,
+// até o cliente desconectar
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+	lastID, err := lastEventID(r)
+	if err != nil {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	// A conexão fica aberta além do ReadTimeout/WriteTimeout do servidor
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		logging.FromContext(r.Context()).Error("SSE: não foi possível remover o prazo de leitura", "err", err)
+	}
+
+	// Assina antes do replay: eventos gravados no meio chegam pelo canal e
+	// os repetidos são descartados pelo ID
+	sub := h.eventHub.Subscribe(user.ID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // sem buffer no nginx
+	w.WriteHeader(http.StatusOK)
+
+	write := func(frame string) error {
+		rc.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+		if _, err := fmt.Fprint(w, frame); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	send := func(e *models.InboxEvent) error {
+		if e.ID <= lastID {
+			return nil
+		}
+		lastID = e.ID
+		return write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data))
+	}
+
+	if err := write("retry: 3000\n\n"); err != nil {
+		return
+	}
+	if err := h.eventHub.Replay(r.Context(), user.ID, lastID, send); err != nil {
+		return
+	}
+
+	// Com a credencial expirada ou recusada o stream termina; a reconexão
+	// do cliente recebe 401
+	expired, stopExpiry := credentialExpiry(r)
+	defer stopExpiry()
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expired:
+			return
+		case e, ok := <-sub.Events:
+			if !ok {
+				return // o cliente reconecta com Last-Event-ID
+			}
+			if err := send(e); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if !h.streamAuthorized(r, user) {
+				return
+			}
+			if err := write(": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleEventsWebSocket (GET /events/ws) entrega os mesmos eventos de GET
+// /events por WebSocket, um JSON ({id, type, data, createdAt}) por
+// mensagem. A retomada usa ?lastEventId=. Navegadores só conectam a partir
+// das origens do CORS.
+func (h *Handler) handleEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+	lastID, err := lastEventID(r)
+	if err != nil {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	// Sem Origin o cliente não é um navegador
+	if origin := r.Header.Get("Origin"); origin != "" && !slices.Contains(allowedOrigins, origin) {
+		h.respondWithError(w, r, http.StatusForbidden, apperr.CodeOriginNotAllowed, "Origem não permitida")
+		return
+	}
+
+	server := websocket.Server{
+		// A Origin já foi conferida acima
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			h.serveEventsWebSocket(ws, user, lastID)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+func (h *Handler) serveEventsWebSocket(ws *websocket.Conn, user *models.User, lastID int64) {
+	defer ws.Close()
+	// A conexão sequestrada mantém os prazos do servidor HTTP
+	ws.SetDeadline(time.Time{})
+
+	// O cliente não envia nada; ler só serve para notar o fechamento
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		var discard string
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	sub := h.eventHub.Subscribe(user.ID)
+	defer sub.Close()
+
+	send := func(e *models.InboxEvent) error {
+		if e.ID <= lastID {
+			return nil
+		}
+		lastID = e.ID
+		ws.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+		return websocket.JSON.Send(ws, e)
+	}
+	if err := h.eventHub.Replay(ctx, user.ID, lastID, send); err != nil {
+		return
+	}
+
+	expired, stopExpiry := credentialExpiry(ws.Request())
+	defer stopExpiry()
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired:
+			return
+		case e, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := send(e); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if !h.streamAuthorized(ws.Request(), user) {
+				return
+			}
+			ws.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+			ws.PayloadType = websocket.PingFrame
+			_, err := ws.Write(nil)
+			ws.PayloadType = websocket.TextFrame
+			if err != nil {
+				return
+			}
+		}
+	}
+}
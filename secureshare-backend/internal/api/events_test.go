@@ -0,0 +1,315 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+// eventsTestServer serve as rotas da API com o hub de eventos, autenticando
+// com tokens de tokens
+func eventsTestServer(t *testing.T, store *repository.InMemoryStore) (*httptest.Server, *auth.TokenService) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	hub := service.NewEventHub(store)
+	hub.SetPollInterval(10 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+	// Run lê o último ID ao começar; eventos anteriores só saem no Replay
+	time.Sleep(50 * time.Millisecond)
+
+	tokens, err := auth.NewTokenService("segredo-de-teste-com-32-bytes!!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{eventHub: hub, userService: service.NewUserService(store, tokens), validate: newValidator()}
+	srv := httptest.NewServer(h.Routes())
+	t.Cleanup(func() {
+		// Parar o hub encerra as conexões abertas antes de fechar o servidor
+		cancel()
+		<-done
+		srv.Close()
+	})
+	return srv, tokens
+}
+
+func newTestToken(t *testing.T, tokens *auth.TokenService, user *models.User) string {
+	t.Helper()
+	token, err := tokens.NewToken(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func appendTestEvent(t *testing.T, store *repository.InMemoryStore, userID uuid.UUID, eventType string) *models.InboxEvent {
+	t.Helper()
+	e := &models.InboxEvent{
+		UserID:    userID,
+		Type:      eventType,
+		Data:      json.RawMessage(`{"transferId":"` + uuid.NewString() + `"}`),
+		CreatedAt: time.Now(),
+	}
+	if err := store.AppendEvent(context.Background(), e); err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+	return e
+}
+
+type sseFrame struct {
+	id, event, data string
+}
+
+// readSSEFrame lê o próximo evento do stream, pulando comentários e o retry
+func readSSEFrame(t *testing.T, r *bufio.Reader) sseFrame {
+	t.Helper()
+	var f sseFrame
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("leitura do stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if f.id != "" {
+				return f
+			}
+		case strings.HasPrefix(line, "id: "):
+			f.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			f.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			f.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestEventsSSEResumesAndStreams(t *testing.T) {
+	store := repository.NewInMemoryStore()
+	alice := newTestUser(t, store, "alice")
+	bob := newTestUser(t, store, "bob")
+	srv, tokens := eventsTestServer(t, store)
+
+	seen := appendTestEvent(t, store, alice.ID, service.EventTransferCreated)
+	missed := appendTestEvent(t, store, alice.ID, service.EventTransferDownloaded)
+	appendTestEvent(t, store, bob.ID, service.EventTransferCreated)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/events", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, tokens, alice))
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(seen.ID, 10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("esperava 200 text/event-stream, obteve %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	stream := bufio.NewReader(resp.Body)
+
+	// Retomada: só o evento depois do Last-Event-ID, e só os de alice
+	f := readSSEFrame(t, stream)
+	if f.id != strconv.FormatInt(missed.ID, 10) || f.event != service.EventTransferDownloaded || f.data != string(missed.Data) {
+		t.Fatalf("evento retomado inesperado: %+v", f)
+	}
+
+	// Ao vivo
+	live := appendTestEvent(t, store, alice.ID, service.EventTransferRevoked)
+	f = readSSEFrame(t, stream)
+	if f.id != strconv.FormatInt(live.ID, 10) || f.event != service.EventTransferRevoked {
+		t.Fatalf("evento ao vivo inesperado: %+v", f)
+	}
+
+	req.Header.Set("Last-Event-ID", "abc")
+	bad, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Last-Event-ID inválido: esperava 400, obteve %d", bad.StatusCode)
+	}
+}
+
+func TestEventsWebSocket(t *testing.T) {
+	store := repository.NewInMemoryStore()
+	alice := newTestUser(t, store, "alice")
+	srv, tokens := eventsTestServer(t, store)
+
+	seen := appendTestEvent(t, store, alice.ID, service.EventTransferCreated)
+	missed := appendTestEvent(t, store, alice.ID, service.EventTransferCreated)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/events/ws?lastEventId=" + strconv.FormatInt(seen.ID, 10) +
+		"&access_token=" + newTestToken(t, tokens, alice)
+	ws, err := websocket.Dial(url, "", "http://localhost:3000")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var got models.InboxEvent
+	if err := websocket.JSON.Receive(ws, &got); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if got.ID != missed.ID || got.Type != service.EventTransferCreated || string(got.Data) != string(missed.Data) {
+		t.Fatalf("evento retomado inesperado: %+v", got)
+	}
+
+	live := appendTestEvent(t, store, alice.ID, service.EventTransferDownloaded)
+	if err := websocket.JSON.Receive(ws, &got); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if got.ID != live.ID || got.Type != service.EventTransferDownloaded {
+		t.Fatalf("evento ao vivo inesperado: %+v", got)
+	}
+}
+
+func TestEventsWebSocketRejectsForeignOrigin(t *testing.T) {
+	store := repository.NewInMemoryStore()
+	alice := newTestUser(t, store, "alice")
+	srv, tokens := eventsTestServer(t, store)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/events/ws?access_token=" + newTestToken(t, tokens, alice)
+
+	if ws, err := websocket.Dial(url, "", "https://evil.example"); err == nil {
+		ws.Close()
+		t.Fatal("conexão aceita de uma origem fora do CORS")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/events/ws", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, tokens, alice))
+	req.Header.Set("Origin", "https://evil.example")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var problem Problem
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden || problem.Code != string(apperr.CodeOriginNotAllowed) {
+		t.Fatalf("esperava 403 %s, obteve %d %s", apperr.CodeOriginNotAllowed, resp.StatusCode, problem.Code)
+	}
+}
+
+// expectStreamClosed espera o servidor encerrar o stream SSE
+func expectStreamClosed(t *testing.T, body io.Reader) {
+	t.Helper()
+	closed := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, body)
+		closed <- err
+	}()
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("o stream continuou aberto")
+	}
+}
+
+func TestEventsStreamEndsWithCredential(t *testing.T) {
+	previous := eventsKeepAlive
+	t.Cleanup(func() { eventsKeepAlive = previous })
+
+	openStream := func(t *testing.T, srv *httptest.Server, token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/events", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("esperava 200, obteve %d", resp.StatusCode)
+		}
+		return resp
+	}
+
+	t.Run("token expirado", func(t *testing.T) {
+		eventsKeepAlive = time.Hour // só o exp encerra o stream
+		store := repository.NewInMemoryStore()
+		alice := newTestUser(t, store, "alice")
+		srv, _ := eventsTestServer(t, store)
+
+		now := time.Now()
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": alice.ID.String(),
+			"iat": float64(now.UnixMicro()) / 1e6,
+			"exp": float64(now.Add(time.Second).UnixMicro()) / 1e6,
+		}).SignedString([]byte("segredo-de-teste-com-32-bytes!!"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectStreamClosed(t, openStream(t, srv, token).Body)
+	})
+
+	t.Run("sessão revogada", func(t *testing.T) {
+		eventsKeepAlive = 20 * time.Millisecond
+		store := repository.NewInMemoryStore()
+		alice := newTestUser(t, store, "alice")
+		srv, tokens := eventsTestServer(t, store)
+
+		resp := openStream(t, srv, newTestToken(t, tokens, alice))
+		if err := store.UpdateUserPassword(context.Background(), alice.ID, "hash", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		expectStreamClosed(t, resp.Body)
+	})
+
+	t.Run("conta removida", func(t *testing.T) {
+		eventsKeepAlive = 20 * time.Millisecond
+		store := repository.NewInMemoryStore()
+		alice := newTestUser(t, store, "alice")
+		srv, tokens := eventsTestServer(t, store)
+
+		resp := openStream(t, srv, newTestToken(t, tokens, alice))
+		if err := store.DeleteUser(context.Background(), alice.ID); err != nil {
+			t.Fatal(err)
+		}
+		expectStreamClosed(t, resp.Body)
+	})
+}
+
+func TestEventsQueryToken(t *testing.T) {
+	var got *http.Request
+	handler := eventsQueryToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/events?access_token=segredo&lastEventId=7", nil))
+	if got.Header.Get("Authorization") != "Bearer segredo" {
+		t.Fatalf("token não foi movido para o header: %q", got.Header.Get("Authorization"))
+	}
+	if strings.Contains(got.RequestURI, "segredo") || got.URL.Query().Get("lastEventId") != "7" {
+		t.Fatalf("URL inesperada depois de remover o token: %q", got.RequestURI)
+	}
+
+	// Fora de /v1/events o parâmetro é ignorado
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/transfers?access_token=segredo", nil))
+	if got.Header.Get("Authorization") != "" {
+		t.Fatalf("token aceito fora de /v1/events: %q", got.Header.Get("Authorization"))
+	}
+}
@@ -1,17 +1,23 @@
 package api
 
 import (
+	"crypto/subtle"
 	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
-	"strings"
+	"net/url"
+	"strconv"
 	"time"
 
+	"secureshare-backend/internal/apperr"
 	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/auth/oidc"
+	"secureshare-backend/internal/logging"
 	"secureshare-backend/internal/models"
 	"secureshare-backend/internal/repository"
 	"secureshare-backend/internal/service"
+	"secureshare-backend/pkg/keybackup"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -20,29 +26,76 @@ import (
 
 // Handler gerencia as dependências para os handlers HTTP
 type Handler struct {
-	userService     *service.UserService
-	transferService *service.TransferService
-	tokenService    *auth.TokenService
-	userStore       repository.UserStore // Necessário para mapear IDs nos handlers
-	validate        *validator.Validate
-	s3Service       *service.S3Service
+	userService        *service.UserService
+	transferService    *service.TransferService
+	accountService     *service.AccountService
+	keyBackup          *service.KeyBackupService
+	deviceService      *service.DeviceService
+	apiKeyService      *service.APIKeyService
+	auditService       *service.AuditService
+	eventHub           *service.EventHub
+	webhookService     *service.WebhookService
+	idempotencyService *service.IdempotencyService
+	emailService       *service.EmailService // nil se o SMTP não estiver configurado
+	ssoService         *service.SSOService   // nil se o SSO não estiver configurado
+	tokenService       *auth.TokenService
+	userStore          repository.UserStore // Necessário para mapear IDs nos handlers
+	validate           *validator.Validate
+	s3Service          *service.S3Service
+
+	ssoPostLoginRedirect string
+	admins               map[string]bool // usernames com acesso a /admin
 }
 
 // NewHandler cria uma nova instância do Handler
 func NewHandler(
 	userSvc *service.UserService,
 	transferSvc *service.TransferService,
+	accountSvc *service.AccountService,
+	keyBackupSvc *service.KeyBackupService,
+	deviceSvc *service.DeviceService,
+	apiKeySvc *service.APIKeyService,
+	auditSvc *service.AuditService,
+	eventHub *service.EventHub,
+	webhookSvc *service.WebhookService,
+	idempotencySvc *service.IdempotencyService,
+	emailSvc *service.EmailService,
+	ssoSvc *service.SSOService,
 	tokenSvc *auth.TokenService,
 	userStore repository.UserStore,
 	s3Svc *service.S3Service,
 ) *Handler {
 	return &Handler{
-		userService:     userSvc,
-		transferService: transferSvc,
-		tokenService:    tokenSvc,
-		userStore:       userStore,
-		validate:        validator.New(),
-		s3Service:       s3Svc,
+		userService:        userSvc,
+		transferService:    transferSvc,
+		accountService:     accountSvc,
+		keyBackup:          keyBackupSvc,
+		deviceService:      deviceSvc,
+		apiKeyService:      apiKeySvc,
+		auditService:       auditSvc,
+		eventHub:           eventHub,
+		webhookService:     webhookSvc,
+		idempotencyService: idempotencySvc,
+		emailService:       emailSvc,
+		ssoService:         ssoSvc,
+		tokenService:       tokenSvc,
+		userStore:          userStore,
+		validate:           newValidator(),
+		s3Service:          s3Svc,
+	}
+}
+
+// SetSSOPostLoginRedirect define a URL do frontend para onde o callback SSO
+// redireciona. Sem ela, o callback responde com JSON.
+func (h *Handler) SetSSOPostLoginRedirect(url string) {
+	h.ssoPostLoginRedirect = url
+}
+
+// SetAdmins define os usernames com acesso às rotas /admin
+func (h *Handler) SetAdmins(usernames []string) {
+	h.admins = make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		h.admins[username] = true
 	}
 }
 
@@ -57,25 +110,46 @@ type (
 
 // === Funções Auxiliares de Resposta ===
 
-func (h *Handler) respondWithError(w http.ResponseWriter, code int, message string) {
-	h.respondWithJSON(w, code, map[string]interface{}{
-		"error": map[string]interface{}{
-			"code":    code,
-			"message": message,
-		},
-	})
+// audit grava um evento de auditoria com o IP e o User-Agent da requisição.
+// actor é nil em eventos anônimos (ex: login recusado).
+func (h *Handler) audit(r *http.Request, eventType string, actor *models.User, target string, details map[string]string) {
+	event := &models.AuditEvent{
+		Type:      eventType,
+		UserAgent: r.UserAgent(),
+		Target:    target,
+		Details:   details,
+	}
+	if ip := remoteIP(r); ip != nil {
+		event.IP = ip.String()
+	}
+	if actor != nil {
+		event.ActorID = &actor.ID
+		event.Actor = actor.Username
+	}
+	if key, ok := r.Context().Value(apiKeyContextKey).(*models.APIKey); ok {
+		if event.Details == nil {
+			event.Details = make(map[string]string, 1)
+		}
+		event.Details["apiKeyId"] = key.ID.String()
+	}
+	h.auditService.Record(r.Context(), event)
 }
 
 func (h *Handler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	writeJSON(w, code, "application/json", payload)
+}
+
+func writeJSON(w http.ResponseWriter, code int, contentType string, payload interface{}) {
 	response, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("Erro ao serializar JSON: %v", err)
+		slog.Error("Erro ao serializar JSON", "err", err)
+		w.Header().Set("Content-Type", problemContentType)
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"error":{"code":500,"message":"Erro interno ao serializar resposta"}}`))
+		w.Write([]byte(`{"type":"urn:secureshare:problem:INTERNAL_ERROR","title":"Erro interno","status":500,"code":"INTERNAL_ERROR"}`))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(code)
 	w.Write(response)
 }
@@ -84,10 +158,23 @@ func (h *Handler) respondWithJSON(w http.ResponseWriter, code int, payload inter
 
 type (
 	// PublicKeyResponse (conforme OpenAPI)
+	// PublicKey/PublicKeySign são as chaves do dispositivo ativo mais antigo,
+	// mantidas para clientes que ainda não cifram por dispositivo
 	PublicKeyResponse struct {
-		Username      string `json:"username"`
-		PublicKey     string `json:"publicKey"`
-		PublicKeySign string `json:"publicKeySign"`
+		Username      string              `json:"username"`
+		PublicKey     string              `json:"publicKey"`
+		PublicKeySign string              `json:"publicKeySign"`
+		Devices       []DeviceKeyResponse `json:"devices"`
+	}
+
+	// DeviceKeyResponse são as chaves públicas de um dispositivo ativo
+	DeviceKeyResponse struct {
+		DeviceID       string `json:"deviceId"`
+		Name           string `json:"name"`
+		PublicKey      string `json:"publicKey"`
+		PublicKeySign  string `json:"publicKeySign"`
+		SignerDeviceID string `json:"signerDeviceId,omitempty"`
+		Signature      string `json:"signature,omitempty"`
 	}
 
 	// TransferMetadata (conforme OpenAPI)
@@ -99,82 +186,234 @@ type (
 		SKB           string    `json:"skb"`
 		Sig           string    `json:"sig"`
 		CreatedAt     time.Time `json:"createdAt"`
+		Size          int64     `json:"size"`
+		// SHA-256 (base64) do arquivo cifrado: confira o download antes de decifrar
+		ChecksumSHA256 string `json:"checksumSha256,omitempty"`
+		// SKBs mapeia deviceId -> SKB cifrada para aquele dispositivo
+		SKBs map[string]string `json:"skbs,omitempty"`
 	}
 )
 
+// deviceSKBsToResponse converte as SKBs por dispositivo para o formato da API
+func deviceSKBsToResponse(skbs map[uuid.UUID]string) map[string]string {
+	if len(skbs) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(skbs))
+	for deviceID, skb := range skbs {
+		out[deviceID.String()] = skb
+	}
+	return out
+}
+
 // === Handlers de Usuário ===
 
-// handleRegisterUser (POST /users/register)
-func (h *Handler) handleRegisterUser(w http.ResponseWriter, r *http.Request) {
-	var req struct {
+type (
+	// RegisterRequest (POST /users/register)
+	RegisterRequest struct {
 		Username      string `json:"username" validate:"required"`
 		Password      string `json:"password" validate:"required,min=8"`
 		PublicKey     string `json:"publicKey" validate:"required"`
 		PublicKeySign string `json:"publicKeySign" validate:"required"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondWithError(w, http.StatusBadRequest, "Payload JSON inválido")
+	// LoginRequest (POST /users/login)
+	LoginRequest struct {
+		Username string `json:"username" validate:"required"`
+		Password string `json:"password" validate:"required"`
+	}
+
+	// MessageResponse é uma confirmação sem dados
+	MessageResponse struct {
+		Message string `json:"message"`
+	}
+
+	// TokenResponse traz o JWT da sessão
+	TokenResponse struct {
+		Token string `json:"token"`
+	}
+)
+
+// handleRegisterUser (POST /users/register)
+func (h *Handler) handleRegisterUser(w http.ResponseWriter, r *http.Request) {
+	var req RegisterRequest
+
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
 		return
 	}
 
 	if err := h.validate.Struct(req); err != nil {
-		h.respondWithError(w, http.StatusBadRequest, "Dados inválidos: "+err.Error())
+		h.respondWithValidationError(w, r, err)
 		return
 	}
 
-	_, err := h.userService.Register(r.Context(), req.Username, req.Password, req.PublicKey, req.PublicKeySign)
+	user, err := h.userService.Register(r.Context(), req.Username, req.Password, req.PublicKey, req.PublicKeySign)
 	if err != nil {
-		// Verifica se é um erro de "usuário já existe"
-		if err.Error() == "usuário '"+req.Username+"' já existe" {
-			h.respondWithError(w, http.StatusConflict, err.Error())
-			return
-		}
-		h.respondWithError(w, http.StatusInternalServerError, err.Error())
+		h.respondWithAppError(w, r, err)
 		return
 	}
+	h.audit(r, service.AuditUserRegistered, user, "user:"+user.Username, nil)
 
-	h.respondWithJSON(w, http.StatusCreated, map[string]string{"message": "Usuário criado com sucesso."})
+	h.respondWithJSON(w, http.StatusCreated, MessageResponse{Message: "Usuário criado com sucesso."})
 }
 
 // handleLoginUser (POST /users/login)
 func (h *Handler) handleLoginUser(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Username string `json:"username" validate:"required"`
-		Password string `json:"password" validate:"required"`
-	}
+	var req LoginRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondWithError(w, http.StatusBadRequest, "Payload JSON inválido")
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
 		return
 	}
 
 	if err := h.validate.Struct(req); err != nil {
-		h.respondWithError(w, http.StatusBadRequest, "Dados inválidos: "+err.Error())
+		h.respondWithValidationError(w, r, err)
 		return
 	}
 
 	token, err := h.userService.Login(r.Context(), req.Username, req.Password)
 	if err != nil {
-		// Erro de login (usuário/senha errados)
-		h.respondWithError(w, http.StatusUnauthorized, err.Error())
+		if errors.Is(err, apperr.ErrUnauthorized) {
+			h.audit(r, service.AuditLoginFailed, nil, "user:"+req.Username, map[string]string{"method": "password"})
+		}
+		h.respondWithAppError(w, r, err)
 		return
 	}
+	// O login só devolve o token; o autor do evento vem do store
+	user, _ := h.userStore.GetUserByUsername(r.Context(), req.Username)
+	h.audit(r, service.AuditLoginSucceeded, user, "user:"+req.Username, map[string]string{"method": "password"})
 
-	h.respondWithJSON(w, http.StatusOK, map[string]string{"token": token})
+	h.respondWithJSON(w, http.StatusOK, TokenResponse{Token: token})
+}
+
+// === Handlers de SSO (OIDC) ===
+
+const (
+	oidcStateCookie = "secureshare_oidc"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+// OIDCLoginResponse é o resultado do login SSO quando não há
+// SSO_POST_LOGIN_REDIRECT. NeedsKeys indica uma conta ainda sem dispositivo.
+type OIDCLoginResponse struct {
+	Token     string `json:"token"`
+	Username  string `json:"username"`
+	NeedsKeys bool   `json:"needsKeys"`
+}
+
+// handleOIDCLogin (GET /auth/oidc/login)
+// Gera state, nonce e code verifier (PKCE), guarda-os em um cookie assinado
+// e redireciona o navegador para o IdP
+func (h *Handler) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	values := make(map[string]string, 3)
+	for _, name := range []string{"state", "nonce", "verifier"} {
+		v, err := oidc.RandomString()
+		if err != nil {
+			h.respondWithError(w, r, http.StatusInternalServerError, apperr.CodeInternal, "Erro interno ao iniciar login SSO")
+			return
+		}
+		values[name] = v
+	}
+
+	stateToken, err := h.tokenService.NewStateToken(values, oidcStateTTL)
+	if err != nil {
+		h.respondWithError(w, r, http.StatusInternalServerError, apperr.CodeInternal, "Erro interno ao iniciar login SSO")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    stateToken,
+		Path:     "/v1/auth/oidc",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode, // O retorno do IdP é uma navegação top-level
+	})
+
+	authURL := h.ssoService.AuthCodeURL(values["state"], values["nonce"], values["verifier"])
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleOIDCCallback (GET /auth/oidc/callback)
+func (h *Handler) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	// O cookie de estado é de uso único
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/v1/auth/oidc", MaxAge: -1})
+
+	if idpErr := r.URL.Query().Get("error"); idpErr != "" {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeSSOFailed, "Login SSO recusado pelo provedor: "+idpErr)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeSSOStateInvalid, "Sessão de login SSO ausente ou expirada")
+		return
+	}
+	values, err := h.tokenService.ParseStateToken(cookie.Value)
+	if err != nil {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeSSOStateInvalid, "Sessão de login SSO ausente ou expirada")
+		return
+	}
+
+	state := r.URL.Query().Get("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(values["state"])) != 1 {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeSSOStateInvalid, "Parâmetro 'state' inválido")
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "Parâmetro 'code' é obrigatório")
+		return
+	}
+
+	result, err := h.ssoService.CompleteLogin(r.Context(), code, values["verifier"], values["nonce"])
+	if err != nil {
+		if errors.Is(err, apperr.ErrUnauthorized) || errors.Is(err, apperr.ErrForbidden) {
+			h.audit(r, service.AuditLoginFailed, nil, "", map[string]string{"method": "oidc"})
+		}
+		h.respondWithAppError(w, r, err)
+		return
+	}
+	h.audit(r, service.AuditLoginSucceeded, result.User, "user:"+result.User.Username, map[string]string{"method": "oidc"})
+
+	if h.ssoPostLoginRedirect != "" {
+		// O token vai no fragmento, que não é enviado a servidores nem
+		// registrado em logs de acesso
+		fragment := url.Values{
+			"token":     {result.Token},
+			"username":  {result.User.Username},
+			"needsKeys": {strconv.FormatBool(result.NeedsKeys)},
+		}
+		http.Redirect(w, r, h.ssoPostLoginRedirect+"#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, OIDCLoginResponse{
+		Token:     result.Token,
+		Username:  result.User.Username,
+		NeedsKeys: result.NeedsKeys,
+	})
 }
 
 // handleGetUserKey (GET /users/{username}/key)
 func (h *Handler) handleGetUserKey(w http.ResponseWriter, r *http.Request) {
 	username := chi.URLParam(r, "username")
 	if username == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Nome de usuário não fornecido")
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "Nome de usuário não fornecido")
 		return
 	}
 
+	requester, _ := r.Context().Value(userContextKey).(*models.User)
 	user, err := h.userService.GetUserPublicKey(r.Context(), username)
 	if err != nil {
-		h.respondWithError(w, http.StatusNotFound, "Usuário não encontrado")
+		h.respondWithAppError(w, r, err)
+		return
+	}
+	h.audit(r, service.AuditKeyFetched, requester, "user:"+user.Username, nil)
+
+	devices, err := h.deviceService.GetActiveDevices(r.Context(), user.ID)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
 		return
 	}
 
@@ -183,51 +422,336 @@ func (h *Handler) handleGetUserKey(w http.ResponseWriter, r *http.Request) {
 		Username:      user.Username,
 		PublicKey:     user.PublicKey,
 		PublicKeySign: user.PublicKeySign,
+		Devices:       make([]DeviceKeyResponse, 0, len(devices)),
+	}
+	if len(devices) > 0 {
+		response.PublicKey = devices[0].PublicKey
+		response.PublicKeySign = devices[0].PublicKeySign
+	}
+	for _, d := range devices {
+		deviceKey := DeviceKeyResponse{
+			DeviceID:      d.ID.String(),
+			Name:          d.Name,
+			PublicKey:     d.PublicKey,
+			PublicKeySign: d.PublicKeySign,
+			Signature:     d.Signature,
+		}
+		if d.SignerDeviceID != nil {
+			deviceKey.SignerDeviceID = d.SignerDeviceID.String()
+		}
+		response.Devices = append(response.Devices, deviceKey)
 	}
 
 	h.respondWithJSON(w, http.StatusOK, response)
 }
 
+// === Handlers de Dispositivo ===
+
+// handleListDevices (GET /devices)
+func (h *Handler) handleListDevices(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	devices, err := h.deviceService.ListDevices(r.Context(), user.ID)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, devices)
+}
+
+// handleAddDevice (POST /devices)
+func (h *Handler) handleAddDevice(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	var req service.AddDeviceRequest
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.respondWithValidationError(w, r, err)
+		return
+	}
+
+	device, err := h.deviceService.AddDevice(r.Context(), user, req)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, device)
+}
+
+// handleRevokeDevice (DELETE /devices/{deviceId})
+func (h *Handler) handleRevokeDevice(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	deviceID, err := uuid.Parse(chi.URLParam(r, "deviceId"))
+	if err != nil {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "ID de dispositivo inválido")
+		return
+	}
+
+	if err := h.deviceService.RevokeDevice(r.Context(), user.ID, deviceID); err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+	h.audit(r, service.AuditDeviceRevoked, user, "device:"+deviceID.String(), nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type (
+	// ChangePasswordRequest (PUT /users/me/password)
+	// oldPassword pode faltar em contas sem senha (SSO), que se reautenticam
+	// com um login recente
+	ChangePasswordRequest struct {
+		OldPassword string `json:"oldPassword"`
+		NewPassword string `json:"newPassword" validate:"required,min=8"`
+	}
+
+	// DeleteAccountRequest (DELETE /users/me) confirma a exclusão com a senha
+	// (ou, em contas sem senha, com um login recente)
+	DeleteAccountRequest struct {
+		Password string `json:"password"`
+	}
+)
+
+// handleChangePassword (PUT /users/me/password)
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	var req ChangePasswordRequest
+
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.respondWithValidationError(w, r, err)
+		return
+	}
+
+	token, err := h.userService.ChangePassword(r.Context(), user.ID, req.OldPassword, req.NewPassword, sessionIssuedAt(r))
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	// As sessões anteriores foram revogadas; o cliente passa a usar este token
+	h.respondWithJSON(w, http.StatusOK, TokenResponse{Token: token})
+}
+
+// handleDeleteAccount (DELETE /users/me)
+func (h *Handler) handleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	var req DeleteAccountRequest
+
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.respondWithValidationError(w, r, err)
+		return
+	}
+
+	if err := h.accountService.DeleteAccount(r.Context(), user.ID, req.Password, sessionIssuedAt(r)); err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+	h.audit(r, service.AuditAccountDeleted, user, "user:"+user.Username, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetKeyBackup (GET /users/me/key-backup)
+func (h *Handler) handleGetKeyBackup(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	backup, err := h.keyBackup.GetBackup(r.Context(), user.ID)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, backup)
+}
+
+// handlePutKeyBackup (PUT /users/me/key-backup)
+// O corpo é um blob opaco já cifrado no cliente (ver pkg/keybackup)
+func (h *Handler) handlePutKeyBackup(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	var req keybackup.Backup
+	if !h.decodeJSON(w, r, &req, maxKeyBackupBody) {
+		return
+	}
+
+	backup, err := h.keyBackup.PutBackup(r.Context(), user.ID, req)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, backup)
+}
+
+type (
+	// UploadURLResponse (POST /transfers/upload-url). O PUT para UploadURL
+	// precisa levar exatamente os Headers assinados.
+	UploadURLResponse struct {
+		UploadURL     string            `json:"uploadUrl"`
+		LinkToEncFile string            `json:"linkToEncFile"`
+		Headers       map[string]string `json:"headers"`
+	}
+
+	// DownloadURLResponse (GET /transfers/download-url)
+	DownloadURLResponse struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+)
+
 func (h *Handler) handleGetUploadURL(w http.ResponseWriter, r *http.Request) {
 	// 1. Obter o usuário autenticado (que está fazendo o upload)
 	user, ok := r.Context().Value(userContextKey).(*models.User)
 	if !ok || user == nil {
-		h.respondWithError(w, http.StatusUnauthorized, "Contexto de usuário inválido")
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	// 2. Tamanho e SHA-256 do arquivo cifrado: entram na assinatura da URL
+	// (e o tamanho, na cota)
+	var req service.UploadRequest
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
+		return
+	}
+	if req.Size <= 0 || req.ChecksumSHA256 == "" {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeValidation, "Campos 'size' (bytes) e 'checksumSha256' obrigatórios")
 		return
 	}
 
-	// 2. Gerar uma chave de objeto (caminho) única e segura para o S3
+	// 3. Gerar e reservar uma chave de objeto (caminho) única para o S3
 	// Formato: uploads/USER_ID/ARQUIVO_UUID
-	fileUUID := uuid.New().String()
-	objectKey := fmt.Sprintf("uploads/%s/%s", user.ID.String(), fileUUID)
+	// Ex: checksum inválido (400), arquivo acima do máximo (413), cota
+	// excedida (507)
+	objectKey, err := h.transferService.ReserveUpload(r.Context(), user, req)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	// 4. Gerar a URL pré-assinada e responder
+	h.respondWithUploadURL(w, r, user, &models.UploadReservation{
+		ObjectKey:      objectKey,
+		Size:           req.Size,
+		ChecksumSHA256: req.ChecksumSHA256,
+	})
+}
+
+// reissueUploadURL responde ao reenvio de POST /transfers/upload-url com o
+// mesmo Idempotency-Key: a URL guardada pode já ter expirado, então uma
+// nova é assinada para o mesmo arquivo, sem uma segunda reserva
+func (h *Handler) reissueUploadURL(w http.ResponseWriter, r *http.Request, stored *models.IdempotencyRecord) {
+	var previous UploadURLResponse
+	if stored.StatusCode != http.StatusOK || json.Unmarshal(stored.Body, &previous) != nil || previous.LinkToEncFile == "" {
+		// Erros (ex: cota excedida) são repetidos como foram gravados
+		writeStoredResponse(w, r, stored)
+		return
+	}
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
 
-	// 3. Gerar a URL pré-assinada
-	// A URL expira em 15 minutos
-	uploadURL, err := h.s3Service.GeneratePresignedPutURL(r.Context(), objectKey, 15*time.Minute)
+	reservation, err := h.transferService.GetUploadReservation(r.Context(), user, previous.LinkToEncFile)
 	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Não foi possível gerar a URL de upload")
+		h.respondWithAppError(w, r, err)
 		return
 	}
+	h.respondWithUploadURL(w, r, user, reservation)
+}
+
+// respondWithUploadURL assina a URL de upload do arquivo reservado e a
+// devolve ao cliente
+func (h *Handler) respondWithUploadURL(w http.ResponseWriter, r *http.Request, user *models.User, reservation *models.UploadReservation) {
+	// A URL expira em 15 minutos e só aceita exatamente o arquivo declarado
+	uploadURL, headers, err := h.s3Service.GeneratePresignedPutURL(r.Context(), reservation.ObjectKey, reservation.Size, reservation.ChecksumSHA256, 15*time.Minute)
+	if err != nil {
+		h.respondWithError(w, r, http.StatusInternalServerError, apperr.CodeInternal, "Não foi possível gerar a URL de upload")
+		return
+	}
+	h.audit(r, service.AuditUploadURLIssued, user, "object:"+reservation.ObjectKey, map[string]string{
+		"size": strconv.FormatInt(reservation.Size, 10),
+	})
 
-	// 4. Responder ao cliente com a URL e a chave do arquivo
 	// O 'linkToEncFile' é a chave que o cliente deve nos enviar de volta no
-	// POST /transfers (após o upload ser concluído).
-	response := struct {
-		UploadURL     string `json:"uploadUrl"`
-		LinkToEncFile string `json:"linkToEncFile"`
-	}{
+	// POST /transfers (após o upload ser concluído). O PUT precisa levar
+	// os cabeçalhos assinados (Content-Length, x-amz-checksum-sha256...).
+	response := UploadURLResponse{
 		UploadURL:     uploadURL,
-		LinkToEncFile: objectKey,
+		LinkToEncFile: reservation.ObjectKey,
+		Headers:       make(map[string]string, len(headers)),
+	}
+	for name := range headers {
+		response.Headers[name] = headers.Get(name)
 	}
 
 	h.respondWithJSON(w, http.StatusOK, response)
 }
 
+func (h *Handler) handleGetUsage(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	usage, err := h.transferService.GetUsage(r.Context(), user)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, usage)
+}
+
 func (h *Handler) handleGetDownloadURL(w http.ResponseWriter, r *http.Request) {
 	// 1. Obter o usuário autenticado
-	_, ok := r.Context().Value(userContextKey).(*models.User)
+	user, ok := r.Context().Value(userContextKey).(*models.User)
 	if !ok {
-		h.respondWithError(w, http.StatusUnauthorized, "Contexto de usuário inválido")
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
 		return
 	}
 
@@ -235,27 +759,199 @@ func (h *Handler) handleGetDownloadURL(w http.ResponseWriter, r *http.Request) {
 	// (Ex: /transfers/download-url?fileKey=uploads/123/456)
 	fileKey := r.URL.Query().Get("fileKey")
 	if fileKey == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Parâmetro 'fileKey' é obrigatório")
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "Parâmetro 'fileKey' é obrigatório")
+		return
+	}
+
+	// 3. Só remetente e destinatário de uma transferência do arquivo
+	if err := h.transferService.AuthorizeDownload(r.Context(), user, fileKey); err != nil {
+		h.respondWithAppError(w, r, err)
 		return
 	}
 
-	// 3. Gerar a URL pré-assinada (válida por 5 minutos)
+	// 4. Gerar a URL pré-assinada (válida por 5 minutos)
 	downloadURL, err := h.s3Service.GeneratePresignedGetURL(r.Context(), fileKey, 5*time.Minute)
 	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Não foi possível gerar a URL de download")
+		h.respondWithError(w, r, http.StatusInternalServerError, apperr.CodeInternal, "Não foi possível gerar a URL de download")
 		return
 	}
+	h.audit(r, service.AuditDownloadURLIssued, user, "object:"+fileKey, nil)
+	// Avisa o remetente; uma falha aqui não impede o download
+	if err := h.transferService.RecordDownload(r.Context(), user, fileKey); err != nil {
+		logging.FromContext(r.Context()).Error("Erro ao registrar download", "object", fileKey, "err", err)
+	}
 
-	// 4. Responder ao cliente
-	response := struct {
-		DownloadURL string `json:"downloadUrl"`
-	}{
+	// 5. Responder ao cliente
+	response := DownloadURLResponse{
 		DownloadURL: downloadURL,
 	}
 
 	h.respondWithJSON(w, http.StatusOK, response)
 }
 
+// === Handlers de Contas de Serviço ===
+
+type (
+	// ServiceAccountResponse é uma conta de serviço do usuário autenticado
+	ServiceAccountResponse struct {
+		Username  string    `json:"username"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	// CreateServiceAccountRequest (POST /service-accounts)
+	CreateServiceAccountRequest struct {
+		Username      string `json:"username" validate:"required"`
+		PublicKey     string `json:"publicKey" validate:"required"`
+		PublicKeySign string `json:"publicKeySign" validate:"required"`
+	}
+
+	// CreatedAPIKeyResponse é a API key recém-criada, com a chave em texto
+	// claro (só exibida nesta resposta)
+	CreatedAPIKeyResponse struct {
+		*models.APIKey
+		Key string `json:"key"`
+	}
+)
+
+// handleListServiceAccounts (GET /service-accounts)
+func (h *Handler) handleListServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	owner, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || owner == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	accounts, err := h.userService.ListServiceAccounts(r.Context(), owner.ID)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	response := make([]ServiceAccountResponse, 0, len(accounts))
+	for _, a := range accounts {
+		response = append(response, ServiceAccountResponse{Username: a.Username, CreatedAt: a.CreatedAt})
+	}
+
+	h.respondWithJSON(w, http.StatusOK, response)
+}
+
+// handleCreateServiceAccount (POST /service-accounts)
+func (h *Handler) handleCreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	owner, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || owner == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+
+	var req CreateServiceAccountRequest
+
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.respondWithValidationError(w, r, err)
+		return
+	}
+
+	account, err := h.userService.CreateServiceAccount(r.Context(), owner, req.Username, req.PublicKey, req.PublicKeySign)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, ServiceAccountResponse{Username: account.Username, CreatedAt: account.CreatedAt})
+}
+
+// serviceAccountFromRequest resolve a conta de serviço {username} do dono autenticado
+func (h *Handler) serviceAccountFromRequest(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	owner, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || owner == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return nil, false
+	}
+
+	account, err := h.userService.GetServiceAccount(r.Context(), owner, chi.URLParam(r, "username"))
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return nil, false
+	}
+	return account, true
+}
+
+// handleListAPIKeys (GET /service-accounts/{username}/api-keys)
+func (h *Handler) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.serviceAccountFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(r.Context(), account.ID)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, keys)
+}
+
+// handleCreateAPIKey (POST /service-accounts/{username}/api-keys)
+func (h *Handler) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.serviceAccountFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.CreateAPIKeyRequest
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.respondWithValidationError(w, r, err)
+		return
+	}
+
+	plaintext, key, err := h.apiKeyService.CreateAPIKey(r.Context(), account, req)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	// A chave em texto claro só é exibida nesta resposta
+	response := CreatedAPIKeyResponse{
+		APIKey: key,
+		Key:    plaintext,
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, response)
+}
+
+// handleRevokeAPIKey (DELETE /service-accounts/{username}/api-keys/{keyId})
+func (h *Handler) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.serviceAccountFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(chi.URLParam(r, "keyId"))
+	if err != nil {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "ID de API key inválido")
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(r.Context(), account.ID, keyID); err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+	owner, _ := r.Context().Value(userContextKey).(*models.User)
+	h.audit(r, service.AuditAPIKeyRevoked, owner, "apikey:"+keyID.String(), map[string]string{
+		"serviceAccount": account.Username,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // === Handlers de Transferência ===
 
 // handleCreateTransfer (POST /transfers)
@@ -263,45 +959,52 @@ func (h *Handler) handleCreateTransfer(w http.ResponseWriter, r *http.Request) {
 	// 1. Obter o usuário de origem (SourceUser) do contexto (injetado pelo middleware)
 	sourceUser, ok := r.Context().Value(userContextKey).(*models.User)
 	if !ok || sourceUser == nil {
-		h.respondWithError(w, http.StatusUnauthorized, "Contexto de usuário inválido")
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
 		return
 	}
 
 	// 2. Decodificar o request (NewTransferRequest da OpenAPI)
 	var req service.CreateTransferRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondWithError(w, http.StatusBadRequest, "Payload JSON inválido")
+	if !h.decodeJSON(w, r, &req, maxTransferBody) {
 		return
 	}
 
-	// Validação (simples, OpenAPI já define os campos required)
-	if req.DestUsername == "" || req.LinkToEncFile == "" || req.SKB == "" || req.Sig == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Campos obrigatórios ausentes")
+	// Campos obrigatórios, base64 (skb, sig, skbs) e formato de linkToEncFile
+	if err := h.validate.Struct(req); err != nil {
+		h.respondWithValidationError(w, r, err)
+		return
+	}
+	// É preciso ao menos uma SKB: a legada (skb) ou as por dispositivo (skbs)
+	if req.SKB == "" && len(req.SKBs) == 0 {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeEmptySKB, "Informe 'skb' ou 'skbs'")
 		return
 	}
 
 	// 3. Chamar o serviço para criar a transferência
 	transfer, err := h.transferService.CreateTransfer(r.Context(), sourceUser.ID, req)
 	if err != nil {
-		// Verifica se o erro foi "usuário de destino não encontrado"
-		if strings.Contains(err.Error(), "não encontrado") {
-			h.respondWithError(w, http.StatusNotFound, err.Error())
-			return
-		}
-		h.respondWithError(w, http.StatusInternalServerError, err.Error())
+		// Ex: usuário/dispositivo de destino não encontrado (404), SKB vazia (400)
+		h.respondWithAppError(w, r, err)
 		return
 	}
+	h.audit(r, service.AuditTransferCreated, sourceUser, "transfer:"+transfer.ID.String(), map[string]string{
+		"destUser": req.DestUsername,
+		"object":   transfer.LinkToEncFile,
+	})
 
 	// 4. Mapear o modelo interno (models.Transfer) para o modelo de resposta (TransferMetadata)
 	// O modelo de resposta precisa dos nomes de usuário, não dos IDs
 	metadata := TransferMetadata{
-		TransferID:    transfer.ID.String(),
-		SourceUser:    sourceUser.Username, // Já temos o usuário de origem
-		DestUser:      req.DestUsername,    // Já temos o nome de usuário de destino
-		LinkToEncFile: transfer.LinkToEncFile,
-		SKB:           transfer.SKB,
-		Sig:           transfer.Sig,
-		CreatedAt:     transfer.CreatedAt,
+		TransferID:     transfer.ID.String(),
+		SourceUser:     sourceUser.Username, // Já temos o usuário de origem
+		DestUser:       req.DestUsername,    // Já temos o nome de usuário de destino
+		LinkToEncFile:  transfer.LinkToEncFile,
+		SKB:            transfer.SKB,
+		Sig:            transfer.Sig,
+		CreatedAt:      transfer.CreatedAt,
+		Size:           transfer.Size,
+		ChecksumSHA256: transfer.ChecksumSHA256,
+		SKBs:           deviceSKBsToResponse(transfer.DeviceSKBs),
 	}
 
 	h.respondWithJSON(w, http.StatusCreated, metadata)
@@ -312,14 +1015,14 @@ func (h *Handler) handleGetTransfers(w http.ResponseWriter, r *http.Request) {
 	// 1. Obter o usuário de destino (DestUser) do contexto
 	destUser, ok := r.Context().Value(userContextKey).(*models.User)
 	if !ok || destUser == nil {
-		h.respondWithError(w, http.StatusUnauthorized, "Contexto de usuário inválido")
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
 		return
 	}
 
 	// 2. Chamar o serviço para buscar as transferências
 	transfers, err := h.transferService.GetPendingTransfers(r.Context(), destUser.ID)
 	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, err.Error())
+		h.respondWithAppError(w, r, err)
 		return
 	}
 
@@ -330,18 +1033,21 @@ func (h *Handler) handleGetTransfers(w http.ResponseWriter, r *http.Request) {
 		// Precisamos encontrar o nome de usuário do remetente (SourceUser)
 		sourceUser, err := h.userStore.GetUserByID(r.Context(), t.SourceUserID)
 		if err != nil {
-			log.Printf("Erro: transferência %s tem um sourceUserID inválido: %s", t.ID, t.SourceUserID)
+			logging.FromContext(r.Context()).Error("Transferência com sourceUserID inválido", "transfer_id", t.ID, "source_user_id", t.SourceUserID)
 			continue // Pular esta transferência
 		}
 
 		metadataList = append(metadataList, TransferMetadata{
-			TransferID:    t.ID.String(),
-			SourceUser:    sourceUser.Username, // Mapeado do ID
-			DestUser:      destUser.Username,   // O usuário atual
-			LinkToEncFile: t.LinkToEncFile,
-			SKB:           t.SKB,
-			Sig:           t.Sig,
-			CreatedAt:     t.CreatedAt,
+			TransferID:     t.ID.String(),
+			SourceUser:     sourceUser.Username, // Mapeado do ID
+			DestUser:       destUser.Username,   // O usuário atual
+			LinkToEncFile:  t.LinkToEncFile,
+			SKB:            t.SKB,
+			Sig:            t.Sig,
+			CreatedAt:      t.CreatedAt,
+			Size:           t.Size,
+			ChecksumSHA256: t.ChecksumSHA256,
+			SKBs:           deviceSKBsToResponse(t.DeviceSKBs),
 		})
 	}
 
@@ -352,14 +1058,14 @@ func (h *Handler) handleGetAllUsers(w http.ResponseWriter, r *http.Request) {
 	// 1. Obter o usuário autenticado (só para garantir que a rota é protegida)
 	_, ok := r.Context().Value(userContextKey).(*models.User)
 	if !ok {
-		h.respondWithError(w, http.StatusUnauthorized, "Contexto de usuário inválido")
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
 		return
 	}
 
 	// 2. Chamar o serviço
 	users, err := h.userService.GetAllUsers(r.Context())
 	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, err.Error())
+		h.respondWithAppError(w, r, err)
 		return
 	}
 
@@ -375,3 +1081,68 @@ func (h *Handler) handleGetAllUsers(w http.ResponseWriter, r *http.Request) {
 
 	h.respondWithJSON(w, http.StatusOK, response)
 }
+
+// === Handlers de Administração ===
+
+// AuditEventsResponse é uma página do log de auditoria
+type AuditEventsResponse struct {
+	Events []*models.AuditEvent `json:"events"`
+	// NextBefore é o cursor da próxima página (ausente na última)
+	NextBefore int64 `json:"nextBefore,omitempty"`
+}
+
+// handleListAuditEvents (GET /admin/audit)
+// Filtros: type, actor (username), target, since e until (RFC 3339), before
+// (seq, para paginar) e limit. Os eventos vêm dos mais recentes para os
+// mais antigos.
+func (h *Handler) handleListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.AuditFilter{
+		Type:       query.Get("type"),
+		Actor:      query.Get("actor"),
+		Target:     query.Get("target"),
+		Descending: true,
+	}
+
+	for name, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if raw := query.Get(name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "Parâmetro '"+name+"' deve estar no formato RFC 3339")
+				return
+			}
+			*dst = t
+		}
+	}
+	if raw := query.Get("before"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n <= 0 {
+			h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "Parâmetro 'before' deve ser um inteiro positivo")
+			return
+		}
+		filter.BeforeSeq = n
+	}
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "Parâmetro 'limit' deve ser um inteiro positivo")
+			return
+		}
+		filter.Limit = n
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 100
+	}
+
+	events, err := h.auditService.List(r.Context(), filter)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	response := AuditEventsResponse{Events: events}
+	if len(events) > 0 && len(events) == min(filter.Limit, service.MaxAuditPageSize) && events[len(events)-1].Seq > 1 {
+		response.NextBefore = events[len(events)-1].Seq
+	}
+	h.respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/service"
+)
+
+const (
+	// IdempotencyKeyHeader identifica a requisição nos reenvios
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marca uma resposta repetida (valor "true")
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotentBody limita o corpo lido para calcular o fingerprint
+	maxIdempotentBody = 1 << 20
+)
+
+// Idempotent repete a resposta de uma requisição reenviada com o mesmo
+// Idempotency-Key (por usuário, por service.IdempotencyTTL). A mesma chave
+// com outro método, caminho ou corpo recebe 422; enquanto a original não
+// termina (até service.IdempotencyLease), 409. Respostas 5xx não são
+// guardadas. Sem o cabeçalho, a requisição segue normalmente. Deve ser
+// usado depois do AuthMiddleware, e não em rotas cuja resposta traz
+// segredos (API keys, segredos de webhook), que ficariam gravados no banco.
+func (h *Handler) Idempotent(next http.Handler) http.Handler {
+	return h.idempotent(next, writeStoredResponse)
+}
+
+// IdempotentReissue é o Idempotent de rotas cuja resposta vence antes da
+// chave (ex: URL pré-assinada): no reenvio, reissue recebe a resposta
+// guardada e responde no lugar dela
+func (h *Handler) IdempotentReissue(reissue func(w http.ResponseWriter, r *http.Request, stored *models.IdempotencyRecord)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return h.idempotent(next, reissue)
+	}
+}
+
+// writeStoredResponse repete a resposta guardada como foi gravada
+func writeStoredResponse(w http.ResponseWriter, r *http.Request, stored *models.IdempotencyRecord) {
+	if stored.ContentType != "" {
+		w.Header().Set("Content-Type", stored.ContentType)
+	}
+	w.WriteHeader(stored.StatusCode)
+	w.Write(stored.Body)
+}
+
+func (h *Handler) idempotent(next http.Handler, replay func(w http.ResponseWriter, r *http.Request, stored *models.IdempotencyRecord)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := r.Header[IdempotencyKeyHeader]
+		if !ok || h.idempotencyService == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, ok := r.Context().Value(userContextKey).(*models.User)
+		if !ok || user == nil {
+			h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				h.respondWithError(w, r, http.StatusRequestEntityTooLarge, apperr.CodeBodyTooLarge, "Corpo da requisição grande demais")
+				return
+			}
+			h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "Falha ao ler o corpo da requisição")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := service.IdempotencyFingerprint(r.Method, r.URL.Path, body)
+		reserved, stored, err := h.idempotencyService.Begin(r.Context(), user.ID, key[0], fingerprint)
+		if err != nil {
+			h.respondWithAppError(w, r, err)
+			return
+		}
+		if stored != nil {
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			replay(w, r, stored)
+			return
+		}
+
+		// O resultado é gravado mesmo se o cliente já desistiu (o caso do
+		// timeout): senão a chave ficaria "em andamento" até o fim da
+		// reserva. Se o handler entrar em pânico, a chave é liberada antes
+		// de o Recoverer responder.
+		ctx := context.WithoutCancel(r.Context())
+		rec := &responseRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			if !completed {
+				h.idempotencyService.Release(ctx, reserved)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		h.idempotencyService.Complete(ctx, reserved, status, rec.Header().Get("Content-Type"), rec.body.Bytes())
+		completed = true
+	})
+}
+
+// responseRecorder repassa a resposta ao cliente e guarda uma cópia do
+// status e do corpo
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
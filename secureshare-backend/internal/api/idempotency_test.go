@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+)
+
+func TestIdempotentReplaysResponse(t *testing.T) {
+	store := repository.NewInMemoryStore()
+	h := &Handler{idempotencyService: service.NewIdempotencyService(store)}
+	alice := newTestUser(t, store, "alice")
+	bob := newTestUser(t, store, "bob")
+
+	var calls atomic.Int32
+	status := http.StatusCreated
+	route := h.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		h.respondWithJSON(w, status, map[string]string{"call": strconv.Itoa(int(n))})
+	}))
+
+	post := func(user *models.User, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		route.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Sem o cabeçalho, cada requisição é executada
+	post(alice, "/v1/transfers", "", `{}`)
+	post(alice, "/v1/transfers", "", `{}`)
+	if calls.Load() != 2 {
+		t.Fatalf("sem Idempotency-Key: esperava 2 execuções, obteve %d", calls.Load())
+	}
+
+	first := post(alice, "/v1/transfers", "k1", `{"destUser":"bob"}`)
+	if first.Code != http.StatusCreated || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("primeira requisição: %d %v", first.Code, first.Header())
+	}
+	again := post(alice, "/v1/transfers", "k1", `{"destUser":"bob"}`)
+	if again.Code != http.StatusCreated || again.Body.String() != first.Body.String() ||
+		again.Header().Get(IdempotentReplayedHeader) != "true" || again.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("reenvio deveria repetir a resposta: %d %s %v", again.Code, again.Body, again.Header())
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("o reenvio não deveria executar o handler (%d execuções)", calls.Load())
+	}
+
+	// A mesma chave com outro corpo ou outra rota é recusada
+	if rec := post(alice, "/v1/transfers", "k1", `{"destUser":"carol"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("outro corpo: esperava 422, obteve %d", rec.Code)
+	}
+	if rec := post(alice, "/v1/outra-rota", "k1", `{"destUser":"bob"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("outra rota: esperava 422, obteve %d", rec.Code)
+	}
+	// As chaves são por usuário
+	if rec := post(bob, "/v1/transfers", "k1", `{"destUser":"bob"}`); rec.Code != http.StatusCreated || rec.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("chave de outro usuário: %d %v", rec.Code, rec.Header())
+	}
+	if rec := post(alice, "/v1/transfers", strings.Repeat("x", service.MaxIdempotencyKeyLength+1), `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("chave longa demais: esperava 400, obteve %d", rec.Code)
+	}
+
+	// Erros 5xx não são guardados: o reenvio executa de novo
+	status = http.StatusInternalServerError
+	post(alice, "/v1/transfers", "k2", `{}`)
+	status = http.StatusCreated
+	if rec := post(alice, "/v1/transfers", "k2", `{}`); rec.Code != http.StatusCreated || rec.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("reenvio após 500: %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestIdempotentInProgress(t *testing.T) {
+	store := repository.NewInMemoryStore()
+	h := &Handler{idempotencyService: service.NewIdempotencyService(store)}
+	alice := newTestUser(t, store, "alice")
+
+	started, release := make(chan struct{}), make(chan struct{})
+	route := h.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/transfers", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		return req.WithContext(context.WithValue(req.Context(), userContextKey, alice))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		route.ServeHTTP(httptest.NewRecorder(), newRequest())
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	route.ServeHTTP(rec, newRequest())
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("requisição em andamento: esperava 409, obteve %d", rec.Code)
+	}
+
+	close(release)
+	<-done
+	rec = httptest.NewRecorder()
+	route.ServeHTTP(rec, newRequest())
+	if rec.Code != http.StatusCreated || rec.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("após a original: %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestIdempotentTakesOverExpiredLease(t *testing.T) {
+	store := repository.NewInMemoryStore()
+	h := &Handler{idempotencyService: service.NewIdempotencyService(store)}
+	alice := newTestUser(t, store, "alice")
+
+	// A original ficou "em andamento" (ex: o processo caiu) e a reserva venceu
+	created := time.Now().Add(-2 * service.IdempotencyLease)
+	err := store.CreateIdempotencyRecord(context.Background(), &models.IdempotencyRecord{
+		UserID:      alice.ID,
+		Key:         "k1",
+		Fingerprint: service.IdempotencyFingerprint(http.MethodPost, "/v1/transfers", []byte(`{}`)),
+		CreatedAt:   created,
+		ExpiresAt:   created.Add(service.IdempotencyLease),
+	}, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls atomic.Int32
+	route := h.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/transfers", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, alice))
+		rec := httptest.NewRecorder()
+		route.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("tentativa %d: esperava 201, obteve %d", i+1, rec.Code)
+		}
+	}
+	// O reenvio assume a chave e executa uma vez; o seguinte é repetido
+	if calls.Load() != 1 {
+		t.Fatalf("esperava 1 execução, obteve %d", calls.Load())
+	}
+}
+
+func TestIdempotentUploadURLReissues(t *testing.T) {
+	store := repository.NewInMemoryStore()
+	alice := newTestUser(t, store, "alice")
+	s3Client := s3.New(s3.Options{
+		Region: "us-east-1",
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKIDTESTE", SecretAccessKey: "segredo"}, nil
+		}),
+	})
+	h := &Handler{
+		idempotencyService: service.NewIdempotencyService(store),
+		transferService:    service.NewTransferService(store, nil, service.UploadLimits{}),
+		auditService:       service.NewAuditService(store),
+		s3Service:          service.NewS3Service(s3Client, "bucket"),
+	}
+	route := h.IdempotentReissue(h.reissueUploadURL)(http.HandlerFunc(h.handleGetUploadURL))
+
+	body := `{"size":1024,"checksumSha256":"` + base64.StdEncoding.EncodeToString(make([]byte, 32)) + `"}`
+	post := func() (*httptest.ResponseRecorder, UploadURLResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/transfers/upload-url", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, alice))
+		rec := httptest.NewRecorder()
+		route.ServeHTTP(rec, req)
+		var resp UploadURLResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec, resp
+	}
+
+	rec, first := post()
+	if rec.Code != http.StatusOK || first.LinkToEncFile == "" {
+		t.Fatalf("primeira requisição: %d %s", rec.Code, rec.Body)
+	}
+	rec, again := post()
+	if rec.Code != http.StatusOK || rec.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("reenvio: %d %v %s", rec.Code, rec.Header(), rec.Body)
+	}
+	if again.LinkToEncFile != first.LinkToEncFile || again.UploadURL == "" {
+		t.Fatalf("o reenvio deveria devolver uma URL do mesmo arquivo: %+v / %+v", first, again)
+	}
+	// Cada URL entregue (a original e a assinada de novo) é auditada
+	issued, err := store.ListAuditEvents(context.Background(), repository.AuditFilter{Type: service.AuditUploadURLIssued})
+	if err != nil || len(issued) != 2 {
+		t.Fatalf("esperava 2 URLs emitidas, obteve %d (err=%v)", len(issued), err)
+	}
+	usage, err := store.GetStorageUsage(context.Background(), []uuid.UUID{alice.ID}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.ReservedBytes != 1024 {
+		t.Fatalf("esperava uma só reserva de 1024 bytes, obteve %+v", usage)
+	}
+
+	// Usada (ou expirada) a reserva, o reenvio não recebe outra URL
+	if err := store.ReleaseUpload(context.Background(), first.LinkToEncFile); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := post(); rec.Code != http.StatusNotFound {
+		t.Fatalf("reserva liberada: esperava 404, obteve %d", rec.Code)
+	}
+}
+
+// newTestUser cria o usuário no store (os registros de idempotência
+// referenciam users)
+func newTestUser(t *testing.T, store *repository.InMemoryStore, username string) *models.User {
+	t.Helper()
+	user := &models.User{ID: uuid.New(), Username: username, Kind: models.UserKindHuman}
+	if err := store.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
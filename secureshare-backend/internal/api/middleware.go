@@ -2,56 +2,236 @@ package api
 
 import (
 	"context"
+	"log/slog"
+	"net"
 	"net/http"
+	"runtime/debug"
+	"slices"
 	"strings"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/metrics"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 // contextKey é um tipo privado para evitar colisões de chaves no contexto
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey       = contextKey("user")
+	apiKeyContextKey     = contextKey("apiKey")  // presente só em requisições com API key
+	sessionContextKey    = contextKey("session") // service.Session; só em sessões JWT
+	requestLogContextKey = contextKey("requestLog")
+)
 
 // AuthMiddleware é um middleware para validar o token JWT
 func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 1. Obter o header "Authorization"
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			h.respondWithError(w, http.StatusUnauthorized, "Token de autorização não fornecido")
+		user, key, session, err := h.authenticate(r)
+		if err != nil {
+			h.respondWithAppError(w, r, err)
 			return
 		}
 
-		// 2. Verificar se o formato é "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			h.respondWithError(w, http.StatusUnauthorized, "Formato do token inválido")
-			return
+		// Armazenar o usuário (e a API key ou a sessão) no contexto da
+		// requisição
+		var ctx context.Context
+		if key != nil {
+			ctx = context.WithValue(identifyRequest(r, user, "api_key_id", key.ID), userContextKey, user)
+			ctx = context.WithValue(ctx, apiKeyContextKey, key)
+		} else {
+			ctx = context.WithValue(identifyRequest(r, user), userContextKey, user)
+			ctx = context.WithValue(ctx, sessionContextKey, session)
 		}
-		tokenString := parts[1]
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		// 3. Validar o token
-		token, err := h.tokenService.ValidateToken(tokenString)
-		if err != nil {
-			h.respondWithError(w, http.StatusUnauthorized, "Token inválido")
+// authenticate valida a credencial do header Authorization: um JWT de
+// sessão ou a API key de uma conta de serviço (key != nil). Usado pelo
+// AuthMiddleware e, a cada keepalive, pelos streams de eventos.
+func (h *Handler) authenticate(r *http.Request) (*models.User, *models.APIKey, service.Session, error) {
+	// 1. Obter o header "Authorization"
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, nil, service.Session{}, apperr.New(apperr.ErrUnauthorized, apperr.CodeTokenMissing, "Token de autorização não fornecido")
+	}
+
+	// 2. Verificar se o formato é "Bearer <token>"
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return nil, nil, service.Session{}, apperr.New(apperr.ErrUnauthorized, apperr.CodeTokenMalformed, "Formato do token inválido")
+	}
+	tokenString := parts[1]
+
+	// 2.1. API keys de contas de serviço usam o mesmo header
+	if auth.IsAPIKey(tokenString) {
+		user, key, err := h.apiKeyService.Authenticate(r.Context(), tokenString, remoteIP(r))
+		return user, key, service.Session{}, err
+	}
+
+	// 3. Validar o token, conferir se o usuário ainda existe e rejeitar
+	// tokens emitidos antes da última revogação de sessões (ex: troca
+	// de senha)
+	user, session, err := h.userService.AuthenticateSession(r.Context(), tokenString)
+	return user, nil, session, err
+}
+
+// RequireScope restringe a rota a sessões (JWT) e a API keys com o escopo
+// informado. Deve ser usado depois do AuthMiddleware.
+func (h *Handler) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := r.Context().Value(apiKeyContextKey).(*models.APIKey)
+			if ok && !slices.Contains(key.Scopes, scope) {
+				h.respondWithError(w, r, http.StatusForbidden, apperr.CodeMissingScope, "API key sem o escopo '"+scope+"'")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession restringe a rota a sessões de login (JWT), recusando
+// API keys. Usado em rotas de gerenciamento de conta.
+func (h *Handler) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(apiKeyContextKey).(*models.APIKey); ok {
+			h.respondWithError(w, r, http.StatusForbidden, apperr.CodeSessionRequired, "Rota não disponível para API keys")
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		// 4. Obter o UserID do token
-		userID, err := h.tokenService.GetUserIDFromToken(token)
-		if err != nil {
-			h.respondWithError(w, http.StatusUnauthorized, "Token inválido (claims)")
+// RequireAdmin restringe a rota aos administradores (ADMIN_USERNAMES), em
+// sessões de login. Deve ser usado depois do AuthMiddleware.
+func (h *Handler) RequireAdmin(next http.Handler) http.Handler {
+	return h.RequireSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(userContextKey).(*models.User)
+		if !h.isAdmin(user) {
+			h.respondWithError(w, r, http.StatusForbidden, apperr.CodeAdminRequired, "Rota restrita a administradores")
 			return
 		}
+		next.ServeHTTP(w, r)
+	}))
+}
 
-		// 5. (Opcional, mas recomendado) Verificar se o usuário ainda existe no DB
-		user, err := h.userStore.GetUserByID(r.Context(), userID)
-		if err != nil {
-			h.respondWithError(w, http.StatusUnauthorized, "Usuário do token não encontrado")
-			return
+// isAdmin diz se o usuário é um humano listado em ADMIN_USERNAMES
+func (h *Handler) isAdmin(user *models.User) bool {
+	return user != nil && user.Kind == models.UserKindHuman && h.admins[user.Username]
+}
+
+// remoteIP extrai o IP de origem da conexão
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// sessionIssuedAt retorna quando foi feito o login da sessão JWT da
+// requisição (zero em requisições com API key)
+func sessionIssuedAt(r *http.Request) time.Time {
+	session, _ := r.Context().Value(sessionContextKey).(service.Session)
+	return session.IssuedAt
+}
+
+// echoRequestID devolve o ID da requisição (middleware.RequestID) no header
+// X-Request-Id, o mesmo do campo requestId das respostas de erro e do log
+func echoRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			w.Header().Set(middleware.RequestIDHeader, id)
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		// 6. Armazenar o usuário no contexto da requisição
-		ctx := context.WithValue(r.Context(), userContextKey, user)
-		next.ServeHTTP(w, r.WithContext(ctx))
+// requestLog guarda o que o log de acesso só descobre depois da
+// autenticação
+type requestLog struct {
+	userID string
+}
+
+// logRequests põe no contexto o logger da requisição (ver internal/logging),
+// com o ID de middleware.RequestID, e registra o acesso ao final: método,
+// caminho, rota, status, tamanho, duração e usuário. A duração também vai
+// para o histograma de internal/metrics, por rota.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &requestLog{}
+		logger := slog.Default().With("request_id", middleware.GetReqID(r.Context()))
+		ctx := logging.WithContext(context.WithValue(r.Context(), requestLogContextKey, entry), logger)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route := chi.RouteContext(ctx).RoutePattern()
+		duration := time.Since(start)
+		metrics.ObserveHTTPRequest(r.Method, route, status, duration)
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", route,
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration_ms", duration.Milliseconds(),
+		}
+		if entry.userID != "" {
+			attrs = append(attrs, "user_id", entry.userID)
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.Log(ctx, level, "Requisição atendida", attrs...)
+	})
+}
+
+// identifyRequest acrescenta o usuário autenticado e a rota ao logger da
+// requisição (e o usuário ao log de acesso). O AuthMiddleware roda depois
+// do roteamento, quando a rota já é conhecida.
+func identifyRequest(r *http.Request, user *models.User, args ...any) context.Context {
+	if entry, ok := r.Context().Value(requestLogContextKey).(*requestLog); ok {
+		entry.userID = user.ID.String()
+	}
+	args = append([]any{"user_id", user.ID, "route", chi.RouteContext(r.Context()).RoutePattern()}, args...)
+	return logging.With(r.Context(), args...)
+}
+
+// recoverPanics responde 500 a um pânico no handler e o registra, com a
+// pilha, no logger da requisição
+func (h *Handler) recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				// Conexão abortada de propósito: o servidor já trata
+				panic(rec)
+			}
+			logging.FromContext(r.Context()).Error("Pânico no handler", "panic", rec, "stack", string(debug.Stack()))
+			if r.Header.Get("Connection") != "Upgrade" {
+				h.respondWithError(w, r, http.StatusInternalServerError, apperr.CodeInternal, "Erro interno")
+			}
+		}()
+		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+)
+
+func TestRequestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	store := repository.NewInMemoryStore()
+	alice := newTestUser(t, store, "alice")
+	tokens, err := auth.NewTokenService("segredo-de-teste-com-32-bytes!!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := tokens.NewToken(alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{userService: service.NewUserService(store, tokens), validate: newValidator()}
+	routes := h.Routes()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Request-Id", "req-123")
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Request-Id") != "req-123" {
+		t.Fatalf("resposta %d %v", rec.Code, rec.Header())
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log de acesso não é uma linha JSON: %v\n%s", err, buf.String())
+	}
+	want := map[string]any{
+		"request_id": "req-123",
+		"method":     "GET",
+		"path":       "/v1/users",
+		"route":      "/v1/users",
+		"status":     float64(http.StatusOK),
+		"user_id":    alice.ID.String(),
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, esperado %v", key, entry[key], value)
+		}
+	}
+	if strings.Contains(buf.String(), token) {
+		t.Fatalf("token no log: %s", buf.String())
+	}
+}
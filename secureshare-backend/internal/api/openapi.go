@@ -0,0 +1,40 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec é a especificação OpenAPI 3.1 da API. É mantida à mão e
+// conferida contra as rotas e os schemas de resposta em openapi_test.go:
+// ao mudar um handler, atualize o openapi.json junto.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// docsPage renderiza a especificação com o Redoc
+const docsPage = `<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>SecureShare API</title>
+</head>
+<body>
+  <redoc spec-url="/v1/openapi.json"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+// handleOpenAPI (GET /openapi.json)
+func (h *Handler) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// handleDocs (GET /docs)
+func (h *Handler) handleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(docsPage))
+}
@@ -0,0 +1,458 @@
+package api
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/service"
+	"secureshare-backend/pkg/keybackup"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// openAPISchemas liga cada schema de components ao tipo Go que o handler
+// serializa (ou decodifica, nos requests). Um tipo novo de resposta precisa
+// entrar aqui e no openapi.json.
+var openAPISchemas = []struct {
+	name    string
+	value   any
+	request bool // requests aceitam campos opcionais sem omitempty
+}{
+	{"Problem", Problem{}, false},
+	{"FieldError", FieldError{}, false},
+	{"MessageResponse", MessageResponse{}, false},
+	{"TokenResponse", TokenResponse{}, false},
+	{"RegisterRequest", RegisterRequest{}, true},
+	{"LoginRequest", LoginRequest{}, true},
+	{"OIDCLoginResponse", OIDCLoginResponse{}, false},
+	{"ChangePasswordRequest", ChangePasswordRequest{}, true},
+	{"DeleteAccountRequest", DeleteAccountRequest{}, true},
+	{"EmailSettingsRequest", EmailSettingsRequest{}, true},
+	{"EmailSettingsResponse", EmailSettingsResponse{}, false},
+	{"VerifyEmailRequest", VerifyEmailRequest{}, true},
+	{"UserListResponse", UserListResponse{}, false},
+	{"PublicKeyResponse", PublicKeyResponse{}, false},
+	{"DeviceKeyResponse", DeviceKeyResponse{}, false},
+	{"Device", models.Device{}, false},
+	{"AddDeviceRequest", service.AddDeviceRequest{}, true},
+	{"KeyBackup", models.KeyBackup{}, false},
+	{"KeyBackupRequest", keybackup.Backup{}, true},
+	{"UploadRequest", service.UploadRequest{}, true},
+	{"UploadURLResponse", UploadURLResponse{}, false},
+	{"DownloadURLResponse", DownloadURLResponse{}, false},
+	{"NewTransferRequest", service.CreateTransferRequest{}, true},
+	{"TransferMetadata", TransferMetadata{}, false},
+	{"StorageUsageReport", service.StorageUsageReport{}, false},
+	{"QuotaUsage", service.QuotaUsage{}, false},
+	{"ServiceAccountResponse", ServiceAccountResponse{}, false},
+	{"CreateServiceAccountRequest", CreateServiceAccountRequest{}, true},
+	{"APIKey", models.APIKey{}, false},
+	{"CreateAPIKeyRequest", service.CreateAPIKeyRequest{}, true},
+	{"CreatedAPIKeyResponse", CreatedAPIKeyResponse{}, false},
+	{"Webhook", models.Webhook{}, false},
+	{"CreateWebhookRequest", service.CreateWebhookRequest{}, true},
+	{"CreatedWebhookResponse", CreatedWebhookResponse{}, false},
+	{"WebhookDelivery", models.WebhookDelivery{}, false},
+	{"AuditEvent", models.AuditEvent{}, false},
+	{"AuditEventsResponse", AuditEventsResponse{}, false},
+	{"InboxEvent", models.InboxEvent{}, false},
+}
+
+// specSchema é o subconjunto de JSON Schema usado no openapi.json
+type specSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 json.RawMessage        `json:"type"` // "string" ou ["string", "null"]
+	Format               string                 `json:"format"`
+	Properties           map[string]*specSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	Items                *specSchema            `json:"items"`
+	AdditionalProperties *specSchema            `json:"additionalProperties"`
+}
+
+func (s *specSchema) types() []string {
+	if len(s.Type) == 0 {
+		return nil
+	}
+	var one string
+	if json.Unmarshal(s.Type, &one) == nil {
+		return []string{one}
+	}
+	var many []string
+	json.Unmarshal(s.Type, &many)
+	return many
+}
+
+type openAPIDocument struct {
+	OpenAPI string `json:"openapi"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]*specSchema `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadOpenAPI(t *testing.T) *openAPIDocument {
+	t.Helper()
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json inválido: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" || len(doc.Servers) != 1 || doc.Servers[0].URL != "/v1" {
+		t.Fatalf("esperava OpenAPI 3.1.0 com servidor /v1, obteve %s %+v", doc.OpenAPI, doc.Servers)
+	}
+	return &doc
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	// Serviços opcionais não nulos, para registrar todas as rotas
+	h := &Handler{emailService: &service.EmailService{}, ssoService: &service.SSOService{}}
+	routes := map[string]bool{}
+	err := chi.Walk(h.Routes().(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		path, ok := strings.CutPrefix(route, "/v1")
+		if !ok {
+			t.Errorf("rota fora de /v1: %s %s", method, route)
+			return nil
+		}
+		routes[strings.ToLower(method)+" "+path] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documented := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			documented[method+" "+path] = true
+		}
+	}
+
+	for route := range routes {
+		if !documented[route] {
+			t.Errorf("rota sem documentação no openapi.json: %s", route)
+		}
+	}
+	for op := range documented {
+		if !routes[op] {
+			t.Errorf("operação do openapi.json sem rota: %s", op)
+		}
+	}
+}
+
+func TestOpenAPIRefsResolve(t *testing.T) {
+	var raw map[string]any
+	if err := json.Unmarshal(openAPISpec, &raw); err != nil {
+		t.Fatal(err)
+	}
+	components := raw["components"].(map[string]any)
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+				section, _ := components[parts[0]].(map[string]any)
+				if len(parts) != 2 || section[parts[1]] == nil {
+					t.Errorf("$ref sem destino: %s", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(raw)
+}
+
+func TestOpenAPISchemasMatchTypes(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	byName := make(map[string]reflect.Type, len(openAPISchemas))
+	for _, s := range openAPISchemas {
+		byName[s.name] = reflect.TypeOf(s.value)
+	}
+	for name := range doc.Components.Schemas {
+		if byName[name] == nil {
+			t.Errorf("schema %s sem tipo Go em openAPISchemas", name)
+		}
+	}
+
+	c := &schemaChecker{t: t, types: byName}
+	for _, s := range openAPISchemas {
+		schema := doc.Components.Schemas[s.name]
+		if schema == nil {
+			t.Errorf("schema %s ausente do openapi.json", s.name)
+			continue
+		}
+		c.checkObject(s.name, schema, reflect.TypeOf(s.value), s.request)
+	}
+}
+
+// TestOpenAPICoversExportedTypes garante que todo struct exportado do pacote
+// (os schemas de request e resposta) esteja em openAPISchemas
+func TestOpenAPICoversExportedTypes(t *testing.T) {
+	mapped := map[string]bool{}
+	apiPkg := reflect.TypeOf(Handler{}).PkgPath()
+	for _, s := range openAPISchemas {
+		if typ := reflect.TypeOf(s.value); typ.PkgPath() == apiPkg {
+			mapped[typ.Name()] = true
+		}
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(n ast.Node) bool {
+				spec, ok := n.(*ast.TypeSpec)
+				if !ok || !spec.Name.IsExported() || spec.Name.Name == "Handler" {
+					return true
+				}
+				if _, isStruct := spec.Type.(*ast.StructType); isStruct && !mapped[spec.Name.Name] {
+					t.Errorf("%s não está em openAPISchemas nem no openapi.json", spec.Name.Name)
+				}
+				return true
+			})
+		}
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	srv := httptest.NewServer((&Handler{}).Routes())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var doc map[string]any
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("GET /v1/openapi.json: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil || doc["openapi"] != "3.1.0" {
+		t.Fatalf("especificação servida inválida: %v", err)
+	}
+
+	resp, err = http.Get(srv.URL + "/v1/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("GET /v1/docs: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
+
+// === Comparação entre schema e tipo Go ===
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+	rawType  = reflect.TypeOf(json.RawMessage(nil))
+)
+
+type schemaChecker struct {
+	t     *testing.T
+	types map[string]reflect.Type
+}
+
+type jsonField struct {
+	name      string
+	typ       reflect.Type
+	omitempty bool
+	required  bool // validate:"required"
+}
+
+// jsonFields lista os campos como encoding/json os vê, achatando os structs
+// embutidos
+func jsonFields(typ reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			fields = append(fields, jsonFields(embedded)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		// Regras depois de "dive" valem para os elementos, não para o campo
+		rules, _, _ := strings.Cut(f.Tag.Get("validate"), ",dive")
+		fields = append(fields, jsonField{
+			name:      name,
+			typ:       f.Type,
+			omitempty: slices.Contains(strings.Split(opts, ","), "omitempty"),
+			required:  slices.Contains(strings.Split(rules, ","), "required"),
+		})
+	}
+	return fields
+}
+
+// checkObject confere propriedades e required. Em respostas, required são
+// exatamente os campos sem omitempty (sempre presentes no JSON); em
+// requests, required só pode ter campos sem omitempty e precisa ter os
+// obrigatórios da validação.
+func (c *schemaChecker) checkObject(path string, schema *specSchema, typ reflect.Type, request bool) {
+	if got := schema.types(); len(got) != 1 || got[0] != "object" {
+		c.t.Errorf("%s: esperava type object, obteve %v", path, got)
+		return
+	}
+
+	fields := jsonFields(typ)
+	var names, always, validated []string
+	for _, f := range fields {
+		names = append(names, f.name)
+		if !f.omitempty {
+			always = append(always, f.name)
+		}
+		if f.required {
+			validated = append(validated, f.name)
+		}
+		prop := schema.Properties[f.name]
+		if prop == nil {
+			c.t.Errorf("%s: campo %s (%s) ausente do schema", path, f.name, f.typ)
+			continue
+		}
+		c.checkType(path+"."+f.name, prop, f.typ, !f.omitempty)
+	}
+	for name := range schema.Properties {
+		if !slices.Contains(names, name) {
+			c.t.Errorf("%s: propriedade %s não existe em %s", path, name, typ)
+		}
+	}
+
+	required := slices.Clone(schema.Required)
+	sort.Strings(required)
+	sort.Strings(always)
+	if !request {
+		if !slices.Equal(required, always) {
+			c.t.Errorf("%s: required %v, mas os campos sempre presentes são %v", path, required, always)
+		}
+		return
+	}
+	for _, name := range required {
+		if !slices.Contains(always, name) {
+			c.t.Errorf("%s: %s é required, mas tem omitempty", path, name)
+		}
+	}
+	for _, name := range validated {
+		if !slices.Contains(required, name) {
+			c.t.Errorf("%s: %s é obrigatório na validação, mas não é required", path, name)
+		}
+	}
+}
+
+// checkType confere o tipo de uma propriedade. present indica um campo sem
+// omitempty: se for ponteiro, o Go pode serializá-lo como null.
+func (c *schemaChecker) checkType(path string, schema *specSchema, typ reflect.Type, present bool) {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if want := c.types[name]; want != typ {
+			c.t.Errorf("%s: $ref %s aponta para %v, mas o campo é %v", path, name, want, typ)
+		}
+		return
+	}
+
+	types := schema.types()
+	nullable := slices.Contains(types, "null")
+	types = slices.DeleteFunc(slices.Clone(types), func(s string) bool { return s == "null" })
+	switch typ.Kind() {
+	case reflect.Pointer:
+		if present && !nullable {
+			c.t.Errorf("%s: ponteiro sem omitempty pode ser null, mas o schema não aceita", path)
+		}
+		typ = typ.Elem()
+	case reflect.Slice, reflect.Map:
+	default:
+		if nullable {
+			c.t.Errorf("%s: schema aceita null, mas %v nunca é null", path, typ)
+		}
+	}
+
+	expect := func(wantType, wantFormat string) {
+		if len(types) != 1 || types[0] != wantType {
+			c.t.Errorf("%s: esperava type %s para %v, obteve %v", path, wantType, typ, types)
+		}
+		if wantFormat != "" && schema.Format != wantFormat {
+			c.t.Errorf("%s: esperava format %s para %v, obteve %q", path, wantFormat, typ, schema.Format)
+		}
+	}
+
+	switch {
+	case typ == rawType:
+		if len(types) != 0 {
+			c.t.Errorf("%s: JSON arbitrário não deve ter type, obteve %v", path, types)
+		}
+	case typ == timeType:
+		expect("string", "date-time")
+	case typ == uuidType:
+		expect("string", "uuid")
+	case typ.Kind() == reflect.String:
+		expect("string", "")
+	case typ.Kind() == reflect.Bool:
+		expect("boolean", "")
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Int64:
+		expect("integer", "")
+	case typ.Kind() == reflect.Slice:
+		expect("array", "")
+		if schema.Items == nil {
+			c.t.Errorf("%s: array sem items", path)
+			return
+		}
+		c.checkType(path+"[]", schema.Items, typ.Elem(), false)
+	case typ.Kind() == reflect.Map:
+		expect("object", "")
+		if schema.AdditionalProperties == nil {
+			c.t.Errorf("%s: mapa sem additionalProperties", path)
+			return
+		}
+		c.checkType(path+"{}", schema.AdditionalProperties, typ.Elem(), false)
+	case typ.Kind() == reflect.Struct:
+		c.t.Errorf("%s: struct %v deve usar $ref para um schema de components", path, typ)
+	default:
+		c.t.Errorf("%s: tipo %v sem mapeamento para JSON Schema", path, typ)
+	}
+}
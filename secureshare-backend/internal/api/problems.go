@@ -0,0 +1,147 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/i18n"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// problemTitles são os títulos (campo "title" do Problem) de cada código,
+// por idioma. Todo código de internal/apperr deve estar aqui (ver
+// TestProblemTitlesCoverCodes).
+var problemTitles = map[apperr.Code]map[string]string{
+	apperr.CodeValidation:    {i18n.LocalePtBR: "Dados inválidos", i18n.LocaleEn: "Invalid data"},
+	apperr.CodeUnauthorized:  {i18n.LocalePtBR: "Não autenticado", i18n.LocaleEn: "Not authenticated"},
+	apperr.CodeForbidden:     {i18n.LocalePtBR: "Acesso negado", i18n.LocaleEn: "Access denied"},
+	apperr.CodeNotFound:      {i18n.LocalePtBR: "Recurso não encontrado", i18n.LocaleEn: "Resource not found"},
+	apperr.CodeConflict:      {i18n.LocalePtBR: "Conflito com o estado atual", i18n.LocaleEn: "Conflict with the current state"},
+	apperr.CodeTooLarge:      {i18n.LocalePtBR: "Tamanho acima do limite", i18n.LocaleEn: "Size over the limit"},
+	apperr.CodeQuotaExceeded: {i18n.LocalePtBR: "Cota excedida", i18n.LocaleEn: "Quota exceeded"},
+	apperr.CodeUnprocessable: {i18n.LocalePtBR: "Requisição não processável", i18n.LocaleEn: "Unprocessable request"},
+	apperr.CodeInternal:      {i18n.LocalePtBR: "Erro interno", i18n.LocaleEn: "Internal error"},
+
+	apperr.CodeInvalidJSON:      {i18n.LocalePtBR: "Payload JSON inválido", i18n.LocaleEn: "Invalid JSON payload"},
+	apperr.CodeInvalidParameter: {i18n.LocalePtBR: "Parâmetro inválido", i18n.LocaleEn: "Invalid parameter"},
+	apperr.CodeBodyTooLarge:     {i18n.LocalePtBR: "Corpo da requisição grande demais", i18n.LocaleEn: "Request body too large"},
+	apperr.CodeUnknownField:     {i18n.LocalePtBR: "Campo desconhecido", i18n.LocaleEn: "Unknown field"},
+	apperr.CodeRouteNotFound:    {i18n.LocalePtBR: "Rota não encontrada", i18n.LocaleEn: "Route not found"},
+	apperr.CodeMethodNotAllowed: {i18n.LocalePtBR: "Método não permitido", i18n.LocaleEn: "Method not allowed"},
+
+	apperr.CodeTokenMissing:       {i18n.LocalePtBR: "Token de autorização não fornecido", i18n.LocaleEn: "Authorization token missing"},
+	apperr.CodeTokenMalformed:     {i18n.LocalePtBR: "Formato do token inválido", i18n.LocaleEn: "Malformed token"},
+	apperr.CodeInvalidToken:       {i18n.LocalePtBR: "Token inválido ou expirado", i18n.LocaleEn: "Invalid or expired token"},
+	apperr.CodeSessionRevoked:     {i18n.LocalePtBR: "Sessão revogada", i18n.LocaleEn: "Session revoked"},
+	apperr.CodeInvalidCredentials: {i18n.LocalePtBR: "Credenciais inválidas", i18n.LocaleEn: "Invalid credentials"},
+	apperr.CodeInvalidAPIKey:      {i18n.LocalePtBR: "API key inválida", i18n.LocaleEn: "Invalid API key"},
+	apperr.CodeAPIKeyRevoked:      {i18n.LocalePtBR: "API key revogada", i18n.LocaleEn: "API key revoked"},
+	apperr.CodeAPIKeyExpired:      {i18n.LocalePtBR: "API key expirada", i18n.LocaleEn: "API key expired"},
+	apperr.CodeAPIKeyIPDenied:     {i18n.LocalePtBR: "IP não permitido para a API key", i18n.LocaleEn: "IP not allowed for the API key"},
+	apperr.CodeMissingScope:       {i18n.LocalePtBR: "API key sem o escopo necessário", i18n.LocaleEn: "API key lacks the required scope"},
+	apperr.CodeSessionRequired:    {i18n.LocalePtBR: "Rota não disponível para API keys", i18n.LocaleEn: "Route not available to API keys"},
+	apperr.CodeAdminRequired:      {i18n.LocalePtBR: "Rota restrita a administradores", i18n.LocaleEn: "Route restricted to administrators"},
+	apperr.CodeSSOFailed:          {i18n.LocalePtBR: "Login SSO recusado", i18n.LocaleEn: "SSO login rejected"},
+	apperr.CodeSSOStateInvalid:    {i18n.LocalePtBR: "Sessão de login SSO inválida", i18n.LocaleEn: "Invalid SSO login session"},
+	apperr.CodeOriginNotAllowed:   {i18n.LocalePtBR: "Origem não permitida", i18n.LocaleEn: "Origin not allowed"},
+
+	apperr.CodeUserExists:               {i18n.LocalePtBR: "Usuário já existe", i18n.LocaleEn: "User already exists"},
+	apperr.CodeUserNotFound:             {i18n.LocalePtBR: "Usuário não encontrado", i18n.LocaleEn: "User not found"},
+	apperr.CodeWrongPassword:            {i18n.LocalePtBR: "Senha incorreta", i18n.LocaleEn: "Wrong password"},
+	apperr.CodeReauthRequired:           {i18n.LocalePtBR: "Reautenticação necessária", i18n.LocaleEn: "Reauthentication required"},
+	apperr.CodeServiceAccountNotFound:   {i18n.LocalePtBR: "Conta de serviço não encontrada", i18n.LocaleEn: "Service account not found"},
+	apperr.CodeInvalidEmail:             {i18n.LocalePtBR: "E-mail inválido", i18n.LocaleEn: "Invalid email"},
+	apperr.CodeUnsupportedLocale:        {i18n.LocalePtBR: "Idioma não suportado", i18n.LocaleEn: "Unsupported locale"},
+	apperr.CodeInvalidVerificationToken: {i18n.LocalePtBR: "Token de verificação inválido", i18n.LocaleEn: "Invalid verification token"},
+
+	apperr.CodeInvalidKeyFormat:       {i18n.LocalePtBR: "Formato de chave inválido", i18n.LocaleEn: "Invalid key format"},
+	apperr.CodeInvalidKeyBackup:       {i18n.LocalePtBR: "Backup de chaves inválido", i18n.LocaleEn: "Invalid key backup"},
+	apperr.CodeKeyBackupNotFound:      {i18n.LocalePtBR: "Backup de chaves não encontrado", i18n.LocaleEn: "Key backup not found"},
+	apperr.CodeDeviceNotFound:         {i18n.LocalePtBR: "Dispositivo não encontrado", i18n.LocaleEn: "Device not found"},
+	apperr.CodeSignerDeviceNotFound:   {i18n.LocalePtBR: "Dispositivo assinante não encontrado", i18n.LocaleEn: "Signing device not found"},
+	apperr.CodeInvalidCrossSignature:  {i18n.LocalePtBR: "Assinatura cruzada inválida", i18n.LocaleEn: "Invalid cross-signature"},
+	apperr.CodeLastActiveDevice:       {i18n.LocalePtBR: "Último dispositivo ativo", i18n.LocaleEn: "Last active device"},
+	apperr.CodeInvalidScope:           {i18n.LocalePtBR: "Escopo inválido", i18n.LocaleEn: "Invalid scope"},
+	apperr.CodeAPIKeyNotFound:         {i18n.LocalePtBR: "API key não encontrada", i18n.LocaleEn: "API key not found"},
+	apperr.CodeInvalidAllowedIP:       {i18n.LocalePtBR: "IP ou rede permitida inválida", i18n.LocaleEn: "Invalid allowed IP or network"},
+	apperr.CodeInvalidAPIKeyExpiresAt: {i18n.LocalePtBR: "Data de expiração inválida", i18n.LocaleEn: "Invalid expiration date"},
+
+	apperr.CodeDestUserNotFound:   {i18n.LocalePtBR: "Usuário de destino não encontrado", i18n.LocaleEn: "Destination user not found"},
+	apperr.CodeDestDeviceNotFound: {i18n.LocalePtBR: "Dispositivo de destino não encontrado", i18n.LocaleEn: "Destination device not found"},
+	apperr.CodeEmptySKB:           {i18n.LocalePtBR: "Chave de sessão ausente", i18n.LocaleEn: "Session key missing"},
+	apperr.CodeInvalidChecksum:    {i18n.LocalePtBR: "Checksum inválido", i18n.LocaleEn: "Invalid checksum"},
+	apperr.CodeChecksumMismatch:   {i18n.LocalePtBR: "Checksum não confere", i18n.LocaleEn: "Checksum mismatch"},
+	apperr.CodeInvalidFileSize:    {i18n.LocalePtBR: "Tamanho de arquivo inválido", i18n.LocaleEn: "Invalid file size"},
+	apperr.CodeUploadNotFound:     {i18n.LocalePtBR: "Upload não encontrado", i18n.LocaleEn: "Upload not found"},
+	apperr.CodeUploadNotOwned:     {i18n.LocalePtBR: "Upload de outro usuário", i18n.LocaleEn: "Upload belongs to another user"},
+	apperr.CodeFileNotFound:       {i18n.LocalePtBR: "Arquivo não encontrado", i18n.LocaleEn: "File not found"},
+	apperr.CodeFileTooLarge:       {i18n.LocalePtBR: "Arquivo grande demais", i18n.LocaleEn: "File too large"},
+	apperr.CodeUserQuotaExceeded:  {i18n.LocalePtBR: "Cota do usuário excedida", i18n.LocaleEn: "User quota exceeded"},
+	apperr.CodeOrgQuotaExceeded:   {i18n.LocalePtBR: "Cota da organização excedida", i18n.LocaleEn: "Organization quota exceeded"},
+
+	apperr.CodeWebhookNotFound:   {i18n.LocalePtBR: "Webhook não encontrado", i18n.LocaleEn: "Webhook not found"},
+	apperr.CodeDeliveryNotFound:  {i18n.LocalePtBR: "Entrega não encontrada", i18n.LocaleEn: "Delivery not found"},
+	apperr.CodeInvalidWebhookURL: {i18n.LocalePtBR: "URL de webhook inválida", i18n.LocaleEn: "Invalid webhook URL"},
+	apperr.CodeUnknownEventType:  {i18n.LocalePtBR: "Tipo de evento desconhecido", i18n.LocaleEn: "Unknown event type"},
+
+	apperr.CodeInvalidIdempotencyKey:    {i18n.LocalePtBR: "Idempotency-Key inválida", i18n.LocaleEn: "Invalid Idempotency-Key"},
+	apperr.CodeIdempotencyKeyReused:     {i18n.LocalePtBR: "Idempotency-Key já utilizada com outra requisição", i18n.LocaleEn: "Idempotency-Key reused with a different request"},
+	apperr.CodeIdempotencyKeyInProgress: {i18n.LocalePtBR: "Requisição original ainda em andamento", i18n.LocaleEn: "Original request still in progress"},
+}
+
+// problemTitle retorna o título do código no idioma, ou o de CodeInternal
+func problemTitle(locale string, code apperr.Code) string {
+	titles, ok := problemTitles[code]
+	if !ok {
+		titles = problemTitles[apperr.CodeInternal]
+	}
+	return titles[locale]
+}
+
+// fieldMessage descreve, no idioma, a regra de validação violada pelo campo
+func fieldMessage(locale string, fe validator.FieldError) string {
+	param := fe.Param()
+	// Em strings, min/max contam caracteres; em listas, itens
+	if tag := fe.Tag(); tag == "min" || tag == "max" {
+		unit := "chars"
+		if k := fe.Kind(); k == reflect.Slice || k == reflect.Map || k == reflect.Array {
+			unit = "items"
+		}
+		return fieldRuleMessage(locale, tag+"_"+unit, param)
+	}
+	return fieldRuleMessage(locale, fe.Tag(), param)
+}
+
+// fieldRules são as mensagens das regras de validação dos campos, por
+// idioma; %s é o parâmetro da regra
+var fieldRules = map[string]map[string]string{
+	"required":      {i18n.LocalePtBR: "é obrigatório", i18n.LocaleEn: "is required"},
+	"required_with": {i18n.LocalePtBR: "é obrigatório junto com %s", i18n.LocaleEn: "is required with %s"},
+	"min_chars":     {i18n.LocalePtBR: "deve ter no mínimo %s caracteres", i18n.LocaleEn: "must have at least %s characters"},
+	"max_chars":     {i18n.LocalePtBR: "deve ter no máximo %s caracteres", i18n.LocaleEn: "must have at most %s characters"},
+	"min_items":     {i18n.LocalePtBR: "deve ter no mínimo %s itens", i18n.LocaleEn: "must have at least %s items"},
+	"max_items":     {i18n.LocalePtBR: "deve ter no máximo %s itens", i18n.LocaleEn: "must have at most %s items"},
+	"base64":        {i18n.LocalePtBR: "deve estar em base64", i18n.LocaleEn: "must be base64"},
+	"uuid":          {i18n.LocalePtBR: "deve ser um UUID", i18n.LocaleEn: "must be a UUID"},
+	"upload_key":    {i18n.LocalePtBR: "deve ser a chave retornada por /transfers/upload-url", i18n.LocaleEn: "must be the key returned by /transfers/upload-url"},
+	"unknown":       {i18n.LocalePtBR: "não é aceito nesta rota", i18n.LocaleEn: "is not accepted by this route"},
+	"type":          {i18n.LocalePtBR: "deve ser do tipo %s", i18n.LocaleEn: "must be of type %s"},
+}
+
+// fieldRuleMessage retorna a mensagem da regra no idioma
+func fieldRuleMessage(locale, rule, param string) string {
+	messages, ok := fieldRules[rule]
+	if !ok {
+		if locale == i18n.LocaleEn {
+			return fmt.Sprintf("fails the %q rule", rule)
+		}
+		return fmt.Sprintf("não atende à regra %q", rule)
+	}
+	if strings.Contains(messages[locale], "%s") {
+		return fmt.Sprintf(messages[locale], param)
+	}
+	return messages[locale]
+}
@@ -4,50 +4,134 @@ package api
 import (
 	"net/http"
 
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors" // <-- 1. Importe o pacote
 )
 
+// allowedOrigins são as origens do frontend aceitas pelo CORS e pelo
+// handshake do WebSocket de eventos
+var allowedOrigins = []string{"http://localhost:3000"}
+
 // Routes configura e retorna o roteador Chi
 func (h *Handler) Routes() http.Handler {
 	r := chi.NewRouter()
 
-	// Middlewares globais
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	// Middlewares globais (eventsQueryToken antes do log, para o token
+	// não aparecer no log de acesso)
+	r.Use(eventsQueryToken)
+	r.Use(middleware.RequestID)
+	r.Use(echoRequestID)
+	r.Use(logRequests)
+	r.Use(h.recoverPanics)
 	r.Use(middleware.StripSlashes)
 
 	// --- 2. ADICIONE A CONFIGURAÇÃO DE CORS AQUI ---
 	// Isso permite que seu frontend (localhost:3000)
 	// se comunique com seu backend (localhost:8080)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000"},
+		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Last-Event-ID", IdempotencyKeyHeader, middleware.RequestIDHeader},
+		ExposedHeaders:   []string{middleware.RequestIDHeader, IdempotentReplayedHeader},
 		AllowCredentials: true,
 		MaxAge:           300, // Tempo de cache da preflight
 	}))
 	// ------------------------------------------
 
+	// Erros do roteador no mesmo formato dos handlers (definidos antes de
+	// r.Route, para valerem também no subroteador)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		h.respondWithError(w, r, http.StatusNotFound, apperr.CodeRouteNotFound, "Rota não encontrada")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		h.respondWithError(w, r, http.StatusMethodNotAllowed, apperr.CodeMethodNotAllowed, "Método não permitido nesta rota")
+	})
+
 	// Rotas da API V1
 	r.Route("/v1", func(r chi.Router) {
 		// Endpoints públicos (sem autenticação)
+		r.Get("/openapi.json", h.handleOpenAPI)
+		r.Get("/docs", h.handleDocs)
 		r.Post("/users/register", h.handleRegisterUser)
 		r.Post("/users/login", h.handleLoginUser)
 
-		// Endpoints protegidos (requerem autenticação)
+		// Confirmação de e-mail (apenas com SMTP configurado)
+		if h.emailService != nil {
+			r.Post("/users/verify-email", h.handleVerifyEmail)
+		}
+
+		// Login SSO (apenas se configurado)
+		if h.ssoService != nil {
+			r.Get("/auth/oidc/login", h.handleOIDCLogin)
+			r.Get("/auth/oidc/callback", h.handleOIDCCallback)
+		}
+
+		// Endpoints protegidos (requerem autenticação: JWT ou API key)
 		r.Group(func(r chi.Router) {
 			r.Use(h.AuthMiddleware)
 
-			r.Get("/users", h.handleGetAllUsers)
-			r.Get("/users/{username}/key", h.handleGetUserKey)
-			
-			r.Get("/transfers/download-url", h.handleGetDownloadURL)
-			r.Post("/transfers/upload-url", h.handleGetUploadURL)
+			// Rotas acessíveis a API keys, conforme o escopo
+			r.With(h.RequireScope(auth.ScopeUsersRead)).Get("/users", h.handleGetAllUsers)
+			r.With(h.RequireScope(auth.ScopeUsersRead)).Get("/users/{username}/key", h.handleGetUserKey)
+
+			r.With(h.RequireScope(auth.ScopeTransfersRead)).Get("/transfers/download-url", h.handleGetDownloadURL)
+			// Reenvios com Idempotency-Key repetem a resposta (ver Idempotent);
+			// os de upload-url reusam a reserva com uma URL assinada de novo
+			r.With(h.RequireScope(auth.ScopeTransfersCreate), h.IdempotentReissue(h.reissueUploadURL)).Post("/transfers/upload-url", h.handleGetUploadURL)
+
+			r.With(h.RequireScope(auth.ScopeTransfersCreate), h.Idempotent).Post("/transfers", h.handleCreateTransfer)
+			r.With(h.RequireScope(auth.ScopeTransfersRead)).Get("/transfers", h.handleGetTransfers)
+			r.With(h.RequireScope(auth.ScopeTransfersRead)).Get("/users/me/usage", h.handleGetUsage)
+
+			// Notificações em tempo real (SSE e WebSocket)
+			r.With(h.RequireScope(auth.ScopeTransfersRead)).Get("/events", h.handleEvents)
+			r.With(h.RequireScope(auth.ScopeTransfersRead)).Get("/events/ws", h.handleEventsWebSocket)
+
+			// Gerenciamento de conta: apenas sessões de login
+			r.Group(func(r chi.Router) {
+				r.Use(h.RequireSession)
+
+				r.Put("/users/me/password", h.handleChangePassword)
+				r.Delete("/users/me", h.handleDeleteAccount)
+				r.Get("/users/me/key-backup", h.handleGetKeyBackup)
+				r.Put("/users/me/key-backup", h.handlePutKeyBackup)
+				if h.emailService != nil {
+					r.Get("/users/me/email", h.handleGetEmail)
+					r.Put("/users/me/email", h.handlePutEmail)
+					r.Delete("/users/me/email", h.handleDeleteEmail)
+				}
+
+				r.Get("/devices", h.handleListDevices)
+				r.Post("/devices", h.handleAddDevice)
+				r.Delete("/devices/{deviceId}", h.handleRevokeDevice)
+
+				r.Get("/service-accounts", h.handleListServiceAccounts)
+				r.Post("/service-accounts", h.handleCreateServiceAccount)
+				r.Get("/service-accounts/{username}/api-keys", h.handleListAPIKeys)
+				r.Post("/service-accounts/{username}/api-keys", h.handleCreateAPIKey)
+				r.Delete("/service-accounts/{username}/api-keys/{keyId}", h.handleRevokeAPIKey)
+				r.Get("/service-accounts/{username}/webhooks", h.handleListServiceAccountWebhooks)
+				r.Post("/service-accounts/{username}/webhooks", h.handleCreateServiceAccountWebhook)
+
+				r.Get("/webhooks", h.handleListWebhooks)
+				r.Post("/webhooks", h.handleCreateWebhook)
+				r.Delete("/webhooks/{webhookId}", h.handleDeleteWebhook)
+				r.Get("/webhooks/{webhookId}/deliveries", h.handleListWebhookDeliveries)
+				r.Post("/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", h.handleRedeliverWebhook)
+			})
+
+			// Administração: apenas sessões de ADMIN_USERNAMES
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(h.RequireAdmin)
 
-			r.Post("/transfers", h.handleCreateTransfer)
-			r.Get("/transfers", h.handleGetTransfers)
+				r.Get("/audit", h.handleListAuditEvents)
+				r.Get("/users/{username}/webhooks", h.handleAdminListWebhooks)
+				r.Post("/users/{username}/webhooks", h.handleAdminCreateWebhook)
+			})
 		})
 	})
 
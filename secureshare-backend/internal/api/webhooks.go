@@ -0,0 +1,226 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// === Handlers de Webhooks ===
+
+// CreatedWebhookResponse é o webhook recém-criado, com o segredo do HMAC
+// (só exibido nesta resposta)
+type CreatedWebhookResponse struct {
+	*models.Webhook
+	Secret string `json:"secret"`
+}
+
+// handleListWebhooks (GET /webhooks)
+func (h *Handler) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+	h.listWebhooks(w, r, user)
+}
+
+// handleCreateWebhook (POST /webhooks)
+func (h *Handler) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return
+	}
+	h.createWebhook(w, r, user)
+}
+
+// handleListServiceAccountWebhooks (GET /service-accounts/{username}/webhooks)
+func (h *Handler) handleListServiceAccountWebhooks(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.serviceAccountFromRequest(w, r)
+	if !ok {
+		return
+	}
+	h.listWebhooks(w, r, account)
+}
+
+// handleCreateServiceAccountWebhook (POST /service-accounts/{username}/webhooks)
+func (h *Handler) handleCreateServiceAccountWebhook(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.serviceAccountFromRequest(w, r)
+	if !ok {
+		return
+	}
+	h.createWebhook(w, r, account)
+}
+
+// handleAdminListWebhooks (GET /admin/users/{username}/webhooks)
+func (h *Handler) handleAdminListWebhooks(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	h.listWebhooks(w, r, owner)
+}
+
+// handleAdminCreateWebhook (POST /admin/users/{username}/webhooks)
+func (h *Handler) handleAdminCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	h.createWebhook(w, r, owner)
+}
+
+// adminTargetUser resolve o usuário {username} das rotas /admin/users
+func (h *Handler) adminTargetUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	username := chi.URLParam(r, "username")
+	user, err := h.userStore.GetUserByUsername(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			h.respondWithError(w, r, http.StatusNotFound, apperr.CodeUserNotFound, "Usuário '"+username+"' não encontrado")
+			return nil, false
+		}
+		h.respondWithAppError(w, r, err)
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *Handler) listWebhooks(w http.ResponseWriter, r *http.Request, owner *models.User) {
+	webhooks, err := h.webhookService.ListWebhooks(r.Context(), owner.ID)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, webhooks)
+}
+
+func (h *Handler) createWebhook(w http.ResponseWriter, r *http.Request, owner *models.User) {
+	actor, _ := r.Context().Value(userContextKey).(*models.User)
+
+	var req service.CreateWebhookRequest
+	if !h.decodeJSON(w, r, &req, maxJSONBody) {
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.respondWithValidationError(w, r, err)
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(r.Context(), owner, actor.Username, req)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+	h.audit(r, service.AuditWebhookCreated, actor, "webhook:"+webhook.ID.String(), map[string]string{
+		"owner": owner.Username,
+		"url":   webhook.URL,
+	})
+
+	// O segredo do HMAC só é exibido nesta resposta
+	response := CreatedWebhookResponse{
+		Webhook: webhook,
+		Secret:  webhook.Secret,
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, response)
+}
+
+// webhookFromRequest resolve o webhook {webhookId} que o usuário autenticado
+// pode gerenciar (próprio, de uma conta de serviço dele ou, para
+// administradores, qualquer um)
+func (h *Handler) webhookFromRequest(w http.ResponseWriter, r *http.Request) (*models.Webhook, bool) {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok || user == nil {
+		h.respondWithError(w, r, http.StatusUnauthorized, apperr.CodeUnauthorized, "Contexto de usuário inválido")
+		return nil, false
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "webhookId"))
+	if err != nil {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "ID de webhook inválido")
+		return nil, false
+	}
+
+	webhook, err := h.webhookService.GetWebhook(r.Context(), user, h.isAdmin(user), id)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return nil, false
+	}
+	return webhook, true
+}
+
+// handleDeleteWebhook (DELETE /webhooks/{webhookId})
+func (h *Handler) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.webhookFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(r.Context(), webhook.ID); err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+	actor, _ := r.Context().Value(userContextKey).(*models.User)
+	h.audit(r, service.AuditWebhookDeleted, actor, "webhook:"+webhook.ID.String(), nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListWebhookDeliveries (GET /webhooks/{webhookId}/deliveries)
+// Lista as entregas mais recentes primeiro; ?limit= até 100.
+func (h *Handler) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.webhookFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "Parâmetro 'limit' deve ser um inteiro positivo")
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), webhook.ID, limit)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, deliveries)
+}
+
+// handleRedeliverWebhook (POST /webhooks/{webhookId}/deliveries/{deliveryId}/redeliver)
+// Enfileira uma nova entrega com o mesmo corpo; responde 202 com ela.
+func (h *Handler) handleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.webhookFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		h.respondWithError(w, r, http.StatusBadRequest, apperr.CodeInvalidParameter, "ID de entrega inválido")
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(r.Context(), webhook.ID, deliveryID)
+	if err != nil {
+		h.respondWithAppError(w, r, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusAccepted, delivery)
+}
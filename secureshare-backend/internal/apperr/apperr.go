@@ -0,0 +1,107 @@
+// Package apperr define os erros tipados compartilhados por stores e
+// serviços. A camada HTTP decide o status pelo tipo (errors.Is), nunca pelo
+// texto da mensagem, que pode ser traduzida ou reescrita livremente.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Categorias de erro. Use errors.Is(err, apperr.ErrNotFound) para testar.
+var (
+	ErrNotFound     = errors.New("não encontrado")
+	ErrConflict     = errors.New("conflito")
+	ErrForbidden    = errors.New("proibido")
+	ErrValidation   = errors.New("dados inválidos")
+	ErrUnauthorized = errors.New("não autenticado")
+	// ErrTooLarge: o arquivo passa do tamanho máximo permitido
+	ErrTooLarge = errors.New("arquivo grande demais")
+	// ErrQuotaExceeded: a operação passaria da cota de armazenamento
+	ErrQuotaExceeded = errors.New("cota de armazenamento excedida")
+	// ErrUnprocessable: o pedido é bem formado, mas contradiz um anterior
+	// (ex: Idempotency-Key reutilizada com outro corpo)
+	ErrUnprocessable = errors.New("requisição não processável")
+)
+
+// Error é um erro de uma categoria conhecida com mensagem própria. A
+// mensagem é exibida ao cliente; a categoria define o status HTTP e, se
+// houver, code identifica o erro para os clientes (ver Code).
+type Error struct {
+	kind  error
+	code  Code
+	msg   string
+	cause error
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// Unwrap expõe a categoria e, se houver, a causa original
+func (e *Error) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.kind}
+	}
+	return []error{e.kind, e.cause}
+}
+
+func newError(kind error, format string, args ...any) error {
+	return &Error{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// NotFound cria um erro da categoria ErrNotFound
+func NotFound(format string, args ...any) error {
+	return newError(ErrNotFound, format, args...)
+}
+
+// Conflict cria um erro da categoria ErrConflict
+func Conflict(format string, args ...any) error {
+	return newError(ErrConflict, format, args...)
+}
+
+// Forbidden cria um erro da categoria ErrForbidden
+func Forbidden(format string, args ...any) error {
+	return newError(ErrForbidden, format, args...)
+}
+
+// Validation cria um erro da categoria ErrValidation
+func Validation(format string, args ...any) error {
+	return newError(ErrValidation, format, args...)
+}
+
+// Unauthorized cria um erro da categoria ErrUnauthorized
+func Unauthorized(format string, args ...any) error {
+	return newError(ErrUnauthorized, format, args...)
+}
+
+// TooLarge cria um erro da categoria ErrTooLarge
+func TooLarge(format string, args ...any) error {
+	return newError(ErrTooLarge, format, args...)
+}
+
+// QuotaExceeded cria um erro da categoria ErrQuotaExceeded
+func QuotaExceeded(format string, args ...any) error {
+	return newError(ErrQuotaExceeded, format, args...)
+}
+
+// Unprocessable cria um erro da categoria ErrUnprocessable
+func Unprocessable(format string, args ...any) error {
+	return newError(ErrUnprocessable, format, args...)
+}
+
+// New cria um erro da categoria kind com um código estável
+func New(kind error, code Code, format string, args ...any) error {
+	return &Error{kind: kind, code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// Wrap cria um erro da categoria kind que preserva cause na cadeia
+// (errors.Is/As), mas exibe apenas a mensagem formatada
+func Wrap(kind, cause error, format string, args ...any) error {
+	return &Error{kind: kind, msg: fmt.Sprintf(format, args...), cause: cause}
+}
+
+// WrapCode é o Wrap com um código estável
+func WrapCode(kind error, code Code, cause error, format string, args ...any) error {
+	return &Error{kind: kind, code: code, msg: fmt.Sprintf(format, args...), cause: cause}
+}
@@ -0,0 +1,145 @@
+package apperr
+
+import "errors"
+
+// Code é o identificador estável de um erro, exposto aos clientes (campo
+// "code" das respostas de erro da API). Ao contrário da mensagem, nunca
+// muda: clientes podem decidir por ele.
+type Code string
+
+// Códigos padrão de cada categoria, usados quando o erro não tem um código
+// próprio (ver CodeOf)
+const (
+	CodeValidation    Code = "VALIDATION_FAILED"
+	CodeUnauthorized  Code = "UNAUTHORIZED"
+	CodeForbidden     Code = "FORBIDDEN"
+	CodeNotFound      Code = "NOT_FOUND"
+	CodeConflict      Code = "CONFLICT"
+	CodeTooLarge      Code = "TOO_LARGE"
+	CodeQuotaExceeded Code = "QUOTA_EXCEEDED"
+	CodeUnprocessable Code = "UNPROCESSABLE"
+	CodeInternal      Code = "INTERNAL_ERROR"
+)
+
+// Requisição
+const (
+	CodeInvalidJSON      Code = "INVALID_JSON"
+	CodeInvalidParameter Code = "INVALID_PARAMETER"
+	CodeBodyTooLarge     Code = "BODY_TOO_LARGE"
+	CodeUnknownField     Code = "UNKNOWN_FIELD"
+	CodeRouteNotFound    Code = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed Code = "METHOD_NOT_ALLOWED"
+)
+
+// Autenticação e autorização
+const (
+	CodeTokenMissing       Code = "TOKEN_MISSING"
+	CodeTokenMalformed     Code = "TOKEN_MALFORMED"
+	CodeInvalidToken       Code = "INVALID_TOKEN"
+	CodeSessionRevoked     Code = "SESSION_REVOKED"
+	CodeInvalidCredentials Code = "INVALID_CREDENTIALS"
+	CodeInvalidAPIKey      Code = "INVALID_API_KEY"
+	CodeAPIKeyRevoked      Code = "API_KEY_REVOKED"
+	CodeAPIKeyExpired      Code = "API_KEY_EXPIRED"
+	CodeAPIKeyIPDenied     Code = "API_KEY_IP_DENIED"
+	CodeMissingScope       Code = "MISSING_SCOPE"
+	CodeSessionRequired    Code = "SESSION_REQUIRED"
+	CodeAdminRequired      Code = "ADMIN_REQUIRED"
+	CodeSSOFailed          Code = "SSO_FAILED"
+	CodeSSOStateInvalid    Code = "SSO_STATE_INVALID"
+	CodeOriginNotAllowed   Code = "ORIGIN_NOT_ALLOWED"
+)
+
+// Usuários e contas de serviço
+const (
+	CodeUserExists               Code = "USER_EXISTS"
+	CodeUserNotFound             Code = "USER_NOT_FOUND"
+	CodeWrongPassword            Code = "WRONG_PASSWORD"
+	CodeReauthRequired           Code = "REAUTH_REQUIRED"
+	CodeServiceAccountNotFound   Code = "SERVICE_ACCOUNT_NOT_FOUND"
+	CodeInvalidEmail             Code = "INVALID_EMAIL"
+	CodeUnsupportedLocale        Code = "UNSUPPORTED_LOCALE"
+	CodeInvalidVerificationToken Code = "INVALID_VERIFICATION_TOKEN"
+)
+
+// Chaves e dispositivos
+const (
+	CodeInvalidKeyFormat       Code = "INVALID_KEY_FORMAT"
+	CodeInvalidKeyBackup       Code = "INVALID_KEY_BACKUP"
+	CodeKeyBackupNotFound      Code = "KEY_BACKUP_NOT_FOUND"
+	CodeDeviceNotFound         Code = "DEVICE_NOT_FOUND"
+	CodeSignerDeviceNotFound   Code = "SIGNER_DEVICE_NOT_FOUND"
+	CodeInvalidCrossSignature  Code = "INVALID_CROSS_SIGNATURE"
+	CodeLastActiveDevice       Code = "LAST_ACTIVE_DEVICE"
+	CodeInvalidScope           Code = "INVALID_SCOPE"
+	CodeAPIKeyNotFound         Code = "API_KEY_NOT_FOUND"
+	CodeInvalidAllowedIP       Code = "INVALID_ALLOWED_IP"
+	CodeInvalidAPIKeyExpiresAt Code = "INVALID_EXPIRES_AT"
+)
+
+// Transferências e uploads
+const (
+	CodeDestUserNotFound   Code = "DEST_USER_NOT_FOUND"
+	CodeDestDeviceNotFound Code = "DEST_DEVICE_NOT_FOUND"
+	CodeEmptySKB           Code = "EMPTY_SKB"
+	CodeInvalidChecksum    Code = "INVALID_CHECKSUM"
+	CodeChecksumMismatch   Code = "CHECKSUM_MISMATCH"
+	CodeInvalidFileSize    Code = "INVALID_FILE_SIZE"
+	CodeUploadNotFound     Code = "UPLOAD_NOT_FOUND"
+	CodeUploadNotOwned     Code = "UPLOAD_NOT_OWNED"
+	CodeFileNotFound       Code = "FILE_NOT_FOUND"
+	CodeFileTooLarge       Code = "FILE_TOO_LARGE"
+	CodeUserQuotaExceeded  Code = "USER_QUOTA_EXCEEDED"
+	CodeOrgQuotaExceeded   Code = "ORG_QUOTA_EXCEEDED"
+)
+
+// Webhooks
+const (
+	CodeWebhookNotFound   Code = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound  Code = "DELIVERY_NOT_FOUND"
+	CodeInvalidWebhookURL Code = "INVALID_WEBHOOK_URL"
+	CodeUnknownEventType  Code = "UNKNOWN_EVENT_TYPE"
+)
+
+// Idempotency-Key
+const (
+	CodeInvalidIdempotencyKey    Code = "INVALID_IDEMPOTENCY_KEY"
+	CodeIdempotencyKeyReused     Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyKeyInProgress Code = "IDEMPOTENCY_KEY_IN_PROGRESS"
+)
+
+// CodeOf retorna o código do erro: o próprio, se houver um *Error com
+// código na cadeia; senão o padrão da categoria; senão CodeInternal
+func CodeOf(err error) Code {
+	var e *Error
+	for cur := err; errors.As(cur, &e); {
+		if e.code != "" {
+			return e.code
+		}
+		if e.cause == nil {
+			break
+		}
+		cur = e.cause
+	}
+
+	switch {
+	case errors.Is(err, ErrValidation):
+		return CodeValidation
+	case errors.Is(err, ErrUnauthorized):
+		return CodeUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return CodeForbidden
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrConflict):
+		return CodeConflict
+	case errors.Is(err, ErrTooLarge):
+		return CodeTooLarge
+	case errors.Is(err, ErrQuotaExceeded):
+		return CodeQuotaExceeded
+	case errors.Is(err, ErrUnprocessable):
+		return CodeUnprocessable
+	default:
+		return CodeInternal
+	}
+}
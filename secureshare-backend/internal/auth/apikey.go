@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Escopos que uma API key pode carregar
+const (
+	ScopeTransfersCreate = "transfers:create"
+	ScopeTransfersRead   = "transfers:read"
+	ScopeUsersRead       = "users:read"
+)
+
+// ValidScopes lista todos os escopos aceitos na criação de API keys
+var ValidScopes = []string{ScopeTransfersCreate, ScopeTransfersRead, ScopeUsersRead}
+
+// Formato: ssk_<prefixo>_<segredo>. O prefixo é público (usado para
+// localizar a chave no DB); apenas o hash da chave inteira é armazenado.
+const (
+	apiKeyMarker    = "ssk_"
+	apiKeyPrefixLen = 8
+	apiKeySecretLen = 32 // bytes aleatórios
+)
+
+var apiKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// IsAPIKey indica se o valor do Bearer tem o formato de uma API key
+func IsAPIKey(raw string) bool {
+	return strings.HasPrefix(raw, apiKeyMarker)
+}
+
+// GenerateAPIKey gera uma nova API key e retorna a chave completa (mostrada
+// uma única vez ao usuário), seu prefixo e seu hash
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	buf := make([]byte, 5+apiKeySecretLen)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("falha ao gerar API key: %w", err)
+	}
+
+	prefix = strings.ToLower(apiKeyEncoding.EncodeToString(buf[:5]))[:apiKeyPrefixLen]
+	secret := strings.ToLower(apiKeyEncoding.EncodeToString(buf[5:]))
+	key = apiKeyMarker + prefix + "_" + secret
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// ParseAPIKey extrai o prefixo de uma API key
+func ParseAPIKey(key string) (prefix string, err error) {
+	rest, ok := strings.CutPrefix(key, apiKeyMarker)
+	if !ok {
+		return "", fmt.Errorf("formato de API key inválido")
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != apiKeyPrefixLen || secret == "" {
+		return "", fmt.Errorf("formato de API key inválido")
+	}
+	return prefix, nil
+}
+
+// HashAPIKey calcula o hash armazenado de uma API key. As chaves têm 256
+// bits de entropia, então SHA-256 basta (não é preciso um KDF lento).
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAPIKey compara a chave com o hash armazenado em tempo constante
+func VerifyAPIKey(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(hash)) == 1
+}
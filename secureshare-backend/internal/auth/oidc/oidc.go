@@ -0,0 +1,346 @@
+// Package oidc implementa o lado cliente (Relying Party) do fluxo
+// OpenID Connect authorization code + PKCE usado no login SSO.
+//
+// Apenas o necessário para o SecureShare: discovery, JWKS (RSA e EC),
+// troca do código no token endpoint e verificação do ID token.
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config são os parâmetros do cliente registrado no IdP
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string // Opcional: clientes públicos usam apenas PKCE
+	RedirectURL  string
+	Scopes       []string
+}
+
+// discoveryDocument é o subconjunto usado de /.well-known/openid-configuration
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider é um IdP OIDC já descoberto
+type Provider struct {
+	cfg        Config
+	doc        discoveryDocument
+	httpClient *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]any // kid -> *rsa.PublicKey | *ecdsa.PublicKey
+}
+
+// Claims são as claims do ID token usadas no mapeamento para models.User
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// Raw dá acesso a qualquer claim (ex: preferred_username)
+	Raw map[string]any
+}
+
+// NewProvider busca o documento de discovery do emissor e valida que ele
+// corresponde ao emissor configurado
+func NewProvider(ctx context.Context, cfg Config, httpClient *http.Client) (*Provider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc: issuer, client ID e redirect URL são obrigatórios")
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	p := &Provider{cfg: cfg, httpClient: httpClient}
+
+	wellKnown := strings.TrimSuffix(cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, &p.doc); err != nil {
+		return nil, fmt.Errorf("oidc: falha no discovery: %w", err)
+	}
+	if p.doc.Issuer != cfg.IssuerURL {
+		return nil, fmt.Errorf("oidc: emissor do discovery ('%s') difere do configurado ('%s')", p.doc.Issuer, cfg.IssuerURL)
+	}
+	if p.doc.AuthorizationEndpoint == "" || p.doc.TokenEndpoint == "" || p.doc.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: documento de discovery incompleto")
+	}
+
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// --- PKCE, state e nonce ---
+
+// RandomString gera um valor aleatório base64url (usado em state, nonce e
+// code verifier)
+func RandomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("oidc: falha ao gerar valor aleatório: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallengeS256 calcula o code_challenge (método S256) do verifier
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL monta a URL de autorização para onde o navegador é redirecionado
+func (p *Provider) AuthCodeURL(state, nonce, codeVerifier string) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallengeS256(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(p.doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.doc.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// --- Troca do código e verificação do ID token ---
+
+// Exchange troca o código de autorização pelo ID token e o verifica
+// (assinatura, emissor, audiência, expiração e nonce)
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: falha ao chamar token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: falha ao ler resposta do token endpoint: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token endpoint retornou %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil || tokens.IDToken == "" {
+		return nil, fmt.Errorf("oidc: resposta do token endpoint sem id_token")
+	}
+
+	return p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+// VerifyIDToken valida um ID token emitido por este provedor
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	mapClaims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, mapClaims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}),
+		jwt.WithIssuer(p.cfg.IssuerURL),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: ID token inválido: %w", err)
+	}
+
+	if got, _ := mapClaims["nonce"].(string); nonce == "" || got != nonce {
+		return nil, fmt.Errorf("oidc: nonce do ID token não confere")
+	}
+
+	claims := &Claims{Raw: mapClaims}
+	claims.Issuer, _ = mapClaims["iss"].(string)
+	claims.Subject, _ = mapClaims["sub"].(string)
+	claims.Email, _ = mapClaims["email"].(string)
+	switch v := mapClaims["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = v
+	case string: // Alguns IdPs enviam "true" como string
+		claims.EmailVerified = v == "true"
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("oidc: ID token sem 'sub'")
+	}
+	return claims, nil
+}
+
+// --- JWKS ---
+
+// key retorna a chave pública kid, recarregando o JWKS uma vez caso ela não
+// seja conhecida (rotação de chaves no IdP)
+func (p *Provider) key(ctx context.Context, kid string) (any, error) {
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("oidc: chave '%s' não encontrada no JWKS", kid)
+}
+
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+func (p *Provider) refreshKeys(ctx context.Context) error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.doc.JWKSURI, &set); err != nil {
+		return fmt.Errorf("oidc: falha ao buscar JWKS: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		pub, err := jwk.publicKey()
+		if err != nil {
+			continue // Ignora tipos de chave não suportados
+		}
+		keys[jwk.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("oidc: JWKS sem chaves de assinatura suportadas")
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	return nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("curva não suportada: %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("tipo de chave não suportado: %s", k.Kty)
+	}
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s retornou %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
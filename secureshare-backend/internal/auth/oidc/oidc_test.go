@@ -0,0 +1,242 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/auth/oidc"
+	"secureshare-backend/internal/auth/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID    = "secureshare"
+	testRedirectURL = "http://localhost:8080/v1/auth/oidc/callback"
+)
+
+func newProvider(t *testing.T, iss *oidctest.Issuer) *oidc.Provider {
+	t.Helper()
+	p, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:    iss.URL(),
+		ClientID:     testClientID,
+		ClientSecret: iss.ClientSecret,
+		RedirectURL:  testRedirectURL,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	return p
+}
+
+// authorize segue o redirecionamento do IdP e retorna o código e o state
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d", resp.StatusCode)
+	}
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize: Location inválida: %v", err)
+	}
+	if !strings.HasPrefix(loc.String(), testRedirectURL) {
+		t.Fatalf("authorize: redirecionou para %s", loc)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+type flow struct {
+	state, nonce, verifier string
+}
+
+func newFlow(t *testing.T) flow {
+	t.Helper()
+	var f flow
+	var err error
+	for _, v := range []*string{&f.state, &f.nonce, &f.verifier} {
+		if *v, err = oidc.RandomString(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func TestExchangeHappyPath(t *testing.T) {
+	iss := oidctest.NewIssuer(testClientID)
+	defer iss.Close()
+	iss.SetClaims(map[string]any{"sub": "abc-123", "email": "bob@example.com", "email_verified": true})
+
+	p := newProvider(t, iss)
+	f := newFlow(t)
+
+	code, state := authorize(t, p.AuthCodeURL(f.state, f.nonce, f.verifier))
+	if state != f.state {
+		t.Fatalf("state = %q, esperado %q", state, f.state)
+	}
+
+	claims, err := p.Exchange(context.Background(), code, f.verifier, f.nonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "abc-123" || claims.Email != "bob@example.com" || !claims.EmailVerified {
+		t.Fatalf("claims inesperadas: %+v", claims)
+	}
+	if claims.Issuer != iss.URL() {
+		t.Fatalf("issuer = %q, esperado %q", claims.Issuer, iss.URL())
+	}
+}
+
+func TestExchangeWithClientSecret(t *testing.T) {
+	iss := oidctest.NewIssuer(testClientID)
+	defer iss.Close()
+	iss.ClientSecret = "s3cr3t"
+
+	p := newProvider(t, iss)
+	f := newFlow(t)
+
+	code, _ := authorize(t, p.AuthCodeURL(f.state, f.nonce, f.verifier))
+	if _, err := p.Exchange(context.Background(), code, f.verifier, f.nonce); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	iss := oidctest.NewIssuer(testClientID)
+	defer iss.Close()
+
+	p := newProvider(t, iss)
+	f := newFlow(t)
+
+	code, _ := authorize(t, p.AuthCodeURL(f.state, f.nonce, f.verifier))
+	if _, err := p.Exchange(context.Background(), code, "outro-verifier", f.nonce); err == nil {
+		t.Fatal("esperava erro com code_verifier incorreto")
+	}
+}
+
+func TestExchangeRejectsReusedCode(t *testing.T) {
+	iss := oidctest.NewIssuer(testClientID)
+	defer iss.Close()
+
+	p := newProvider(t, iss)
+	f := newFlow(t)
+
+	code, _ := authorize(t, p.AuthCodeURL(f.state, f.nonce, f.verifier))
+	if _, err := p.Exchange(context.Background(), code, f.verifier, f.nonce); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := p.Exchange(context.Background(), code, f.verifier, f.nonce); err == nil {
+		t.Fatal("esperava erro ao reutilizar o código")
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	iss := oidctest.NewIssuer(testClientID)
+	defer iss.Close()
+
+	p := newProvider(t, iss)
+	f := newFlow(t)
+
+	code, _ := authorize(t, p.AuthCodeURL(f.state, f.nonce, f.verifier))
+	if _, err := p.Exchange(context.Background(), code, f.verifier, "nonce-de-outra-sessao"); err == nil {
+		t.Fatal("esperava erro com nonce diferente")
+	}
+}
+
+func TestVerifyIDTokenRejectsBadClaims(t *testing.T) {
+	iss := oidctest.NewIssuer(testClientID)
+	defer iss.Close()
+	p := newProvider(t, iss)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   iss.URL(),
+			"aud":   testClientID,
+			"sub":   "abc",
+			"nonce": "n",
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Minute).Unix(),
+		}
+	}
+
+	if _, err := p.VerifyIDToken(context.Background(), iss.SignIDToken(valid()), "n"); err != nil {
+		t.Fatalf("token válido rejeitado: %v", err)
+	}
+
+	cases := map[string]func(jwt.MapClaims){
+		"audiência errada": func(c jwt.MapClaims) { c["aud"] = "outro-cliente" },
+		"emissor errado":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"expirado":         func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"sem exp":          func(c jwt.MapClaims) { delete(c, "exp") },
+		"sem sub":          func(c jwt.MapClaims) { delete(c, "sub") },
+		"sem nonce":        func(c jwt.MapClaims) { delete(c, "nonce") },
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := valid()
+			mutate(claims)
+			if _, err := p.VerifyIDToken(context.Background(), iss.SignIDToken(claims), "n"); err == nil {
+				t.Fatal("esperava erro")
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsForeignSignature(t *testing.T) {
+	iss := oidctest.NewIssuer(testClientID)
+	defer iss.Close()
+	other := oidctest.NewIssuer(testClientID)
+	defer other.Close()
+
+	p := newProvider(t, iss)
+	forged := other.SignIDToken(jwt.MapClaims{
+		"iss":   iss.URL(),
+		"aud":   testClientID,
+		"sub":   "abc",
+		"nonce": "n",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	})
+	if _, err := p.VerifyIDToken(context.Background(), forged, "n"); err == nil {
+		t.Fatal("esperava erro com token assinado por outra chave")
+	}
+}
+
+func TestKeyRotationRefreshesJWKS(t *testing.T) {
+	iss := oidctest.NewIssuer(testClientID)
+	defer iss.Close()
+
+	p := newProvider(t, iss)
+	iss.RotateKey() // O provider ainda tem apenas a chave antiga em cache
+
+	f := newFlow(t)
+	code, _ := authorize(t, p.AuthCodeURL(f.state, f.nonce, f.verifier))
+	if _, err := p.Exchange(context.Background(), code, f.verifier, f.nonce); err != nil {
+		t.Fatalf("Exchange após rotação de chave: %v", err)
+	}
+}
+
+func TestNewProviderRejectsIssuerMismatch(t *testing.T) {
+	iss := oidctest.NewIssuer(testClientID)
+	defer iss.Close()
+
+	_, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:   iss.URL() + "/",
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+	}, nil)
+	if err == nil {
+		t.Fatal("esperava erro com emissor divergente")
+	}
+}
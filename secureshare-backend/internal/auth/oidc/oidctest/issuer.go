@@ -0,0 +1,236 @@
+// Package oidctest fornece um emissor OIDC falso (httptest) para testes do
+// login SSO, sem depender de um IdP real.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer é um emissor OIDC mínimo: discovery, JWKS, authorize (aprova
+// automaticamente) e token (com verificação de PKCE S256)
+type Issuer struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string // Se vazio, o token endpoint não exige autenticação
+
+	mu     sync.Mutex
+	key    *rsa.PrivateKey
+	kid    string
+	codes  map[string]pendingCode
+	claims map[string]any // Claims da próxima autorização
+	// TokenHook, se definido, pode alterar as claims do ID token antes da
+	// assinatura (ex: para simular audiência ou nonce incorretos)
+	TokenHook func(claims jwt.MapClaims)
+}
+
+type pendingCode struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+	claims      map[string]any
+}
+
+// NewIssuer inicia o emissor. Chame Close ao final do teste.
+func NewIssuer(clientID string) *Issuer {
+	iss := &Issuer{
+		ClientID: clientID,
+		codes:    make(map[string]pendingCode),
+		claims:   map[string]any{"sub": "user-1", "email": "alice@example.com", "email_verified": true},
+	}
+	iss.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", iss.handleDiscovery)
+	mux.HandleFunc("/jwks", iss.handleJWKS)
+	mux.HandleFunc("/authorize", iss.handleAuthorize)
+	mux.HandleFunc("/token", iss.handleToken)
+	iss.Server = httptest.NewServer(mux)
+	return iss
+}
+
+// URL é o identificador do emissor (claim 'iss')
+func (iss *Issuer) URL() string {
+	return iss.Server.URL
+}
+
+// Close encerra o servidor
+func (iss *Issuer) Close() {
+	iss.Server.Close()
+}
+
+// SetClaims define as claims de identidade (sub, email, ...) usadas na
+// próxima autorização
+func (iss *Issuer) SetClaims(claims map[string]any) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.claims = claims
+}
+
+// RotateKey troca a chave de assinatura (com um novo kid)
+func (iss *Issuer) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: falha ao gerar chave: %v", err))
+	}
+	kidBytes := make([]byte, 8)
+	rand.Read(kidBytes)
+
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.key = key
+	iss.kid = base64.RawURLEncoding.EncodeToString(kidBytes)
+}
+
+// SignIDToken assina claims arbitrárias com a chave atual
+func (iss *Issuer) SignIDToken(claims jwt.MapClaims) string {
+	iss.mu.Lock()
+	key, kid := iss.key, iss.kid
+	iss.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: falha ao assinar token: %v", err))
+	}
+	return signed
+}
+
+func (iss *Issuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                iss.URL(),
+		"authorization_endpoint":                iss.URL() + "/authorize",
+		"token_endpoint":                        iss.URL() + "/token",
+		"jwks_uri":                              iss.URL() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (iss *Issuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	iss.mu.Lock()
+	pub, kid := iss.key.PublicKey, iss.kid
+	iss.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// handleAuthorize aprova automaticamente e redireciona com o código
+func (iss *Issuer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != iss.ClientID {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE S256 obrigatório", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "redirect_uri inválida", http.StatusBadRequest)
+		return
+	}
+
+	codeBytes := make([]byte, 16)
+	rand.Read(codeBytes)
+	code := base64.RawURLEncoding.EncodeToString(codeBytes)
+
+	iss.mu.Lock()
+	iss.codes[code] = pendingCode{
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		redirectURI: redirectURI.String(),
+		claims:      iss.claims,
+	}
+	iss.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (iss *Issuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	if iss.ClientSecret != "" {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != iss.ClientID || secret != iss.ClientSecret {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			return
+		}
+	}
+
+	iss.mu.Lock()
+	pending, ok := iss.codes[r.PostForm.Get("code")]
+	delete(iss.codes, r.PostForm.Get("code")) // Códigos são de uso único
+	iss.mu.Unlock()
+
+	if !ok || pending.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != pending.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": iss.URL(),
+		"aud": iss.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	if pending.nonce != "" {
+		claims["nonce"] = pending.nonce
+	}
+	for k, v := range pending.claims {
+		claims[k] = v
+	}
+	if iss.TokenHook != nil {
+		iss.TokenHook(claims)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     iss.SignIDToken(claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(payload)
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -25,10 +27,13 @@ func NewTokenService(secret string) (*TokenService, error) {
 
 // NewToken cria um novo token JWT para um usuário
 func (s *TokenService) NewToken(userID uuid.UUID) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"sub": userID.String(), // 'subject' (o ID do usuário)
-		"iat": time.Now().Unix(),
-		"exp": time.Now().Add(time.Hour * 24).Unix(), // Token expira em 24h
+		// Em microssegundos, para que a revogação de sessões (ex: troca de
+		// senha) pegue também tokens emitidos no mesmo segundo
+		"iat": float64(now.UnixMicro()) / 1e6,
+		"exp": now.Add(time.Hour * 24).Unix(), // Token expira em 24h
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -75,3 +80,71 @@ func (s *TokenService) GetUserIDFromToken(token *jwt.Token) (uuid.UUID, error) {
 
 	return userID, nil
 }
+
+// GetIssuedAtFromToken extrai o 'iat' (instante de emissão) de um token
+// validado, com a precisão de microssegundos de NewToken. O NumericDate do
+// jwt trunca para segundos (jwt.TimePrecision), por isso o claim é lido
+// direto.
+func (s *TokenService) GetIssuedAtFromToken(token *jwt.Token) (time.Time, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return time.Time{}, fmt.Errorf("não foi possível ler claims do token")
+	}
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("não foi possível obter 'iat' do token")
+	}
+	return time.UnixMicro(int64(math.Round(iat * 1e6))), nil
+}
+
+// GetExpiresAtFromToken extrai o 'exp' (expiração) de um token validado
+func (s *TokenService) GetExpiresAtFromToken(token *jwt.Token) (time.Time, error) {
+	exp, err := token.Claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}, fmt.Errorf("não foi possível obter 'exp' do token")
+	}
+	return exp.Time, nil
+}
+
+// stateTokenType marca tokens de estado (ex: fluxo OIDC), para que nunca
+// sejam confundidos com tokens de sessão
+const stateTokenType = "state"
+
+// NewStateToken assina valores temporários (ex: state, nonce e code verifier
+// do login OIDC) para guardá-los no cliente, em um cookie
+func (s *TokenService) NewStateToken(values map[string]string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"typ": stateTokenType,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(ttl).Unix(),
+	}
+	for k, v := range values {
+		claims["v_"+k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.jwtSecret)
+}
+
+// ParseStateToken valida um token criado por NewStateToken e retorna os valores
+func (s *TokenService) ParseStateToken(tokenString string) (map[string]string, error) {
+	token, err := s.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != stateTokenType {
+		return nil, fmt.Errorf("token de estado inválido")
+	}
+
+	values := make(map[string]string)
+	for k, v := range claims {
+		if name, ok := strings.CutPrefix(k, "v_"); ok {
+			if str, ok := v.(string); ok {
+				values[name] = str
+			}
+		}
+	}
+	return values, nil
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -11,6 +13,63 @@ type Config struct {
 	DatabaseURL   string `envconfig:"DATABASE_URL" required:"true"`
 	AWSBucketName string `envconfig:"AWS_BUCKET_NAME" required:"true"`
 	AWSRegion     string `envconfig:"AWS_REGION" required:"true"`
+	// Aplica migrações pendentes no start; se falso, o servidor recusa subir
+	// até alguém rodar "server migrate up"
+	AutoMigrate bool `envconfig:"AUTO_MIGRATE" default:"true"`
+	// Roda a fila de jobs no próprio servidor. Desligue
+	// quando houver processos "server worker" dedicados.
+	RunWorkers bool `envconfig:"RUN_WORKERS" default:"true"`
+	// Tamanho máximo de um arquivo (padrão: 5 GiB, o limite de um PUT no S3)
+	MaxFileSize int64 `envconfig:"MAX_FILE_SIZE" default:"5368709120"`
+	// Cotas em bytes (0: sem limite). A da organização soma uma pessoa e as
+	// contas de serviço dela.
+	UserQuotaBytes int64 `envconfig:"USER_QUOTA_BYTES" default:"0"`
+	OrgQuotaBytes  int64 `envconfig:"ORG_QUOTA_BYTES" default:"0"`
+	// Coletor de arquivos órfãos em uploads/ (expressão cron; vazia desabilita)
+	BlobGCSchedule string `envconfig:"BLOB_GC_SCHEDULE" default:"@daily"`
+	// Objetos mais novos que isto nunca são coletados
+	BlobGCGrace time.Duration `envconfig:"BLOB_GC_GRACE" default:"24h"`
+	// Por quanto tempo os eventos de GET /v1/events ficam disponíveis para
+	// a retomada com Last-Event-ID
+	EventsRetention time.Duration `envconfig:"EVENTS_RETENTION" default:"24h"`
+
+	// API gRPC (proto/secureshare/v1) para serviços internos; 0 desabilita
+	GRPCPort int `envconfig:"GRPC_PORT" default:"0"`
+	// Listener de administração com as métricas Prometheus em /metrics,
+	// separado da API pública; 0 desabilita
+	MetricsPort int `envconfig:"METRICS_PORT" default:"0"`
+
+	// Avisos por e-mail (desabilitados se SMTP_HOST estiver vazio)
+	SMTPHost     string `envconfig:"SMTP_HOST"`
+	SMTPPort     int    `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername string `envconfig:"SMTP_USERNAME"`
+	SMTPPassword string `envconfig:"SMTP_PASSWORD"`
+	SMTPFrom     string `envconfig:"SMTP_FROM" default:"SecureShare <no-reply@localhost>"`
+	// Página do frontend que confirma o e-mail (recebe ?token=). Se vazia,
+	// o e-mail de confirmação traz só o token.
+	EmailVerifyURL string `envconfig:"EMAIL_VERIFY_URL"`
+
+	// Aceita webhooks http e em loopback/redes privadas. Só para
+	// desenvolvimento: em produção, abre a rede interna a quem cadastra
+	// webhooks.
+	WebhookAllowPrivate bool `envconfig:"WEBHOOK_ALLOW_PRIVATE" default:"false"`
+
+	// Usernames com acesso às rotas /v1/admin (ex: log de auditoria)
+	AdminUsernames []string `envconfig:"ADMIN_USERNAMES"`
+
+	// Login SSO via OIDC (desabilitado se OIDC_ISSUER_URL estiver vazio)
+	OIDCIssuerURL    string   `envconfig:"OIDC_ISSUER_URL"`
+	OIDCClientID     string   `envconfig:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string   `envconfig:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL  string   `envconfig:"OIDC_REDIRECT_URL"`
+	OIDCScopes       []string `envconfig:"OIDC_SCOPES" default:"openid,email,profile"`
+	// Claim usada como username de contas criadas via SSO
+	OIDCUsernameClaim string `envconfig:"OIDC_USERNAME_CLAIM" default:"email"`
+	// Cria a conta no primeiro login SSO (senão, só identidades já vinculadas entram)
+	OIDCAutoProvision bool `envconfig:"OIDC_AUTO_PROVISION" default:"true"`
+	// URL do frontend para onde o callback redireciona (com o token no fragmento).
+	// Se vazia, o callback responde com JSON.
+	OIDCPostLoginRedirect string `envconfig:"OIDC_POST_LOGIN_REDIRECT"`
 }
 
 // Load carrega a configuração das variáveis de ambiente
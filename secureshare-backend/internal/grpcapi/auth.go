@@ -0,0 +1,156 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	pb "secureshare-backend/pkg/securesharev1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// contextKey é um tipo privado para evitar colisões de chaves no contexto
+type contextKey string
+
+const (
+	userContextKey   = contextKey("user")
+	apiKeyContextKey = contextKey("apiKey") // presente só em chamadas com API key
+)
+
+// methodScopes é o escopo de API key exigido por cada RPC, o mesmo da rota
+// REST equivalente. Um RPC fora do mapa é recusado para API keys.
+var methodScopes = map[string]string{
+	pb.SecureShare_ListUsers_FullMethodName:       auth.ScopeUsersRead,
+	pb.SecureShare_GetUserKey_FullMethodName:      auth.ScopeUsersRead,
+	pb.SecureShare_CreateUploadURL_FullMethodName: auth.ScopeTransfersCreate,
+	pb.SecureShare_GetDownloadURL_FullMethodName:  auth.ScopeTransfersRead,
+	pb.SecureShare_CreateTransfer_FullMethodName:  auth.ScopeTransfersCreate,
+	pb.SecureShare_ListTransfers_FullMethodName:   auth.ScopeTransfersRead,
+	pb.SecureShare_StreamEvents_FullMethodName:    auth.ScopeTransfersRead,
+}
+
+// unaryAuth autentica as chamadas unárias (ver authenticate)
+func (s *Server) unaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamAuth autentica os streams (ver authenticate)
+func (s *Server) streamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream troca o contexto do stream pelo que leva o usuário
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate valida o metadata "authorization" como o AuthMiddleware da
+// API REST ("Bearer <JWT ou API key>") e confere o escopo da API key.
+// Retorna o contexto com o usuário (e a API key, se houver) e o logger da
+// chamada (ver internal/logging).
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 || values[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "Token de autorização não fornecido")
+	}
+
+	parts := strings.Split(values[0], " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return nil, status.Error(codes.Unauthenticated, "Formato do token inválido")
+	}
+	tokenString := parts[1]
+
+	// API keys de contas de serviço usam o mesmo metadata
+	if auth.IsAPIKey(tokenString) {
+		user, key, err := s.apiKeyService.Authenticate(ctx, tokenString, remoteIP(ctx))
+		if err != nil {
+			return nil, statusFromError(err)
+		}
+		scope, ok := methodScopes[method]
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, "RPC não disponível para API keys")
+		}
+		if !slices.Contains(key.Scopes, scope) {
+			return nil, status.Error(codes.PermissionDenied, "API key sem o escopo '"+scope+"'")
+		}
+		ctx = logging.With(ctx, "rpc", method, "user_id", user.ID, "api_key_id", key.ID)
+		ctx = context.WithValue(ctx, userContextKey, user)
+		return context.WithValue(ctx, apiKeyContextKey, key), nil
+	}
+
+	user, err := s.userService.AuthenticateToken(ctx, tokenString)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	ctx = logging.With(ctx, "rpc", method, "user_id", user.ID)
+	return context.WithValue(ctx, userContextKey, user), nil
+}
+
+// userFromContext retorna o usuário autenticado. Os interceptors garantem
+// que todo RPC tenha um.
+func userFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userContextKey).(*models.User)
+	return user
+}
+
+// audit grava um evento de auditoria do usuário autenticado, com o IP e o
+// User-Agent da chamada
+func (s *Server) audit(ctx context.Context, eventType, target string, details map[string]string) {
+	event := &models.AuditEvent{
+		Type:    eventType,
+		Target:  target,
+		Details: details,
+	}
+	if ua := metadata.ValueFromIncomingContext(ctx, "user-agent"); len(ua) > 0 {
+		event.UserAgent = ua[0]
+	}
+	if ip := remoteIP(ctx); ip != nil {
+		event.IP = ip.String()
+	}
+	if user := userFromContext(ctx); user != nil {
+		event.ActorID = &user.ID
+		event.Actor = user.Username
+	}
+	if key, ok := ctx.Value(apiKeyContextKey).(*models.APIKey); ok {
+		if event.Details == nil {
+			event.Details = make(map[string]string, 1)
+		}
+		event.Details["apiKeyId"] = key.ID.String()
+	}
+	s.auditService.Record(ctx, event)
+}
+
+// remoteIP extrai o IP de origem da conexão
+func remoteIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return net.ParseIP(host)
+}
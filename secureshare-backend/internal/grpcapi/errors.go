@@ -0,0 +1,42 @@
+package grpcapi
+
+import (
+	"errors"
+
+	"secureshare-backend/internal/apperr"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// codeForError traduz a categoria do erro (ver internal/apperr) no código
+// gRPC, como o statusForError da API REST faz com o status HTTP
+func codeForError(err error) codes.Code {
+	switch {
+	case errors.Is(err, apperr.ErrValidation):
+		return codes.InvalidArgument
+	case errors.Is(err, apperr.ErrUnauthorized):
+		return codes.Unauthenticated
+	case errors.Is(err, apperr.ErrForbidden):
+		return codes.PermissionDenied
+	case errors.Is(err, apperr.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, apperr.ErrConflict):
+		return codes.AlreadyExists
+	case errors.Is(err, apperr.ErrTooLarge):
+		return codes.InvalidArgument
+	case errors.Is(err, apperr.ErrQuotaExceeded):
+		return codes.ResourceExhausted
+	case errors.Is(err, apperr.ErrUnprocessable):
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
+
+// statusFromError converte um erro de serviço em status gRPC. Erros sem
+// categoria viram Internal; os serviços já os reescrevem como "erro interno
+// ...", sem detalhes do store.
+func statusFromError(err error) error {
+	return status.Error(codeForError(err), err.Error())
+}
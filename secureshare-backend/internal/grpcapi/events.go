@@ -0,0 +1,80 @@
+package grpcapi
+
+import (
+	"time"
+
+	"secureshare-backend/internal/models"
+	pb "secureshare-backend/pkg/securesharev1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// eventsKeepAlive é o intervalo dos pings do HTTP/2 numa conexão ociosa,
+// abaixo do timeout comum de proxies (o mesmo de GET /v1/events)
+const eventsKeepAlive = 25 * time.Second
+
+// StreamEvents espelha GET /events: primeiro os eventos guardados depois de
+// last_event_id, depois os novos, até o cliente cancelar
+func (s *Server) StreamEvents(req *pb.StreamEventsRequest, stream pb.SecureShare_StreamEventsServer) error {
+	ctx := stream.Context()
+	user := userFromContext(ctx)
+	lastID := req.GetLastEventId()
+	if lastID < 0 {
+		return status.Error(codes.InvalidArgument, "last_event_id inválido")
+	}
+
+	// Assina antes do replay: eventos gravados no meio chegam pelo canal e
+	// os repetidos são descartados pelo ID
+	sub := s.eventHub.Subscribe(user.ID)
+	defer sub.Close()
+
+	send := func(e *models.InboxEvent) error {
+		if e.ID <= lastID {
+			return nil
+		}
+		lastID = e.ID
+		msg, err := eventToProto(e)
+		if err != nil {
+			return status.Errorf(codes.Internal, "evento %d inválido: %v", e.ID, err)
+		}
+		return stream.Send(msg)
+	}
+	if err := s.eventHub.Replay(ctx, user.ID, lastID, send); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-sub.Events:
+			if !ok {
+				// O hub encerrou a assinatura (desligamento ou cliente lento):
+				// o cliente reconecta
+				return status.Error(codes.Unavailable, "stream de eventos encerrado; reconecte com last_event_id")
+			}
+			if err := send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func eventToProto(e *models.InboxEvent) (*pb.InboxEvent, error) {
+	data := &structpb.Struct{}
+	if len(e.Data) > 0 {
+		if err := protojson.Unmarshal(e.Data, data); err != nil {
+			return nil, err
+		}
+	}
+	return &pb.InboxEvent{
+		Id:        e.ID,
+		Type:      e.Type,
+		Data:      data,
+		CreatedAt: timestamppb.New(e.CreatedAt),
+	}, nil
+}
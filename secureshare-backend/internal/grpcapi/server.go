@@ -0,0 +1,279 @@
+// Package grpcapi é a API gRPC do SecureShare (proto/secureshare/v1). Ela
+// espelha as rotas REST de internal/api sobre os mesmos serviços, com a
+// mesma autenticação (JWT de sessão ou API key com escopo).
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/service"
+	pb "secureshare-backend/pkg/securesharev1"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// URLSigner gera as URLs pré-assinadas do bucket (ver service.S3Service)
+type URLSigner interface {
+	GeneratePresignedPutURL(ctx context.Context, objectKey string, size int64, checksumSHA256 string, lifetime time.Duration) (string, http.Header, error)
+	GeneratePresignedGetURL(ctx context.Context, objectKey string, lifetime time.Duration) (string, error)
+}
+
+// Server implementa o serviço SecureShare
+type Server struct {
+	pb.UnimplementedSecureShareServer
+
+	userService     *service.UserService
+	transferService *service.TransferService
+	deviceService   *service.DeviceService
+	apiKeyService   *service.APIKeyService
+	auditService    *service.AuditService
+	eventHub        *service.EventHub
+	urls            URLSigner
+}
+
+// NewServer cria o servidor gRPC sobre os serviços da API REST
+func NewServer(
+	userSvc *service.UserService,
+	transferSvc *service.TransferService,
+	deviceSvc *service.DeviceService,
+	apiKeySvc *service.APIKeyService,
+	auditSvc *service.AuditService,
+	eventHub *service.EventHub,
+	urls URLSigner,
+) *Server {
+	return &Server{
+		userService:     userSvc,
+		transferService: transferSvc,
+		deviceService:   deviceSvc,
+		apiKeyService:   apiKeySvc,
+		auditService:    auditSvc,
+		eventHub:        eventHub,
+		urls:            urls,
+	}
+}
+
+// NewGRPCServer cria um *grpc.Server com os interceptors de autenticação e
+// o serviço registrado. opts são somados aos padrões (ex: credenciais TLS).
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryAuth),
+		grpc.ChainStreamInterceptor(s.streamAuth),
+		// Pings em conexões ociosas, como o keepalive de GET /v1/events
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: eventsKeepAlive}),
+	}, opts...)
+	gs := grpc.NewServer(opts...)
+	pb.RegisterSecureShareServer(gs, s)
+	return gs
+}
+
+// === Usuários ===
+
+// ListUsers espelha GET /users
+func (s *Server) ListUsers(ctx context.Context, _ *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	users, err := s.userService.GetAllUsers(ctx)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	response := &pb.ListUsersResponse{Users: make([]*pb.User, 0, len(users))}
+	for _, user := range users {
+		response.Users = append(response.Users, &pb.User{
+			Username:      user.Username,
+			PublicKey:     user.PublicKey,
+			PublicKeySign: user.PublicKeySign,
+		})
+	}
+	return response, nil
+}
+
+// GetUserKey espelha GET /users/{username}/key
+func (s *Server) GetUserKey(ctx context.Context, req *pb.GetUserKeyRequest) (*pb.PublicKey, error) {
+	if req.GetUsername() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Nome de usuário não fornecido")
+	}
+
+	user, err := s.userService.GetUserPublicKey(ctx, req.GetUsername())
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	s.audit(ctx, service.AuditKeyFetched, "user:"+user.Username, nil)
+
+	devices, err := s.deviceService.GetActiveDevices(ctx, user.ID)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	response := &pb.PublicKey{
+		Username:      user.Username,
+		PublicKey:     user.PublicKey,
+		PublicKeySign: user.PublicKeySign,
+		Devices:       make([]*pb.DeviceKey, 0, len(devices)),
+	}
+	if len(devices) > 0 {
+		response.PublicKey = devices[0].PublicKey
+		response.PublicKeySign = devices[0].PublicKeySign
+	}
+	for _, d := range devices {
+		deviceKey := &pb.DeviceKey{
+			DeviceId:      d.ID.String(),
+			Name:          d.Name,
+			PublicKey:     d.PublicKey,
+			PublicKeySign: d.PublicKeySign,
+			Signature:     d.Signature,
+		}
+		if d.SignerDeviceID != nil {
+			deviceKey.SignerDeviceId = d.SignerDeviceID.String()
+		}
+		response.Devices = append(response.Devices, deviceKey)
+	}
+	return response, nil
+}
+
+// === Upload e download ===
+
+// CreateUploadURL espelha POST /transfers/upload-url
+func (s *Server) CreateUploadURL(ctx context.Context, req *pb.CreateUploadURLRequest) (*pb.CreateUploadURLResponse, error) {
+	user := userFromContext(ctx)
+	if req.GetSize() <= 0 || req.GetChecksumSha256() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Campos 'size' (bytes) e 'checksum_sha256' obrigatórios")
+	}
+
+	upload := service.UploadRequest{Size: req.GetSize(), ChecksumSHA256: req.GetChecksumSha256()}
+	objectKey, err := s.transferService.ReserveUpload(ctx, user, upload)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	uploadURL, headers, err := s.urls.GeneratePresignedPutURL(ctx, objectKey, upload.Size, upload.ChecksumSHA256, 15*time.Minute)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao gerar URL de upload", "err", err)
+		return nil, status.Error(codes.Internal, "Não foi possível gerar a URL de upload")
+	}
+	s.audit(ctx, service.AuditUploadURLIssued, "object:"+objectKey, map[string]string{
+		"size": strconv.FormatInt(upload.Size, 10),
+	})
+
+	response := &pb.CreateUploadURLResponse{
+		UploadUrl:     uploadURL,
+		LinkToEncFile: objectKey,
+		Headers:       make(map[string]string, len(headers)),
+	}
+	for name := range headers {
+		response.Headers[name] = headers.Get(name)
+	}
+	return response, nil
+}
+
+// GetDownloadURL espelha GET /transfers/download-url
+func (s *Server) GetDownloadURL(ctx context.Context, req *pb.GetDownloadURLRequest) (*pb.GetDownloadURLResponse, error) {
+	user := userFromContext(ctx)
+	fileKey := req.GetFileKey()
+	if fileKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "Campo 'file_key' é obrigatório")
+	}
+	if err := s.transferService.AuthorizeDownload(ctx, user, fileKey); err != nil {
+		return nil, statusFromError(err)
+	}
+
+	downloadURL, err := s.urls.GeneratePresignedGetURL(ctx, fileKey, 5*time.Minute)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao gerar URL de download", "err", err)
+		return nil, status.Error(codes.Internal, "Não foi possível gerar a URL de download")
+	}
+	s.audit(ctx, service.AuditDownloadURLIssued, "object:"+fileKey, nil)
+	// Avisa o remetente; uma falha aqui não impede o download
+	if err := s.transferService.RecordDownload(ctx, user, fileKey); err != nil {
+		logging.FromContext(ctx).Error("Erro ao registrar download", "object", fileKey, "err", err)
+	}
+
+	return &pb.GetDownloadURLResponse{DownloadUrl: downloadURL}, nil
+}
+
+// === Transferências ===
+
+// CreateTransfer espelha POST /transfers
+func (s *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.Transfer, error) {
+	sourceUser := userFromContext(ctx)
+	// É preciso ao menos uma SKB: a legada (skb) ou as por dispositivo (skbs)
+	if req.GetDestUser() == "" || req.GetLinkToEncFile() == "" || req.GetSig() == "" || (req.GetSkb() == "" && len(req.GetSkbs()) == 0) {
+		return nil, status.Error(codes.InvalidArgument, "Campos obrigatórios ausentes")
+	}
+
+	transfer, err := s.transferService.CreateTransfer(ctx, sourceUser.ID, service.CreateTransferRequest{
+		DestUsername:   req.GetDestUser(),
+		LinkToEncFile:  req.GetLinkToEncFile(),
+		SKB:            req.GetSkb(),
+		Sig:            req.GetSig(),
+		ChecksumSHA256: req.GetChecksumSha256(),
+		SKBs:           req.GetSkbs(),
+	})
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	s.audit(ctx, service.AuditTransferCreated, "transfer:"+transfer.ID.String(), map[string]string{
+		"destUser": req.GetDestUser(),
+		"object":   transfer.LinkToEncFile,
+	})
+
+	return transferToProto(transfer, sourceUser.Username, req.GetDestUser()), nil
+}
+
+// ListTransfers espelha GET /transfers
+func (s *Server) ListTransfers(ctx context.Context, _ *pb.ListTransfersRequest) (*pb.ListTransfersResponse, error) {
+	destUser := userFromContext(ctx)
+	transfers, err := s.transferService.GetPendingTransfers(ctx, destUser.ID)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	// Os remetentes se repetem: cada um é buscado uma vez
+	senders := make(map[uuid.UUID]string)
+	response := &pb.ListTransfersResponse{Transfers: make([]*pb.Transfer, 0, len(transfers))}
+	for _, t := range transfers {
+		sender, ok := senders[t.SourceUserID]
+		if !ok {
+			sourceUser, err := s.userService.GetUserByID(ctx, t.SourceUserID)
+			if err != nil {
+				logging.FromContext(ctx).Error("Transferência com sourceUserID inválido", "transfer_id", t.ID, "source_user_id", t.SourceUserID)
+				continue
+			}
+			sender = sourceUser.Username
+			senders[t.SourceUserID] = sender
+		}
+		response.Transfers = append(response.Transfers, transferToProto(t, sender, destUser.Username))
+	}
+	return response, nil
+}
+
+// transferToProto converte a transferência, com os nomes de usuário no
+// lugar dos IDs (como o TransferMetadata da API REST)
+func transferToProto(t *models.Transfer, sourceUser, destUser string) *pb.Transfer {
+	out := &pb.Transfer{
+		TransferId:     t.ID.String(),
+		SourceUser:     sourceUser,
+		DestUser:       destUser,
+		LinkToEncFile:  t.LinkToEncFile,
+		Skb:            t.SKB,
+		Sig:            t.Sig,
+		CreatedAt:      timestamppb.New(t.CreatedAt),
+		Size:           t.Size,
+		ChecksumSha256: t.ChecksumSHA256,
+	}
+	if len(t.DeviceSKBs) > 0 {
+		out.Skbs = make(map[string]string, len(t.DeviceSKBs))
+		for deviceID, skb := range t.DeviceSKBs {
+			out.Skbs[deviceID.String()] = skb
+		}
+	}
+	return out
+}
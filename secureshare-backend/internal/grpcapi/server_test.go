@@ -0,0 +1,262 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+	pb "secureshare-backend/pkg/securesharev1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testChecksum = "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+
+// fakeBucket assina URLs falsas e guarda os objetos "enviados"
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string]service.BlobObject
+}
+
+func (b *fakeBucket) GeneratePresignedPutURL(ctx context.Context, objectKey string, size int64, checksumSHA256 string, lifetime time.Duration) (string, http.Header, error) {
+	headers := http.Header{}
+	headers.Set("x-amz-checksum-sha256", checksumSHA256)
+	return "https://bucket.example.com/" + objectKey + "?put", headers, nil
+}
+
+func (b *fakeBucket) GeneratePresignedGetURL(ctx context.Context, objectKey string, lifetime time.Duration) (string, error) {
+	return "https://bucket.example.com/" + objectKey + "?get", nil
+}
+
+func (b *fakeBucket) StatObject(ctx context.Context, key string) (*service.BlobObject, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	obj, ok := b.objects[key]
+	if !ok {
+		return nil, apperr.NotFound("objeto '%s' não encontrado", key)
+	}
+	return &obj, nil
+}
+
+func (b *fakeBucket) put(key string, size int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[key] = service.BlobObject{Key: key, Size: size, LastModified: time.Now(), ChecksumSHA256: testChecksum}
+}
+
+type testEnv struct {
+	store  *repository.InMemoryStore
+	bucket *fakeBucket
+	users  *service.UserService
+	conn   *grpc.ClientConn
+	client pb.SecureShareClient
+}
+
+// newTestEnv sobe o servidor gRPC num bufconn, sobre o store em memória
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := repository.NewInMemoryStore()
+	bucket := &fakeBucket{objects: make(map[string]service.BlobObject)}
+	tokens, err := auth.NewTokenService("segredo-de-teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := service.NewUserService(store, tokens)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hub := service.NewEventHub(store)
+	hub.SetPollInterval(10 * time.Millisecond)
+	hubDone := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(hubDone)
+	}()
+
+	gs := NewServer(
+		users,
+		service.NewTransferService(store, bucket, service.UploadLimits{}),
+		service.NewDeviceService(store),
+		service.NewAPIKeyService(store, store),
+		service.NewAuditService(store),
+		hub,
+		bucket,
+	).NewGRPCServer()
+	lis := bufconn.Listen(1 << 20)
+	go gs.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		<-hubDone
+		gs.Stop()
+	})
+	return &testEnv{store: store, bucket: bucket, users: users, conn: conn, client: pb.NewSecureShareClient(conn)}
+}
+
+// login registra o usuário e retorna um contexto com o token de sessão
+func (e *testEnv) login(t *testing.T, username string) context.Context {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := e.users.Register(ctx, username, "senha-forte", "pk-"+username, "pks-"+username); err != nil {
+		t.Fatalf("Register(%s): %v", username, err)
+	}
+	token, err := e.users.Login(ctx, username, "senha-forte")
+	if err != nil {
+		t.Fatalf("Login(%s): %v", username, err)
+	}
+	return withToken(token)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func assertCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Fatalf("esperava %s, obteve %s (%v)", want, got, err)
+	}
+}
+
+func TestTransferFlow(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.login(t, "alice")
+	bob := env.login(t, "bob")
+
+	// Sem token, nada passa
+	_, err := env.client.ListUsers(context.Background(), &pb.ListUsersRequest{})
+	assertCode(t, err, codes.Unauthenticated)
+	_, err = env.client.ListUsers(withToken("token-falso"), &pb.ListUsersRequest{})
+	assertCode(t, err, codes.Unauthenticated)
+
+	key, err := env.client.GetUserKey(alice, &pb.GetUserKeyRequest{Username: "bob"})
+	if err != nil {
+		t.Fatalf("GetUserKey: %v", err)
+	}
+	if key.GetPublicKey() != "pk-bob" || len(key.GetDevices()) != 1 {
+		t.Fatalf("chaves inesperadas: %+v", key)
+	}
+	_, err = env.client.GetUserKey(alice, &pb.GetUserKeyRequest{Username: "ninguem"})
+	assertCode(t, err, codes.NotFound)
+
+	// O stream de bob começa antes da transferência
+	streamCtx, stopStream := context.WithCancel(bob)
+	defer stopStream()
+	events, err := env.client.StreamEvents(streamCtx, &pb.StreamEventsRequest{})
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+
+	_, err = env.client.CreateUploadURL(alice, &pb.CreateUploadURLRequest{Size: 100})
+	assertCode(t, err, codes.InvalidArgument)
+	upload, err := env.client.CreateUploadURL(alice, &pb.CreateUploadURLRequest{Size: 100, ChecksumSha256: testChecksum})
+	if err != nil {
+		t.Fatalf("CreateUploadURL: %v", err)
+	}
+	if upload.GetHeaders()["X-Amz-Checksum-Sha256"] != testChecksum {
+		t.Fatalf("cabeçalhos assinados ausentes: %v", upload.GetHeaders())
+	}
+	env.bucket.put(upload.GetLinkToEncFile(), 100)
+
+	created, err := env.client.CreateTransfer(alice, &pb.CreateTransferRequest{
+		DestUser: "bob", LinkToEncFile: upload.GetLinkToEncFile(), Skb: "skb", Sig: "sig",
+	})
+	if err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	if created.GetSourceUser() != "alice" || created.GetDestUser() != "bob" || created.GetSize() != 100 {
+		t.Fatalf("transferência inesperada: %+v", created)
+	}
+
+	event, err := events.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if event.GetType() != service.EventTransferCreated || event.GetData().GetFields()["transferId"].GetStringValue() != created.GetTransferId() {
+		t.Fatalf("evento inesperado: %+v", event)
+	}
+
+	list, err := env.client.ListTransfers(bob, &pb.ListTransfersRequest{})
+	if err != nil {
+		t.Fatalf("ListTransfers: %v", err)
+	}
+	if len(list.GetTransfers()) != 1 || list.GetTransfers()[0].GetTransferId() != created.GetTransferId() {
+		t.Fatalf("transferências de bob: %+v", list.GetTransfers())
+	}
+
+	download, err := env.client.GetDownloadURL(bob, &pb.GetDownloadURLRequest{FileKey: created.GetLinkToEncFile()})
+	if err != nil || download.GetDownloadUrl() == "" {
+		t.Fatalf("GetDownloadURL: %v", err)
+	}
+	// O remetente também pode baixar; quem não participa da transferência, não
+	if _, err := env.client.GetDownloadURL(alice, &pb.GetDownloadURLRequest{FileKey: created.GetLinkToEncFile()}); err != nil {
+		t.Fatalf("GetDownloadURL do remetente: %v", err)
+	}
+	carol := env.login(t, "carol")
+	_, err = env.client.GetDownloadURL(carol, &pb.GetDownloadURLRequest{FileKey: created.GetLinkToEncFile()})
+	assertCode(t, err, codes.NotFound)
+
+	// Retomada: com last_event_id, só chegam os eventos posteriores
+	resumed, err := env.client.StreamEvents(bob, &pb.StreamEventsRequest{LastEventId: event.GetId() - 1})
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+	replayed, err := resumed.Recv()
+	if err != nil || replayed.GetId() != event.GetId() {
+		t.Fatalf("replay: %+v (err=%v)", replayed, err)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	env := newTestEnv(t)
+	env.login(t, "alice")
+	ctx := context.Background()
+
+	owner, err := env.store.GetUserByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := env.users.CreateServiceAccount(ctx, owner, "alice-bot", "pk-bot", "pks-bot")
+	if err != nil {
+		t.Fatalf("CreateServiceAccount: %v", err)
+	}
+	apiKeys := service.NewAPIKeyService(env.store, env.store)
+	rawKey, _, err := apiKeys.CreateAPIKey(ctx, account, service.CreateAPIKeyRequest{
+		Name:   "leitura",
+		Scopes: []string{auth.ScopeTransfersRead},
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	bot := withToken(rawKey)
+
+	if _, err := env.client.ListTransfers(bot, &pb.ListTransfersRequest{}); err != nil {
+		t.Fatalf("ListTransfers com transfers:read: %v", err)
+	}
+	_, err = env.client.ListUsers(bot, &pb.ListUsersRequest{})
+	assertCode(t, err, codes.PermissionDenied)
+	_, err = env.client.CreateUploadURL(bot, &pb.CreateUploadURLRequest{Size: 1, ChecksumSha256: testChecksum})
+	assertCode(t, err, codes.PermissionDenied)
+
+	_, err = env.client.ListTransfers(withToken("ssk_chave-falsa"), &pb.ListTransfersRequest{})
+	assertCode(t, err, codes.Unauthenticated)
+}
@@ -0,0 +1,43 @@
+// Package i18n define os idiomas suportados, compartilhados pelas respostas
+// de erro da API (internal/api) e pelos e-mails (internal/notify), e a
+// escolha do idioma a partir do Accept-Language.
+package i18n
+
+import (
+	"slices"
+
+	"golang.org/x/text/language"
+)
+
+// Idiomas das mensagens
+const (
+	LocalePtBR = "pt-BR"
+	LocaleEn   = "en"
+	// DefaultLocale vale para quem não tem idioma ou tem um desconhecido
+	DefaultLocale = LocalePtBR
+)
+
+// SupportedLocales lista os idiomas suportados; o primeiro é o padrão
+var SupportedLocales = []string{LocalePtBR, LocaleEn}
+
+// matcher segue a ordem de SupportedLocales
+var matcher = language.NewMatcher([]language.Tag{language.BrazilianPortuguese, language.English})
+
+// IsSupported diz se locale é um dos idiomas suportados
+func IsSupported(locale string) bool {
+	return slices.Contains(SupportedLocales, locale)
+}
+
+// FromAcceptLanguage escolhe o idioma suportado mais próximo do cabeçalho
+// Accept-Language; sem cabeçalho ou sem correspondência, DefaultLocale
+func FromAcceptLanguage(header string) string {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return SupportedLocales[index]
+}
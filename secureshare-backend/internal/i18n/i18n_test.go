@@ -0,0 +1,34 @@
+package i18n
+
+import "testing"
+
+func TestFromAcceptLanguage(t *testing.T) {
+	cases := []struct {
+		header string
+		want   string
+	}{
+		{"", LocalePtBR},
+		{"pt-BR", LocalePtBR},
+		{"pt-PT,pt;q=0.9", LocalePtBR},
+		{"en-US,en;q=0.9", LocaleEn},
+		{"fr-FR,en;q=0.5", LocaleEn},
+		{"ja", DefaultLocale},
+		{"não é um cabeçalho válido;;", DefaultLocale},
+	}
+	for _, tc := range cases {
+		if got := FromAcceptLanguage(tc.header); got != tc.want {
+			t.Errorf("FromAcceptLanguage(%q) = %q, esperado %q", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	for _, locale := range SupportedLocales {
+		if !IsSupported(locale) {
+			t.Errorf("IsSupported(%q) = false", locale)
+		}
+	}
+	if IsSupported("pt") {
+		t.Error("IsSupported(\"pt\") = true; só as tags exatas são aceitas")
+	}
+}
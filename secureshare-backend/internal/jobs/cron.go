@@ -0,0 +1,193 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron é uma expressão cron de 5 campos (minuto, hora, dia do mês, mês,
+// dia da semana), sempre avaliada em UTC. Aceita listas (1,15), intervalos
+// (1-5), passos (*/10, 0-30/5) e os atalhos @hourly, @daily, @weekly,
+// @monthly e @every <duração>.
+type Cron struct {
+	spec string
+
+	minute, hour, dom, month, dow uint64
+	// Como no cron tradicional: se dia do mês e dia da semana forem ambos
+	// restritos, basta um deles casar
+	domStar, dowStar bool
+
+	every time.Duration // @every: intervalo fixo, ignora os campos
+}
+
+var cronShortcuts = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// ParseCron interpreta uma expressão cron
+func ParseCron(spec string) (*Cron, error) {
+	spec = strings.TrimSpace(spec)
+	c := &Cron{spec: spec}
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || every < time.Second {
+			return nil, fmt.Errorf("cron '%s': intervalo inválido (mínimo 1s)", spec)
+		}
+		c.every = every
+		return c, nil
+	}
+	if expanded, ok := cronShortcuts[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron '%s': esperava 5 campos, obteve %d", c.spec, len(fields))
+	}
+
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron '%s': minuto: %w", c.spec, err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron '%s': hora: %w", c.spec, err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron '%s': dia do mês: %w", c.spec, err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron '%s': mês: %w", c.spec, err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron '%s': dia da semana: %w", c.spec, err)
+	}
+	// 7 também é domingo
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = fields[2] == "*"
+	c.dowStar = fields[4] == "*"
+	return c, nil
+}
+
+// MustParseCron é ParseCron para expressões fixas no código
+func MustParseCron(spec string) *Cron {
+	c, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c *Cron) String() string {
+	return c.spec
+}
+
+// Next retorna o primeiro instante estritamente depois de t que casa com a
+// expressão
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.UTC()
+	if c.every > 0 {
+		return t.Truncate(c.every).Add(c.every)
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Toda expressão válida casa com algum instante em até 5 anos
+	// (ex: 29 de fevereiro caindo em um dia da semana específico)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	// Expressões impossíveis (ex: 31 de fevereiro) nunca disparam
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// parseCronField converte um campo em um bitmap de valores permitidos
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("passo inválido '%s'", stepPart)
+			}
+			step = n
+		}
+
+		start, end := lo, hi
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = cronValue(a, lo, hi); err != nil {
+				return 0, err
+			}
+			if end, err = cronValue(b, lo, hi); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("intervalo invertido '%s'", rangePart)
+			}
+		default:
+			v, err := cronValue(rangePart, lo, hi)
+			if err != nil {
+				return 0, err
+			}
+			start = v
+			// "5/10" significa "de 5 até o fim, de 10 em 10"
+			if !hasStep {
+				end = v
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, lo, hi int) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < lo || v > hi {
+		return 0, fmt.Errorf("valor '%s' fora de %d-%d", s, lo, hi)
+	}
+	return v, nil
+}
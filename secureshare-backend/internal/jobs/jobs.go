@@ -0,0 +1,295 @@
+// Package jobs é a fila persistente de trabalho assíncrono: jobs gravados
+// no banco (ver repository.JobStore), executados por um Runner com novas
+// tentativas e backoff exponencial, dead letter para os que esgotam as
+// tentativas e agendamentos recorrentes (cron).
+//
+// O Runner pode rodar dentro do servidor ou em processos "server worker"
+// dedicados; várias réplicas podem rodar ao mesmo tempo, já que cada job é
+// reservado por uma só e cada disparo de um agendamento é vencido por uma só.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// KindPruneDead é o job interno que poda a dead letter
+const KindPruneDead = "jobs.prune-dead"
+
+// Handler executa um job. Deve ser idempotente: o mesmo job pode ser
+// executado mais de uma vez (ex: se o worker morrer antes de confirmar).
+type Handler func(ctx context.Context, payload []byte) error
+
+// Options ajusta a execução de um tipo de job
+type Options struct {
+	// Tentativas antes de ir para a dead letter (padrão: 10)
+	MaxAttempts int
+	// Tempo máximo de cada execução (padrão e teto: o lease do Runner)
+	Timeout time.Duration
+}
+
+type registration struct {
+	handler Handler
+	opts    Options
+}
+
+type schedule struct {
+	name    string
+	cron    *Cron
+	kind    string
+	payload []byte
+}
+
+// permanentError marca um erro que não adianta tentar de novo
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent embrulha err para que o job vá direto para a dead letter, sem
+// novas tentativas (ex: payload inválido)
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// Enqueue grava um job para execução imediata. Chame com o Store da
+// transação (WithTx) para que o job só exista se a mudança for confirmada.
+func Enqueue(ctx context.Context, store repository.JobStore, kind string, payload any) error {
+	return EnqueueAt(ctx, store, kind, payload, time.Now())
+}
+
+// EnqueueAt grava um job para execução a partir de runAt
+func EnqueueAt(ctx context.Context, store repository.JobStore, kind string, payload any, runAt time.Time) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("falha ao serializar job '%s': %w", kind, err)
+	}
+	return enqueueRaw(ctx, store, kind, raw, runAt)
+}
+
+func enqueueRaw(ctx context.Context, store repository.JobStore, kind string, payload []byte, runAt time.Time) error {
+	return store.EnqueueJob(ctx, &models.Job{
+		ID:        uuid.New(),
+		Kind:      kind,
+		Payload:   payload,
+		RunAt:     runAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+// Runner reserva e executa jobs
+type Runner struct {
+	store        repository.Store
+	handlers     map[string]registration
+	schedules    []schedule
+	pollInterval time.Duration
+	lease        time.Duration
+	batchSize    int
+	// Por quanto tempo jobs mortos ficam na dead letter
+	deadRetention time.Duration
+}
+
+// NewRunner cria um Runner com o job interno de poda da dead letter (diário)
+// já registrado; registre os demais com Register e Schedule
+func NewRunner(store repository.Store) *Runner {
+	r := &Runner{
+		store:         store,
+		handlers:      make(map[string]registration),
+		pollInterval:  5 * time.Second,
+		lease:         5 * time.Minute,
+		batchSize:     20,
+		deadRetention: 30 * 24 * time.Hour,
+	}
+	r.Register(KindPruneDead, r.pruneDead, Options{MaxAttempts: 3})
+	if err := r.Schedule(KindPruneDead, "@daily", KindPruneDead, nil); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Register registra o handler de um tipo de job
+func (r *Runner) Register(kind string, handler Handler, opts Options) {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 10
+	}
+	if opts.Timeout <= 0 || opts.Timeout > r.lease {
+		// Termina antes do lease, senão outro worker pode reservar o job
+		opts.Timeout = r.lease
+	}
+	r.handlers[kind] = registration{handler: handler, opts: opts}
+}
+
+// Schedule enfileira um job do tipo kind sempre que a expressão cron
+// disparar. name identifica o agendamento no banco e deve ser único.
+func (r *Runner) Schedule(name, cronSpec, kind string, payload any) error {
+	cron, err := ParseCron(cronSpec)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("falha ao serializar payload do agendamento '%s': %w", name, err)
+	}
+	for _, s := range r.schedules {
+		if s.name == name {
+			return fmt.Errorf("agendamento '%s' já registrado", name)
+		}
+	}
+	r.schedules = append(r.schedules, schedule{name: name, cron: cron, kind: kind, payload: raw})
+	return nil
+}
+
+// Run processa a fila até ctx ser cancelado
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.RunOnce(ctx, time.Now())
+		if err != nil {
+			logging.FromContext(ctx).Error("Erro ao processar fila de jobs", "err", err)
+		}
+		// Lote cheio: provavelmente há mais, não espera o próximo tick
+		if err == nil && n == r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce dispara os agendamentos vencidos em now e então reserva e executa
+// um lote de jobs. Retorna quantos jobs foram reservados.
+func (r *Runner) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	if err := r.fireSchedules(ctx, now); err != nil {
+		return 0, err
+	}
+
+	jobs, err := r.store.ClaimJobs(ctx, now, r.batchSize, r.lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, job := range jobs {
+		r.execute(ctx, job, now)
+	}
+	return len(jobs), nil
+}
+
+func (r *Runner) fireSchedules(ctx context.Context, now time.Time) error {
+	for _, s := range r.schedules {
+		next := s.cron.Next(now)
+		if next.IsZero() {
+			continue
+		}
+		err := r.store.WithTx(ctx, func(tx repository.Store) error {
+			fired, err := tx.AdvanceSchedule(ctx, s.name, now, next)
+			if err != nil || !fired {
+				return err
+			}
+			return enqueueRaw(ctx, tx, s.kind, s.payload, now)
+		})
+		if err != nil {
+			return fmt.Errorf("falha ao disparar agendamento '%s': %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) execute(ctx context.Context, job *models.Job, now time.Time) {
+	reg, ok := r.handlers[job.Kind]
+	if !ok {
+		// Pode ter sido gravado por uma versão mais nova; fica na fila (sem
+		// ir para a dead letter) até um worker que o conheça aparecer
+		r.retry(ctx, job, now.Add(backoff(job.Attempts)), fmt.Errorf("nenhum handler para '%s'", job.Kind))
+		return
+	}
+
+	err := r.call(ctx, reg, job)
+	if err == nil {
+		if err := r.store.CompleteJob(ctx, job.ID); err != nil {
+			logging.FromContext(ctx).Error("Erro ao confirmar job", "job_id", job.ID, "err", err)
+		}
+		return
+	}
+
+	var permanent permanentError
+	if errors.As(err, &permanent) || job.Attempts >= reg.opts.MaxAttempts {
+		logging.FromContext(ctx).Error("Job movido para a dead letter",
+			"job_id", job.ID, "kind", job.Kind, "attempts", job.Attempts, "err", err)
+		if err := r.store.FailJob(ctx, job.ID, now, err.Error()); err != nil {
+			logging.FromContext(ctx).Error("Erro ao mover job para a dead letter", "job_id", job.ID, "err", err)
+		}
+		return
+	}
+	r.retry(ctx, job, now.Add(backoff(job.Attempts)), err)
+}
+
+// call executa o handler com timeout, convertendo panics em erro
+func (r *Runner) call(ctx context.Context, reg registration, job *models.Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, reg.opts.Timeout)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return reg.handler(ctx, job.Payload)
+}
+
+func (r *Runner) retry(ctx context.Context, job *models.Job, runAt time.Time, cause error) {
+	logging.FromContext(ctx).Warn("Job falhou; nova tentativa agendada",
+		"job_id", job.ID, "kind", job.Kind, "attempts", job.Attempts, "err", cause, "retry_at", runAt)
+	if err := r.store.RetryJob(ctx, job.ID, runAt, cause.Error()); err != nil {
+		logging.FromContext(ctx).Error("Erro ao reagendar job", "job_id", job.ID, "err", err)
+	}
+}
+
+func (r *Runner) pruneDead(ctx context.Context, _ []byte) error {
+	n, err := r.store.PruneDeadJobs(ctx, time.Now().Add(-r.deadRetention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		logging.FromContext(ctx).Info("Jobs podados da dead letter", "count", n)
+	}
+	return nil
+}
+
+// Kinds lista os tipos de job registrados (para logs de inicialização)
+func (r *Runner) Kinds() []string {
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// backoff dobra a espera a cada tentativa: 10s, 20s, 40s... até 1h
+func backoff(attempts int) time.Duration {
+	const base, max = 10 * time.Second, time.Hour
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 20 {
+		return max
+	}
+	return min(base<<(attempts-1), max)
+}
@@ -0,0 +1,249 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type recorder struct {
+	mu       sync.Mutex
+	payloads []string
+	err      error
+}
+
+func (r *recorder) handle(ctx context.Context, payload []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloads = append(r.payloads, string(payload))
+	return r.err
+}
+
+func (r *recorder) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.payloads)
+}
+
+func TestRunnerExecutesAndCompletes(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	runner := NewRunner(store)
+	rec := &recorder{}
+	runner.Register("teste", rec.handle, Options{})
+
+	if err := Enqueue(ctx, store, "teste", map[string]int{"n": 1}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := EnqueueAt(ctx, store, "teste", map[string]int{"n": 2}, later); err != nil {
+		t.Fatalf("EnqueueAt: %v", err)
+	}
+
+	now := time.Now()
+	n, err := runner.RunOnce(ctx, now)
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if n != 1 || rec.calls() != 1 || rec.payloads[0] != `{"n":1}` {
+		t.Fatalf("esperava só o job imediato; reservados=%d payloads=%v", n, rec.payloads)
+	}
+
+	// Concluído sai da fila; o agendado roda na hora dele
+	if n, _ := runner.RunOnce(ctx, now.Add(time.Minute)); n != 0 {
+		t.Fatalf("job concluído foi executado de novo (%d)", n)
+	}
+	if n, _ := runner.RunOnce(ctx, later); n != 1 || rec.payloads[1] != `{"n":2}` {
+		t.Fatalf("job agendado não executou na hora; payloads=%v", rec.payloads)
+	}
+}
+
+func TestRunnerRetriesThenDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	runner := NewRunner(store)
+	rec := &recorder{err: errors.New("indisponível")}
+	runner.Register("teste", rec.handle, Options{MaxAttempts: 3})
+
+	if err := Enqueue(ctx, store, "teste", nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	now := time.Now()
+	runner.RunOnce(ctx, now)
+	// Backoff: a segunda tentativa não acontece antes de 10s
+	if n, _ := runner.RunOnce(ctx, now.Add(5*time.Second)); n != 0 {
+		t.Fatalf("nova tentativa antes do backoff (%d)", n)
+	}
+	runner.RunOnce(ctx, now.Add(10*time.Second))
+	runner.RunOnce(ctx, now.Add(40*time.Second))
+	if rec.calls() != 3 {
+		t.Fatalf("esperava 3 tentativas, obteve %d", rec.calls())
+	}
+
+	dead, err := store.GetDeadJobs(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetDeadJobs: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Attempts != 3 || dead[0].LastError != "indisponível" {
+		t.Fatalf("esperava o job na dead letter após 3 tentativas: %+v", dead)
+	}
+	runner.RunOnce(ctx, now.Add(24*time.Hour))
+	if rec.calls() != 3 {
+		t.Fatalf("job da dead letter foi executado (%d chamadas)", rec.calls())
+	}
+}
+
+func TestRunnerPermanentErrorSkipsRetries(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	runner := NewRunner(store)
+	runner.Register("teste", func(ctx context.Context, payload []byte) error {
+		var v struct{ N int }
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return Permanent(err)
+		}
+		return nil
+	}, Options{})
+
+	if err := store.EnqueueJob(ctx, newTestJob("teste", `"texto"`)); err != nil {
+		t.Fatalf("EnqueueJob: %v", err)
+	}
+	runner.RunOnce(ctx, time.Now())
+
+	if dead, _ := store.GetDeadJobs(ctx, 10); len(dead) != 1 || dead[0].Attempts != 1 {
+		t.Fatalf("erro permanente deveria ir direto para a dead letter: %+v", dead)
+	}
+}
+
+func TestRunnerRecoversPanics(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	runner := NewRunner(store)
+	runner.Register("teste", func(ctx context.Context, payload []byte) error {
+		panic("bug")
+	}, Options{MaxAttempts: 1})
+
+	if err := Enqueue(ctx, store, "teste", nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	runner.RunOnce(ctx, time.Now())
+
+	if dead, _ := store.GetDeadJobs(ctx, 10); len(dead) != 1 || dead[0].LastError != "panic: bug" {
+		t.Fatalf("panic deveria virar falha do job: %+v", dead)
+	}
+}
+
+func TestRunnerKeepsUnknownKinds(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	runner := NewRunner(store)
+
+	if err := Enqueue(ctx, store, "de.uma.versao.nova", nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	now := time.Now()
+	for i := 0; i < 15; i++ {
+		runner.RunOnce(ctx, now.Add(time.Duration(i)*2*time.Hour))
+	}
+
+	if dead, _ := store.GetDeadJobs(ctx, 10); len(dead) != 0 {
+		t.Fatalf("tipo desconhecido não deveria ir para a dead letter: %+v", dead)
+	}
+	rec := &recorder{}
+	runner.Register("de.uma.versao.nova", rec.handle, Options{})
+	runner.RunOnce(ctx, now.Add(48*time.Hour))
+	if rec.calls() != 1 {
+		t.Fatalf("job deveria rodar quando o handler aparecer (%d chamadas)", rec.calls())
+	}
+}
+
+func TestScheduleFiresOncePerTickAcrossRunners(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	rec := &recorder{}
+
+	// Duas réplicas com o mesmo agendamento
+	runners := []*Runner{NewRunner(store), NewRunner(store)}
+	for _, r := range runners {
+		r.Register("relatorio", rec.handle, Options{})
+		if err := r.Schedule("relatorio-horario", "@hourly", "relatorio", map[string]string{"tipo": "uso"}); err != nil {
+			t.Fatalf("Schedule: %v", err)
+		}
+	}
+
+	start := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	// O primeiro ciclo só registra o próximo disparo (11:00)
+	for _, r := range runners {
+		r.RunOnce(ctx, start)
+	}
+	if rec.calls() != 0 {
+		t.Fatalf("agendamento disparou no registro (%d)", rec.calls())
+	}
+
+	for _, at := range []time.Time{start.Add(20 * time.Minute), start.Add(31 * time.Minute), start.Add(45 * time.Minute)} {
+		for _, r := range runners {
+			r.RunOnce(ctx, at)
+		}
+	}
+	if rec.calls() != 1 || rec.payloads[0] != `{"tipo":"uso"}` {
+		t.Fatalf("esperava 1 disparo às 11:00, obteve %v", rec.payloads)
+	}
+
+	for _, r := range runners {
+		r.RunOnce(ctx, start.Add(90*time.Minute))
+	}
+	if rec.calls() != 2 {
+		t.Fatalf("esperava o disparo das 12:00, obteve %d", rec.calls())
+	}
+}
+
+func TestScheduleRejectsInvalid(t *testing.T) {
+	runner := NewRunner(repository.NewInMemoryStore())
+	if err := runner.Schedule("x", "not a cron", "teste", nil); err == nil {
+		t.Fatal("esperava erro para expressão inválida")
+	}
+	if err := runner.Schedule(KindPruneDead, "@daily", "teste", nil); err == nil {
+		t.Fatal("esperava erro para nome duplicado")
+	}
+}
+
+func TestPruneDeadJob(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	runner := NewRunner(store)
+
+	job := newTestJob("teste", `{}`)
+	if err := store.EnqueueJob(ctx, job); err != nil {
+		t.Fatalf("EnqueueJob: %v", err)
+	}
+	if err := store.FailJob(ctx, job.ID, time.Now().Add(-60*24*time.Hour), "antigo"); err != nil {
+		t.Fatalf("FailJob: %v", err)
+	}
+
+	if err := runner.pruneDead(ctx, nil); err != nil {
+		t.Fatalf("pruneDead: %v", err)
+	}
+	if dead, _ := store.GetDeadJobs(ctx, 10); len(dead) != 0 {
+		t.Fatalf("job antigo deveria ter sido podado: %+v", dead)
+	}
+}
+
+func newTestJob(kind, payload string) *models.Job {
+	now := time.Now()
+	return &models.Job{
+		ID:        uuid.New(),
+		Kind:      kind,
+		Payload:   []byte(payload),
+		RunAt:     now,
+		CreatedAt: now,
+	}
+}
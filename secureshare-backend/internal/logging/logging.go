@@ -0,0 +1,89 @@
+// Package logging configura o log estruturado (log/slog, em JSON) do
+// servidor. Cada requisição leva no contexto um logger com o ID da
+// requisição, o usuário e a rota (ver api.logRequests); atributos com nomes
+// de segredos (senhas, tokens, chaves, SKBs) são mascarados na saída.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Redacted substitui o valor dos atributos sensíveis
+const Redacted = "[REDACTED]"
+
+// New cria um logger JSON em w, a partir do nível level
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}))
+}
+
+// ParseLevel interpreta LOG_LEVEL: debug, info, warn ou error (vazio: info)
+func ParseLevel(s string) (slog.Level, error) {
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("LOG_LEVEL inválido %q: use debug, info, warn ou error", s)
+	}
+	return level, nil
+}
+
+type contextKey struct{}
+
+// WithContext guarda o logger no contexto
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext retorna o logger do contexto, ou slog.Default() se não houver
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With acrescenta atributos ao logger do contexto
+func With(ctx context.Context, args ...any) context.Context {
+	return WithContext(ctx, FromContext(ctx).With(args...))
+}
+
+// sensitiveParts marcam um atributo como segredo se aparecerem no nome
+// (sem maiúsculas, "_" e "-"); sensitiveNames, só se forem o nome inteiro
+var (
+	sensitiveParts = []string{"password", "passwd", "secret", "token", "authorization", "cookie", "privatekey", "ciphertext"}
+	sensitiveNames = []string{"apikey", "key", "skb", "skbs", "sig", "signature"}
+)
+
+// redact mascara os atributos sensíveis e valores com cara de credencial
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if isSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	if a.Value.Kind() == slog.KindString && strings.HasPrefix(strings.ToLower(a.Value.String()), "bearer ") {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+func isSensitive(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	for _, part := range sensitiveParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	for _, name := range sensitiveNames {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"secureshare-backend/internal/logging"
+)
+
+func TestRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, slog.LevelInfo)
+
+	logger.Info("login",
+		"user_id", "u1",
+		"password", "hunter2",
+		"newPassword", "hunter3",
+		"access_token", "eyJ...",
+		"api_key", "ssk_abc",
+		"skb", "c2ti",
+		"webhook_secret", "whsec",
+		"header", "Bearer eyJ...",
+		"transfer_id", "t1",
+		slog.Group("req", "Authorization", "Bearer x", "path", "/v1/users/login"),
+	)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("saída não é JSON: %v\n%s", err, buf.String())
+	}
+	for _, key := range []string{"password", "newPassword", "access_token", "api_key", "skb", "webhook_secret", "header"} {
+		if entry[key] != logging.Redacted {
+			t.Errorf("%s = %v, deveria estar mascarado", key, entry[key])
+		}
+	}
+	if entry["user_id"] != "u1" || entry["transfer_id"] != "t1" {
+		t.Errorf("atributos comuns alterados: %v", entry)
+	}
+	group := entry["req"].(map[string]any)
+	if group["Authorization"] != logging.Redacted || group["path"] != "/v1/users/login" {
+		t.Errorf("grupo: %v", group)
+	}
+	for _, secret := range []string{"hunter", "eyJ", "ssk_", "whsec"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("segredo %q na saída: %s", secret, buf.String())
+		}
+	}
+}
+
+func TestLevelAndContext(t *testing.T) {
+	level, err := logging.ParseLevel("warn")
+	if err != nil || level != slog.LevelWarn {
+		t.Fatalf("ParseLevel(warn) = %v, %v", level, err)
+	}
+	if level, err := logging.ParseLevel(""); err != nil || level != slog.LevelInfo {
+		t.Fatalf("ParseLevel(\"\") = %v, %v", level, err)
+	}
+	if _, err := logging.ParseLevel("verbose"); err == nil {
+		t.Fatal("ParseLevel aceitou nível inválido")
+	}
+
+	var buf bytes.Buffer
+	ctx := logging.WithContext(context.Background(), logging.New(&buf, level))
+	ctx = logging.With(ctx, "request_id", "r1")
+	logging.FromContext(ctx).Info("ignorada")
+	logging.FromContext(ctx).Warn("registrada")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"request_id":"r1"`) || !strings.Contains(lines[0], "registrada") {
+		t.Fatalf("saída inesperada: %s", buf.String())
+	}
+	if logging.FromContext(context.Background()) != slog.Default() {
+		t.Fatal("sem logger no contexto, deveria usar slog.Default()")
+	}
+}
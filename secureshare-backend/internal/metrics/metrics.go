@@ -0,0 +1,124 @@
+// Package metrics define as métricas Prometheus do servidor. Elas ficam num
+// registro próprio (Registry), servido em /metrics pelo listener de
+// administração (METRICS_PORT), separado da API pública.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "secureshare"
+
+// Registry reúne as métricas do processo; coletores que dependem de
+// recursos criados no start (pool do banco, fila de jobs) são registrados
+// pelo cmd/server
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duração das requisições HTTP, por rota (padrão do chi), método e status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Tentativas de login, por método (password ou sso) e resultado (success ou failure).",
+	}, []string{"method", "result"})
+
+	transfersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_created_total",
+		Help:      "Transferências criadas.",
+	})
+
+	presignedURLs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "presigned_urls_total",
+		Help:      "URLs pré-assinadas emitidas, por operação (put ou get).",
+	}, []string{"operation"})
+
+	s3RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "s3_request_duration_seconds",
+		Help:      "Duração das chamadas ao S3, por operação.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	s3Errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "s3_errors_total",
+		Help:      "Chamadas ao S3 que falharam, por operação.",
+	}, []string{"operation"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		logins,
+		transfersCreated,
+		presignedURLs,
+		s3RequestDuration,
+		s3Errors,
+	)
+}
+
+// Handler serve as métricas do Registry no formato de exposição do Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveHTTPRequest registra uma requisição atendida. route é o padrão da
+// rota (ex: /v1/transfers/{id}), nunca o caminho, para limitar as séries;
+// vazio vira "unmatched" (404 e 405 do roteador).
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// Métodos de login
+const (
+	LoginPassword = "password"
+	LoginSSO      = "sso"
+)
+
+// ObserveLogin conta uma tentativa de login
+func ObserveLogin(method string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	logins.WithLabelValues(method, result).Inc()
+}
+
+// TransferCreated conta uma transferência criada
+func TransferCreated() {
+	transfersCreated.Inc()
+}
+
+// PresignedURLIssued conta uma URL pré-assinada emitida para operation
+// ("put" ou "get")
+func PresignedURLIssued(operation string) {
+	presignedURLs.WithLabelValues(operation).Inc()
+}
+
+// ObserveS3 registra a duração de uma chamada ao S3 iniciada em start e,
+// se failed, conta o erro
+func ObserveS3(operation string, start time.Time, failed bool) {
+	s3RequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if failed {
+		s3Errors.WithLabelValues(operation).Inc()
+	}
+}
@@ -0,0 +1,84 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/metrics"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// scrape lê /metrics no formato texto
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func TestHandlerExposesMetrics(t *testing.T) {
+	metrics.ObserveHTTPRequest(http.MethodGet, "/v1/transfers/{id}", http.StatusOK, 30*time.Millisecond)
+	metrics.ObserveHTTPRequest(http.MethodGet, "", http.StatusNotFound, time.Millisecond)
+	metrics.ObserveLogin(metrics.LoginPassword, nil)
+	metrics.ObserveLogin(metrics.LoginSSO, context.Canceled)
+	metrics.PresignedURLIssued("put")
+	metrics.ObserveS3("head_object", time.Now(), true)
+
+	body := scrape(t)
+	for _, want := range []string{
+		`secureshare_http_request_duration_seconds_count{method="GET",route="/v1/transfers/{id}",status="200"} 1`,
+		`secureshare_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+		`secureshare_logins_total{method="password",result="success"} 1`,
+		`secureshare_logins_total{method="sso",result="failure"} 1`,
+		`secureshare_presigned_urls_total{operation="put"} 1`,
+		`secureshare_s3_request_duration_seconds_count{operation="head_object"} 1`,
+		`secureshare_s3_errors_total{operation="head_object"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("faltou %q em /metrics", want)
+		}
+	}
+}
+
+func TestJobQueueCollector(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	now := time.Now()
+	jobs := []*models.Job{
+		{ID: uuid.New(), Kind: "email", Payload: []byte("{}"), RunAt: now, CreatedAt: now},
+		{ID: uuid.New(), Kind: "email", Payload: []byte("{}"), RunAt: now, CreatedAt: now},
+	}
+	for _, job := range jobs {
+		if err := store.EnqueueJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.FailJob(ctx, jobs[1].ID, now, "falhou"); err != nil {
+		t.Fatal(err)
+	}
+
+	collector := metrics.NewJobQueueCollector(store)
+	metrics.Registry.MustRegister(collector)
+	t.Cleanup(func() { metrics.Registry.Unregister(collector) })
+
+	body := scrape(t)
+	for _, want := range []string{
+		`secureshare_jobs_queued{kind="email"} 1`,
+		`secureshare_jobs_dead{kind="email"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("faltou %q em /metrics:\n%s", want, body)
+		}
+	}
+}
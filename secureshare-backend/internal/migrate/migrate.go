@@ -0,0 +1,422 @@
+// Package migrate aplica as migrações versionadas do schema (ver
+// /migrations) no PostgreSQL.
+//
+// O estado fica na tabela schema_migrations, uma linha por versão aplicada.
+// Cada passo roda em transação, sob um advisory lock, para que réplicas
+// subindo ao mesmo tempo não apliquem a mesma migração em paralelo. Uma
+// versão que falha fica marcada como "suja" (dirty) e bloqueia novos passos
+// e o start do servidor até alguém intervir (migrate force).
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"secureshare-backend/internal/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockKey identifica o advisory lock das migrações (valor arbitrário, fixo)
+const lockKey int64 = 0x5ec5_4a4e_0001
+
+// ErrDirty indica que uma migração falhou no meio e o schema precisa de
+// intervenção manual
+var ErrDirty = errors.New("schema sujo: uma migração falhou; corrija o banco e use 'migrate force <versão>'")
+
+// Migration é um par de scripts up/down de uma versão
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status é a situação de uma versão conhecida
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+}
+
+// Migrator aplica migrações de um fs.FS em um banco PostgreSQL
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration // ordenadas por versão
+}
+
+var fileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// New carrega as migrações de source (arquivos NNN_nome.up.sql e
+// NNN_nome.down.sql na raiz). Toda versão precisa dos dois scripts.
+func New(db *pgxpool.Pool, source fs.FS) (*Migrator, error) {
+	migrations, err := Load(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load lê e valida as migrações de source, ordenadas por versão
+func Load(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("falha ao listar migrações: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := fileRe.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue // ex: migrations.go
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		if version <= 0 {
+			return nil, fmt.Errorf("migração '%s': versão deve ser positiva", entry.Name())
+		}
+
+		sql, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("falha ao ler migração '%s': %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("versão %d duplicada: '%s' e '%s'", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(sql)
+		} else {
+			mig.Down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migração %03d_%s: faltam os scripts up e down", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("nenhuma migração encontrada")
+	}
+	return migrations, nil
+}
+
+// Latest retorna a maior versão conhecida
+func (m *Migrator) Latest() int64 {
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up aplica todas as migrações pendentes
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverte a última migração aplicada
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.current(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if current == 0 {
+			return nil
+		}
+		return m.stepDown(ctx, conn, current)
+	})
+}
+
+// To migra para exatamente target, aplicando ou revertendo o necessário.
+// target 0 reverte tudo.
+func (m *Migrator) To(ctx context.Context, target int64) error {
+	if target != 0 && m.find(target) == nil {
+		return fmt.Errorf("versão %d desconhecida", target)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.current(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if target >= current {
+			for _, mig := range m.migrations {
+				if mig.Version > current && mig.Version <= target {
+					if err := m.stepUp(ctx, conn, mig); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if mig.Version <= current && mig.Version > target {
+				if err := m.stepDown(ctx, conn, mig.Version); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Force marca version como a versão atual e limpa a marca de sujo, sem
+// executar nenhum script. Use depois de corrigir o banco manualmente.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("versão %d desconhecida", version)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version > $1`, version); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `UPDATE schema_migrations SET dirty = FALSE`); err != nil {
+				return err
+			}
+			for _, mig := range m.migrations {
+				if mig.Version > version {
+					break
+				}
+				_, err := tx.Exec(ctx,
+					`INSERT INTO schema_migrations (version, name, dirty) VALUES ($1, $2, FALSE)
+					 ON CONFLICT (version) DO NOTHING`,
+					mig.Version, mig.Name,
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Status lista todas as versões conhecidas e se estão aplicadas. Versões
+// presentes no banco mas desconhecidas pelo binário também aparecem.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if a, ok := applied[mig.Version]; ok {
+			st.Applied = true
+			st.Dirty = a.Dirty
+			st.AppliedAt = a.AppliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, st)
+	}
+	for _, a := range applied {
+		statuses = append(statuses, a)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Check confere se o schema está limpo e na versão mais recente. Retorna
+// ErrDirty se uma migração falhou no meio.
+func (m *Migrator) Check(ctx context.Context) (pending int, err error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, st := range statuses {
+		if st.Dirty {
+			return 0, fmt.Errorf("versão %d: %w", st.Version, ErrDirty)
+		}
+		if !st.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// --- Internos ---
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// ensureTable cria schema_migrations. Só roda com o advisory lock: réplicas
+// concorrentes em CREATE TABLE IF NOT EXISTS podem colidir no catálogo.
+func (m *Migrator) ensureTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version     BIGINT PRIMARY KEY,
+            name        TEXT NOT NULL,
+            dirty       BOOLEAN NOT NULL DEFAULT FALSE,
+            applied_at  TIMESTAMPTZ NOT NULL DEFAULT (NOW())
+        )`)
+	if err != nil {
+		return fmt.Errorf("falha ao criar schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// withLock executa fn com o advisory lock em uma conexão dedicada. O lock
+// é por sessão, então precisa ser liberado na mesma conexão.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("falha ao obter conexão para migração: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("falha ao obter lock de migração: %w", err)
+	}
+	defer func() {
+		// Usa um contexto novo: o da chamada pode já ter sido cancelado
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			logging.FromContext(ctx).Error("Erro ao liberar lock de migração", "err", err)
+		}
+	}()
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	// Outra réplica pode ter deixado o schema sujo enquanto esperávamos
+	var dirty int64
+	err = conn.QueryRow(ctx, `SELECT COALESCE(MIN(version), 0) FROM schema_migrations WHERE dirty`).Scan(&dirty)
+	if err != nil {
+		return fmt.Errorf("falha ao ler schema_migrations: %w", err)
+	}
+	if dirty != 0 {
+		return fmt.Errorf("versão %d: %w", dirty, ErrDirty)
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) current(ctx context.Context, conn *pgxpool.Conn) (int64, error) {
+	var current int64
+	err := conn.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current)
+	if err != nil {
+		return 0, fmt.Errorf("falha ao ler versão atual: %w", err)
+	}
+	return current, nil
+}
+
+// applied lê as versões aplicadas sem criar schema_migrations (e sem o
+// lock): se a tabela ainda não existe, nenhuma foi aplicada
+func (m *Migrator) applied(ctx context.Context) (map[int64]Status, error) {
+	var exists bool
+	err := m.db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler schema_migrations: %w", err)
+	}
+	if !exists {
+		return map[int64]Status{}, nil
+	}
+
+	rows, err := m.db.Query(ctx, `SELECT version, name, dirty, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]Status)
+	for rows.Next() {
+		var st Status
+		var appliedAt time.Time
+		if err := rows.Scan(&st.Version, &st.Name, &st.Dirty, &appliedAt); err != nil {
+			return nil, fmt.Errorf("falha ao escanear schema_migrations: %w", err)
+		}
+		st.Applied = true
+		st.AppliedAt = &appliedAt
+		applied[st.Version] = st
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre schema_migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// stepUp aplica uma migração. A versão é registrada como suja antes, e
+// marcada como limpa na mesma transação do script: se o processo morrer ou
+// o script falhar, a marca de sujo permanece.
+func (m *Migrator) stepUp(ctx context.Context, conn *pgxpool.Conn, mig Migration) error {
+	_, err := conn.Exec(ctx,
+		`INSERT INTO schema_migrations (version, name, dirty) VALUES ($1, $2, TRUE)`,
+		mig.Version, mig.Name,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao registrar migração %d: %w", mig.Version, err)
+	}
+
+	err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, mig.Up); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `UPDATE schema_migrations SET dirty = FALSE, applied_at = NOW() WHERE version = $1`, mig.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migração %03d_%s (up) falhou: %w", mig.Version, mig.Name, err)
+	}
+	logging.FromContext(ctx).Info("Migração aplicada", "version", mig.Version, "name", mig.Name)
+	return nil
+}
+
+// stepDown reverte a versão informada (que deve ser a atual)
+func (m *Migrator) stepDown(ctx context.Context, conn *pgxpool.Conn, version int64) error {
+	mig := m.find(version)
+	if mig == nil {
+		return fmt.Errorf("versão %d aplicada no banco, mas desconhecida por este binário", version)
+	}
+
+	if _, err := conn.Exec(ctx, `UPDATE schema_migrations SET dirty = TRUE WHERE version = $1`, version); err != nil {
+		return fmt.Errorf("falha ao registrar reversão da migração %d: %w", version, err)
+	}
+
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, mig.Down); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migração %03d_%s (down) falhou: %w", mig.Version, mig.Name, err)
+	}
+	logging.FromContext(ctx).Info("Migração revertida", "version", mig.Version, "name", mig.Name)
+	return nil
+}
@@ -0,0 +1,193 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"secureshare-backend/internal/migrate"
+	"secureshare-backend/internal/testutil/pgtest"
+	"secureshare-backend/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestLoadEmbeddedMigrations(t *testing.T) {
+	migs, err := migrate.Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for i, m := range migs {
+		if m.Version != int64(i+1) {
+			t.Fatalf("versões não contíguas: posição %d tem versão %d", i, m.Version)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidSets(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"sem down": {
+			"001_a.up.sql": {Data: []byte("SELECT 1")},
+		},
+		"versão duplicada": {
+			"001_a.up.sql":   {Data: []byte("SELECT 1")},
+			"001_a.down.sql": {Data: []byte("SELECT 1")},
+			"001_b.up.sql":   {Data: []byte("SELECT 1")},
+			"001_b.down.sql": {Data: []byte("SELECT 1")},
+		},
+		"vazio": {
+			"README.md": {Data: []byte("nada aqui")},
+		},
+	}
+	for name, fsys := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := migrate.Load(fsys); err == nil {
+				t.Fatal("esperava erro")
+			}
+		})
+	}
+}
+
+func TestLoadSortsByVersion(t *testing.T) {
+	migs, err := migrate.Load(fstest.MapFS{
+		"010_b.up.sql":   {Data: []byte("SELECT 10")},
+		"010_b.down.sql": {Data: []byte("SELECT -10")},
+		"002_a.up.sql":   {Data: []byte("SELECT 2")},
+		"002_a.down.sql": {Data: []byte("SELECT -2")},
+		"migrations.go":  {Data: []byte("package migrations")},
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migs) != 2 || migs[0].Version != 2 || migs[1].Version != 10 {
+		t.Fatalf("ordem inesperada: %+v", migs)
+	}
+	if migs[1].Name != "b" || migs[1].Up != "SELECT 10" || migs[1].Down != "SELECT -10" {
+		t.Fatalf("migração 10 lida incorretamente: %+v", migs[1])
+	}
+}
+
+func newPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	pool, err := pgxpool.New(context.Background(), pgtest.NewDatabase(t))
+	if err != nil {
+		t.Fatalf("pgxpool.New: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestEmbeddedMigrationsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	pool := newPool(t)
+
+	m, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if pending, err := m.Check(ctx); err != nil || pending != 0 {
+		t.Fatalf("Check após Up: pending=%d err=%v", pending, err)
+	}
+
+	// Todos os scripts down precisam funcionar, e o up de novo em seguida
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatalf("To(0): %v", err)
+	}
+	if pending, _ := m.Check(ctx); pending != int(m.Latest()) {
+		t.Fatalf("esperava %d pendentes após To(0), obteve %d", m.Latest(), pending)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up após To(0): %v", err)
+	}
+
+	if err := m.Down(ctx); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if pending, _ := m.Check(ctx); pending != 1 {
+		t.Fatalf("esperava 1 pendente após Down, obteve %d", pending)
+	}
+}
+
+func TestConcurrentUpOnFreshDatabase(t *testing.T) {
+	ctx := context.Background()
+	pool := newPool(t)
+
+	m, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Status não cria schema_migrations (isso só acontece sob o lock)
+	if pending, err := m.Check(ctx); err != nil || pending != int(m.Latest()) {
+		t.Fatalf("Check em banco vazio: pending=%d err=%v", pending, err)
+	}
+
+	// Réplicas subindo juntas: todas passam pelo lock, inclusive na criação
+	// de schema_migrations
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = m.Up(ctx)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Up %d: %v", i, err)
+		}
+	}
+	if pending, err := m.Check(ctx); err != nil || pending != 0 {
+		t.Fatalf("Check após Up: pending=%d err=%v", pending, err)
+	}
+}
+
+func TestFailedMigrationLeavesSchemaDirty(t *testing.T) {
+	ctx := context.Background()
+	pool := newPool(t)
+
+	m, err := migrate.New(pool, fstest.MapFS{
+		"001_ok.up.sql":       {Data: []byte("CREATE TABLE t1 (id INT)")},
+		"001_ok.down.sql":     {Data: []byte("DROP TABLE t1")},
+		"002_broken.up.sql":   {Data: []byte("CREATE TABLE t2 (id INT); SELECT * FROM nao_existe")},
+		"002_broken.down.sql": {Data: []byte("DROP TABLE t2")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(ctx); err == nil {
+		t.Fatal("esperava falha na migração 002")
+	}
+
+	// O script roda em transação: t2 não pode ter ficado para trás
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('t2') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("migração que falhou deixou a tabela t2 criada")
+	}
+
+	if _, err := m.Check(ctx); !errors.Is(err, migrate.ErrDirty) {
+		t.Fatalf("esperava ErrDirty, obteve %v", err)
+	}
+	if err := m.Up(ctx); !errors.Is(err, migrate.ErrDirty) {
+		t.Fatalf("Up com schema sujo deveria falhar com ErrDirty, obteve %v", err)
+	}
+
+	if err := m.Force(ctx, 1); err != nil {
+		t.Fatalf("Force: %v", err)
+	}
+	pending, err := m.Check(ctx)
+	if err != nil || pending != 1 {
+		t.Fatalf("após Force(1): pending=%d err=%v", pending, err)
+	}
+}
@@ -0,0 +1,220 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"secureshare-backend/internal/logging"
+)
+
+// SQLiteMigrator aplica migrações em um banco SQLite.
+//
+// No SQLite o DDL é transacional e o próprio arquivo tem lock de escrita,
+// então cada passo (script + registro em schema_migrations) é atômico: não
+// existe estado "sujo" nem advisory lock.
+type SQLiteMigrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewSQLite carrega as migrações de source (mesmo formato de New)
+func NewSQLite(db *sql.DB, source fs.FS) (*SQLiteMigrator, error) {
+	migrations, err := Load(source)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteMigrator{db: db, migrations: migrations}, nil
+}
+
+// Latest retorna a maior versão conhecida
+func (m *SQLiteMigrator) Latest() int64 {
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up aplica todas as migrações pendentes
+func (m *SQLiteMigrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverte a última migração aplicada
+func (m *SQLiteMigrator) Down(ctx context.Context) error {
+	current, err := m.current(ctx)
+	if err != nil || current == 0 {
+		return err
+	}
+	return m.stepDown(ctx, current)
+}
+
+// To migra para exatamente target, aplicando ou revertendo o necessário.
+// target 0 reverte tudo.
+func (m *SQLiteMigrator) To(ctx context.Context, target int64) error {
+	if target != 0 && m.find(target) == nil {
+		return fmt.Errorf("versão %d desconhecida", target)
+	}
+
+	current, err := m.current(ctx)
+	if err != nil {
+		return err
+	}
+
+	if target >= current {
+		for _, mig := range m.migrations {
+			if mig.Version > current && mig.Version <= target {
+				if err := m.stepUp(ctx, mig); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version <= current && mig.Version > target {
+			if err := m.stepDown(ctx, mig.Version); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *SQLiteMigrator) stepUp(ctx context.Context, mig Migration) error {
+	err := m.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			mig.Version, mig.Name, time.Now().UTC().Format(time.RFC3339Nano),
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migração %03d_%s (up) falhou: %w", mig.Version, mig.Name, err)
+	}
+	logging.FromContext(ctx).Info("Migração aplicada", "version", mig.Version, "name", mig.Name)
+	return nil
+}
+
+func (m *SQLiteMigrator) stepDown(ctx context.Context, version int64) error {
+	mig := m.find(version)
+	if mig == nil {
+		return fmt.Errorf("versão %d aplicada no banco, mas desconhecida por este binário", version)
+	}
+
+	err := m.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, mig.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migração %03d_%s (down) falhou: %w", mig.Version, mig.Name, err)
+	}
+	logging.FromContext(ctx).Info("Migração revertida", "version", mig.Version, "name", mig.Name)
+	return nil
+}
+
+func (m *SQLiteMigrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// Status lista as versões conhecidas e se estão aplicadas
+func (m *SQLiteMigrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("falha ao escanear schema_migrations: %w", err)
+		}
+		applied[version], _ = time.Parse(time.RFC3339Nano, appliedAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre schema_migrations: %w", err)
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = &at
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// Pending retorna quantas migrações ainda não foram aplicadas
+func (m *SQLiteMigrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, st := range statuses {
+		if !st.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (m *SQLiteMigrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version     INTEGER PRIMARY KEY,
+            name        TEXT NOT NULL,
+            applied_at  TEXT NOT NULL
+        )`)
+	if err != nil {
+		return fmt.Errorf("falha ao criar schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func (m *SQLiteMigrator) current(ctx context.Context) (int64, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	var current int64
+	err := m.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current)
+	if err != nil {
+		return 0, fmt.Errorf("falha ao ler versão atual: %w", err)
+	}
+	return current, nil
+}
+
+func (m *SQLiteMigrator) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
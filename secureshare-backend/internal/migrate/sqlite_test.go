@@ -0,0 +1,60 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"secureshare-backend/internal/migrate"
+	"secureshare-backend/migrations"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLiteMigrateTo(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrate.NewSQLite(db, migrations.SQLiteFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := m.Latest()
+
+	// Para cima até uma versão intermediária, depois até a última
+	if err := m.To(ctx, 3); err != nil {
+		t.Fatalf("To(3): %v", err)
+	}
+	if pending, err := m.Pending(ctx); err != nil || pending != int(latest-3) {
+		t.Fatalf("esperava %d pendentes após To(3), obteve %d (err=%v)", latest-3, pending, err)
+	}
+	if err := m.To(ctx, latest); err != nil {
+		t.Fatalf("To(%d): %v", latest, err)
+	}
+
+	// Para baixo: todos os scripts down precisam funcionar
+	if err := m.To(ctx, 2); err != nil {
+		t.Fatalf("To(2): %v", err)
+	}
+	if pending, _ := m.Pending(ctx); pending != int(latest-2) {
+		t.Fatalf("esperava %d pendentes após To(2), obteve %d", latest-2, pending)
+	}
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatalf("To(0): %v", err)
+	}
+	if pending, _ := m.Pending(ctx); pending != int(latest) {
+		t.Fatalf("esperava %d pendentes após To(0), obteve %d", latest, pending)
+	}
+
+	if err := m.To(ctx, latest+1); err == nil {
+		t.Fatal("To com versão desconhecida deveria falhar")
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up após To(0): %v", err)
+	}
+}
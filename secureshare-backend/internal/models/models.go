@@ -1,6 +1,9 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +17,41 @@ type User struct {
 	PublicKey     string    `json:"publicKey"`
 	PublicKeySign string    `json:"publicKeySign"`
 	CreatedAt     time.Time `json:"createdAt"`
+	// SessionsValidAfter invalida tokens emitidos antes deste instante
+	// (ex: após troca de senha)
+	SessionsValidAfter time.Time `json:"-"`
+	// Kind distingue pessoas (UserKindHuman) de contas de serviço
+	// (UserKindService), que não têm senha e autenticam só via API key
+	Kind    string     `json:"kind"`
+	OwnerID *uuid.UUID `json:"-"` // Dono da conta de serviço
+	// Email (opcional) recebe avisos de novas transferências, mas só depois
+	// de verificado (EmailVerifiedAt)
+	Email           string     `json:"-"`
+	EmailVerifiedAt *time.Time `json:"-"`
+	// Locale é o idioma dos e-mails ("pt-BR" ou "en"; vazio: pt-BR)
+	Locale string `json:"-"`
+}
+
+// Tipos de usuário
+const (
+	UserKindHuman   = "user"
+	UserKindService = "service"
+)
+
+// APIKey é uma credencial de longa duração de uma conta de serviço.
+// Apenas o hash da chave é armazenado; Prefix permite localizá-la.
+type APIKey struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"-"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	Hash         string     `json:"-"` // Nunca expor em JSON
+	Scopes       []string   `json:"scopes"`
+	AllowedCIDRs []string   `json:"allowedIps,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
 }
 
 // Transfer representa os metadados de uma transferência de arquivo
@@ -25,4 +63,215 @@ type Transfer struct {
 	SKB           string    `json:"skb"` // Chave Simétrica Encapsulada (Symmetric Key Boxed)
 	Sig           string    `json:"sig"`
 	CreatedAt     time.Time `json:"createdAt"`
+	// Size é o tamanho real do objeto, lido do bucket na criação (0 para
+	// transferências anteriores às cotas)
+	Size int64 `json:"size"`
+	// ChecksumSHA256 é o SHA-256 do arquivo cifrado (base64), conferido pelo
+	// S3 no upload; o destinatário confere o download com ele antes de
+	// decifrar. Vazio em transferências anteriores aos checksums.
+	ChecksumSHA256 string `json:"checksumSha256,omitempty"`
+	// DeviceSKBs guarda uma SKB por dispositivo do destinatário
+	// (cifrada com a chave pública de cada dispositivo)
+	DeviceSKBs map[uuid.UUID]string `json:"skbs,omitempty"`
+}
+
+// Device é um dispositivo de um usuário, com seu próprio par de chaves.
+// Todo dispositivo além do primeiro é assinado (cross-signed) por um
+// dispositivo já existente do mesmo usuário.
+type Device struct {
+	ID             uuid.UUID  `json:"deviceId"`
+	UserID         uuid.UUID  `json:"-"`
+	Name           string     `json:"name"`
+	PublicKey      string     `json:"publicKey"`     // RSA-OAEP
+	PublicKeySign  string     `json:"publicKeySign"` // ECDSA P-256
+	SignerDeviceID *uuid.UUID `json:"signerDeviceId,omitempty"`
+	Signature      string     `json:"signature,omitempty"` // Base64, feita pelo dispositivo signatário
+	CreatedAt      time.Time  `json:"createdAt"`
+	RevokedAt      *time.Time `json:"revokedAt,omitempty"`
+}
+
+// KeyBackup é o backup cifrado das chaves privadas de um usuário.
+// As chaves são cifradas no cliente com uma chave derivada da senha de
+// backup (KDF); o servidor armazena apenas o blob opaco e os parâmetros
+// necessários para derivar a chave novamente.
+type KeyBackup struct {
+	UserID         uuid.UUID `json:"-"`
+	KDF            string    `json:"kdf"` // "argon2id" ou "pbkdf2-sha256"
+	KDFSalt        string    `json:"kdfSalt"`
+	KDFIterations  int       `json:"kdfIterations"`            // argon2id: time; pbkdf2: iterações
+	KDFMemoryKiB   int       `json:"kdfMemoryKiB,omitempty"`   // apenas argon2id
+	KDFParallelism int       `json:"kdfParallelism,omitempty"` // apenas argon2id
+	Cipher         string    `json:"cipher"`                   // "aes-256-gcm"
+	Nonce          string    `json:"nonce"`
+	Ciphertext     string    `json:"ciphertext"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Identity vincula um usuário a uma identidade externa (OIDC), identificada
+// pelo par (emissor, sub)
+type Identity struct {
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	UserID    uuid.UUID `json:"-"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Job é uma tarefa assíncrona da fila persistente (ver internal/jobs)
+type Job struct {
+	ID        uuid.UUID `json:"id"`
+	Kind      string    `json:"kind"`
+	Payload   []byte    `json:"payload"` // JSON, interpretado pelo handler de Kind
+	Attempts  int       `json:"attempts"`
+	RunAt     time.Time `json:"runAt"` // não executar antes disto
+	LastError string    `json:"lastError,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// FailedAt é preenchido quando o job esgota as tentativas (dead letter);
+	// a partir daí ele não é mais reservado
+	FailedAt *time.Time `json:"failedAt,omitempty"`
+}
+
+// JobCount é o tamanho da fila de um tipo de job (ver JobStore.CountJobs)
+type JobCount struct {
+	Kind   string
+	Queued int // vivos, aguardando execução ou em execução
+	Dead   int // na dead letter
+}
+
+// UploadReservation registra uma chave de objeto entregue por
+// POST /transfers/upload-url. Enquanto não expira, o coletor de órfãos não
+// remove o objeto, mesmo que nenhuma transferência aponte para ele ainda, e
+// o tamanho declarado conta na cota do usuário.
+type UploadReservation struct {
+	ObjectKey string    `json:"objectKey"`
+	UserID    uuid.UUID `json:"userId"`
+	Size      int64     `json:"size"` // assinado na URL (Content-Length)
+	// ChecksumSHA256 (base64) também é assinado na URL (x-amz-checksum-sha256)
+	ChecksumSHA256 string    `json:"checksumSha256"`
+	CreatedAt      time.Time `json:"createdAt"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+}
+
+// StorageUsage é o espaço ocupado por um conjunto de usuários
+type StorageUsage struct {
+	// UsedBytes soma os objetos já ligados a transferências (cada objeto
+	// conta uma vez, mesmo enviado a vários destinatários)
+	UsedBytes int64 `json:"usedBytes"`
+	// ReservedBytes soma os uploads reservados que ainda não viraram
+	// transferência
+	ReservedBytes int64 `json:"reservedBytes"`
+}
+
+// AuditEvent é um evento relevante para segurança (login, emissão de URLs,
+// remoções...). Os eventos formam uma cadeia: Hash cobre o evento e o Hash
+// do anterior (PrevHash), então alterar, inserir ou remover um evento no
+// meio quebra a cadeia a partir dele.
+type AuditEvent struct {
+	Seq  int64  `json:"seq"` // posição na cadeia, a partir de 1
+	Type string `json:"type"`
+	// ActorID fica vazio em eventos anônimos (ex: login recusado); Actor é o
+	// username na época, preservado mesmo se a conta for removida
+	ActorID   *uuid.UUID        `json:"actorId,omitempty"`
+	Actor     string            `json:"actor,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"userAgent,omitempty"`
+	Target    string            `json:"target,omitempty"` // ex: "user:alice", "transfer:<id>"
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	PrevHash  string            `json:"prevHash"` // vazio no primeiro evento
+	Hash      string            `json:"hash"`
+}
+
+// ComputeHash calcula o SHA-256 (hex) do evento encadeado ao PrevHash. O
+// instante entra em microssegundos (a precisão do PostgreSQL), para que o
+// hash não mude depois de gravado.
+func (e *AuditEvent) ComputeHash() string {
+	canonical, _ := json.Marshal(struct {
+		Seq       int64             `json:"seq"`
+		Type      string            `json:"type"`
+		ActorID   *uuid.UUID        `json:"actorId,omitempty"`
+		Actor     string            `json:"actor,omitempty"`
+		IP        string            `json:"ip,omitempty"`
+		UserAgent string            `json:"userAgent,omitempty"`
+		Target    string            `json:"target,omitempty"`
+		Details   map[string]string `json:"details,omitempty"`
+		CreatedAt string            `json:"createdAt"`
+		PrevHash  string            `json:"prevHash"`
+	}{
+		Seq:       e.Seq,
+		Type:      e.Type,
+		ActorID:   e.ActorID,
+		Actor:     e.Actor,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		Target:    e.Target,
+		Details:   e.Details,
+		CreatedAt: e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		PrevHash:  e.PrevHash,
+	})
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// InboxEvent é uma notificação para um usuário (ex: transferência recebida),
+// entregue em tempo real por GET /events. Fica guardada por um tempo para
+// que clientes que reconectam retomem do último ID visto (Last-Event-ID).
+type InboxEvent struct {
+	ID        int64           `json:"id"` // crescente; é o id do evento SSE
+	UserID    uuid.UUID       `json:"-"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// Webhook é um endpoint HTTP que recebe, por POST assinado, os eventos de
+// um usuário (os mesmos de InboxEvent) dos tipos em EventTypes
+type Webhook struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"-"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"` // chave do HMAC; só é mostrada na criação
+	EventTypes []string  `json:"eventTypes"`
+	CreatedAt  time.Time `json:"createdAt"`
+	CreatedBy  string    `json:"createdBy"` // username de quem registrou
+}
+
+// Situações de uma entrega de webhook
+const (
+	WebhookDeliveryPending   = "pending" // aguardando (nova) tentativa
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed" // tentativas esgotadas
+)
+
+// WebhookDelivery é o registro de uma entrega: o corpo enviado e o
+// resultado da última tentativa
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	WebhookID      uuid.UUID       `json:"webhookId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode int             `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+	// RedeliveryOf aponta a entrega original de um reenvio manual
+	RedeliveryOf *uuid.UUID `json:"redeliveryOf,omitempty"`
+}
+
+// IdempotencyRecord guarda a resposta de uma requisição feita com o
+// cabeçalho Idempotency-Key, repetida quando o cliente reenvia a mesma
+// requisição com a mesma chave
+type IdempotencyRecord struct {
+	UserID uuid.UUID
+	Key    string
+	// Fingerprint é o hash do método, do caminho e do corpo da requisição
+	Fingerprint string
+	// StatusCode é 0 enquanto a requisição original está em andamento
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
 }
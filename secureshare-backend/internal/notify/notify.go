@@ -0,0 +1,19 @@
+// Package notify envia avisos aos usuários fora do aplicativo (hoje, por
+// e-mail). As mensagens são montadas a partir dos modelos em templates.go e
+// nunca levam conteúdo de arquivos, SKBs ou chaves: só nomes de usuário e
+// links.
+package notify
+
+import "context"
+
+// Message é uma mensagem pronta para envio
+type Message struct {
+	To      string // Endereço do destinatário
+	Subject string
+	Body    string // Texto puro (UTF-8)
+}
+
+// Notifier entrega mensagens. Send pode ser chamado de várias goroutines.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,196 @@
+// Package notifytest fornece um servidor SMTP falso, em memória, para testes
+// dos e-mails, sem depender de um servidor real.
+package notifytest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+)
+
+// Message é uma mensagem recebida, já decodificada
+type Message struct {
+	From    string   // MAIL FROM
+	To      []string // RCPT TO
+	Header  mail.Header
+	Subject string // decodificado (RFC 2047)
+	Body    string // decodificado (quoted-printable)
+	Raw     string // como chegou no DATA
+}
+
+// Server aceita qualquer remetente e destinatário e guarda as mensagens.
+// Não oferece STARTTLS nem AUTH.
+type Server struct {
+	Addr string // host:porta
+
+	ln       net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	messages []Message
+	reject   bool
+}
+
+// NewServer inicia o servidor em 127.0.0.1, numa porta livre. Chame Close
+// ao final do teste.
+func NewServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("notifytest: falha ao escutar: %v", err))
+	}
+	s := &Server{Addr: ln.Addr().String(), ln: ln}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Host e Port separam Addr para a configuração do cliente
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr)
+	return host
+}
+
+func (s *Server) Port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+// Messages retorna as mensagens recebidas até agora
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// SetReject liga ou desliga a recusa das mensagens (554 no DATA), para
+// simular falhas de entrega
+func (s *Server) SetReject(reject bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = reject
+}
+
+// Close para de aceitar conexões e espera as abertas terminarem
+func (s *Server) Close() {
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 notifytest ESMTP")
+	var from string
+	var to []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 notifytest")
+		case "MAIL":
+			from = pathArg(arg, "FROM:")
+			to = nil
+			reply("250 OK")
+		case "RCPT":
+			to = append(to, pathArg(arg, "TO:"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 fim com <CRLF>.<CRLF>")
+			raw, err := readData(r)
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			reject := s.reject
+			if !reject {
+				s.messages = append(s.messages, decode(from, to, raw))
+			}
+			s.mu.Unlock()
+			if reject {
+				reply("554 mensagem recusada")
+			} else {
+				reply("250 OK")
+			}
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 tchau")
+			return
+		default:
+			reply("502 comando não implementado")
+		}
+	}
+}
+
+// pathArg extrai o endereço de "FROM:<x>" ou "TO:<x>", ignorando os
+// parâmetros ESMTP
+func pathArg(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	addr, _, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.Trim(addr, "<>")
+}
+
+// readData lê o DATA até a linha com um ponto, desfazendo o dot-stuffing
+func readData(r *bufio.Reader) (string, error) {
+	var b strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return b.String(), nil
+		}
+		b.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+func decode(from string, to []string, raw string) Message {
+	msg := Message{From: from, To: to, Raw: raw}
+	parsed, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		msg.Body = raw
+		return msg
+	}
+	msg.Header = parsed.Header
+	var dec mime.WordDecoder
+	msg.Subject, err = dec.DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		msg.Subject = parsed.Header.Get("Subject")
+	}
+	body := parsed.Body
+	if strings.EqualFold(parsed.Header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	decoded, _ := io.ReadAll(body)
+	msg.Body = strings.ReplaceAll(string(decoded), "\r\n", "\n")
+	return msg
+}
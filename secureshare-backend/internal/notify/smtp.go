@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SMTPConfig configura o envio por um servidor SMTP (submission)
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username e Password habilitam AUTH PLAIN, que o net/smtp só aceita
+	// sobre TLS (ou com localhost)
+	Username string
+	Password string
+	// From é o remetente, com ou sem nome (ex: "SecureShare <no-reply@x>")
+	From string
+	// Timeout limita cada envio quando o contexto não tem prazo (padrão: 30s)
+	Timeout time.Duration
+}
+
+// SMTPNotifier envia mensagens em texto puro por SMTP, com STARTTLS quando
+// o servidor oferece
+type SMTPNotifier struct {
+	cfg  SMTPConfig
+	from *mail.Address
+}
+
+// NewSMTPNotifier valida a configuração; nenhuma conexão é aberta aqui
+func NewSMTPNotifier(cfg SMTPConfig) (*SMTPNotifier, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("host SMTP não pode ser vazio")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("remetente SMTP inválido '%s': %w", cfg.From, err)
+	}
+	return &SMTPNotifier{cfg: cfg, from: from}, nil
+}
+
+// Send entrega a mensagem numa conexão nova
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("destinatário inválido: %w", err)
+	}
+	data, err := n.compose(to, msg)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.cfg.Timeout)
+		defer cancel()
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port)))
+	if err != nil {
+		return fmt.Errorf("falha ao conectar ao servidor SMTP: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("falha ao iniciar sessão SMTP: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.cfg.Host}); err != nil {
+			return fmt.Errorf("falha no STARTTLS: %w", err)
+		}
+	}
+	if n.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)); err != nil {
+			return fmt.Errorf("falha na autenticação SMTP: %w", err)
+		}
+	}
+	if err := client.Mail(n.from.Address); err != nil {
+		return fmt.Errorf("remetente recusado: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("destinatário recusado: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("falha ao enviar mensagem: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("falha ao enviar mensagem: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("mensagem recusada: %w", err)
+	}
+	return client.Quit()
+}
+
+// compose monta a mensagem (RFC 5322). O assunto vai codificado (RFC 2047)
+// e o corpo em quoted-printable, de modo que nenhum texto vindo de usuários
+// consegue injetar headers.
+func (n *SMTPNotifier) compose(to *mail.Address, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	domain := n.from.Address[strings.LastIndex(n.from.Address, "@")+1:]
+	headers := [][2]string{
+		{"From", n.from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", "<" + uuid.NewString() + "@" + domain + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h[0], h[1])
+	}
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	if _, err := qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n"))); err != nil {
+		return nil, fmt.Errorf("falha ao codificar mensagem: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("falha ao codificar mensagem: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,95 @@
+package notify_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"secureshare-backend/internal/i18n"
+	"secureshare-backend/internal/notify"
+	"secureshare-backend/internal/notify/notifytest"
+)
+
+func newTestNotifier(t *testing.T, server *notifytest.Server) *notify.SMTPNotifier {
+	t.Helper()
+	n, err := notify.NewSMTPNotifier(notify.SMTPConfig{
+		Host: server.Host(),
+		Port: server.Port(),
+		From: "SecureShare <no-reply@example.com>",
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPNotifier: %v", err)
+	}
+	return n
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	server := notifytest.NewServer()
+	defer server.Close()
+	n := newTestNotifier(t, server)
+
+	err := n.Send(context.Background(), notify.Message{
+		To:      "bob@example.com",
+		Subject: "Olá\r\nBcc: mallory@example.com",
+		Body:    "linha 1 — acentuação\n.linha com ponto\n",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	msgs := server.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("esperava 1 mensagem, obteve %d", len(msgs))
+	}
+	got := msgs[0]
+	if got.From != "no-reply@example.com" || len(got.To) != 1 || got.To[0] != "bob@example.com" {
+		t.Fatalf("envelope inesperado: %s -> %v", got.From, got.To)
+	}
+	// A quebra de linha no assunto não vira um header novo
+	if got.Header.Get("Bcc") != "" || got.Subject != "Olá\r\nBcc: mallory@example.com" {
+		t.Fatalf("assunto inesperado: %q (Bcc=%q)", got.Subject, got.Header.Get("Bcc"))
+	}
+	if got.Body != "linha 1 — acentuação\n.linha com ponto\n" {
+		t.Fatalf("corpo inesperado: %q", got.Body)
+	}
+
+	server.SetReject(true)
+	if err := n.Send(context.Background(), notify.Message{To: "bob@example.com", Subject: "x", Body: "x"}); err == nil {
+		t.Fatal("esperava erro com a mensagem recusada")
+	}
+	if err := n.Send(context.Background(), notify.Message{To: "não é e-mail", Subject: "x", Body: "x"}); err == nil {
+		t.Fatal("esperava erro com destinatário inválido")
+	}
+}
+
+func TestTemplatesAreLocalized(t *testing.T) {
+	data := notify.TransferReceivedData{Recipient: "bob", Sender: "alice"}
+
+	pt, err := notify.TransferReceived(i18n.LocalePtBR, "bob@example.com", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	en, err := notify.TransferReceived(i18n.LocaleEn, "bob@example.com", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt.Subject != "Novo arquivo cifrado de alice" || en.Subject != "New encrypted file from alice" {
+		t.Fatalf("assuntos inesperados: %q / %q", pt.Subject, en.Subject)
+	}
+
+	// Idioma vazio ou desconhecido usa o padrão
+	for _, locale := range []string{"", "fr"} {
+		msg, err := notify.TransferReceived(locale, "bob@example.com", data)
+		if err != nil || msg.Subject != pt.Subject {
+			t.Fatalf("idioma %q: %q (err=%v)", locale, msg.Subject, err)
+		}
+	}
+
+	// Sem página de confirmação, o e-mail traz o token
+	msg, err := notify.EmailVerification(i18n.LocaleEn, "bob@example.com", notify.EmailVerificationData{
+		Recipient: "bob", Email: "bob@example.com", Token: "tok123", ValidHours: 48,
+	})
+	if err != nil || !strings.Contains(msg.Body, "tok123") || !strings.Contains(msg.Body, "48 hours") {
+		t.Fatalf("confirmação inesperada: %q (err=%v)", msg.Body, err)
+	}
+}
@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"secureshare-backend/internal/i18n"
+)
+
+// TransferReceivedData são os campos do aviso de nova transferência
+type TransferReceivedData struct {
+	Recipient string // username do destinatário
+	Sender    string // username do remetente
+}
+
+// EmailVerificationData são os campos do pedido de confirmação de e-mail
+type EmailVerificationData struct {
+	Recipient  string
+	Email      string
+	Link       string // Vazio se não houver página de confirmação configurada
+	Token      string // Mostrado só quando não há Link
+	ValidHours int
+}
+
+// TransferReceived monta o aviso "você recebeu um arquivo cifrado de X"
+func TransferReceived(locale, to string, data TransferReceivedData) (Message, error) {
+	return render("transfer_received", locale, to, data)
+}
+
+// EmailVerification monta o pedido de confirmação do endereço
+func EmailVerification(locale, to string, data EmailVerificationData) (Message, error) {
+	return render("email_verification", locale, to, data)
+}
+
+type messageTemplate struct {
+	subject, body *template.Template
+}
+
+// templates[nome][idioma]
+var templates = map[string]map[string]messageTemplate{
+	"transfer_received": {
+		i18n.LocalePtBR: mustTemplate(
+			`Novo arquivo cifrado de {{.Sender}}`,
+			`Olá, {{.Recipient}}!
+
+{{.Sender}} enviou um novo arquivo cifrado para você no SecureShare.
+
+Abra o aplicativo para baixá-lo e decifrá-lo no seu dispositivo. Por segurança, este e-mail não contém o arquivo nem nenhuma chave.
+
+— SecureShare
+`),
+		i18n.LocaleEn: mustTemplate(
+			`New encrypted file from {{.Sender}}`,
+			`Hi {{.Recipient}},
+
+{{.Sender}} sent you a new encrypted file on SecureShare.
+
+Open the app to download and decrypt it on your device. For your security, this email contains neither the file nor any keys.
+
+— SecureShare
+`),
+	},
+	"email_verification": {
+		i18n.LocalePtBR: mustTemplate(
+			`Confirme seu e-mail no SecureShare`,
+			`Olá, {{.Recipient}}!
+
+Para receber avisos de novos arquivos em {{.Email}}, confirme o endereço {{if .Link}}abrindo o link abaixo:
+
+{{.Link}}{{else}}informando este código no aplicativo:
+
+{{.Token}}{{end}}
+
+A confirmação vale por {{.ValidHours}} horas. Se você não pediu isto, ignore este e-mail.
+
+— SecureShare
+`),
+		i18n.LocaleEn: mustTemplate(
+			`Confirm your email on SecureShare`,
+			`Hi {{.Recipient}},
+
+To get notified of new files at {{.Email}}, confirm this address {{if .Link}}by opening the link below:
+
+{{.Link}}{{else}}by entering this code in the app:
+
+{{.Token}}{{end}}
+
+This confirmation is valid for {{.ValidHours}} hours. If you didn't ask for it, ignore this email.
+
+— SecureShare
+`),
+	},
+}
+
+func mustTemplate(subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+func render(name, locale, to string, data any) (Message, error) {
+	byLocale := templates[name]
+	tmpl, ok := byLocale[locale]
+	if !ok {
+		tmpl = byLocale[i18n.DefaultLocale]
+	}
+
+	var subject, body strings.Builder
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return Message{}, fmt.Errorf("falha ao montar assunto de '%s': %w", name, err)
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return Message{}, fmt.Errorf("falha ao montar mensagem '%s': %w", name, err)
+	}
+	return Message{To: to, Subject: subject.String(), Body: body.String()}, nil
+}
@@ -1,10 +1,15 @@
 package repository
 
 import (
+	"cmp"
 	"context"
-	"fmt"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
+	"secureshare-backend/internal/apperr"
 	"secureshare-backend/internal/models"
 
 	"github.com/google/uuid"
@@ -16,17 +21,105 @@ type InMemoryStore struct {
 	usersByID         map[uuid.UUID]*models.User
 	usersByUsername   map[string]*models.User
 	transfersByDestID map[uuid.UUID][]*models.Transfer
+	devicesByID       map[uuid.UUID]*models.Device
+	apiKeysByPrefix   map[string]*models.APIKey
+	identities        map[[2]string]*models.Identity // (issuer, subject)
+	keyBackups        map[uuid.UUID]*models.KeyBackup
+	jobs              map[uuid.UUID]*models.Job
+	schedules         map[string]time.Time
+	uploads           map[string]*models.UploadReservation
+	auditEvents       []*models.AuditEvent // em ordem de Seq
+	events            []*models.InboxEvent // em ordem de ID
+	lastEventID       int64
+	webhooks          map[uuid.UUID]*models.Webhook
+	deliveries        map[uuid.UUID]*models.WebhookDelivery
+	idempotency       map[idempotencyKey]*models.IdempotencyRecord
 }
 
+// idempotencyKey identifica um IdempotencyRecord (chave por usuário)
+type idempotencyKey struct {
+	userID uuid.UUID
+	key    string
+}
+
+// Garante em tempo de compilação que o InMemoryStore pode substituir o
+// PostgresStore
+var _ Store = (*InMemoryStore)(nil)
+
 // NewInMemoryStore cria uma nova instância do store em memória
 func NewInMemoryStore() *InMemoryStore {
 	return &InMemoryStore{
 		usersByID:         make(map[uuid.UUID]*models.User),
 		usersByUsername:   make(map[string]*models.User),
 		transfersByDestID: make(map[uuid.UUID][]*models.Transfer),
+		devicesByID:       make(map[uuid.UUID]*models.Device),
+		apiKeysByPrefix:   make(map[string]*models.APIKey),
+		identities:        make(map[[2]string]*models.Identity),
+		keyBackups:        make(map[uuid.UUID]*models.KeyBackup),
+		jobs:              make(map[uuid.UUID]*models.Job),
+		schedules:         make(map[string]time.Time),
+		uploads:           make(map[string]*models.UploadReservation),
+		webhooks:          make(map[uuid.UUID]*models.Webhook),
+		deliveries:        make(map[uuid.UUID]*models.WebhookDelivery),
+		idempotency:       make(map[idempotencyKey]*models.IdempotencyRecord),
 	}
 }
 
+// WithTx executa fn sobre uma cópia do estado e, se fn não retornar erro,
+// adota a cópia. Os valores guardados nunca são alterados no lugar (toda
+// escrita troca o ponteiro), então copiar os mapas basta. O store fica
+// bloqueado durante fn, o que serializa as transações.
+func (s *InMemoryStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &InMemoryStore{
+		usersByID:         maps.Clone(s.usersByID),
+		usersByUsername:   maps.Clone(s.usersByUsername),
+		transfersByDestID: make(map[uuid.UUID][]*models.Transfer, len(s.transfersByDestID)),
+		devicesByID:       maps.Clone(s.devicesByID),
+		apiKeysByPrefix:   maps.Clone(s.apiKeysByPrefix),
+		identities:        maps.Clone(s.identities),
+		keyBackups:        maps.Clone(s.keyBackups),
+		jobs:              maps.Clone(s.jobs),
+		schedules:         maps.Clone(s.schedules),
+		uploads:           maps.Clone(s.uploads),
+		auditEvents:       slices.Clone(s.auditEvents),
+		events:            slices.Clone(s.events),
+		lastEventID:       s.lastEventID,
+		webhooks:          maps.Clone(s.webhooks),
+		deliveries:        maps.Clone(s.deliveries),
+		idempotency:       maps.Clone(s.idempotency),
+	}
+	for destID, transfers := range s.transfersByDestID {
+		tx.transfersByDestID[destID] = slices.Clone(transfers)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	s.usersByID = tx.usersByID
+	s.usersByUsername = tx.usersByUsername
+	s.transfersByDestID = tx.transfersByDestID
+	s.devicesByID = tx.devicesByID
+	s.apiKeysByPrefix = tx.apiKeysByPrefix
+	s.identities = tx.identities
+	s.keyBackups = tx.keyBackups
+	s.jobs = tx.jobs
+	s.schedules = tx.schedules
+	s.uploads = tx.uploads
+	s.auditEvents = tx.auditEvents
+	s.events = tx.events
+	s.lastEventID = tx.lastEventID
+	s.webhooks = tx.webhooks
+	s.deliveries = tx.deliveries
+	s.idempotency = tx.idempotency
+	return nil
+}
+
 // --- UserStore ---
 
 func (s *InMemoryStore) CreateUser(ctx context.Context, user *models.User) error {
@@ -34,11 +127,20 @@ func (s *InMemoryStore) CreateUser(ctx context.Context, user *models.User) error
 	defer s.mu.Unlock()
 
 	if _, exists := s.usersByUsername[user.Username]; exists {
-		return fmt.Errorf("usuário '%s' já existe", user.Username)
+		return apperr.Conflict("usuário '%s' já existe", user.Username)
+	}
+	if _, exists := s.usersByID[user.ID]; exists {
+		return apperr.Conflict("falha ao criar usuário: ID '%s' já existe", user.ID)
+	}
+	if user.OwnerID != nil {
+		if _, exists := s.usersByID[*user.OwnerID]; !exists {
+			return apperr.NotFound("falha ao criar usuário: dono '%s' inexistente", *user.OwnerID)
+		}
 	}
 
-	s.usersByID[user.ID] = user
-	s.usersByUsername[user.Username] = user
+	stored := *user
+	s.usersByID[user.ID] = &stored
+	s.usersByUsername[user.Username] = &stored
 	return nil
 }
 
@@ -48,9 +150,10 @@ func (s *InMemoryStore) GetUserByUsername(ctx context.Context, username string)
 
 	user, exists := s.usersByUsername[username]
 	if !exists {
-		return nil, fmt.Errorf("usuário '%s' não encontrado", username)
+		return nil, apperr.NotFound("usuário '%s' não encontrado", username)
 	}
-	return user, nil
+	stored := *user
+	return &stored, nil
 }
 
 func (s *InMemoryStore) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
@@ -59,9 +162,197 @@ func (s *InMemoryStore) GetUserByID(ctx context.Context, id uuid.UUID) (*models.
 
 	user, exists := s.usersByID[id]
 	if !exists {
-		return nil, fmt.Errorf("usuário com ID '%s' não encontrado", id)
+		return nil, apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	stored := *user
+	return &stored, nil
+}
+
+// GetAllUsers retorna todos os usuários ordenados por username
+func (s *InMemoryStore) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*models.User, 0, len(s.usersByID))
+	for _, u := range s.usersByID {
+		stored := *u
+		users = append(users, &stored)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	return users, nil
+}
+
+func (s *InMemoryStore) UpdateUserPassword(ctx context.Context, id uuid.UUID, passwordHash string, sessionsValidAfter time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.usersByID[id]
+	if !exists {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
 	}
-	return user, nil
+
+	// Copia para não alterar ponteiros já entregues a quem chamou
+	updated := *user
+	updated.PasswordHash = passwordHash
+	updated.SessionsValidAfter = sessionsValidAfter
+	s.usersByID[id] = &updated
+	s.usersByUsername[updated.Username] = &updated
+	return nil
+}
+
+func (s *InMemoryStore) UpdateUserPublicKeys(ctx context.Context, id uuid.UUID, publicKey, publicKeySign string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.usersByID[id]
+	if !exists {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+
+	updated := *user
+	updated.PublicKey = publicKey
+	updated.PublicKeySign = publicKeySign
+	s.usersByID[id] = &updated
+	s.usersByUsername[updated.Username] = &updated
+	return nil
+}
+
+func (s *InMemoryStore) UpdateUserEmail(ctx context.Context, id uuid.UUID, email string, verifiedAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.usersByID[id]
+	if !exists {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+
+	updated := *user
+	updated.Email = email
+	updated.EmailVerifiedAt = nil
+	if verifiedAt != nil {
+		t := *verifiedAt
+		updated.EmailVerifiedAt = &t
+	}
+	s.usersByID[id] = &updated
+	s.usersByUsername[updated.Username] = &updated
+	return nil
+}
+
+func (s *InMemoryStore) UpdateUserLocale(ctx context.Context, id uuid.UUID, locale string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.usersByID[id]
+	if !exists {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+
+	updated := *user
+	updated.Locale = locale
+	s.usersByID[id] = &updated
+	s.usersByUsername[updated.Username] = &updated
+	return nil
+}
+
+// LockUser só confere se o usuário existe: WithTx já segura o mutex do
+// store durante toda a transação
+func (s *InMemoryStore) LockUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.usersByID[id]; !exists {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *InMemoryStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.usersByID[id]
+	if !exists {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	delete(s.usersByID, id)
+	delete(s.usersByUsername, user.Username)
+
+	// Equivalente ao ON DELETE CASCADE: remove dispositivos, API keys,
+	// backup de chaves, reservas de upload e transferências enviadas e
+	// recebidas
+	for deviceID, device := range s.devicesByID {
+		if device.UserID == id {
+			delete(s.devicesByID, deviceID)
+		}
+	}
+	for prefix, key := range s.apiKeysByPrefix {
+		if key.UserID == id {
+			delete(s.apiKeysByPrefix, prefix)
+		}
+	}
+	for k, identity := range s.identities {
+		if identity.UserID == id {
+			delete(s.identities, k)
+		}
+	}
+	for key, reservation := range s.uploads {
+		if reservation.UserID == id {
+			delete(s.uploads, key)
+		}
+	}
+	// ON DELETE SET NULL: contas de serviço perdem o dono
+	for userID, u := range s.usersByID {
+		if u.OwnerID != nil && *u.OwnerID == id {
+			orphan := *u
+			orphan.OwnerID = nil
+			s.usersByID[userID] = &orphan
+			s.usersByUsername[orphan.Username] = &orphan
+		}
+	}
+	delete(s.keyBackups, id)
+	delete(s.transfersByDestID, id)
+	for destID, transfers := range s.transfersByDestID {
+		kept := make([]*models.Transfer, 0, len(transfers))
+		for _, t := range transfers {
+			if t.SourceUserID != id {
+				kept = append(kept, t)
+			}
+		}
+		s.transfersByDestID[destID] = kept
+	}
+	s.events = slices.DeleteFunc(slices.Clone(s.events), func(e *models.InboxEvent) bool {
+		return e.UserID == id
+	})
+	for webhookID, webhook := range s.webhooks {
+		if webhook.UserID == id {
+			s.deleteWebhook(webhookID)
+		}
+	}
+	for k := range s.idempotency {
+		if k.userID == id {
+			delete(s.idempotency, k)
+		}
+	}
+	return nil
+}
+
+func (s *InMemoryStore) GetServiceAccountsByOwner(ctx context.Context, ownerID uuid.UUID) ([]*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := []*models.User{}
+	for _, u := range s.usersByID {
+		if u.Kind == models.UserKindService && u.OwnerID != nil && *u.OwnerID == ownerID {
+			stored := *u
+			users = append(users, &stored)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	return users, nil
 }
 
 // --- TransferStore ---
@@ -70,7 +361,25 @@ func (s *InMemoryStore) CreateTransfer(ctx context.Context, transfer *models.Tra
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.transfersByDestID[transfer.DestUserID] = append(s.transfersByDestID[transfer.DestUserID], transfer)
+	for _, userID := range []uuid.UUID{transfer.SourceUserID, transfer.DestUserID} {
+		if _, exists := s.usersByID[userID]; !exists {
+			return apperr.NotFound("falha ao criar transferência: usuário '%s' inexistente", userID)
+		}
+	}
+	for _, transfers := range s.transfersByDestID {
+		for _, t := range transfers {
+			if t.ID == transfer.ID {
+				return apperr.Conflict("falha ao criar transferência: ID '%s' já existe", transfer.ID)
+			}
+		}
+	}
+	for deviceID := range transfer.DeviceSKBs {
+		if _, exists := s.devicesByID[deviceID]; !exists {
+			return apperr.NotFound("falha ao salvar SKB do dispositivo %s: dispositivo inexistente", deviceID)
+		}
+	}
+
+	s.transfersByDestID[transfer.DestUserID] = append(s.transfersByDestID[transfer.DestUserID], copyTransfer(transfer))
 	return nil
 }
 
@@ -78,10 +387,887 @@ func (s *InMemoryStore) GetTransfersByDestUserID(ctx context.Context, destUserID
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	transfers := s.transfersByDestID[destUserID]
-	if transfers == nil {
-		// Retorna lista vazia em vez de nil, para consistência
-		return []*models.Transfer{}, nil
+	// Retorna lista vazia em vez de nil, para consistência
+	transfers := make([]*models.Transfer, 0, len(s.transfersByDestID[destUserID]))
+	for _, t := range s.transfersByDestID[destUserID] {
+		transfers = append(transfers, copyTransfer(t))
+	}
+	// Mais recentes primeiro, como no PostgresStore
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].CreatedAt.After(transfers[j].CreatedAt)
+	})
+	return transfers, nil
+}
+
+func (s *InMemoryStore) GetTransfersBySourceUserID(ctx context.Context, sourceUserID uuid.UUID) ([]*models.Transfer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	transfers := []*models.Transfer{}
+	for _, received := range s.transfersByDestID {
+		for _, t := range received {
+			if t.SourceUserID == sourceUserID {
+				transfers = append(transfers, copyTransfer(t))
+			}
+		}
 	}
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].CreatedAt.After(transfers[j].CreatedAt)
+	})
 	return transfers, nil
 }
+
+// copyTransfer copia a transferência, inclusive o mapa de SKBs
+func copyTransfer(t *models.Transfer) *models.Transfer {
+	stored := *t
+	if t.DeviceSKBs != nil {
+		stored.DeviceSKBs = make(map[uuid.UUID]string, len(t.DeviceSKBs))
+		for deviceID, skb := range t.DeviceSKBs {
+			stored.DeviceSKBs[deviceID] = skb
+		}
+	}
+	return &stored
+}
+
+// --- DeviceStore ---
+
+func (s *InMemoryStore) CreateDevice(ctx context.Context, device *models.Device) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.usersByID[device.UserID]; !exists {
+		return apperr.NotFound("falha ao criar dispositivo: usuário '%s' inexistente", device.UserID)
+	}
+	if _, exists := s.devicesByID[device.ID]; exists {
+		return apperr.Conflict("falha ao criar dispositivo: ID '%s' já existe", device.ID)
+	}
+	if device.SignerDeviceID != nil {
+		if _, exists := s.devicesByID[*device.SignerDeviceID]; !exists {
+			return apperr.NotFound("falha ao criar dispositivo: signatário '%s' inexistente", *device.SignerDeviceID)
+		}
+	}
+	stored := *device
+	s.devicesByID[device.ID] = &stored
+	return nil
+}
+
+func (s *InMemoryStore) GetDeviceByID(ctx context.Context, id uuid.UUID) (*models.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	device, exists := s.devicesByID[id]
+	if !exists {
+		return nil, apperr.NotFound("dispositivo '%s' não encontrado", id)
+	}
+	stored := *device
+	return &stored, nil
+}
+
+func (s *InMemoryStore) GetDevicesByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	devices := []*models.Device{}
+	for _, device := range s.devicesByID {
+		if device.UserID == userID {
+			stored := *device
+			devices = append(devices, &stored)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].CreatedAt.Before(devices[j].CreatedAt)
+	})
+	return devices, nil
+}
+
+func (s *InMemoryStore) RevokeDevice(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	device, exists := s.devicesByID[id]
+	if !exists || device.RevokedAt != nil {
+		return apperr.NotFound("dispositivo '%s' não encontrado", id)
+	}
+	revoked := *device
+	revoked.RevokedAt = &revokedAt
+	s.devicesByID[id] = &revoked
+
+	// Descarta as SKBs cifradas para o dispositivo (cópia, para não alterar
+	// transferências já entregues a quem chamou)
+	transfers := make([]*models.Transfer, len(s.transfersByDestID[device.UserID]))
+	copy(transfers, s.transfersByDestID[device.UserID])
+	for i, t := range transfers {
+		if _, ok := t.DeviceSKBs[id]; !ok {
+			continue
+		}
+		updated := *t
+		updated.DeviceSKBs = make(map[uuid.UUID]string, len(t.DeviceSKBs)-1)
+		for deviceID, skb := range t.DeviceSKBs {
+			if deviceID != id {
+				updated.DeviceSKBs[deviceID] = skb
+			}
+		}
+		transfers[i] = &updated
+	}
+	s.transfersByDestID[device.UserID] = transfers
+	return nil
+}
+
+// --- APIKeyStore ---
+
+func (s *InMemoryStore) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.usersByID[key.UserID]; !exists {
+		return apperr.NotFound("falha ao criar API key: usuário '%s' inexistente", key.UserID)
+	}
+	if _, exists := s.apiKeysByPrefix[key.Prefix]; exists {
+		return apperr.Conflict("falha ao criar API key: prefixo '%s' já existe", key.Prefix)
+	}
+	for _, k := range s.apiKeysByPrefix {
+		if k.ID == key.ID {
+			return apperr.Conflict("falha ao criar API key: ID '%s' já existe", key.ID)
+		}
+	}
+	stored := *key
+	s.apiKeysByPrefix[key.Prefix] = &stored
+	return nil
+}
+
+func (s *InMemoryStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, exists := s.apiKeysByPrefix[prefix]
+	if !exists {
+		return nil, apperr.NotFound("API key '%s' não encontrada", prefix)
+	}
+	stored := *key
+	return &stored, nil
+}
+
+func (s *InMemoryStore) GetAPIKeysByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []*models.APIKey{}
+	for _, key := range s.apiKeysByPrefix {
+		if key.UserID == userID {
+			stored := *key
+			keys = append(keys, &stored)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (s *InMemoryStore) RevokeAPIKey(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for prefix, key := range s.apiKeysByPrefix {
+		if key.ID == id && key.RevokedAt == nil {
+			revoked := *key
+			revoked.RevokedAt = &revokedAt
+			s.apiKeysByPrefix[prefix] = &revoked
+			return nil
+		}
+	}
+	return apperr.NotFound("API key '%s' não encontrada", id)
+}
+
+func (s *InMemoryStore) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for prefix, key := range s.apiKeysByPrefix {
+		if key.ID == id {
+			touched := *key
+			touched.LastUsedAt = &usedAt
+			s.apiKeysByPrefix[prefix] = &touched
+			return nil
+		}
+	}
+	return nil
+}
+
+// --- IdentityStore ---
+
+func (s *InMemoryStore) CreateIdentity(ctx context.Context, identity *models.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.usersByID[identity.UserID]; !exists {
+		return apperr.NotFound("falha ao vincular identidade: usuário '%s' inexistente", identity.UserID)
+	}
+	k := [2]string{identity.Issuer, identity.Subject}
+	if _, exists := s.identities[k]; exists {
+		return apperr.Conflict("identidade '%s' já vinculada", identity.Subject)
+	}
+	stored := *identity
+	s.identities[k] = &stored
+	return nil
+}
+
+func (s *InMemoryStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	identity, exists := s.identities[[2]string{issuer, subject}]
+	if !exists {
+		return nil, apperr.NotFound("identidade '%s' não encontrada", subject)
+	}
+	user, exists := s.usersByID[identity.UserID]
+	if !exists {
+		return nil, apperr.NotFound("identidade '%s' não encontrada", subject)
+	}
+	stored := *user
+	return &stored, nil
+}
+
+// --- KeyBackupStore ---
+
+func (s *InMemoryStore) PutKeyBackup(ctx context.Context, backup *models.KeyBackup) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.usersByID[backup.UserID]; !exists {
+		return apperr.NotFound("falha ao salvar backup de chaves: usuário '%s' inexistente", backup.UserID)
+	}
+	stored := *backup
+	s.keyBackups[backup.UserID] = &stored
+	return nil
+}
+
+func (s *InMemoryStore) GetKeyBackup(ctx context.Context, userID uuid.UUID) (*models.KeyBackup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	backup, exists := s.keyBackups[userID]
+	if !exists {
+		return nil, apperr.NotFound("backup de chaves do usuário '%s' não encontrado", userID)
+	}
+	stored := *backup
+	return &stored, nil
+}
+
+// --- JobStore ---
+
+func copyJob(job *models.Job) *models.Job {
+	stored := *job
+	stored.Payload = slices.Clone(job.Payload)
+	if job.FailedAt != nil {
+		failedAt := *job.FailedAt
+		stored.FailedAt = &failedAt
+	}
+	return &stored
+}
+
+func (s *InMemoryStore) EnqueueJob(ctx context.Context, job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.ID]; exists {
+		return apperr.Conflict("job '%s' já existe", job.ID)
+	}
+	stored := copyJob(job)
+	stored.FailedAt = nil
+	s.jobs[job.ID] = stored
+	return nil
+}
+
+func (s *InMemoryStore) ClaimJobs(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := []*models.Job{}
+	for _, job := range s.jobs {
+		if job.FailedAt == nil && !job.RunAt.After(now) {
+			due = append(due, job)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].RunAt.Equal(due[j].RunAt) {
+			return due[i].RunAt.Before(due[j].RunAt)
+		}
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*models.Job, 0, len(due))
+	for _, job := range due {
+		updated := copyJob(job)
+		updated.Attempts++
+		updated.RunAt = now.Add(lease)
+		s.jobs[job.ID] = updated
+		claimed = append(claimed, copyJob(updated))
+	}
+	sort.Slice(claimed, func(i, j int) bool {
+		return claimed[i].CreatedAt.Before(claimed[j].CreatedAt)
+	})
+	return claimed, nil
+}
+
+func (s *InMemoryStore) CompleteJob(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[id]; !exists {
+		return apperr.NotFound("job '%s' não encontrado", id)
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *InMemoryStore) RetryJob(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, exists := s.jobs[id]
+	if !exists || job.FailedAt != nil {
+		return apperr.NotFound("job '%s' não encontrado", id)
+	}
+	updated := copyJob(job)
+	updated.RunAt = runAt
+	updated.LastError = lastError
+	s.jobs[id] = updated
+	return nil
+}
+
+func (s *InMemoryStore) FailJob(ctx context.Context, id uuid.UUID, failedAt time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, exists := s.jobs[id]
+	if !exists || job.FailedAt != nil {
+		return apperr.NotFound("job '%s' não encontrado", id)
+	}
+	updated := copyJob(job)
+	updated.FailedAt = &failedAt
+	updated.LastError = lastError
+	s.jobs[id] = updated
+	return nil
+}
+
+func (s *InMemoryStore) GetDeadJobs(ctx context.Context, limit int) ([]*models.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dead := []*models.Job{}
+	for _, job := range s.jobs {
+		if job.FailedAt != nil {
+			dead = append(dead, copyJob(job))
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool {
+		return dead[i].FailedAt.After(*dead[j].FailedAt)
+	})
+	if len(dead) > limit {
+		dead = dead[:limit]
+	}
+	return dead, nil
+}
+
+func (s *InMemoryStore) PruneDeadJobs(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := 0
+	for id, job := range s.jobs {
+		if job.FailedAt != nil && job.FailedAt.Before(before) {
+			delete(s.jobs, id)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (s *InMemoryStore) CountJobs(ctx context.Context) ([]models.JobCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byKind := map[string]*models.JobCount{}
+	for _, job := range s.jobs {
+		c, ok := byKind[job.Kind]
+		if !ok {
+			c = &models.JobCount{Kind: job.Kind}
+			byKind[job.Kind] = c
+		}
+		if job.FailedAt != nil {
+			c.Dead++
+		} else {
+			c.Queued++
+		}
+	}
+	counts := make([]models.JobCount, 0, len(byKind))
+	for _, c := range byKind {
+		counts = append(counts, *c)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Kind < counts[j].Kind })
+	return counts, nil
+}
+
+func (s *InMemoryStore) AdvanceSchedule(ctx context.Context, name string, now, next time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.schedules[name]
+	if !exists {
+		s.schedules[name] = next
+		return false, nil
+	}
+	if current.After(now) {
+		return false, nil
+	}
+	s.schedules[name] = next
+	return true, nil
+}
+
+// --- UploadStore ---
+
+func (s *InMemoryStore) ReserveUpload(ctx context.Context, reservation *models.UploadReservation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.usersByID[reservation.UserID]; !exists {
+		return apperr.NotFound("falha ao reservar upload: usuário inexistente")
+	}
+	if _, exists := s.uploads[reservation.ObjectKey]; exists {
+		return apperr.Conflict("chave '%s' já reservada", reservation.ObjectKey)
+	}
+	stored := *reservation
+	s.uploads[reservation.ObjectKey] = &stored
+	return nil
+}
+
+func (s *InMemoryStore) GetUploadReservation(ctx context.Context, objectKey string) (*models.UploadReservation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reservation, exists := s.uploads[objectKey]
+	if !exists {
+		return nil, apperr.NotFound("reserva de upload '%s' não encontrada", objectKey)
+	}
+	stored := *reservation
+	return &stored, nil
+}
+
+func (s *InMemoryStore) ReferencedKeys(ctx context.Context, keys []string, now time.Time) (map[string]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		wanted[key] = true
+	}
+
+	referenced := make(map[string]bool)
+	for _, transfers := range s.transfersByDestID {
+		for _, t := range transfers {
+			if wanted[t.LinkToEncFile] {
+				referenced[t.LinkToEncFile] = true
+			}
+		}
+	}
+	for key, reservation := range s.uploads {
+		if wanted[key] && reservation.ExpiresAt.After(now) {
+			referenced[key] = true
+		}
+	}
+	return referenced, nil
+}
+
+func (s *InMemoryStore) PruneUploadReservations(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := 0
+	for key, reservation := range s.uploads {
+		if reservation.ExpiresAt.Before(before) {
+			delete(s.uploads, key)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (s *InMemoryStore) ReleaseUpload(ctx context.Context, objectKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.uploads, objectKey)
+	return nil
+}
+
+func (s *InMemoryStore) GetStorageUsage(ctx context.Context, userIDs []uuid.UUID, now time.Time) (*models.StorageUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make(map[uuid.UUID]bool, len(userIDs))
+	for _, id := range userIDs {
+		users[id] = true
+	}
+
+	// Um objeto enviado a vários destinatários conta uma vez só
+	objects := make(map[string]int64)
+	for _, transfers := range s.transfersByDestID {
+		for _, t := range transfers {
+			if users[t.SourceUserID] {
+				objects[t.LinkToEncFile] = max(objects[t.LinkToEncFile], t.Size)
+			}
+		}
+	}
+
+	usage := &models.StorageUsage{}
+	for _, size := range objects {
+		usage.UsedBytes += size
+	}
+	for _, reservation := range s.uploads {
+		if users[reservation.UserID] && reservation.ExpiresAt.After(now) {
+			usage.ReservedBytes += reservation.Size
+		}
+	}
+	return usage, nil
+}
+
+// --- AuditStore ---
+
+func copyAuditEvent(event *models.AuditEvent) *models.AuditEvent {
+	stored := *event
+	stored.Details = maps.Clone(event.Details)
+	if event.ActorID != nil {
+		actorID := *event.ActorID
+		stored.ActorID = &actorID
+	}
+	return &stored
+}
+
+func (s *InMemoryStore) AppendAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event.Seq = 1
+	event.PrevHash = ""
+	if n := len(s.auditEvents); n > 0 {
+		event.Seq = s.auditEvents[n-1].Seq + 1
+		event.PrevHash = s.auditEvents[n-1].Hash
+	}
+	event.Hash = event.ComputeHash()
+	s.auditEvents = append(s.auditEvents, copyAuditEvent(event))
+	return nil
+}
+
+func (s *InMemoryStore) ListAuditEvents(ctx context.Context, filter AuditFilter) ([]*models.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := func(e *models.AuditEvent) bool {
+		return (filter.Type == "" || e.Type == filter.Type) &&
+			(filter.Actor == "" || e.Actor == filter.Actor) &&
+			(filter.Target == "" || e.Target == filter.Target) &&
+			(filter.Since.IsZero() || !e.CreatedAt.Before(filter.Since)) &&
+			(filter.Until.IsZero() || e.CreatedAt.Before(filter.Until)) &&
+			e.Seq > filter.AfterSeq &&
+			(filter.BeforeSeq <= 0 || e.Seq < filter.BeforeSeq)
+	}
+
+	events := []*models.AuditEvent{}
+	for i := range s.auditEvents {
+		e := s.auditEvents[i]
+		if filter.Descending {
+			e = s.auditEvents[len(s.auditEvents)-1-i]
+		}
+		if !matches(e) {
+			continue
+		}
+		events = append(events, copyAuditEvent(e))
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+// --- EventStore ---
+
+func copyInboxEvent(event *models.InboxEvent) *models.InboxEvent {
+	stored := *event
+	stored.Data = slices.Clone(event.Data)
+	return &stored
+}
+
+func (s *InMemoryStore) AppendEvent(ctx context.Context, event *models.InboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.usersByID[event.UserID]; !exists {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", event.UserID)
+	}
+	s.lastEventID++
+	event.ID = s.lastEventID
+	s.events = append(s.events, copyInboxEvent(event))
+	return nil
+}
+
+func (s *InMemoryStore) GetEventsAfter(ctx context.Context, userID uuid.UUID, afterID int64, limit int) ([]*models.InboxEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := []*models.InboxEvent{}
+	start, _ := slices.BinarySearchFunc(s.events, afterID+1, func(e *models.InboxEvent, id int64) int {
+		return cmp.Compare(e.ID, id)
+	})
+	for _, e := range s.events[start:] {
+		if userID != uuid.Nil && e.UserID != userID {
+			continue
+		}
+		events = append(events, copyInboxEvent(e))
+		if limit > 0 && len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (s *InMemoryStore) LatestEventID(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastEventID, nil
+}
+
+func (s *InMemoryStore) PruneEvents(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := slices.DeleteFunc(slices.Clone(s.events), func(e *models.InboxEvent) bool {
+		return e.CreatedAt.Before(before)
+	})
+	pruned := len(s.events) - len(kept)
+	s.events = kept
+	return pruned, nil
+}
+
+// --- WebhookStore ---
+
+func copyWebhook(webhook *models.Webhook) *models.Webhook {
+	stored := *webhook
+	stored.EventTypes = slices.Clone(webhook.EventTypes)
+	return &stored
+}
+
+func copyWebhookDelivery(delivery *models.WebhookDelivery) *models.WebhookDelivery {
+	stored := *delivery
+	stored.Payload = slices.Clone(delivery.Payload)
+	if delivery.RedeliveryOf != nil {
+		original := *delivery.RedeliveryOf
+		stored.RedeliveryOf = &original
+	}
+	return &stored
+}
+
+func (s *InMemoryStore) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.usersByID[webhook.UserID]; !exists {
+		return apperr.NotFound("falha ao criar webhook: usuário '%s' inexistente", webhook.UserID)
+	}
+	if _, exists := s.webhooks[webhook.ID]; exists {
+		return apperr.Conflict("falha ao criar webhook: ID '%s' já existe", webhook.ID)
+	}
+	s.webhooks[webhook.ID] = copyWebhook(webhook)
+	return nil
+}
+
+func (s *InMemoryStore) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhook, exists := s.webhooks[id]
+	if !exists {
+		return nil, apperr.NotFound("webhook '%s' não encontrado", id)
+	}
+	return copyWebhook(webhook), nil
+}
+
+func (s *InMemoryStore) GetWebhooksByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhooks := []*models.Webhook{}
+	for _, webhook := range s.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, copyWebhook(webhook))
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.After(webhooks[j].CreatedAt)
+	})
+	return webhooks, nil
+}
+
+func (s *InMemoryStore) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.webhooks[id]; !exists {
+		return apperr.NotFound("webhook '%s' não encontrado", id)
+	}
+	s.deleteWebhook(id)
+	return nil
+}
+
+// deleteWebhook remove o webhook e suas entregas (ON DELETE CASCADE);
+// chame com s.mu travado
+func (s *InMemoryStore) deleteWebhook(id uuid.UUID) {
+	delete(s.webhooks, id)
+	for deliveryID, delivery := range s.deliveries {
+		if delivery.WebhookID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+}
+
+func (s *InMemoryStore) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.webhooks[delivery.WebhookID]; !exists {
+		return apperr.NotFound("falha ao registrar entrega: webhook '%s' inexistente", delivery.WebhookID)
+	}
+	if _, exists := s.deliveries[delivery.ID]; exists {
+		return apperr.Conflict("falha ao registrar entrega: ID '%s' já existe", delivery.ID)
+	}
+	s.deliveries[delivery.ID] = copyWebhookDelivery(delivery)
+	return nil
+}
+
+func (s *InMemoryStore) GetWebhookDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	delivery, exists := s.deliveries[id]
+	if !exists {
+		return nil, apperr.NotFound("entrega '%s' não encontrada", id)
+	}
+	return copyWebhookDelivery(delivery), nil
+}
+
+func (s *InMemoryStore) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.deliveries[delivery.ID]
+	if !exists {
+		return apperr.NotFound("entrega '%s' não encontrada", delivery.ID)
+	}
+	updated := copyWebhookDelivery(stored)
+	updated.Status = delivery.Status
+	updated.Attempts = delivery.Attempts
+	updated.LastStatusCode = delivery.LastStatusCode
+	updated.LastError = delivery.LastError
+	updated.UpdatedAt = delivery.UpdatedAt
+	s.deliveries[delivery.ID] = updated
+	return nil
+}
+
+func (s *InMemoryStore) ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := []*models.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, copyWebhookDelivery(delivery))
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// --- IdempotencyStore ---
+
+func copyIdempotencyRecord(record *models.IdempotencyRecord) *models.IdempotencyRecord {
+	stored := *record
+	stored.Body = slices.Clone(record.Body)
+	return &stored
+}
+
+func (s *InMemoryStore) CreateIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.usersByID[record.UserID]; !exists {
+		return apperr.NotFound("falha ao registrar chave de idempotência: usuário '%s' inexistente", record.UserID)
+	}
+	k := idempotencyKey{userID: record.UserID, key: record.Key}
+	if existing, exists := s.idempotency[k]; exists && existing.ExpiresAt.After(now) {
+		return apperr.Conflict("chave de idempotência '%s' já utilizada", record.Key)
+	}
+	s.idempotency[k] = copyIdempotencyRecord(record)
+	return nil
+}
+
+func (s *InMemoryStore) GetIdempotencyRecord(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, exists := s.idempotency[idempotencyKey{userID: userID, key: key}]
+	if !exists {
+		return nil, apperr.NotFound("chave de idempotência '%s' não encontrada", key)
+	}
+	return copyIdempotencyRecord(record), nil
+}
+
+func (s *InMemoryStore) CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{userID: record.UserID, key: record.Key}
+	stored, exists := s.idempotency[k]
+	if !exists || !stored.CreatedAt.Equal(record.CreatedAt) {
+		return apperr.NotFound("chave de idempotência '%s' não encontrada", record.Key)
+	}
+	updated := copyIdempotencyRecord(stored)
+	updated.StatusCode = record.StatusCode
+	updated.ContentType = record.ContentType
+	updated.Body = slices.Clone(record.Body)
+	updated.ExpiresAt = record.ExpiresAt
+	s.idempotency[k] = updated
+	return nil
+}
+
+func (s *InMemoryStore) DeleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{userID: record.UserID, key: record.Key}
+	if stored, exists := s.idempotency[k]; exists && stored.CreatedAt.Equal(record.CreatedAt) {
+		delete(s.idempotency, k)
+	}
+	return nil
+}
+
+func (s *InMemoryStore) PruneIdempotencyRecords(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := 0
+	for k, record := range s.idempotency {
+		if record.ExpiresAt.Before(before) {
+			delete(s.idempotency, k)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/repository/storetest"
+)
+
+func TestInMemoryStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) repository.Store {
+		return repository.NewInMemoryStore()
+	})
+}
@@ -2,10 +2,16 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
 
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/logging"
 	"secureshare-backend/internal/models"
 
 	"github.com/google/uuid"
@@ -16,9 +22,21 @@ import (
 
 // PostgresStore é a implementação da interface Store para o PostgreSQL
 type PostgresStore struct {
-	db *pgxpool.Pool
+	pool *pgxpool.Pool
+	db   pgxQuerier // o pool, ou a transação aberta por WithTx
 }
 
+// pgxQuerier é o que PostgresStore usa do pool; pgx.Tx também o implementa
+// (com Begin criando um savepoint)
+type pgxQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+var _ Store = (*PostgresStore)(nil)
+
 // NewPostgresStore cria uma nova instância do PostgresStore e pool de conexões
 func NewPostgresStore(ctx context.Context, databaseURL string) (*PostgresStore, error) {
 	pool, err := pgxpool.New(ctx, databaseURL)
@@ -30,29 +48,47 @@ func NewPostgresStore(ctx context.Context, databaseURL string) (*PostgresStore,
 		return nil, fmt.Errorf("não foi possível pingar o banco de dados: %w", err)
 	}
 
-	log.Println("Pool de conexão com PostgreSQL estabelecido.")
-	return &PostgresStore{db: pool}, nil
+	slog.Info("Pool de conexão com PostgreSQL estabelecido")
+	return &PostgresStore{pool: pool, db: pool}, nil
 }
 
 // Close fecha o pool de conexões
 func (s *PostgresStore) Close() {
-	s.db.Close()
+	s.pool.Close()
 }
 
-// RunMigrations executa o script SQL de migração
-func (s *PostgresStore) RunMigrations(ctx context.Context, migrationSQL string) error {
-	_, err := s.db.Exec(ctx, migrationSQL)
-	if err != nil {
-		return fmt.Errorf("falha ao executar migração: %w", err)
+// Pool expõe o pool de conexões (usado pelo internal/migrate)
+func (s *PostgresStore) Pool() *pgxpool.Pool {
+	return s.pool
+}
+
+// WithTx executa fn em uma transação (ou savepoint, se já estiver em uma)
+func (s *PostgresStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		return fn(&PostgresStore{pool: s.pool, db: tx})
+	})
+}
+
+// Códigos SQLSTATE traduzidos para erros tipados (ver internal/apperr)
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// pgErrorCode retorna o SQLSTATE do erro, ou "" se não vier do PostgreSQL
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
 	}
-	return nil
+	return ""
 }
 
 // --- UserStore ---
 func (s *PostgresStore) CreateUser(ctx context.Context, user *models.User) error {
 	sql := `
-        INSERT INTO users (id, username, password_hash, public_key, public_key_sign, created_at) 
-        VALUES ($1, $2, $3, $4, $5, $6)`
+        INSERT INTO users (id, username, password_hash, public_key, public_key_sign, created_at, sessions_valid_after, kind, owner_id, email, email_verified_at, locale) 
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
 
 	_, err := s.db.Exec(ctx, sql,
 		user.ID,
@@ -61,13 +97,21 @@ func (s *PostgresStore) CreateUser(ctx context.Context, user *models.User) error
 		user.PublicKey,
 		user.PublicKeySign,
 		user.CreatedAt,
+		user.SessionsValidAfter,
+		user.Kind,
+		user.OwnerID,
+		user.Email,
+		user.EmailVerifiedAt,
+		user.Locale,
 	)
 
 	if err != nil {
-		// Verifica se é um erro de violação de constraint (usuário duplicado)
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // 23505 = unique_violation
-			return fmt.Errorf("usuário '%s' já existe", user.Username)
+		// Verifica se é um erro de violação de constraint
+		switch pgErrorCode(err) {
+		case pgUniqueViolation: // usuário duplicado
+			return apperr.Wrap(apperr.ErrConflict, err, "usuário '%s' já existe", user.Username)
+		case pgForeignKeyViolation: // dono inexistente
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao criar usuário: dono inexistente")
 		}
 		// Este é o erro que você está vendo
 		return fmt.Errorf("falha ao criar usuário: %w", err)
@@ -77,7 +121,7 @@ func (s *PostgresStore) CreateUser(ctx context.Context, user *models.User) error
 
 func (s *PostgresStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	sql := `
-        SELECT id, username, password_hash, public_key, public_key_sign, created_at 
+        SELECT id, username, password_hash, public_key, public_key_sign, created_at, sessions_valid_after, kind, owner_id, email, email_verified_at, locale
         FROM users 
         WHERE username = $1`
 
@@ -89,11 +133,17 @@ func (s *PostgresStore) GetUserByUsername(ctx context.Context, username string)
 		&user.PublicKey,
 		&user.PublicKeySign,
 		&user.CreatedAt,
+		&user.SessionsValidAfter,
+		&user.Kind,
+		&user.OwnerID,
+		&user.Email,
+		&user.EmailVerifiedAt,
+		&user.Locale,
 	)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("usuário '%s' não encontrado", username)
+			return nil, apperr.NotFound("usuário '%s' não encontrado", username)
 		}
 		return nil, fmt.Errorf("falha ao buscar usuário por nome: %w", err)
 	}
@@ -102,7 +152,7 @@ func (s *PostgresStore) GetUserByUsername(ctx context.Context, username string)
 
 func (s *PostgresStore) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	sql := `
-        SELECT id, username, password_hash, public_key, public_key_sign, created_at 
+        SELECT id, username, password_hash, public_key, public_key_sign, created_at, sessions_valid_after, kind, owner_id, email, email_verified_at, locale
         FROM users 
         WHERE id = $1`
 
@@ -114,25 +164,120 @@ func (s *PostgresStore) GetUserByID(ctx context.Context, id uuid.UUID) (*models.
 		&user.PublicKey,
 		&user.PublicKeySign,
 		&user.CreatedAt,
+		&user.SessionsValidAfter,
+		&user.Kind,
+		&user.OwnerID,
+		&user.Email,
+		&user.EmailVerifiedAt,
+		&user.Locale,
 	)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("usuário com ID '%s' não encontrado", id)
+			return nil, apperr.NotFound("usuário com ID '%s' não encontrado", id)
 		}
 		return nil, fmt.Errorf("falha ao buscar usuário por ID: %w", err)
 	}
 	return user, nil
 }
 
+// UpdateUserPassword troca o hash da senha e revoga as sessões anteriores
+func (s *PostgresStore) UpdateUserPassword(ctx context.Context, id uuid.UUID, passwordHash string, sessionsValidAfter time.Time) error {
+	sql := `
+        UPDATE users
+        SET password_hash = $2, sessions_valid_after = $3
+        WHERE id = $1`
+
+	tag, err := s.db.Exec(ctx, sql, id, passwordHash, sessionsValidAfter)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar senha: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
+// UpdateUserEmail troca o e-mail e o estado da verificação
+func (s *PostgresStore) UpdateUserEmail(ctx context.Context, id uuid.UUID, email string, verifiedAt *time.Time) error {
+	tag, err := s.db.Exec(ctx,
+		`UPDATE users SET email = $2, email_verified_at = $3 WHERE id = $1`,
+		id, email, verifiedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar e-mail do usuário: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *PostgresStore) UpdateUserLocale(ctx context.Context, id uuid.UUID, locale string) error {
+	tag, err := s.db.Exec(ctx, `UPDATE users SET locale = $2 WHERE id = $1`, id, locale)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar idioma do usuário: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *PostgresStore) UpdateUserPublicKeys(ctx context.Context, id uuid.UUID, publicKey, publicKeySign string) error {
+	tag, err := s.db.Exec(ctx,
+		`UPDATE users SET public_key = $2, public_key_sign = $3 WHERE id = $1`,
+		id, publicKey, publicKeySign,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar chaves do usuário: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *PostgresStore) LockUser(ctx context.Context, id uuid.UUID) error {
+	var locked uuid.UUID
+	err := s.db.QueryRow(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	if err != nil {
+		return fmt.Errorf("falha ao travar usuário: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser remove o usuário. As transferências enviadas e recebidas
+// são removidas pelo ON DELETE CASCADE.
+func (s *PostgresStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("falha ao remover usuário: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
 // --- TransferStore ---
 
 func (s *PostgresStore) CreateTransfer(ctx context.Context, transfer *models.Transfer) error {
 	sql := `
-        INSERT INTO transfers (id, source_user_id, dest_user_id, link_to_enc_file, skb, sig, created_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7)`
+        INSERT INTO transfers (id, source_user_id, dest_user_id, link_to_enc_file, skb, sig, created_at, size_bytes, checksum_sha256)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
-	_, err := s.db.Exec(ctx, sql,
+	// A transferência e suas SKBs por dispositivo são gravadas juntas
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("falha ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, sql,
 		transfer.ID,
 		transfer.SourceUserID,
 		transfer.DestUserID,
@@ -140,22 +285,56 @@ func (s *PostgresStore) CreateTransfer(ctx context.Context, transfer *models.Tra
 		transfer.SKB,
 		transfer.Sig,
 		transfer.CreatedAt,
+		transfer.Size,
+		transfer.ChecksumSHA256,
 	)
-
 	if err != nil {
+		switch pgErrorCode(err) {
+		case pgUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "falha ao criar transferência: ID '%s' já existe", transfer.ID)
+		case pgForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao criar transferência: usuário inexistente")
+		}
+		return fmt.Errorf("falha ao criar transferência: %w", err)
+	}
+
+	for deviceID, skb := range transfer.DeviceSKBs {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO transfer_device_keys (transfer_id, device_id, skb) VALUES ($1, $2, $3)`,
+			transfer.ID, deviceID, skb,
+		)
+		if err != nil {
+			if pgErrorCode(err) == pgForeignKeyViolation {
+				return apperr.Wrap(apperr.ErrNotFound, err, "falha ao salvar SKB do dispositivo %s: dispositivo inexistente", deviceID)
+			}
+			return fmt.Errorf("falha ao salvar SKB do dispositivo %s: %w", deviceID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("falha ao criar transferência: %w", err)
 	}
 	return nil
 }
 
 func (s *PostgresStore) GetTransfersByDestUserID(ctx context.Context, destUserID uuid.UUID) ([]*models.Transfer, error) {
+	return s.queryTransfers(ctx, `dest_user_id = $1`, destUserID)
+}
+
+func (s *PostgresStore) GetTransfersBySourceUserID(ctx context.Context, sourceUserID uuid.UUID) ([]*models.Transfer, error) {
+	return s.queryTransfers(ctx, `source_user_id = $1`, sourceUserID)
+}
+
+// queryTransfers lista as transferências que satisfazem where, mais
+// recentes primeiro, com as SKBs por dispositivo
+func (s *PostgresStore) queryTransfers(ctx context.Context, where string, args ...any) ([]*models.Transfer, error) {
 	sql := `
-        SELECT id, source_user_id, dest_user_id, link_to_enc_file, skb, sig, created_at 
+        SELECT id, source_user_id, dest_user_id, link_to_enc_file, skb, sig, created_at, size_bytes, checksum_sha256
         FROM transfers 
-        WHERE dest_user_id = $1
+        WHERE ` + where + `
         ORDER BY created_at DESC`
 
-	rows, err := s.db.Query(ctx, sql, destUserID)
+	rows, err := s.db.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("falha ao buscar transferências: %w", err)
 	}
@@ -174,6 +353,8 @@ func (s *PostgresStore) GetTransfersByDestUserID(ctx context.Context, destUserID
 			&transfer.SKB,
 			&transfer.Sig,
 			&transfer.CreatedAt,
+			&transfer.Size,
+			&transfer.ChecksumSHA256,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("falha ao escanear linha de transferência: %w", err)
@@ -185,19 +366,87 @@ func (s *PostgresStore) GetTransfersByDestUserID(ctx context.Context, destUserID
 		return nil, fmt.Errorf("erro ao iterar sobre as transferências: %w", err)
 	}
 
+	if err := s.loadDeviceSKBs(ctx, transfers); err != nil {
+		return nil, err
+	}
+
 	return transfers, nil
 }
 
+// loadDeviceSKBs preenche DeviceSKBs das transferências com uma única query
+func (s *PostgresStore) loadDeviceSKBs(ctx context.Context, transfers []*models.Transfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*models.Transfer, len(transfers))
+	ids := make([]uuid.UUID, 0, len(transfers))
+	for _, t := range transfers {
+		byID[t.ID] = t
+		ids = append(ids, t.ID)
+	}
+
+	rows, err := s.db.Query(ctx,
+		`SELECT transfer_id, device_id, skb FROM transfer_device_keys WHERE transfer_id = ANY($1)`,
+		ids,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao buscar SKBs por dispositivo: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transferID, deviceID uuid.UUID
+		var skb string
+		if err := rows.Scan(&transferID, &deviceID, &skb); err != nil {
+			return fmt.Errorf("falha ao escanear SKB por dispositivo: %w", err)
+		}
+		t := byID[transferID]
+		if t.DeviceSKBs == nil {
+			t.DeviceSKBs = make(map[uuid.UUID]string)
+		}
+		t.DeviceSKBs[deviceID] = skb
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("erro ao iterar sobre as SKBs por dispositivo: %w", err)
+	}
+	return nil
+}
+
 func (s *PostgresStore) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 	sql := `
-        SELECT id, username, password_hash, public_key, public_key_sign, created_at
+        SELECT id, username, password_hash, public_key, public_key_sign, created_at, sessions_valid_after, kind, owner_id, email, email_verified_at, locale
         FROM users 
         ORDER BY username`
 
-	rows, err := s.db.Query(ctx, sql)
+	users, err := s.queryUsers(ctx, sql)
 	if err != nil {
 		return nil, fmt.Errorf("falha ao buscar todos os usuários: %w", err)
 	}
+	return users, nil
+}
+
+// GetServiceAccountsByOwner lista as contas de serviço criadas por ownerID
+func (s *PostgresStore) GetServiceAccountsByOwner(ctx context.Context, ownerID uuid.UUID) ([]*models.User, error) {
+	sql := `
+        SELECT id, username, password_hash, public_key, public_key_sign, created_at, sessions_valid_after, kind, owner_id, email, email_verified_at, locale
+        FROM users
+        WHERE owner_id = $1 AND kind = 'service'
+        ORDER BY username`
+
+	users, err := s.queryUsers(ctx, sql, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar contas de serviço: %w", err)
+	}
+	return users, nil
+}
+
+func (s *PostgresStore) queryUsers(ctx context.Context, sql string, args ...any) ([]*models.User, error) {
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	// Inicializa como slice vazio para consistência de JSON
@@ -212,6 +461,12 @@ func (s *PostgresStore) GetAllUsers(ctx context.Context) ([]*models.User, error)
 			&user.PublicKey,
 			&user.PublicKeySign,
 			&user.CreatedAt,
+			&user.SessionsValidAfter,
+			&user.Kind,
+			&user.OwnerID,
+			&user.Email,
+			&user.EmailVerifiedAt,
+			&user.Locale,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("falha ao escanear linha de usuário: %w", err)
@@ -225,3 +480,1176 @@ func (s *PostgresStore) GetAllUsers(ctx context.Context) ([]*models.User, error)
 
 	return users, nil
 }
+
+// --- DeviceStore ---
+
+func (s *PostgresStore) CreateDevice(ctx context.Context, device *models.Device) error {
+	sql := `
+        INSERT INTO devices (id, user_id, name, public_key, public_key_sign, signer_device_id, signature, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := s.db.Exec(ctx, sql,
+		device.ID,
+		device.UserID,
+		device.Name,
+		device.PublicKey,
+		device.PublicKeySign,
+		device.SignerDeviceID,
+		device.Signature,
+		device.CreatedAt,
+	)
+	if err != nil {
+		switch pgErrorCode(err) {
+		case pgUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "falha ao criar dispositivo: ID '%s' já existe", device.ID)
+		case pgForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao criar dispositivo: usuário ou signatário inexistente")
+		}
+		return fmt.Errorf("falha ao criar dispositivo: %w", err)
+	}
+	return nil
+}
+
+const deviceColumns = `id, user_id, name, public_key, public_key_sign, signer_device_id, signature, created_at, revoked_at`
+
+func scanDevice(row pgx.Row) (*models.Device, error) {
+	device := &models.Device{}
+	err := row.Scan(
+		&device.ID,
+		&device.UserID,
+		&device.Name,
+		&device.PublicKey,
+		&device.PublicKeySign,
+		&device.SignerDeviceID,
+		&device.Signature,
+		&device.CreatedAt,
+		&device.RevokedAt,
+	)
+	return device, err
+}
+
+func (s *PostgresStore) GetDeviceByID(ctx context.Context, id uuid.UUID) (*models.Device, error) {
+	device, err := scanDevice(s.db.QueryRow(ctx, `SELECT `+deviceColumns+` FROM devices WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperr.NotFound("dispositivo '%s' não encontrado", id)
+		}
+		return nil, fmt.Errorf("falha ao buscar dispositivo: %w", err)
+	}
+	return device, nil
+}
+
+func (s *PostgresStore) GetDevicesByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Device, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT `+deviceColumns+` FROM devices WHERE user_id = $1 ORDER BY created_at ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar dispositivos: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []*models.Device{}
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de dispositivo: %w", err)
+		}
+		devices = append(devices, device)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os dispositivos: %w", err)
+	}
+	return devices, nil
+}
+
+func (s *PostgresStore) RevokeDevice(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("falha ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE devices SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`,
+		id, revokedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao revogar dispositivo: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("dispositivo '%s' não encontrado", id)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM transfer_device_keys WHERE device_id = $1`, id); err != nil {
+		return fmt.Errorf("falha ao descartar SKBs do dispositivo: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("falha ao revogar dispositivo: %w", err)
+	}
+	return nil
+}
+
+// --- APIKeyStore ---
+
+func (s *PostgresStore) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	sql := `
+        INSERT INTO api_keys (id, user_id, name, prefix, hash, scopes, allowed_cidrs, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := s.db.Exec(ctx, sql,
+		key.ID,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		key.Scopes,
+		key.AllowedCIDRs,
+		key.ExpiresAt,
+		key.CreatedAt,
+	)
+	if err != nil {
+		switch pgErrorCode(err) {
+		case pgUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "falha ao criar API key: prefixo '%s' ou ID já existe", key.Prefix)
+		case pgForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao criar API key: usuário '%s' inexistente", key.UserID)
+		}
+		return fmt.Errorf("falha ao criar API key: %w", err)
+	}
+	return nil
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, hash, scopes, allowed_cidrs, expires_at, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		&key.Scopes,
+		&key.AllowedCIDRs,
+		&key.ExpiresAt,
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	return key, err
+}
+
+func (s *PostgresStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1`, prefix))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperr.NotFound("API key '%s' não encontrada", prefix)
+		}
+		return nil, fmt.Errorf("falha ao buscar API key: %w", err)
+	}
+	return key, nil
+}
+
+func (s *PostgresStore) GetAPIKeysByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre as API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *PostgresStore) RevokeAPIKey(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	tag, err := s.db.Exec(ctx,
+		`UPDATE api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`,
+		id, revokedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao revogar API key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("API key '%s' não encontrada", id)
+	}
+	return nil
+}
+
+func (s *PostgresStore) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	_, err := s.db.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, usedAt)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar uso da API key: %w", err)
+	}
+	return nil
+}
+
+// --- IdentityStore ---
+
+func (s *PostgresStore) CreateIdentity(ctx context.Context, identity *models.Identity) error {
+	sql := `
+        INSERT INTO user_identities (issuer, subject, user_id, email, created_at)
+        VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := s.db.Exec(ctx, sql,
+		identity.Issuer,
+		identity.Subject,
+		identity.UserID,
+		identity.Email,
+		identity.CreatedAt,
+	)
+	if err != nil {
+		switch pgErrorCode(err) {
+		case pgUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "identidade '%s' já vinculada", identity.Subject)
+		case pgForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao vincular identidade: usuário '%s' inexistente", identity.UserID)
+		}
+		return fmt.Errorf("falha ao vincular identidade: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	sql := `
+        SELECT u.id, u.username, u.password_hash, u.public_key, u.public_key_sign, u.created_at, u.sessions_valid_after, u.kind, u.owner_id, u.email, u.email_verified_at, u.locale
+        FROM user_identities i
+        JOIN users u ON u.id = i.user_id
+        WHERE i.issuer = $1 AND i.subject = $2`
+
+	users, err := s.queryUsers(ctx, sql, issuer, subject)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar usuário por identidade: %w", err)
+	}
+	if len(users) == 0 {
+		return nil, apperr.NotFound("identidade '%s' não encontrada", subject)
+	}
+	return users[0], nil
+}
+
+// --- KeyBackupStore ---
+
+// PutKeyBackup cria ou substitui o backup de chaves do usuário
+func (s *PostgresStore) PutKeyBackup(ctx context.Context, backup *models.KeyBackup) error {
+	sql := `
+        INSERT INTO key_backups (user_id, kdf, kdf_salt, kdf_iterations, kdf_memory_kib, kdf_parallelism, cipher, nonce, ciphertext, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        ON CONFLICT (user_id) DO UPDATE SET
+            kdf = EXCLUDED.kdf,
+            kdf_salt = EXCLUDED.kdf_salt,
+            kdf_iterations = EXCLUDED.kdf_iterations,
+            kdf_memory_kib = EXCLUDED.kdf_memory_kib,
+            kdf_parallelism = EXCLUDED.kdf_parallelism,
+            cipher = EXCLUDED.cipher,
+            nonce = EXCLUDED.nonce,
+            ciphertext = EXCLUDED.ciphertext,
+            updated_at = EXCLUDED.updated_at`
+
+	_, err := s.db.Exec(ctx, sql,
+		backup.UserID,
+		backup.KDF,
+		backup.KDFSalt,
+		backup.KDFIterations,
+		backup.KDFMemoryKiB,
+		backup.KDFParallelism,
+		backup.Cipher,
+		backup.Nonce,
+		backup.Ciphertext,
+		backup.UpdatedAt,
+	)
+	if err != nil {
+		if pgErrorCode(err) == pgForeignKeyViolation {
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao salvar backup de chaves: usuário '%s' inexistente", backup.UserID)
+		}
+		return fmt.Errorf("falha ao salvar backup de chaves: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetKeyBackup(ctx context.Context, userID uuid.UUID) (*models.KeyBackup, error) {
+	sql := `
+        SELECT user_id, kdf, kdf_salt, kdf_iterations, kdf_memory_kib, kdf_parallelism, cipher, nonce, ciphertext, updated_at
+        FROM key_backups
+        WHERE user_id = $1`
+
+	backup := &models.KeyBackup{}
+	err := s.db.QueryRow(ctx, sql, userID).Scan(
+		&backup.UserID,
+		&backup.KDF,
+		&backup.KDFSalt,
+		&backup.KDFIterations,
+		&backup.KDFMemoryKiB,
+		&backup.KDFParallelism,
+		&backup.Cipher,
+		&backup.Nonce,
+		&backup.Ciphertext,
+		&backup.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperr.NotFound("backup de chaves do usuário '%s' não encontrado", userID)
+		}
+		return nil, fmt.Errorf("falha ao buscar backup de chaves: %w", err)
+	}
+	return backup, nil
+}
+
+// --- JobStore ---
+
+func (s *PostgresStore) EnqueueJob(ctx context.Context, job *models.Job) error {
+	sql := `
+        INSERT INTO jobs (id, kind, payload, attempts, run_at, last_error, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := s.db.Exec(ctx, sql,
+		job.ID,
+		job.Kind,
+		string(job.Payload),
+		job.Attempts,
+		job.RunAt,
+		job.LastError,
+		job.CreatedAt,
+	)
+	if err != nil {
+		if pgErrorCode(err) == pgUniqueViolation {
+			return apperr.Wrap(apperr.ErrConflict, err, "job '%s' já existe", job.ID)
+		}
+		return fmt.Errorf("falha ao enfileirar job: %w", err)
+	}
+	return nil
+}
+
+const jobColumns = `id, kind, payload, attempts, run_at, last_error, created_at, failed_at`
+
+func (s *PostgresStore) queryJobs(ctx context.Context, sql string, args ...any) ([]*models.Job, error) {
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*models.Job{}
+	for rows.Next() {
+		job := &models.Job{}
+		var payload string
+		err := rows.Scan(
+			&job.ID,
+			&job.Kind,
+			&payload,
+			&job.Attempts,
+			&job.RunAt,
+			&job.LastError,
+			&job.CreatedAt,
+			&job.FailedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de job: %w", err)
+		}
+		job.Payload = []byte(payload)
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// ClaimJobs usa SKIP LOCKED para que workers concorrentes (em réplicas
+// diferentes) nunca reservem o mesmo job
+func (s *PostgresStore) ClaimJobs(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.Job, error) {
+	sql := `
+        UPDATE jobs
+        SET run_at = $2, attempts = attempts + 1
+        WHERE id IN (
+            SELECT id FROM jobs
+            WHERE failed_at IS NULL AND run_at <= $1
+            ORDER BY run_at, created_at
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + jobColumns
+
+	jobs, err := s.queryJobs(ctx, sql, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao reservar jobs: %w", err)
+	}
+	// O UPDATE ... RETURNING não garante ordem
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+func (s *PostgresStore) CompleteJob(ctx context.Context, id uuid.UUID) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM jobs WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("falha ao concluir job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("job '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *PostgresStore) RetryJob(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error {
+	tag, err := s.db.Exec(ctx,
+		`UPDATE jobs SET run_at = $2, last_error = $3 WHERE id = $1 AND failed_at IS NULL`,
+		id, runAt, lastError,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao reagendar job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("job '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *PostgresStore) FailJob(ctx context.Context, id uuid.UUID, failedAt time.Time, lastError string) error {
+	tag, err := s.db.Exec(ctx,
+		`UPDATE jobs SET failed_at = $2, last_error = $3 WHERE id = $1 AND failed_at IS NULL`,
+		id, failedAt, lastError,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao mover job para a dead letter: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("job '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetDeadJobs(ctx context.Context, limit int) ([]*models.Job, error) {
+	jobs, err := s.queryJobs(ctx,
+		`SELECT `+jobColumns+` FROM jobs WHERE failed_at IS NOT NULL ORDER BY failed_at DESC LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar jobs na dead letter: %w", err)
+	}
+	return jobs, nil
+}
+
+func (s *PostgresStore) PruneDeadJobs(ctx context.Context, before time.Time) (int, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM jobs WHERE failed_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("falha ao podar a dead letter: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (s *PostgresStore) CountJobs(ctx context.Context) ([]models.JobCount, error) {
+	rows, err := s.db.Query(ctx, `
+        SELECT kind,
+               COUNT(*) FILTER (WHERE failed_at IS NULL),
+               COUNT(*) FILTER (WHERE failed_at IS NOT NULL)
+        FROM jobs
+        GROUP BY kind
+        ORDER BY kind`)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao contar jobs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []models.JobCount{}
+	for rows.Next() {
+		var c models.JobCount
+		if err := rows.Scan(&c.Kind, &c.Queued, &c.Dead); err != nil {
+			return nil, fmt.Errorf("falha ao contar jobs: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("falha ao contar jobs: %w", err)
+	}
+	return counts, nil
+}
+
+func (s *PostgresStore) AdvanceSchedule(ctx context.Context, name string, now, next time.Time) (bool, error) {
+	// Primeira vez: só registra o próximo disparo
+	tag, err := s.db.Exec(ctx,
+		`INSERT INTO job_schedules (name, next_run_at) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`,
+		name, next,
+	)
+	if err != nil {
+		return false, fmt.Errorf("falha ao registrar agendamento: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return false, nil
+	}
+
+	// O UPDATE condicional garante que só uma réplica vence o disparo
+	tag, err = s.db.Exec(ctx,
+		`UPDATE job_schedules SET next_run_at = $3 WHERE name = $1 AND next_run_at <= $2`,
+		name, now, next,
+	)
+	if err != nil {
+		return false, fmt.Errorf("falha ao avançar agendamento: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// --- UploadStore ---
+
+func (s *PostgresStore) ReserveUpload(ctx context.Context, reservation *models.UploadReservation) error {
+	sql := `
+        INSERT INTO upload_reservations (object_key, user_id, size_bytes, checksum_sha256, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := s.db.Exec(ctx, sql,
+		reservation.ObjectKey,
+		reservation.UserID,
+		reservation.Size,
+		reservation.ChecksumSHA256,
+		reservation.CreatedAt,
+		reservation.ExpiresAt,
+	)
+	if err != nil {
+		switch pgErrorCode(err) {
+		case pgUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "chave '%s' já reservada", reservation.ObjectKey)
+		case pgForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao reservar upload: usuário inexistente")
+		}
+		return fmt.Errorf("falha ao reservar upload: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetUploadReservation(ctx context.Context, objectKey string) (*models.UploadReservation, error) {
+	reservation := &models.UploadReservation{ObjectKey: objectKey}
+	err := s.db.QueryRow(ctx, `
+        SELECT user_id, size_bytes, checksum_sha256, created_at, expires_at
+        FROM upload_reservations WHERE object_key = $1`,
+		objectKey,
+	).Scan(&reservation.UserID, &reservation.Size, &reservation.ChecksumSHA256, &reservation.CreatedAt, &reservation.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperr.NotFound("reserva de upload '%s' não encontrada", objectKey)
+		}
+		return nil, fmt.Errorf("falha ao buscar reserva de upload: %w", err)
+	}
+	return reservation, nil
+}
+
+func (s *PostgresStore) ReferencedKeys(ctx context.Context, keys []string, now time.Time) (map[string]bool, error) {
+	sql := `
+        SELECT link_to_enc_file FROM transfers WHERE link_to_enc_file = ANY($1)
+        UNION
+        SELECT object_key FROM upload_reservations WHERE object_key = ANY($1) AND expires_at > $2`
+
+	rows, err := s.db.Query(ctx, sql, keys, now)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar referências de objetos: %w", err)
+	}
+	defer rows.Close()
+
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("falha ao escanear referência de objeto: %w", err)
+		}
+		referenced[key] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre as referências de objetos: %w", err)
+	}
+	return referenced, nil
+}
+
+func (s *PostgresStore) PruneUploadReservations(ctx context.Context, before time.Time) (int, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM upload_reservations WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("falha ao podar reservas de upload: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (s *PostgresStore) ReleaseUpload(ctx context.Context, objectKey string) error {
+	if _, err := s.db.Exec(ctx, `DELETE FROM upload_reservations WHERE object_key = $1`, objectKey); err != nil {
+		return fmt.Errorf("falha ao liberar reserva de upload: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetStorageUsage(ctx context.Context, userIDs []uuid.UUID, now time.Time) (*models.StorageUsage, error) {
+	// Um objeto enviado a vários destinatários gera várias transferências,
+	// mas ocupa espaço uma vez só
+	sql := `
+        SELECT
+            (SELECT COALESCE(SUM(size), 0) FROM (
+                SELECT MAX(size_bytes) AS size FROM transfers
+                WHERE source_user_id = ANY($1)
+                GROUP BY link_to_enc_file
+            ) objects),
+            (SELECT COALESCE(SUM(size_bytes), 0) FROM upload_reservations
+             WHERE user_id = ANY($1) AND expires_at > $2)`
+
+	usage := &models.StorageUsage{}
+	err := s.db.QueryRow(ctx, sql, userIDs, now).Scan(&usage.UsedBytes, &usage.ReservedBytes)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao calcular uso de armazenamento: %w", err)
+	}
+	return usage, nil
+}
+
+// --- AuditStore ---
+
+// auditLockKey identifica o advisory lock de quem grava no log de
+// auditoria (valor arbitrário, fixo)
+const auditLockKey int64 = 0x5ec5_4a4e_0040
+
+const auditColumns = `seq, type, actor_id, actor, ip, user_agent, target, details, created_at, prev_hash, hash`
+
+func (s *PostgresStore) AppendAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("falha ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// O advisory lock serializa só quem grava na cadeia (cada evento precisa
+	// do hash do anterior) até o commit; leituras, VACUUM e o restante do
+	// banco não esperam por ele
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, auditLockKey); err != nil {
+		return fmt.Errorf("falha ao bloquear o log de auditoria: %w", err)
+	}
+	var lastSeq int64
+	var lastHash string
+	err = tx.QueryRow(ctx, `SELECT seq, hash FROM audit_events ORDER BY seq DESC LIMIT 1`).Scan(&lastSeq, &lastHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("falha ao ler o fim do log de auditoria: %w", err)
+	}
+
+	event.Seq = lastSeq + 1
+	event.PrevHash = lastHash
+	event.Hash = event.ComputeHash()
+
+	_, err = tx.Exec(ctx, `INSERT INTO audit_events (`+auditColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		event.Seq,
+		event.Type,
+		event.ActorID,
+		event.Actor,
+		event.IP,
+		event.UserAgent,
+		event.Target,
+		event.Details,
+		event.CreatedAt,
+		event.PrevHash,
+		event.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao gravar evento de auditoria: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("falha ao gravar evento de auditoria: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListAuditEvents(ctx context.Context, filter AuditFilter) ([]*models.AuditEvent, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.Type != "" {
+		add("type = $%d", filter.Type)
+	}
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.Target != "" {
+		add("target = $%d", filter.Target)
+	}
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+	add("seq > $%d", filter.AfterSeq)
+	if filter.BeforeSeq > 0 {
+		add("seq < $%d", filter.BeforeSeq)
+	}
+
+	sql := `SELECT ` + auditColumns + ` FROM audit_events WHERE ` + strings.Join(where, " AND ")
+	if filter.Descending {
+		sql += ` ORDER BY seq DESC`
+	} else {
+		sql += ` ORDER BY seq`
+	}
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		sql += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar eventos de auditoria: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		e := &models.AuditEvent{}
+		err := rows.Scan(&e.Seq, &e.Type, &e.ActorID, &e.Actor, &e.IP, &e.UserAgent, &e.Target,
+			&e.Details, &e.CreatedAt, &e.PrevHash, &e.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear evento de auditoria: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os eventos de auditoria: %w", err)
+	}
+	return events, nil
+}
+
+// --- EventStore ---
+
+// eventsChannel é o canal de NOTIFY dos eventos da caixa de entrada
+const eventsChannel = "secureshare_events"
+
+// eventsLockClass é a classe dos advisory locks por destinatário de
+// AppendEvent (pg_advisory_xact_lock(classe, hashtext(user_id)))
+const eventsLockClass int32 = 0x5ec5_0041
+
+// eventNotification é o payload do NOTIFY: o evento inteiro, já que
+// InboxEvent não serializa o destinatário
+type eventNotification struct {
+	*models.InboxEvent
+	UserID uuid.UUID `json:"userId"`
+}
+
+func (s *PostgresStore) AppendEvent(ctx context.Context, event *models.InboxEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("falha ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// O lock do destinatário (até o commit da transação externa) faz os IDs
+	// de cada usuário seguirem a ordem de commit: quem retoma do
+	// Last-Event-ID, que é por usuário, não perde um evento de ID menor
+	// confirmado depois. Eventos de usuários diferentes não se esperam.
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, eventsLockClass, event.UserID.String())
+	if err != nil {
+		return fmt.Errorf("falha ao bloquear os eventos do usuário: %w", err)
+	}
+	err = tx.QueryRow(ctx, `
+        INSERT INTO inbox_events (user_id, type, data, created_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id`,
+		event.UserID,
+		event.Type,
+		event.Data,
+		event.CreatedAt,
+	).Scan(&event.ID)
+	if err != nil {
+		if pgErrorCode(err) == pgForeignKeyViolation {
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao gravar evento: usuário '%s' inexistente", event.UserID)
+		}
+		return fmt.Errorf("falha ao gravar evento: %w", err)
+	}
+
+	// O NOTIFY só é entregue no commit, e não é entregue se houver rollback
+	payload, err := json.Marshal(eventNotification{InboxEvent: event, UserID: event.UserID})
+	if err != nil {
+		return fmt.Errorf("falha ao serializar evento: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, eventsChannel, string(payload)); err != nil {
+		return fmt.Errorf("falha ao notificar evento: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("falha ao gravar evento: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetEventsAfter(ctx context.Context, userID uuid.UUID, afterID int64, limit int) ([]*models.InboxEvent, error) {
+	sql := `SELECT id, user_id, type, data, created_at FROM inbox_events WHERE id > $1`
+	args := []any{afterID}
+	if userID != uuid.Nil {
+		args = append(args, userID)
+		sql += ` AND user_id = $2`
+	}
+	sql += ` ORDER BY id`
+	if limit > 0 {
+		args = append(args, limit)
+		sql += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar eventos: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.InboxEvent{}
+	for rows.Next() {
+		e := &models.InboxEvent{}
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Type, &e.Data, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("falha ao escanear evento: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os eventos: %w", err)
+	}
+	return events, nil
+}
+
+func (s *PostgresStore) LatestEventID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := s.db.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM inbox_events`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("falha ao buscar o último evento: %w", err)
+	}
+	return id, nil
+}
+
+func (s *PostgresStore) PruneEvents(ctx context.Context, before time.Time) (int, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM inbox_events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("falha ao podar eventos: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ListenEvents ocupa uma conexão do pool com LISTEN até ctx ser cancelado
+// ou a conexão cair. Recebe os eventos gravados por todas as réplicas.
+func (s *PostgresStore) ListenEvents(ctx context.Context, fn func(event *models.InboxEvent)) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("falha ao obter conexão para LISTEN: %w", err)
+	}
+	defer func() {
+		// Não devolve ao pool uma conexão ainda inscrita no canal
+		if _, err := conn.Exec(context.Background(), `UNLISTEN *`); err != nil {
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}()
+
+	if _, err := conn.Exec(ctx, `LISTEN `+eventsChannel); err != nil {
+		return fmt.Errorf("falha ao executar LISTEN: %w", err)
+	}
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("falha ao aguardar notificação: %w", err)
+		}
+		var n eventNotification
+		if err := json.Unmarshal([]byte(notification.Payload), &n); err != nil || n.InboxEvent == nil {
+			logging.FromContext(ctx).Error("Notificação de evento inválida ignorada", "err", err)
+			continue
+		}
+		n.InboxEvent.UserID = n.UserID
+		fn(n.InboxEvent)
+	}
+}
+
+// --- WebhookStore ---
+
+const webhookColumns = `id, user_id, url, secret, event_types, created_at, created_by`
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&webhook.Secret,
+		&webhook.EventTypes,
+		&webhook.CreatedAt,
+		&webhook.CreatedBy,
+	)
+	return webhook, err
+}
+
+func (s *PostgresStore) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	_, err := s.db.Exec(ctx, `INSERT INTO webhooks (`+webhookColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		webhook.ID,
+		webhook.UserID,
+		webhook.URL,
+		webhook.Secret,
+		webhook.EventTypes,
+		webhook.CreatedAt,
+		webhook.CreatedBy,
+	)
+	if err != nil {
+		switch pgErrorCode(err) {
+		case pgUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "falha ao criar webhook: ID '%s' já existe", webhook.ID)
+		case pgForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao criar webhook: usuário '%s' inexistente", webhook.UserID)
+		}
+		return fmt.Errorf("falha ao criar webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	webhook, err := scanWebhook(s.db.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperr.NotFound("webhook '%s' não encontrado", id)
+		}
+		return nil, fmt.Errorf("falha ao buscar webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (s *PostgresStore) GetWebhooksByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Webhook, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = $1 ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (s *PostgresStore) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("falha ao remover webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("webhook '%s' não encontrado", id)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event_type, payload, status, attempts, last_status_code, last_error, created_at, updated_at, redelivery_of`
+
+func scanWebhookDelivery(row pgx.Row) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+		&delivery.RedeliveryOf,
+	)
+	return delivery, err
+}
+
+func (s *PostgresStore) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO webhook_deliveries (`+webhookDeliveryColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		delivery.ID,
+		delivery.WebhookID,
+		delivery.EventType,
+		delivery.Payload,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		delivery.CreatedAt,
+		delivery.UpdatedAt,
+		delivery.RedeliveryOf,
+	)
+	if err != nil {
+		switch pgErrorCode(err) {
+		case pgUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "falha ao registrar entrega: ID '%s' já existe", delivery.ID)
+		case pgForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao registrar entrega: webhook '%s' inexistente", delivery.WebhookID)
+		}
+		return fmt.Errorf("falha ao registrar entrega: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetWebhookDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := scanWebhookDelivery(s.db.QueryRow(ctx,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperr.NotFound("entrega '%s' não encontrada", id)
+		}
+		return nil, fmt.Errorf("falha ao buscar entrega: %w", err)
+	}
+	return delivery, nil
+}
+
+func (s *PostgresStore) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	tag, err := s.db.Exec(ctx, `
+        UPDATE webhook_deliveries
+        SET status = $2, attempts = $3, last_status_code = $4, last_error = $5, updated_at = $6
+        WHERE id = $1`,
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		delivery.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar entrega: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("entrega '%s' não encontrada", delivery.ID)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*models.WebhookDelivery, error) {
+	sql := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC`
+	args := []any{webhookID}
+	if limit > 0 {
+		sql += ` LIMIT $2`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar entregas: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de entrega: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre as entregas: %w", err)
+	}
+	return deliveries, nil
+}
+
+// --- IdempotencyStore ---
+
+func (s *PostgresStore) CreateIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord, now time.Time) error {
+	// Um registro expirado é sobrescrito; um vivo faz o INSERT não afetar
+	// nenhuma linha
+	tag, err := s.db.Exec(ctx, `
+        INSERT INTO idempotency_keys (user_id, idem_key, fingerprint, status_code, content_type, body, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (user_id, idem_key) DO UPDATE
+        SET fingerprint = EXCLUDED.fingerprint, status_code = EXCLUDED.status_code,
+            content_type = EXCLUDED.content_type, body = EXCLUDED.body,
+            created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+        WHERE idempotency_keys.expires_at <= $9`,
+		record.UserID,
+		record.Key,
+		record.Fingerprint,
+		record.StatusCode,
+		record.ContentType,
+		record.Body,
+		record.CreatedAt,
+		record.ExpiresAt,
+		now,
+	)
+	if err != nil {
+		if pgErrorCode(err) == pgForeignKeyViolation {
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao registrar chave de idempotência: usuário '%s' inexistente", record.UserID)
+		}
+		return fmt.Errorf("falha ao registrar chave de idempotência: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.Conflict("chave de idempotência '%s' já utilizada", record.Key)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetIdempotencyRecord(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	record := &models.IdempotencyRecord{UserID: userID, Key: key}
+	err := s.db.QueryRow(ctx, `
+        SELECT fingerprint, status_code, content_type, body, created_at, expires_at
+        FROM idempotency_keys WHERE user_id = $1 AND idem_key = $2`,
+		userID, key,
+	).Scan(&record.Fingerprint, &record.StatusCode, &record.ContentType, &record.Body, &record.CreatedAt, &record.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperr.NotFound("chave de idempotência '%s' não encontrada", key)
+		}
+		return nil, fmt.Errorf("falha ao buscar chave de idempotência: %w", err)
+	}
+	return record, nil
+}
+
+func (s *PostgresStore) CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	tag, err := s.db.Exec(ctx, `
+        UPDATE idempotency_keys SET status_code = $4, content_type = $5, body = $6, expires_at = $7
+        WHERE user_id = $1 AND idem_key = $2 AND created_at = $3`,
+		record.UserID,
+		record.Key,
+		record.CreatedAt,
+		record.StatusCode,
+		record.ContentType,
+		record.Body,
+		record.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao gravar resposta idempotente: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperr.NotFound("chave de idempotência '%s' não encontrada", record.Key)
+	}
+	return nil
+}
+
+func (s *PostgresStore) DeleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND idem_key = $2 AND created_at = $3`,
+		record.UserID, record.Key, record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("falha ao liberar chave de idempotência: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) PruneIdempotencyRecords(ctx context.Context, before time.Time) (int, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("falha ao podar chaves de idempotência: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
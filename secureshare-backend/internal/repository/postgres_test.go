@@ -0,0 +1,50 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"secureshare-backend/internal/migrate"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/repository/storetest"
+	"secureshare-backend/internal/testutil/pgtest"
+	"secureshare-backend/migrations"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TestPostgresStoreConformance roda a suíte contra um banco descartável,
+// criado e removido a cada execução (ver pgtest).
+func TestPostgresStoreConformance(t *testing.T) {
+	ctx := context.Background()
+	dbURL := pgtest.NewDatabase(t)
+
+	store, err := repository.NewPostgresStore(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("NewPostgresStore: %v", err)
+	}
+	t.Cleanup(store.Close)
+
+	migrator, err := migrate.New(store.Pool(), migrations.FS)
+	if err != nil {
+		t.Fatalf("migrate.New: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrações: %v", err)
+	}
+
+	conn, err := pgx.Connect(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("falha ao conectar ao banco de teste: %v", err)
+	}
+	t.Cleanup(func() { conn.Close(context.Background()) })
+
+	storetest.Run(t, func(t *testing.T) repository.Store {
+		// As demais tabelas referenciam users (direta ou indiretamente), exceto
+		// jobs e audit_events
+		if _, err := conn.Exec(ctx, `TRUNCATE users, jobs, job_schedules, upload_reservations, audit_events, inbox_events, webhooks CASCADE`); err != nil {
+			t.Fatalf("falha ao limpar o banco de teste: %v", err)
+		}
+		return store
+	})
+}
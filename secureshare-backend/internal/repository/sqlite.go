@@ -0,0 +1,1747 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SQLiteDriverName é o driver database/sql usado pelo SQLiteStore
+// (modernc.org/sqlite, sem cgo; registrado em sqlite_driver.go)
+const SQLiteDriverName = "sqlite"
+
+// SQLiteStore é a implementação da interface Store para o SQLite, pensada
+// para instalações de um nó só (um binário, sem container de banco).
+//
+// Usa uma única conexão: o SQLite serializa as escritas de qualquer forma,
+// e assim as transações nunca esbarram em SQLITE_BUSY dentro do processo.
+type SQLiteStore struct {
+	db *sql.DB
+	q  sqliteQuerier // o banco, ou a transação aberta por WithTx
+	tx *sql.Tx       // não-nil dentro de WithTx
+}
+
+// sqliteQuerier é o que SQLiteStore usa do banco; *sql.Tx também o implementa
+type sqliteQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var _ Store = (*SQLiteStore)(nil)
+
+// IsSQLiteURL indica se databaseURL seleciona o SQLite (esquema sqlite://)
+func IsSQLiteURL(databaseURL string) bool {
+	return strings.HasPrefix(databaseURL, "sqlite://")
+}
+
+// NewSQLiteStore abre (ou cria) o banco indicado por databaseURL, no formato
+// sqlite://<caminho>, ex: sqlite:///var/lib/secureshare/secureshare.db ou
+// sqlite://./secureshare.db. O banco é aberto em modo WAL com chaves
+// estrangeiras habilitadas.
+func NewSQLiteStore(ctx context.Context, databaseURL string) (*SQLiteStore, error) {
+	if !IsSQLiteURL(databaseURL) {
+		return nil, fmt.Errorf("URL do SQLite deve começar com sqlite://")
+	}
+	path := strings.TrimPrefix(databaseURL, "sqlite://")
+	if path == "" {
+		return nil, fmt.Errorf("URL do SQLite sem caminho do arquivo")
+	}
+
+	// Os pragmas vão no DSN para valer também se o pool reabrir a conexão
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?" + url.Values{
+		"_pragma": {"foreign_keys(1)", "journal_mode(WAL)", "busy_timeout(5000)", "synchronous(NORMAL)"},
+	}.Encode()
+
+	db, err := sql.Open(SQLiteDriverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("não foi possível abrir o banco SQLite: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+
+	var journalMode string
+	if err := db.QueryRowContext(ctx, `PRAGMA journal_mode`).Scan(&journalMode); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("não foi possível acessar o banco SQLite: %w", err)
+	}
+	if !strings.EqualFold(journalMode, "wal") {
+		db.Close()
+		return nil, fmt.Errorf("banco SQLite não está em modo WAL (journal_mode=%s)", journalMode)
+	}
+
+	slog.Info("Banco SQLite aberto", "path", path)
+	return &SQLiteStore{db: db, q: db}, nil
+}
+
+// Close fecha o banco
+func (s *SQLiteStore) Close() {
+	s.db.Close()
+}
+
+// DB expõe o banco (usado pelo internal/migrate)
+func (s *SQLiteStore) DB() *sql.DB {
+	return s.db
+}
+
+// WithTx executa fn em uma transação (ou savepoint, se já estiver em uma)
+func (s *SQLiteStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return fn(&SQLiteStore{db: s.db, q: tx, tx: tx})
+	})
+}
+
+// inTx executa fn em uma transação nova ou, dentro de WithTx, em um
+// savepoint da transação corrente
+func (s *SQLiteStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if s.tx != nil {
+		if _, err := s.tx.ExecContext(ctx, `SAVEPOINT nested`); err != nil {
+			return fmt.Errorf("falha ao criar savepoint: %w", err)
+		}
+		if err := fn(s.tx); err != nil {
+			if _, rbErr := s.tx.ExecContext(ctx, `ROLLBACK TO nested; RELEASE nested`); rbErr != nil {
+				return errors.Join(err, rbErr)
+			}
+			return err
+		}
+		if _, err := s.tx.ExecContext(ctx, `RELEASE nested`); err != nil {
+			return fmt.Errorf("falha ao liberar savepoint: %w", err)
+		}
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("falha ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("falha ao confirmar transação: %w", err)
+	}
+	return nil
+}
+
+// --- Conversões ---
+
+// sqliteTimeLayout tem largura fixa para que a ordenação lexical das
+// colunas TEXT coincida com a cronológica
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+func sqliteNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return sqliteTime(*t)
+}
+
+// timeScanner lê um instante gravado por sqliteTime; com nullable, aceita
+// NULL em um *time.Time
+type timeScanner struct {
+	t        *time.Time
+	nullable **time.Time
+}
+
+func scanTime(t *time.Time) sql.Scanner      { return &timeScanner{t: t} }
+func scanNullTime(t **time.Time) sql.Scanner { return &timeScanner{nullable: t} }
+
+func (ts *timeScanner) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+		if ts.nullable == nil {
+			return fmt.Errorf("instante NULL em coluna obrigatória")
+		}
+		*ts.nullable = nil
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("tipo inesperado para instante: %T", src)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return fmt.Errorf("instante inválido %q: %w", text, err)
+	}
+	if ts.nullable != nil {
+		*ts.nullable = &t
+	} else {
+		*ts.t = t
+	}
+	return nil
+}
+
+// stringList grava um []string como JSON
+type stringList []string
+
+func (l stringList) Value() (driver.Value, error) {
+	if l == nil {
+		l = stringList{}
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+// scanStringList lê uma coluna gravada por stringList
+type stringListScanner struct{ dst *[]string }
+
+func scanStringList(dst *[]string) sql.Scanner { return stringListScanner{dst} }
+
+func (s stringListScanner) Scan(src any) error {
+	var raw []byte
+	switch v := src.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("tipo inesperado para lista: %T", src)
+	}
+	list := []string{}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return fmt.Errorf("lista inválida: %w", err)
+	}
+	*s.dst = list
+	return nil
+}
+
+// stringMap grava um map[string]string como JSON (NULL se vazio)
+type stringMap map[string]string
+
+func (m stringMap) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(map[string]string(m))
+	return string(b), err
+}
+
+// scanStringMap lê uma coluna gravada por stringMap
+type stringMapScanner struct{ dst *map[string]string }
+
+func scanStringMap(dst *map[string]string) sql.Scanner { return stringMapScanner{dst} }
+
+func (s stringMapScanner) Scan(src any) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		*s.dst = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("tipo inesperado para mapa: %T", src)
+	}
+	var m map[string]string
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return fmt.Errorf("mapa inválido: %w", err)
+	}
+	*s.dst = m
+	return nil
+}
+
+// Violações de constraint traduzidas para erros tipados (ver internal/apperr)
+const (
+	sqliteUniqueViolation     = "unique"
+	sqliteForeignKeyViolation = "fk"
+)
+
+// Códigos estendidos do SQLite para as constraints que nos interessam
+const (
+	sqliteConstraintForeignKey = 787
+	sqliteConstraintPrimaryKey = 1555
+	sqliteConstraintUnique     = 2067
+)
+
+// sqliteConstraint classifica a violação de constraint, ou retorna "". Usa o
+// código estendido quando o driver o expõe (modernc.org/sqlite expõe) e,
+// na falta dele, a mensagem padrão do próprio SQLite.
+func sqliteConstraint(err error) string {
+	var coded interface{ Code() int }
+	if errors.As(err, &coded) {
+		switch coded.Code() {
+		case sqliteConstraintUnique, sqliteConstraintPrimaryKey:
+			return sqliteUniqueViolation
+		case sqliteConstraintForeignKey:
+			return sqliteForeignKeyViolation
+		}
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"):
+		return sqliteUniqueViolation
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		return sqliteForeignKeyViolation
+	}
+	return ""
+}
+
+func rowsAffected(res sql.Result) int64 {
+	n, _ := res.RowsAffected()
+	return n
+}
+
+// --- UserStore ---
+
+const userColumns = `id, username, password_hash, public_key, public_key_sign, created_at, sessions_valid_after, kind, owner_id, email, email_verified_at, locale`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSQLiteUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.PasswordHash,
+		&user.PublicKey,
+		&user.PublicKeySign,
+		scanTime(&user.CreatedAt),
+		scanTime(&user.SessionsValidAfter),
+		&user.Kind,
+		&user.OwnerID,
+		&user.Email,
+		scanNullTime(&user.EmailVerifiedAt),
+		&user.Locale,
+	)
+	return user, err
+}
+
+func (s *SQLiteStore) CreateUser(ctx context.Context, user *models.User) error {
+	query := `
+        INSERT INTO users (` + userColumns + `)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := s.q.ExecContext(ctx, query,
+		user.ID,
+		user.Username,
+		user.PasswordHash,
+		user.PublicKey,
+		user.PublicKeySign,
+		sqliteTime(user.CreatedAt),
+		sqliteTime(user.SessionsValidAfter),
+		user.Kind,
+		user.OwnerID,
+		user.Email,
+		sqliteNullTime(user.EmailVerifiedAt),
+		user.Locale,
+	)
+	if err != nil {
+		switch sqliteConstraint(err) {
+		case sqliteUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "usuário '%s' já existe", user.Username)
+		case sqliteForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao criar usuário: dono inexistente")
+		}
+		return fmt.Errorf("falha ao criar usuário: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	user, err := scanSQLiteUser(s.q.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE username = ?`, username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("usuário '%s' não encontrado", username)
+		}
+		return nil, fmt.Errorf("falha ao buscar usuário por nome: %w", err)
+	}
+	return user, nil
+}
+
+func (s *SQLiteStore) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, err := scanSQLiteUser(s.q.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("usuário com ID '%s' não encontrado", id)
+		}
+		return nil, fmt.Errorf("falha ao buscar usuário por ID: %w", err)
+	}
+	return user, nil
+}
+
+func (s *SQLiteStore) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	users, err := s.queryUsers(ctx, `SELECT `+userColumns+` FROM users ORDER BY username`)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar todos os usuários: %w", err)
+	}
+	return users, nil
+}
+
+// GetServiceAccountsByOwner lista as contas de serviço criadas por ownerID
+func (s *SQLiteStore) GetServiceAccountsByOwner(ctx context.Context, ownerID uuid.UUID) ([]*models.User, error) {
+	users, err := s.queryUsers(ctx,
+		`SELECT `+userColumns+` FROM users WHERE owner_id = ? AND kind = 'service' ORDER BY username`,
+		ownerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar contas de serviço: %w", err)
+	}
+	return users, nil
+}
+
+func (s *SQLiteStore) queryUsers(ctx context.Context, query string, args ...any) ([]*models.User, error) {
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Inicializa como slice vazio para consistência de JSON
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanSQLiteUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de usuário: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os usuários: %w", err)
+	}
+	return users, nil
+}
+
+// UpdateUserPassword troca o hash da senha e revoga as sessões anteriores
+func (s *SQLiteStore) UpdateUserPassword(ctx context.Context, id uuid.UUID, passwordHash string, sessionsValidAfter time.Time) error {
+	res, err := s.q.ExecContext(ctx,
+		`UPDATE users SET password_hash = ?, sessions_valid_after = ? WHERE id = ?`,
+		passwordHash, sqliteTime(sessionsValidAfter), id,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar senha: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UpdateUserEmail(ctx context.Context, id uuid.UUID, email string, verifiedAt *time.Time) error {
+	res, err := s.q.ExecContext(ctx,
+		`UPDATE users SET email = ?, email_verified_at = ? WHERE id = ?`,
+		email, sqliteNullTime(verifiedAt), id,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar e-mail do usuário: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UpdateUserLocale(ctx context.Context, id uuid.UUID, locale string) error {
+	res, err := s.q.ExecContext(ctx, `UPDATE users SET locale = ? WHERE id = ?`, locale, id)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar idioma do usuário: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UpdateUserPublicKeys(ctx context.Context, id uuid.UUID, publicKey, publicKeySign string) error {
+	res, err := s.q.ExecContext(ctx,
+		`UPDATE users SET public_key = ?, public_key_sign = ? WHERE id = ?`,
+		publicKey, publicKeySign, id,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar chaves do usuário: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
+// LockUser só confere se o usuário existe: com uma única conexão, as
+// transações do SQLiteStore já rodam uma de cada vez
+func (s *SQLiteStore) LockUser(ctx context.Context, id uuid.UUID) error {
+	var exists int
+	err := s.q.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = ?`, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	if err != nil {
+		return fmt.Errorf("falha ao travar usuário: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser remove o usuário. Os dados dependentes são removidos pelo
+// ON DELETE CASCADE.
+func (s *SQLiteStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("falha ao remover usuário: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("usuário com ID '%s' não encontrado", id)
+	}
+	return nil
+}
+
+// --- TransferStore ---
+
+func (s *SQLiteStore) CreateTransfer(ctx context.Context, transfer *models.Transfer) error {
+	// A transferência e suas SKBs por dispositivo são gravadas juntas
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO transfers (id, source_user_id, dest_user_id, link_to_enc_file, skb, sig, created_at, size_bytes, checksum_sha256)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			transfer.ID,
+			transfer.SourceUserID,
+			transfer.DestUserID,
+			transfer.LinkToEncFile,
+			transfer.SKB,
+			transfer.Sig,
+			sqliteTime(transfer.CreatedAt),
+			transfer.Size,
+			transfer.ChecksumSHA256,
+		)
+		if err != nil {
+			switch sqliteConstraint(err) {
+			case sqliteUniqueViolation:
+				return apperr.Wrap(apperr.ErrConflict, err, "falha ao criar transferência: ID '%s' já existe", transfer.ID)
+			case sqliteForeignKeyViolation:
+				return apperr.Wrap(apperr.ErrNotFound, err, "falha ao criar transferência: usuário inexistente")
+			}
+			return fmt.Errorf("falha ao criar transferência: %w", err)
+		}
+
+		for deviceID, skb := range transfer.DeviceSKBs {
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO transfer_device_keys (transfer_id, device_id, skb) VALUES (?, ?, ?)`,
+				transfer.ID, deviceID, skb,
+			)
+			if err != nil {
+				if sqliteConstraint(err) == sqliteForeignKeyViolation {
+					return apperr.Wrap(apperr.ErrNotFound, err, "falha ao salvar SKB do dispositivo %s: dispositivo inexistente", deviceID)
+				}
+				return fmt.Errorf("falha ao salvar SKB do dispositivo %s: %w", deviceID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) GetTransfersByDestUserID(ctx context.Context, destUserID uuid.UUID) ([]*models.Transfer, error) {
+	return s.queryTransfers(ctx, `dest_user_id = ?`, destUserID)
+}
+
+func (s *SQLiteStore) GetTransfersBySourceUserID(ctx context.Context, sourceUserID uuid.UUID) ([]*models.Transfer, error) {
+	return s.queryTransfers(ctx, `source_user_id = ?`, sourceUserID)
+}
+
+// queryTransfers lista as transferências que satisfazem where, mais
+// recentes primeiro, com as SKBs por dispositivo
+func (s *SQLiteStore) queryTransfers(ctx context.Context, where string, args ...any) ([]*models.Transfer, error) {
+	rows, err := s.q.QueryContext(ctx, `
+        SELECT id, source_user_id, dest_user_id, link_to_enc_file, skb, sig, created_at, size_bytes, checksum_sha256
+        FROM transfers
+        WHERE `+where+`
+        ORDER BY created_at DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar transferências: %w", err)
+	}
+	defer rows.Close()
+
+	// Importante: inicializa como slice vazio, não nil, para consistência de JSON
+	transfers := []*models.Transfer{}
+	for rows.Next() {
+		transfer := &models.Transfer{}
+		err := rows.Scan(
+			&transfer.ID,
+			&transfer.SourceUserID,
+			&transfer.DestUserID,
+			&transfer.LinkToEncFile,
+			&transfer.SKB,
+			&transfer.Sig,
+			scanTime(&transfer.CreatedAt),
+			&transfer.Size,
+			&transfer.ChecksumSHA256,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de transferência: %w", err)
+		}
+		transfers = append(transfers, transfer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre as transferências: %w", err)
+	}
+	// Libera a conexão (única) antes da próxima query
+	rows.Close()
+
+	if err := s.loadDeviceSKBs(ctx, transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// loadDeviceSKBs preenche DeviceSKBs das transferências com uma única query
+func (s *SQLiteStore) loadDeviceSKBs(ctx context.Context, transfers []*models.Transfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*models.Transfer, len(transfers))
+	args := make([]any, 0, len(transfers))
+	for _, t := range transfers {
+		byID[t.ID] = t
+		args = append(args, t.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT transfer_id, device_id, skb FROM transfer_device_keys WHERE transfer_id IN (`+placeholders+`)`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao buscar SKBs por dispositivo: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transferID, deviceID uuid.UUID
+		var skb string
+		if err := rows.Scan(&transferID, &deviceID, &skb); err != nil {
+			return fmt.Errorf("falha ao escanear SKB por dispositivo: %w", err)
+		}
+		t := byID[transferID]
+		if t.DeviceSKBs == nil {
+			t.DeviceSKBs = make(map[uuid.UUID]string)
+		}
+		t.DeviceSKBs[deviceID] = skb
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("erro ao iterar sobre as SKBs por dispositivo: %w", err)
+	}
+	return nil
+}
+
+// --- DeviceStore ---
+
+func (s *SQLiteStore) CreateDevice(ctx context.Context, device *models.Device) error {
+	_, err := s.q.ExecContext(ctx, `
+        INSERT INTO devices (id, user_id, name, public_key, public_key_sign, signer_device_id, signature, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		device.ID,
+		device.UserID,
+		device.Name,
+		device.PublicKey,
+		device.PublicKeySign,
+		device.SignerDeviceID,
+		device.Signature,
+		sqliteTime(device.CreatedAt),
+	)
+	if err != nil {
+		switch sqliteConstraint(err) {
+		case sqliteUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "falha ao criar dispositivo: ID '%s' já existe", device.ID)
+		case sqliteForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao criar dispositivo: usuário ou signatário inexistente")
+		}
+		return fmt.Errorf("falha ao criar dispositivo: %w", err)
+	}
+	return nil
+}
+
+func scanSQLiteDevice(row rowScanner) (*models.Device, error) {
+	device := &models.Device{}
+	err := row.Scan(
+		&device.ID,
+		&device.UserID,
+		&device.Name,
+		&device.PublicKey,
+		&device.PublicKeySign,
+		&device.SignerDeviceID,
+		&device.Signature,
+		scanTime(&device.CreatedAt),
+		scanNullTime(&device.RevokedAt),
+	)
+	return device, err
+}
+
+func (s *SQLiteStore) GetDeviceByID(ctx context.Context, id uuid.UUID) (*models.Device, error) {
+	device, err := scanSQLiteDevice(s.q.QueryRowContext(ctx, `SELECT `+deviceColumns+` FROM devices WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("dispositivo '%s' não encontrado", id)
+		}
+		return nil, fmt.Errorf("falha ao buscar dispositivo: %w", err)
+	}
+	return device, nil
+}
+
+func (s *SQLiteStore) GetDevicesByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Device, error) {
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT `+deviceColumns+` FROM devices WHERE user_id = ? ORDER BY created_at ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar dispositivos: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []*models.Device{}
+	for rows.Next() {
+		device, err := scanSQLiteDevice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de dispositivo: %w", err)
+		}
+		devices = append(devices, device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os dispositivos: %w", err)
+	}
+	return devices, nil
+}
+
+func (s *SQLiteStore) RevokeDevice(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE devices SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`,
+			sqliteTime(revokedAt), id,
+		)
+		if err != nil {
+			return fmt.Errorf("falha ao revogar dispositivo: %w", err)
+		}
+		if rowsAffected(res) == 0 {
+			return apperr.NotFound("dispositivo '%s' não encontrado", id)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM transfer_device_keys WHERE device_id = ?`, id); err != nil {
+			return fmt.Errorf("falha ao descartar SKBs do dispositivo: %w", err)
+		}
+		return nil
+	})
+}
+
+// --- APIKeyStore ---
+
+func (s *SQLiteStore) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	_, err := s.q.ExecContext(ctx, `
+        INSERT INTO api_keys (id, user_id, name, prefix, hash, scopes, allowed_cidrs, expires_at, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.ID,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		stringList(key.Scopes),
+		stringList(key.AllowedCIDRs),
+		sqliteNullTime(key.ExpiresAt),
+		sqliteTime(key.CreatedAt),
+	)
+	if err != nil {
+		switch sqliteConstraint(err) {
+		case sqliteUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "falha ao criar API key: prefixo '%s' ou ID já existe", key.Prefix)
+		case sqliteForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao criar API key: usuário '%s' inexistente", key.UserID)
+		}
+		return fmt.Errorf("falha ao criar API key: %w", err)
+	}
+	return nil
+}
+
+func scanSQLiteAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		scanStringList(&key.Scopes),
+		scanStringList(&key.AllowedCIDRs),
+		scanNullTime(&key.ExpiresAt),
+		scanTime(&key.CreatedAt),
+		scanNullTime(&key.LastUsedAt),
+		scanNullTime(&key.RevokedAt),
+	)
+	return key, err
+}
+
+func (s *SQLiteStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	key, err := scanSQLiteAPIKey(s.q.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = ?`, prefix))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("API key '%s' não encontrada", prefix)
+		}
+		return nil, fmt.Errorf("falha ao buscar API key: %w", err)
+	}
+	return key, nil
+}
+
+func (s *SQLiteStore) GetAPIKeysByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error) {
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = ? ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre as API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *SQLiteStore) RevokeAPIKey(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	res, err := s.q.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`,
+		sqliteTime(revokedAt), id,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao revogar API key: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("API key '%s' não encontrada", id)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	_, err := s.q.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, sqliteTime(usedAt), id)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar uso da API key: %w", err)
+	}
+	return nil
+}
+
+// --- IdentityStore ---
+
+func (s *SQLiteStore) CreateIdentity(ctx context.Context, identity *models.Identity) error {
+	_, err := s.q.ExecContext(ctx, `
+        INSERT INTO user_identities (issuer, subject, user_id, email, created_at)
+        VALUES (?, ?, ?, ?, ?)`,
+		identity.Issuer,
+		identity.Subject,
+		identity.UserID,
+		identity.Email,
+		sqliteTime(identity.CreatedAt),
+	)
+	if err != nil {
+		switch sqliteConstraint(err) {
+		case sqliteUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "identidade '%s' já vinculada", identity.Subject)
+		case sqliteForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao vincular identidade: usuário '%s' inexistente", identity.UserID)
+		}
+		return fmt.Errorf("falha ao vincular identidade: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	query := `
+        SELECT u.id, u.username, u.password_hash, u.public_key, u.public_key_sign, u.created_at, u.sessions_valid_after, u.kind, u.owner_id, u.email, u.email_verified_at, u.locale
+        FROM user_identities i
+        JOIN users u ON u.id = i.user_id
+        WHERE i.issuer = ? AND i.subject = ?`
+
+	user, err := scanSQLiteUser(s.q.QueryRowContext(ctx, query, issuer, subject))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("identidade '%s' não encontrada", subject)
+		}
+		return nil, fmt.Errorf("falha ao buscar usuário por identidade: %w", err)
+	}
+	return user, nil
+}
+
+// --- KeyBackupStore ---
+
+// PutKeyBackup cria ou substitui o backup de chaves do usuário
+func (s *SQLiteStore) PutKeyBackup(ctx context.Context, backup *models.KeyBackup) error {
+	_, err := s.q.ExecContext(ctx, `
+        INSERT INTO key_backups (user_id, kdf, kdf_salt, kdf_iterations, kdf_memory_kib, kdf_parallelism, cipher, nonce, ciphertext, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT (user_id) DO UPDATE SET
+            kdf = excluded.kdf,
+            kdf_salt = excluded.kdf_salt,
+            kdf_iterations = excluded.kdf_iterations,
+            kdf_memory_kib = excluded.kdf_memory_kib,
+            kdf_parallelism = excluded.kdf_parallelism,
+            cipher = excluded.cipher,
+            nonce = excluded.nonce,
+            ciphertext = excluded.ciphertext,
+            updated_at = excluded.updated_at`,
+		backup.UserID,
+		backup.KDF,
+		backup.KDFSalt,
+		backup.KDFIterations,
+		backup.KDFMemoryKiB,
+		backup.KDFParallelism,
+		backup.Cipher,
+		backup.Nonce,
+		backup.Ciphertext,
+		sqliteTime(backup.UpdatedAt),
+	)
+	if err != nil {
+		if sqliteConstraint(err) == sqliteForeignKeyViolation {
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao salvar backup de chaves: usuário '%s' inexistente", backup.UserID)
+		}
+		return fmt.Errorf("falha ao salvar backup de chaves: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetKeyBackup(ctx context.Context, userID uuid.UUID) (*models.KeyBackup, error) {
+	backup := &models.KeyBackup{}
+	err := s.q.QueryRowContext(ctx, `
+        SELECT user_id, kdf, kdf_salt, kdf_iterations, kdf_memory_kib, kdf_parallelism, cipher, nonce, ciphertext, updated_at
+        FROM key_backups
+        WHERE user_id = ?`,
+		userID,
+	).Scan(
+		&backup.UserID,
+		&backup.KDF,
+		&backup.KDFSalt,
+		&backup.KDFIterations,
+		&backup.KDFMemoryKiB,
+		&backup.KDFParallelism,
+		&backup.Cipher,
+		&backup.Nonce,
+		&backup.Ciphertext,
+		scanTime(&backup.UpdatedAt),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("backup de chaves do usuário '%s' não encontrado", userID)
+		}
+		return nil, fmt.Errorf("falha ao buscar backup de chaves: %w", err)
+	}
+	return backup, nil
+}
+
+// --- JobStore ---
+
+func (s *SQLiteStore) EnqueueJob(ctx context.Context, job *models.Job) error {
+	_, err := s.q.ExecContext(ctx, `
+        INSERT INTO jobs (id, kind, payload, attempts, run_at, last_error, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		job.ID,
+		job.Kind,
+		string(job.Payload),
+		job.Attempts,
+		sqliteTime(job.RunAt),
+		job.LastError,
+		sqliteTime(job.CreatedAt),
+	)
+	if err != nil {
+		if sqliteConstraint(err) == sqliteUniqueViolation {
+			return apperr.Wrap(apperr.ErrConflict, err, "job '%s' já existe", job.ID)
+		}
+		return fmt.Errorf("falha ao enfileirar job: %w", err)
+	}
+	return nil
+}
+
+const sqliteJobColumns = `id, kind, payload, attempts, run_at, last_error, created_at, failed_at`
+
+func querySQLiteJobs(ctx context.Context, q sqliteQuerier, query string, args ...any) ([]*models.Job, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*models.Job{}
+	for rows.Next() {
+		job := &models.Job{}
+		var payload string
+		err := rows.Scan(
+			&job.ID,
+			&job.Kind,
+			&payload,
+			&job.Attempts,
+			scanTime(&job.RunAt),
+			&job.LastError,
+			scanTime(&job.CreatedAt),
+			scanNullTime(&job.FailedAt),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de job: %w", err)
+		}
+		job.Payload = []byte(payload)
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// ClaimJobs não precisa de SKIP LOCKED: o SQLite tem um único escritor,
+// e a leitura e a atualização ficam na mesma transação
+func (s *SQLiteStore) ClaimJobs(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.Job, error) {
+	var jobs []*models.Job
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		jobs, err = querySQLiteJobs(ctx, tx, `
+            SELECT `+sqliteJobColumns+`
+            FROM jobs
+            WHERE failed_at IS NULL AND run_at <= ?
+            ORDER BY run_at, created_at
+            LIMIT ?`,
+			sqliteTime(now), limit,
+		)
+		if err != nil {
+			return fmt.Errorf("falha ao reservar jobs: %w", err)
+		}
+
+		leaseUntil := now.Add(lease)
+		for _, job := range jobs {
+			_, err := tx.ExecContext(ctx,
+				`UPDATE jobs SET run_at = ?, attempts = attempts + 1 WHERE id = ?`,
+				sqliteTime(leaseUntil), job.ID,
+			)
+			if err != nil {
+				return fmt.Errorf("falha ao reservar job: %w", err)
+			}
+			job.Attempts++
+			job.RunAt = leaseUntil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+func (s *SQLiteStore) CompleteJob(ctx context.Context, id uuid.UUID) error {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM jobs WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("falha ao concluir job: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("job '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) RetryJob(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error {
+	res, err := s.q.ExecContext(ctx,
+		`UPDATE jobs SET run_at = ?, last_error = ? WHERE id = ? AND failed_at IS NULL`,
+		sqliteTime(runAt), lastError, id,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao reagendar job: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("job '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) FailJob(ctx context.Context, id uuid.UUID, failedAt time.Time, lastError string) error {
+	res, err := s.q.ExecContext(ctx,
+		`UPDATE jobs SET failed_at = ?, last_error = ? WHERE id = ? AND failed_at IS NULL`,
+		sqliteTime(failedAt), lastError, id,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao mover job para a dead letter: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("job '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetDeadJobs(ctx context.Context, limit int) ([]*models.Job, error) {
+	jobs, err := querySQLiteJobs(ctx, s.q,
+		`SELECT `+sqliteJobColumns+` FROM jobs WHERE failed_at IS NOT NULL ORDER BY failed_at DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar jobs na dead letter: %w", err)
+	}
+	return jobs, nil
+}
+
+func (s *SQLiteStore) PruneDeadJobs(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM jobs WHERE failed_at < ?`, sqliteTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("falha ao podar a dead letter: %w", err)
+	}
+	return int(rowsAffected(res)), nil
+}
+
+func (s *SQLiteStore) CountJobs(ctx context.Context) ([]models.JobCount, error) {
+	rows, err := s.q.QueryContext(ctx, `
+        SELECT kind,
+               SUM(CASE WHEN failed_at IS NULL THEN 1 ELSE 0 END),
+               SUM(CASE WHEN failed_at IS NOT NULL THEN 1 ELSE 0 END)
+        FROM jobs
+        GROUP BY kind
+        ORDER BY kind`)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao contar jobs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []models.JobCount{}
+	for rows.Next() {
+		var c models.JobCount
+		if err := rows.Scan(&c.Kind, &c.Queued, &c.Dead); err != nil {
+			return nil, fmt.Errorf("falha ao contar jobs: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("falha ao contar jobs: %w", err)
+	}
+	return counts, nil
+}
+
+func (s *SQLiteStore) AdvanceSchedule(ctx context.Context, name string, now, next time.Time) (bool, error) {
+	res, err := s.q.ExecContext(ctx,
+		`INSERT INTO job_schedules (name, next_run_at) VALUES (?, ?) ON CONFLICT (name) DO NOTHING`,
+		name, sqliteTime(next),
+	)
+	if err != nil {
+		return false, fmt.Errorf("falha ao registrar agendamento: %w", err)
+	}
+	if rowsAffected(res) == 1 {
+		return false, nil
+	}
+
+	res, err = s.q.ExecContext(ctx,
+		`UPDATE job_schedules SET next_run_at = ? WHERE name = ? AND next_run_at <= ?`,
+		sqliteTime(next), name, sqliteTime(now),
+	)
+	if err != nil {
+		return false, fmt.Errorf("falha ao avançar agendamento: %w", err)
+	}
+	return rowsAffected(res) == 1, nil
+}
+
+// --- UploadStore ---
+
+func (s *SQLiteStore) ReserveUpload(ctx context.Context, reservation *models.UploadReservation) error {
+	_, err := s.q.ExecContext(ctx, `
+        INSERT INTO upload_reservations (object_key, user_id, size_bytes, checksum_sha256, created_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?)`,
+		reservation.ObjectKey,
+		reservation.UserID,
+		reservation.Size,
+		reservation.ChecksumSHA256,
+		sqliteTime(reservation.CreatedAt),
+		sqliteTime(reservation.ExpiresAt),
+	)
+	if err != nil {
+		switch sqliteConstraint(err) {
+		case sqliteUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "chave '%s' já reservada", reservation.ObjectKey)
+		case sqliteForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao reservar upload: usuário inexistente")
+		}
+		return fmt.Errorf("falha ao reservar upload: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetUploadReservation(ctx context.Context, objectKey string) (*models.UploadReservation, error) {
+	reservation := &models.UploadReservation{ObjectKey: objectKey}
+	err := s.q.QueryRowContext(ctx, `
+        SELECT user_id, size_bytes, checksum_sha256, created_at, expires_at
+        FROM upload_reservations WHERE object_key = ?`,
+		objectKey,
+	).Scan(
+		&reservation.UserID,
+		&reservation.Size,
+		&reservation.ChecksumSHA256,
+		scanTime(&reservation.CreatedAt),
+		scanTime(&reservation.ExpiresAt),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("reserva de upload '%s' não encontrada", objectKey)
+		}
+		return nil, fmt.Errorf("falha ao buscar reserva de upload: %w", err)
+	}
+	return reservation, nil
+}
+
+// ReferencedKeys passa as chaves como um array JSON (o SQLite não tem
+// parâmetros do tipo array)
+func (s *SQLiteStore) ReferencedKeys(ctx context.Context, keys []string, now time.Time) (map[string]bool, error) {
+	rows, err := s.q.QueryContext(ctx, `
+        SELECT link_to_enc_file FROM transfers
+        WHERE link_to_enc_file IN (SELECT value FROM json_each(?1))
+        UNION
+        SELECT object_key FROM upload_reservations
+        WHERE object_key IN (SELECT value FROM json_each(?1)) AND expires_at > ?2`,
+		stringList(keys), sqliteTime(now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar referências de objetos: %w", err)
+	}
+	defer rows.Close()
+
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("falha ao escanear referência de objeto: %w", err)
+		}
+		referenced[key] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre as referências de objetos: %w", err)
+	}
+	return referenced, nil
+}
+
+func (s *SQLiteStore) PruneUploadReservations(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM upload_reservations WHERE expires_at < ?`, sqliteTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("falha ao podar reservas de upload: %w", err)
+	}
+	return int(rowsAffected(res)), nil
+}
+
+func (s *SQLiteStore) ReleaseUpload(ctx context.Context, objectKey string) error {
+	if _, err := s.q.ExecContext(ctx, `DELETE FROM upload_reservations WHERE object_key = ?`, objectKey); err != nil {
+		return fmt.Errorf("falha ao liberar reserva de upload: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetStorageUsage(ctx context.Context, userIDs []uuid.UUID, now time.Time) (*models.StorageUsage, error) {
+	ids := make(stringList, 0, len(userIDs))
+	for _, id := range userIDs {
+		ids = append(ids, id.String())
+	}
+
+	// Um objeto enviado a vários destinatários conta uma vez só
+	usage := &models.StorageUsage{}
+	err := s.q.QueryRowContext(ctx, `
+        SELECT
+            (SELECT COALESCE(SUM(size), 0) FROM (
+                SELECT MAX(size_bytes) AS size FROM transfers
+                WHERE source_user_id IN (SELECT value FROM json_each(?1))
+                GROUP BY link_to_enc_file
+            )),
+            (SELECT COALESCE(SUM(size_bytes), 0) FROM upload_reservations
+             WHERE user_id IN (SELECT value FROM json_each(?1)) AND expires_at > ?2)`,
+		ids, sqliteTime(now),
+	).Scan(&usage.UsedBytes, &usage.ReservedBytes)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao calcular uso de armazenamento: %w", err)
+	}
+	return usage, nil
+}
+
+// --- AuditStore ---
+
+const sqliteAuditColumns = `seq, type, actor_id, actor, ip, user_agent, target, details, created_at, prev_hash, hash`
+
+func (s *SQLiteStore) AppendAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	// A conexão única já serializa as transações: ninguém insere entre a
+	// leitura do último hash e a gravação
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		var lastSeq int64
+		var lastHash string
+		err := tx.QueryRowContext(ctx, `SELECT seq, hash FROM audit_events ORDER BY seq DESC LIMIT 1`).Scan(&lastSeq, &lastHash)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("falha ao ler o fim do log de auditoria: %w", err)
+		}
+
+		event.Seq = lastSeq + 1
+		event.PrevHash = lastHash
+		event.Hash = event.ComputeHash()
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO audit_events (`+sqliteAuditColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			event.Seq,
+			event.Type,
+			event.ActorID,
+			event.Actor,
+			event.IP,
+			event.UserAgent,
+			event.Target,
+			stringMap(event.Details),
+			sqliteTime(event.CreatedAt),
+			event.PrevHash,
+			event.Hash,
+		)
+		if err != nil {
+			return fmt.Errorf("falha ao gravar evento de auditoria: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) ListAuditEvents(ctx context.Context, filter AuditFilter) ([]*models.AuditEvent, error) {
+	where := []string{"seq > ?"}
+	args := []any{filter.AfterSeq}
+	if filter.BeforeSeq > 0 {
+		where = append(where, "seq < ?")
+		args = append(args, filter.BeforeSeq)
+	}
+	if filter.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, filter.Type)
+	}
+	if filter.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Target != "" {
+		where = append(where, "target = ?")
+		args = append(args, filter.Target)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, sqliteTime(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, sqliteTime(filter.Until))
+	}
+
+	query := `SELECT ` + sqliteAuditColumns + ` FROM audit_events WHERE ` + strings.Join(where, " AND ")
+	if filter.Descending {
+		query += ` ORDER BY seq DESC`
+	} else {
+		query += ` ORDER BY seq`
+	}
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar eventos de auditoria: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		e := &models.AuditEvent{}
+		err := rows.Scan(&e.Seq, &e.Type, &e.ActorID, &e.Actor, &e.IP, &e.UserAgent, &e.Target,
+			scanStringMap(&e.Details), scanTime(&e.CreatedAt), &e.PrevHash, &e.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear evento de auditoria: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os eventos de auditoria: %w", err)
+	}
+	return events, nil
+}
+
+// --- EventStore ---
+
+func (s *SQLiteStore) AppendEvent(ctx context.Context, event *models.InboxEvent) error {
+	// Com a conexão única, os IDs já seguem a ordem de commit
+	res, err := s.q.ExecContext(ctx, `
+        INSERT INTO inbox_events (user_id, type, data, created_at)
+        VALUES (?, ?, ?, ?)`,
+		event.UserID,
+		event.Type,
+		string(event.Data),
+		sqliteTime(event.CreatedAt),
+	)
+	if err != nil {
+		if sqliteConstraint(err) == sqliteForeignKeyViolation {
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao gravar evento: usuário '%s' inexistente", event.UserID)
+		}
+		return fmt.Errorf("falha ao gravar evento: %w", err)
+	}
+	if event.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("falha ao obter o ID do evento: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetEventsAfter(ctx context.Context, userID uuid.UUID, afterID int64, limit int) ([]*models.InboxEvent, error) {
+	query := `SELECT id, user_id, type, data, created_at FROM inbox_events WHERE id > ?`
+	args := []any{afterID}
+	if userID != uuid.Nil {
+		query += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY id`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar eventos: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.InboxEvent{}
+	for rows.Next() {
+		e := &models.InboxEvent{}
+		var data string
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Type, &data, scanTime(&e.CreatedAt)); err != nil {
+			return nil, fmt.Errorf("falha ao escanear evento: %w", err)
+		}
+		e.Data = json.RawMessage(data)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os eventos: %w", err)
+	}
+	return events, nil
+}
+
+func (s *SQLiteStore) LatestEventID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := s.q.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM inbox_events`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("falha ao buscar o último evento: %w", err)
+	}
+	return id, nil
+}
+
+func (s *SQLiteStore) PruneEvents(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM inbox_events WHERE created_at < ?`, sqliteTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("falha ao podar eventos: %w", err)
+	}
+	return int(rowsAffected(res)), nil
+}
+
+// --- WebhookStore ---
+
+func scanSQLiteWebhook(row rowScanner) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&webhook.Secret,
+		scanStringList(&webhook.EventTypes),
+		scanTime(&webhook.CreatedAt),
+		&webhook.CreatedBy,
+	)
+	return webhook, err
+}
+
+func (s *SQLiteStore) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	_, err := s.q.ExecContext(ctx, `INSERT INTO webhooks (`+webhookColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		webhook.ID,
+		webhook.UserID,
+		webhook.URL,
+		webhook.Secret,
+		stringList(webhook.EventTypes),
+		sqliteTime(webhook.CreatedAt),
+		webhook.CreatedBy,
+	)
+	if err != nil {
+		switch sqliteConstraint(err) {
+		case sqliteUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "falha ao criar webhook: ID '%s' já existe", webhook.ID)
+		case sqliteForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao criar webhook: usuário '%s' inexistente", webhook.UserID)
+		}
+		return fmt.Errorf("falha ao criar webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	webhook, err := scanSQLiteWebhook(s.q.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("webhook '%s' não encontrado", id)
+		}
+		return nil, fmt.Errorf("falha ao buscar webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (s *SQLiteStore) GetWebhooksByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Webhook, error) {
+	rows, err := s.q.QueryContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanSQLiteWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre os webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (s *SQLiteStore) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("falha ao remover webhook: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("webhook '%s' não encontrado", id)
+	}
+	return nil
+}
+
+func scanSQLiteWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	var payload string
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventType,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		scanTime(&delivery.CreatedAt),
+		scanTime(&delivery.UpdatedAt),
+		&delivery.RedeliveryOf,
+	)
+	delivery.Payload = json.RawMessage(payload)
+	return delivery, err
+}
+
+func (s *SQLiteStore) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	_, err := s.q.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (`+webhookDeliveryColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		delivery.ID,
+		delivery.WebhookID,
+		delivery.EventType,
+		string(delivery.Payload),
+		delivery.Status,
+		delivery.Attempts,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		sqliteTime(delivery.CreatedAt),
+		sqliteTime(delivery.UpdatedAt),
+		delivery.RedeliveryOf,
+	)
+	if err != nil {
+		switch sqliteConstraint(err) {
+		case sqliteUniqueViolation:
+			return apperr.Wrap(apperr.ErrConflict, err, "falha ao registrar entrega: ID '%s' já existe", delivery.ID)
+		case sqliteForeignKeyViolation:
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao registrar entrega: webhook '%s' inexistente", delivery.WebhookID)
+		}
+		return fmt.Errorf("falha ao registrar entrega: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetWebhookDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := scanSQLiteWebhookDelivery(s.q.QueryRowContext(ctx,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("entrega '%s' não encontrada", id)
+		}
+		return nil, fmt.Errorf("falha ao buscar entrega: %w", err)
+	}
+	return delivery, nil
+}
+
+func (s *SQLiteStore) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	res, err := s.q.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = ?, attempts = ?, last_status_code = ?, last_error = ?, updated_at = ?
+        WHERE id = ?`,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		sqliteTime(delivery.UpdatedAt),
+		delivery.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar entrega: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("entrega '%s' não encontrada", delivery.ID)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = ? ORDER BY created_at DESC`
+	args := []any{webhookID}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar entregas: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanSQLiteWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear linha de entrega: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar sobre as entregas: %w", err)
+	}
+	return deliveries, nil
+}
+
+// --- IdempotencyStore ---
+
+func (s *SQLiteStore) CreateIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord, now time.Time) error {
+	// Como no PostgreSQL: só um registro expirado é sobrescrito
+	res, err := s.q.ExecContext(ctx, `
+        INSERT INTO idempotency_keys (user_id, idem_key, fingerprint, status_code, content_type, body, created_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT (user_id, idem_key) DO UPDATE
+        SET fingerprint = excluded.fingerprint, status_code = excluded.status_code,
+            content_type = excluded.content_type, body = excluded.body,
+            created_at = excluded.created_at, expires_at = excluded.expires_at
+        WHERE idempotency_keys.expires_at <= ?`,
+		record.UserID,
+		record.Key,
+		record.Fingerprint,
+		record.StatusCode,
+		record.ContentType,
+		record.Body,
+		sqliteTime(record.CreatedAt),
+		sqliteTime(record.ExpiresAt),
+		sqliteTime(now),
+	)
+	if err != nil {
+		if sqliteConstraint(err) == sqliteForeignKeyViolation {
+			return apperr.Wrap(apperr.ErrNotFound, err, "falha ao registrar chave de idempotência: usuário '%s' inexistente", record.UserID)
+		}
+		return fmt.Errorf("falha ao registrar chave de idempotência: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.Conflict("chave de idempotência '%s' já utilizada", record.Key)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetIdempotencyRecord(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	record := &models.IdempotencyRecord{UserID: userID, Key: key}
+	err := s.q.QueryRowContext(ctx, `
+        SELECT fingerprint, status_code, content_type, body, created_at, expires_at
+        FROM idempotency_keys WHERE user_id = ? AND idem_key = ?`,
+		userID, key,
+	).Scan(
+		&record.Fingerprint,
+		&record.StatusCode,
+		&record.ContentType,
+		&record.Body,
+		scanTime(&record.CreatedAt),
+		scanTime(&record.ExpiresAt),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("chave de idempotência '%s' não encontrada", key)
+		}
+		return nil, fmt.Errorf("falha ao buscar chave de idempotência: %w", err)
+	}
+	return record, nil
+}
+
+func (s *SQLiteStore) CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	res, err := s.q.ExecContext(ctx, `
+        UPDATE idempotency_keys SET status_code = ?, content_type = ?, body = ?, expires_at = ?
+        WHERE user_id = ? AND idem_key = ? AND created_at = ?`,
+		record.StatusCode,
+		record.ContentType,
+		record.Body,
+		sqliteTime(record.ExpiresAt),
+		record.UserID,
+		record.Key,
+		sqliteTime(record.CreatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao gravar resposta idempotente: %w", err)
+	}
+	if rowsAffected(res) == 0 {
+		return apperr.NotFound("chave de idempotência '%s' não encontrada", record.Key)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	_, err := s.q.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE user_id = ? AND idem_key = ? AND created_at = ?`,
+		record.UserID, record.Key, sqliteTime(record.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("falha ao liberar chave de idempotência: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) PruneIdempotencyRecords(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < ?`, sqliteTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("falha ao podar chaves de idempotência: %w", err)
+	}
+	return int(rowsAffected(res)), nil
+}
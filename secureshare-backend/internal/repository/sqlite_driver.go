@@ -0,0 +1,5 @@
+package repository
+
+// Registra o driver "sqlite" (modernc.org/sqlite, Go puro, sem cgo), linkado
+// sempre: assim qualquer binário aceita DATABASE_URL=sqlite://
+import _ "modernc.org/sqlite"
@@ -0,0 +1,62 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"secureshare-backend/internal/migrate"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/repository/storetest"
+	"secureshare-backend/migrations"
+)
+
+// TestSQLiteStoreConformance roda a suíte contra um arquivo novo por
+// subteste
+func TestSQLiteStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) repository.Store {
+		return newSQLiteStore(t)
+	})
+}
+
+func TestSQLiteStoreMigrationsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteStore(t)
+
+	migrator, err := migrate.NewSQLite(store.DB(), migrations.SQLiteFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range migrator.Latest() {
+		if err := migrator.Down(ctx); err != nil {
+			t.Fatalf("Down: %v", err)
+		}
+	}
+	if pending, err := migrator.Pending(ctx); err != nil || pending != int(migrator.Latest()) {
+		t.Fatalf("após reverter tudo: pending=%d err=%v", pending, err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up após reverter tudo: %v", err)
+	}
+}
+
+func newSQLiteStore(t *testing.T) *repository.SQLiteStore {
+	t.Helper()
+	ctx := context.Background()
+
+	url := "sqlite://" + filepath.Join(t.TempDir(), "secureshare.db")
+	store, err := repository.NewSQLiteStore(ctx, url)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(store.Close)
+
+	migrator, err := migrate.NewSQLite(store.DB(), migrations.SQLiteFS)
+	if err != nil {
+		t.Fatalf("migrate.NewSQLite: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrações: %v", err)
+	}
+	return store
+}
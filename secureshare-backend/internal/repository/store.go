@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"secureshare-backend/internal/models"
 
@@ -14,12 +15,196 @@ type UserStore interface {
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
 	GetAllUsers(ctx context.Context) ([]*models.User, error)
+	UpdateUserPassword(ctx context.Context, id uuid.UUID, passwordHash string, sessionsValidAfter time.Time) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	// LockUser trava o usuário até o fim da transação corrente (WithTx),
+	// serializando operações que leem e depois gravam dados dele (ex: a
+	// revogação do último dispositivo). Usuário inexistente retorna
+	// ErrNotFound.
+	LockUser(ctx context.Context, id uuid.UUID) error
+	GetServiceAccountsByOwner(ctx context.Context, ownerID uuid.UUID) ([]*models.User, error)
+	// UpdateUserPublicKeys atualiza as chaves "da conta" (as do dispositivo
+	// ativo mais antigo), usadas por clientes sem suporte a dispositivos
+	UpdateUserPublicKeys(ctx context.Context, id uuid.UUID, publicKey, publicKeySign string) error
+	// UpdateUserEmail troca o e-mail (vazio remove) e o instante da
+	// verificação (nil: não verificado)
+	UpdateUserEmail(ctx context.Context, id uuid.UUID, email string, verifiedAt *time.Time) error
+	UpdateUserLocale(ctx context.Context, id uuid.UUID, locale string) error
+}
+
+// IdentityStore define a interface para identidades externas (SSO)
+type IdentityStore interface {
+	CreateIdentity(ctx context.Context, identity *models.Identity) error
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error)
 }
 
 // TransferStore define a interface para operações de transferência no DB
 type TransferStore interface {
 	CreateTransfer(ctx context.Context, transfer *models.Transfer) error
 	GetTransfersByDestUserID(ctx context.Context, destUserID uuid.UUID) ([]*models.Transfer, error)
+	// GetTransfersBySourceUserID lista as transferências enviadas, mais
+	// recentes primeiro
+	GetTransfersBySourceUserID(ctx context.Context, sourceUserID uuid.UUID) ([]*models.Transfer, error)
+}
+
+// DeviceStore define a interface para operações de dispositivos no DB
+type DeviceStore interface {
+	CreateDevice(ctx context.Context, device *models.Device) error
+	GetDeviceByID(ctx context.Context, id uuid.UUID) (*models.Device, error)
+	// GetDevicesByUserID retorna todos os dispositivos (inclusive revogados),
+	// do mais antigo para o mais novo
+	GetDevicesByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Device, error)
+	// RevokeDevice marca o dispositivo como revogado e descarta as SKBs
+	// cifradas para ele
+	RevokeDevice(ctx context.Context, id uuid.UUID, revokedAt time.Time) error
+}
+
+// APIKeyStore define a interface para as API keys de contas de serviço
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	GetAPIKeysByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id uuid.UUID, revokedAt time.Time) error
+	// TouchAPIKey registra o último uso da chave
+	TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+// KeyBackupStore define a interface para o backup cifrado de chaves privadas
+type KeyBackupStore interface {
+	PutKeyBackup(ctx context.Context, backup *models.KeyBackup) error
+	GetKeyBackup(ctx context.Context, userID uuid.UUID) (*models.KeyBackup, error)
+}
+
+// JobStore define a interface da fila persistente de jobs (ver internal/jobs)
+type JobStore interface {
+	EnqueueJob(ctx context.Context, job *models.Job) error
+	// ClaimJobs reserva até limit jobs vivos com RunAt <= now, mais antigos
+	// primeiro. Os jobs reservados ficam invisíveis até now+lease (se o
+	// worker morrer, voltam à fila) e têm Attempts incrementado.
+	ClaimJobs(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.Job, error)
+	// CompleteJob remove o job executado com sucesso
+	CompleteJob(ctx context.Context, id uuid.UUID) error
+	// RetryJob registra a falha e reagenda o job para runAt
+	RetryJob(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error
+	// FailJob move o job para a dead letter (FailedAt = failedAt)
+	FailJob(ctx context.Context, id uuid.UUID, failedAt time.Time, lastError string) error
+	// GetDeadJobs lista os jobs na dead letter, mais recentes primeiro
+	GetDeadJobs(ctx context.Context, limit int) ([]*models.Job, error)
+	// PruneDeadJobs apaga os jobs na dead letter há mais tempo que before
+	PruneDeadJobs(ctx context.Context, before time.Time) (int, error)
+	// CountJobs conta os jobs vivos e mortos de cada tipo, ordenado por tipo
+	CountJobs(ctx context.Context) ([]models.JobCount, error)
+	// AdvanceSchedule decide se o agendamento name dispara em now. Na
+	// primeira chamada só registra next e retorna false; depois, se o
+	// horário registrado já passou, troca-o por next e retorna true. Só uma
+	// réplica vence para cada disparo.
+	AdvanceSchedule(ctx context.Context, name string, now, next time.Time) (bool, error)
+}
+
+// UploadStore guarda as reservas de upload e responde quais objetos do
+// bucket ainda estão em uso (usado pelo coletor de órfãos)
+type UploadStore interface {
+	ReserveUpload(ctx context.Context, reservation *models.UploadReservation) error
+	GetUploadReservation(ctx context.Context, objectKey string) (*models.UploadReservation, error)
+	// ReleaseUpload apaga a reserva da chave (se existir), quando o objeto
+	// passa a ser referenciado por uma transferência
+	ReleaseUpload(ctx context.Context, objectKey string) error
+	// GetStorageUsage soma o espaço dos usuários: objetos das transferências
+	// enviadas e reservas não expiradas em now
+	GetStorageUsage(ctx context.Context, userIDs []uuid.UUID, now time.Time) (*models.StorageUsage, error)
+	// ReferencedKeys retorna, dentre keys, as que são apontadas por uma
+	// transferência ou por uma reserva ainda não expirada em now
+	ReferencedKeys(ctx context.Context, keys []string, now time.Time) (map[string]bool, error)
+	// PruneUploadReservations apaga as reservas expiradas antes de before
+	PruneUploadReservations(ctx context.Context, before time.Time) (int, error)
+}
+
+// AuditFilter seleciona eventos de auditoria. Campos vazios não filtram.
+type AuditFilter struct {
+	Type   string
+	Actor  string // username
+	Target string
+	Since  time.Time // inclusive
+	Until  time.Time // exclusive
+	// Paginação por Seq: só eventos com Seq > AfterSeq e, se BeforeSeq > 0,
+	// com Seq < BeforeSeq
+	AfterSeq  int64
+	BeforeSeq int64
+	// Descending lista os mais recentes primeiro (o padrão é a ordem da cadeia)
+	Descending bool
+	Limit      int
+}
+
+// AuditStore guarda o log de auditoria, que só aceita inserções
+type AuditStore interface {
+	// AppendAuditEvent encadeia o evento ao último gravado: preenche Seq,
+	// PrevHash e Hash. Inserções concorrentes são serializadas.
+	AppendAuditEvent(ctx context.Context, event *models.AuditEvent) error
+	ListAuditEvents(ctx context.Context, filter AuditFilter) ([]*models.AuditEvent, error)
+}
+
+// EventStore guarda os eventos da caixa de entrada (ver GET /events)
+type EventStore interface {
+	// AppendEvent grava o evento e preenche ID. Chamado dentro de WithTx, o
+	// evento só fica visível (e só é notificado) se a transação for
+	// confirmada.
+	AppendEvent(ctx context.Context, event *models.InboxEvent) error
+	// GetEventsAfter lista até limit eventos do usuário com ID > afterID, em
+	// ordem de ID. Com userID == uuid.Nil, lista os de todos os usuários.
+	GetEventsAfter(ctx context.Context, userID uuid.UUID, afterID int64, limit int) ([]*models.InboxEvent, error)
+	// LatestEventID retorna o maior ID gravado (0 se não houver eventos)
+	LatestEventID(ctx context.Context) (int64, error)
+	// PruneEvents apaga os eventos criados antes de before
+	PruneEvents(ctx context.Context, before time.Time) (int, error)
+}
+
+// EventListener é implementado por stores que avisam os eventos gravados
+// por qualquer processo (o PostgreSQL, via LISTEN/NOTIFY). Nos demais, quem
+// distribui os eventos consulta GetEventsAfter periodicamente.
+type EventListener interface {
+	// ListenEvents chama fn para cada evento confirmado, em ordem de commit,
+	// até ctx ser cancelado ou a conexão cair (retorna o erro)
+	ListenEvents(ctx context.Context, fn func(event *models.InboxEvent)) error
+}
+
+// WebhookStore guarda os webhooks de saída e o log das entregas
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error)
+	// GetWebhooksByUserID lista os webhooks do usuário, mais recentes primeiro
+	GetWebhooksByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Webhook, error)
+	// DeleteWebhook remove o webhook e o log das suas entregas
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+
+	CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetWebhookDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error)
+	// UpdateWebhookDelivery grava o resultado de uma tentativa: Status,
+	// Attempts, LastStatusCode, LastError e UpdatedAt
+	UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// ListWebhookDeliveries lista até limit entregas do webhook, mais
+	// recentes primeiro
+	ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*models.WebhookDelivery, error)
+}
+
+// IdempotencyStore guarda as respostas das requisições com Idempotency-Key
+// (uma por usuário e chave)
+type IdempotencyStore interface {
+	// CreateIdempotencyRecord reserva a chave antes de a requisição ser
+	// executada (StatusCode 0). Se já houver um registro da chave ainda não
+	// expirado em now, retorna apperr.Conflict; um expirado (inclusive uma
+	// reserva cujo prazo venceu) é substituído.
+	CreateIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord, now time.Time) error
+	GetIdempotencyRecord(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error)
+	// CompleteIdempotencyRecord grava a resposta (StatusCode, ContentType e
+	// Body) e o novo ExpiresAt da reserva feita com record.CreatedAt. Se a
+	// reserva tiver sido substituída ou liberada, retorna apperr.NotFound.
+	CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error
+	// DeleteIdempotencyRecord libera a reserva feita com record.CreatedAt (se
+	// ainda existir), para que a próxima tentativa execute a requisição de
+	// novo
+	DeleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error
+	// PruneIdempotencyRecords apaga os registros expirados antes de before
+	PruneIdempotencyRecords(ctx context.Context, before time.Time) (int, error)
 }
 
 // Store é uma interface agregada para todas as operações de store
@@ -27,4 +212,21 @@ type TransferStore interface {
 type Store interface {
 	UserStore
 	TransferStore
+	DeviceStore
+	APIKeyStore
+	IdentityStore
+	KeyBackupStore
+	JobStore
+	UploadStore
+	AuditStore
+	EventStore
+	WebhookStore
+	IdempotencyStore
+
+	// WithTx executa fn em uma transação: se fn retornar erro, nada do que
+	// foi feito pelo Store recebido é persistido. fn deve usar apenas esse
+	// Store (usar o original dentro de fn fica fora da transação e, no
+	// SQLite e no InMemoryStore, bloqueia). Chamadas aninhadas viram
+	// savepoints.
+	WithTx(ctx context.Context, fn func(tx Store) error) error
 }
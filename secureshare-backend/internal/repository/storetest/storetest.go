@@ -0,0 +1,2130 @@
+// Package storetest é a suíte de conformidade compartilhada pelas
+// implementações de repository.Store.
+//
+// Cada backend chama Run com uma função que devolve um store vazio; a suíte
+// verifica unicidade, erros de "não encontrado", ordenação, cascatas e
+// acesso concorrente, para que os backends sejam intercambiáveis.
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Factory devolve um store vazio para um subteste. Os subtestes rodam em
+// sequência, então o backend pode reaproveitar a mesma conexão limpando os
+// dados a cada chamada.
+type Factory func(t *testing.T) repository.Store
+
+// Run executa a suíte completa contra o store devolvido por newStore
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s repository.Store)
+	}{
+		{"Users/CreateAndGet", testCreateAndGetUser},
+		{"Users/DuplicateUsername", testDuplicateUsername},
+		{"Users/NotFound", testUserNotFound},
+		{"Users/GetAllUsersOrdered", testGetAllUsersOrdered},
+		{"Users/UpdatePassword", testUpdateUserPassword},
+		{"Users/UpdatePublicKeys", testUpdateUserPublicKeys},
+		{"Users/EmailAndLocale", testUserEmailAndLocale},
+		{"Users/ReturnedValuesAreCopies", testReturnedUsersAreCopies},
+		{"Users/ServiceAccountsByOwner", testServiceAccountsByOwner},
+		{"Users/DeleteCascades", testDeleteUserCascades},
+		{"Users/LockSerializesTx", testLockUser},
+		{"Transfers/CreateAndListNewestFirst", testTransfersNewestFirst},
+		{"Transfers/EmptyListIsNotNil", testTransfersEmptyList},
+		{"Transfers/BySourceUser", testTransfersBySource},
+		{"Transfers/DeviceSKBs", testTransferDeviceSKBs},
+		{"Transfers/UnknownReferences", testTransferUnknownReferences},
+		{"Devices/CreateGetAndOrder", testDevicesOrdered},
+		{"Devices/NotFound", testDeviceNotFound},
+		{"Devices/RevokeDropsSKBs", testRevokeDeviceDropsSKBs},
+		{"APIKeys/CreateAndLookup", testAPIKeys},
+		{"APIKeys/DuplicatePrefix", testAPIKeyDuplicatePrefix},
+		{"APIKeys/RevokeAndTouch", testRevokeAndTouchAPIKey},
+		{"Identities/LinkAndResolve", testIdentities},
+		{"KeyBackups/PutGetReplace", testKeyBackups},
+		{"Tx/Commit", testTxCommit},
+		{"Tx/RollbackOnError", testTxRollback},
+		{"Tx/NestedRollbackKeepsOuter", testTxNestedRollback},
+		{"Jobs/ClaimRetryComplete", testJobLifecycle},
+		{"Jobs/DeadLetterAndPrune", testJobDeadLetter},
+		{"Jobs/ConcurrentClaimsAreDisjoint", testJobConcurrentClaims},
+		{"Jobs/Count", testJobCount},
+		{"Jobs/AdvanceSchedule", testAdvanceSchedule},
+		{"Uploads/ReferencedKeys", testUploadReferencedKeys},
+		{"Uploads/CascadeAndPrune", testUploadCascadeAndPrune},
+		{"Uploads/StorageUsage", testStorageUsage},
+		{"Audit/AppendChainsHashes", testAuditChain},
+		{"Audit/FiltersAndPagination", testAuditFilters},
+		{"Audit/ConcurrentAppendsKeepChain", testAuditConcurrentAppends},
+		{"Events/AppendAndListAfter", testEventsAppendAndList},
+		{"Events/RollbackDropsEvent", testEventsRollback},
+		{"Events/PruneAndCascade", testEventsPruneAndCascade},
+		{"Webhooks/CreateListDelete", testWebhooks},
+		{"Webhooks/DeliveryLog", testWebhookDeliveries},
+		{"Idempotency/ReserveCompleteRelease", testIdempotencyLifecycle},
+		{"Idempotency/ExpiryPruneAndCascade", testIdempotencyExpiry},
+		{"Idempotency/LeaseTakeover", testIdempotencyLeaseTakeover},
+		{"Concurrency/SameUsername", testConcurrentSameUsername},
+		{"Concurrency/DistinctUsers", testConcurrentDistinctUsers},
+		{"Concurrency/ReadsAndWrites", testConcurrentReadsAndWrites},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStore(t))
+		})
+	}
+}
+
+// --- Helpers ---
+
+// now retorna o instante atual na precisão suportada pelo PostgreSQL
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func newUser(username string) *models.User {
+	return &models.User{
+		ID:            uuid.New(),
+		Username:      username,
+		PasswordHash:  "hash-" + username,
+		PublicKey:     "pk-" + username,
+		PublicKeySign: "pks-" + username,
+		CreatedAt:     now(),
+		Kind:          models.UserKindHuman,
+	}
+}
+
+func mustCreateUser(t *testing.T, s repository.Store, username string) *models.User {
+	t.Helper()
+	user := newUser(username)
+	if err := s.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser(%s): %v", username, err)
+	}
+	return user
+}
+
+func mustCreateDevice(t *testing.T, s repository.Store, userID uuid.UUID, name string, createdAt time.Time) *models.Device {
+	t.Helper()
+	device := &models.Device{
+		ID:            uuid.New(),
+		UserID:        userID,
+		Name:          name,
+		PublicKey:     "pk-" + name,
+		PublicKeySign: "pks-" + name,
+		CreatedAt:     createdAt,
+	}
+	if err := s.CreateDevice(context.Background(), device); err != nil {
+		t.Fatalf("CreateDevice(%s): %v", name, err)
+	}
+	return device
+}
+
+func newTransfer(source, dest uuid.UUID, createdAt time.Time) *models.Transfer {
+	return &models.Transfer{
+		ID:            uuid.New(),
+		SourceUserID:  source,
+		DestUserID:    dest,
+		LinkToEncFile: "uploads/" + source.String() + "/" + uuid.NewString(),
+		SKB:           "skb",
+		Sig:           "sig",
+		CreatedAt:     createdAt,
+		Size:          100,
+		// SHA-256 de "" em base64
+		ChecksumSHA256: "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hXuFU=",
+	}
+}
+
+func newAPIKey(userID uuid.UUID, prefix string, createdAt time.Time) *models.APIKey {
+	return &models.APIKey{
+		ID:           uuid.New(),
+		UserID:       userID,
+		Name:         "key-" + prefix,
+		Prefix:       prefix,
+		Hash:         "hash-" + prefix,
+		Scopes:       []string{"transfers:read"},
+		AllowedCIDRs: []string{},
+		CreatedAt:    createdAt,
+	}
+}
+
+func newJob(kind string, runAt time.Time) *models.Job {
+	return &models.Job{
+		ID:        uuid.New(),
+		Kind:      kind,
+		Payload:   []byte(`{"n":1}`),
+		RunAt:     runAt,
+		CreatedAt: runAt,
+	}
+}
+
+// assertKind falha se err não for da categoria esperada (ver internal/apperr)
+func assertKind(t *testing.T, err, kind error, op string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("%s: esperava erro '%v', obteve nil", op, kind)
+	}
+	if !errors.Is(err, kind) {
+		t.Fatalf("%s: esperava erro '%v', obteve %v", op, kind, err)
+	}
+}
+
+func assertSameUser(t *testing.T, got, want *models.User) {
+	t.Helper()
+	if got.ID != want.ID || got.Username != want.Username || got.PasswordHash != want.PasswordHash ||
+		got.PublicKey != want.PublicKey || got.PublicKeySign != want.PublicKeySign ||
+		got.Kind != want.Kind || !got.CreatedAt.Equal(want.CreatedAt) ||
+		!got.SessionsValidAfter.Equal(want.SessionsValidAfter) {
+		t.Fatalf("usuário divergente:\n obtido:   %+v\n esperado: %+v", got, want)
+	}
+	if (got.OwnerID == nil) != (want.OwnerID == nil) || (got.OwnerID != nil && *got.OwnerID != *want.OwnerID) {
+		t.Fatalf("OwnerID divergente: obtido %v, esperado %v", got.OwnerID, want.OwnerID)
+	}
+}
+
+// --- Users ---
+
+func testCreateAndGetUser(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	user := newUser("alice")
+	user.SessionsValidAfter = now().Add(-time.Hour)
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	byID, err := s.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	assertSameUser(t, byID, user)
+
+	byName, err := s.GetUserByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %v", err)
+	}
+	assertSameUser(t, byName, user)
+}
+
+func testDuplicateUsername(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	first := mustCreateUser(t, s, "bob")
+
+	assertKind(t, s.CreateUser(ctx, newUser("bob")), apperr.ErrConflict, "CreateUser com username duplicado")
+
+	dupID := newUser("bob2")
+	dupID.ID = first.ID
+	assertKind(t, s.CreateUser(ctx, dupID), apperr.ErrConflict, "CreateUser com ID duplicado")
+
+	// O original continua intacto
+	got, err := s.GetUserByUsername(ctx, "bob")
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %v", err)
+	}
+	assertSameUser(t, got, first)
+	if _, err := s.GetUserByUsername(ctx, "bob2"); err == nil {
+		t.Fatal("usuário com ID duplicado não deveria ter sido criado")
+	}
+}
+
+func testUserNotFound(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	_, err := s.GetUserByUsername(ctx, "ninguem")
+	assertKind(t, err, apperr.ErrNotFound, "GetUserByUsername")
+	_, err = s.GetUserByID(ctx, uuid.New())
+	assertKind(t, err, apperr.ErrNotFound, "GetUserByID")
+	assertKind(t, s.UpdateUserPassword(ctx, uuid.New(), "h", now()), apperr.ErrNotFound, "UpdateUserPassword")
+	assertKind(t, s.UpdateUserPublicKeys(ctx, uuid.New(), "pk", "pks"), apperr.ErrNotFound, "UpdateUserPublicKeys")
+	assertKind(t, s.UpdateUserEmail(ctx, uuid.New(), "x@example.com", nil), apperr.ErrNotFound, "UpdateUserEmail")
+	assertKind(t, s.UpdateUserLocale(ctx, uuid.New(), "en"), apperr.ErrNotFound, "UpdateUserLocale")
+	assertKind(t, s.DeleteUser(ctx, uuid.New()), apperr.ErrNotFound, "DeleteUser")
+}
+
+func testGetAllUsersOrdered(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+
+	users, err := s.GetAllUsers(ctx)
+	if err != nil {
+		t.Fatalf("GetAllUsers: %v", err)
+	}
+	if users == nil || len(users) != 0 {
+		t.Fatalf("store vazio deveria retornar lista vazia não-nil, obteve %#v", users)
+	}
+
+	for _, name := range []string{"carol", "alice", "bob"} {
+		mustCreateUser(t, s, name)
+	}
+	users, err = s.GetAllUsers(ctx)
+	if err != nil {
+		t.Fatalf("GetAllUsers: %v", err)
+	}
+	var names []string
+	for _, u := range users {
+		names = append(names, u.Username)
+	}
+	if fmt.Sprint(names) != "[alice bob carol]" {
+		t.Fatalf("ordem = %v, esperado por username", names)
+	}
+}
+
+func testUpdateUserPassword(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "dave")
+
+	validAfter := now()
+	if err := s.UpdateUserPassword(ctx, user.ID, "novo-hash", validAfter); err != nil {
+		t.Fatalf("UpdateUserPassword: %v", err)
+	}
+
+	// A alteração é visível pelas duas formas de busca
+	byID, _ := s.GetUserByID(ctx, user.ID)
+	byName, _ := s.GetUserByUsername(ctx, "dave")
+	for _, got := range []*models.User{byID, byName} {
+		if got.PasswordHash != "novo-hash" || !got.SessionsValidAfter.Equal(validAfter) {
+			t.Fatalf("senha não atualizada: %+v", got)
+		}
+	}
+}
+
+func testUpdateUserPublicKeys(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "erin")
+
+	if err := s.UpdateUserPublicKeys(ctx, user.ID, "pk2", "pks2"); err != nil {
+		t.Fatalf("UpdateUserPublicKeys: %v", err)
+	}
+	got, _ := s.GetUserByUsername(ctx, "erin")
+	if got.PublicKey != "pk2" || got.PublicKeySign != "pks2" {
+		t.Fatalf("chaves não atualizadas: %+v", got)
+	}
+	if got.PasswordHash != user.PasswordHash {
+		t.Fatal("UpdateUserPublicKeys alterou outros campos")
+	}
+}
+
+func testUserEmailAndLocale(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "erica")
+	if user.Email != "" || user.EmailVerifiedAt != nil {
+		t.Fatalf("usuário novo não deveria ter e-mail: %+v", user)
+	}
+
+	if err := s.UpdateUserEmail(ctx, user.ID, "erica@example.com", nil); err != nil {
+		t.Fatalf("UpdateUserEmail: %v", err)
+	}
+	if err := s.UpdateUserLocale(ctx, user.ID, "en"); err != nil {
+		t.Fatalf("UpdateUserLocale: %v", err)
+	}
+	got, _ := s.GetUserByUsername(ctx, "erica")
+	if got.Email != "erica@example.com" || got.EmailVerifiedAt != nil || got.Locale != "en" {
+		t.Fatalf("e-mail não verificado inesperado: %+v", got)
+	}
+
+	verified := now()
+	if err := s.UpdateUserEmail(ctx, user.ID, "erica@example.com", &verified); err != nil {
+		t.Fatalf("UpdateUserEmail(verificado): %v", err)
+	}
+	got, _ = s.GetUserByID(ctx, user.ID)
+	if got.EmailVerifiedAt == nil || !got.EmailVerifiedAt.Equal(verified) {
+		t.Fatalf("EmailVerifiedAt = %v, esperava %v", got.EmailVerifiedAt, verified)
+	}
+	if got.PasswordHash != user.PasswordHash || got.Locale != "en" {
+		t.Fatal("UpdateUserEmail alterou outros campos")
+	}
+
+	// E-mail vazio remove
+	if err := s.UpdateUserEmail(ctx, user.ID, "", nil); err != nil {
+		t.Fatalf("UpdateUserEmail(vazio): %v", err)
+	}
+	users, _ := s.GetAllUsers(ctx)
+	if len(users) != 1 || users[0].Email != "" || users[0].EmailVerifiedAt != nil {
+		t.Fatalf("e-mail não removido: %+v", users)
+	}
+}
+
+func testReturnedUsersAreCopies(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "frank")
+
+	// Alterar o valor passado ou retornado não pode alterar o store
+	user.PasswordHash = "alterado-depois"
+	got, _ := s.GetUserByID(ctx, user.ID)
+	got.Username = "mallory"
+
+	again, err := s.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if again.Username != "frank" || again.PasswordHash != "hash-frank" {
+		t.Fatalf("store alterado por ponteiro externo: %+v", again)
+	}
+}
+
+func testServiceAccountsByOwner(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	owner := mustCreateUser(t, s, "owner")
+	other := mustCreateUser(t, s, "other")
+
+	if _, err := s.GetServiceAccountsByOwner(ctx, owner.ID); err != nil {
+		t.Fatalf("GetServiceAccountsByOwner: %v", err)
+	}
+
+	for _, name := range []string{"svc-b", "svc-a"} {
+		svc := newUser(name)
+		svc.PasswordHash = ""
+		svc.Kind = models.UserKindService
+		svc.OwnerID = &owner.ID
+		if err := s.CreateUser(ctx, svc); err != nil {
+			t.Fatalf("CreateUser(%s): %v", name, err)
+		}
+	}
+	foreign := newUser("svc-other")
+	foreign.Kind = models.UserKindService
+	foreign.OwnerID = &other.ID
+	if err := s.CreateUser(ctx, foreign); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	accounts, err := s.GetServiceAccountsByOwner(ctx, owner.ID)
+	if err != nil {
+		t.Fatalf("GetServiceAccountsByOwner: %v", err)
+	}
+	if len(accounts) != 2 || accounts[0].Username != "svc-a" || accounts[1].Username != "svc-b" {
+		t.Fatalf("contas de serviço inesperadas: %v", accounts)
+	}
+
+	orphan := newUser("svc-orphan")
+	missing := uuid.New()
+	orphan.OwnerID = &missing
+	assertKind(t, s.CreateUser(ctx, orphan), apperr.ErrNotFound, "CreateUser com dono inexistente")
+}
+
+func testDeleteUserCascades(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	device := mustCreateDevice(t, s, alice.ID, "laptop", now())
+	bobDevice := mustCreateDevice(t, s, bob.ID, "phone", now())
+	if err := s.CreateAPIKey(ctx, newAPIKey(alice.ID, "alicekey", now())); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if err := s.CreateIdentity(ctx, &models.Identity{Issuer: "https://idp", Subject: "a", UserID: alice.ID, CreatedAt: now()}); err != nil {
+		t.Fatalf("CreateIdentity: %v", err)
+	}
+	if err := s.PutKeyBackup(ctx, &models.KeyBackup{UserID: alice.ID, KDF: "argon2id", UpdatedAt: now()}); err != nil {
+		t.Fatalf("PutKeyBackup: %v", err)
+	}
+	svc := newUser("svc")
+	svc.Kind = models.UserKindService
+	svc.OwnerID = &alice.ID
+	if err := s.CreateUser(ctx, svc); err != nil {
+		t.Fatalf("CreateUser(svc): %v", err)
+	}
+
+	sent := newTransfer(alice.ID, bob.ID, now())
+	sent.DeviceSKBs = map[uuid.UUID]string{bobDevice.ID: "skb-bob"}
+	received := newTransfer(bob.ID, alice.ID, now())
+	received.DeviceSKBs = map[uuid.UUID]string{device.ID: "skb-alice"}
+	for _, tr := range []*models.Transfer{sent, received} {
+		if err := s.CreateTransfer(ctx, tr); err != nil {
+			t.Fatalf("CreateTransfer: %v", err)
+		}
+	}
+	kept := newTransfer(svc.ID, bob.ID, now().Add(time.Second))
+	if err := s.CreateTransfer(ctx, kept); err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+
+	if err := s.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	if _, err := s.GetUserByID(ctx, alice.ID); err == nil {
+		t.Fatal("usuário continua existindo por ID")
+	}
+	if _, err := s.GetUserByUsername(ctx, "alice"); err == nil {
+		t.Fatal("usuário continua existindo por username")
+	}
+	if _, err := s.GetDeviceByID(ctx, device.ID); err == nil {
+		t.Fatal("dispositivo não removido em cascata")
+	}
+	if _, err := s.GetAPIKeyByPrefix(ctx, "alicekey"); err == nil {
+		t.Fatal("API key não removida em cascata")
+	}
+	if _, err := s.GetUserByIdentity(ctx, "https://idp", "a"); err == nil {
+		t.Fatal("identidade não removida em cascata")
+	}
+	if _, err := s.GetKeyBackup(ctx, alice.ID); err == nil {
+		t.Fatal("backup de chaves não removido em cascata")
+	}
+
+	// Transferências enviadas pela conta somem; as de terceiros ficam
+	bobInbox, err := s.GetTransfersByDestUserID(ctx, bob.ID)
+	if err != nil {
+		t.Fatalf("GetTransfersByDestUserID: %v", err)
+	}
+	if len(bobInbox) != 1 || bobInbox[0].ID != kept.ID {
+		t.Fatalf("caixa de entrada de bob após exclusão: %v", bobInbox)
+	}
+	aliceInbox, _ := s.GetTransfersByDestUserID(ctx, alice.ID)
+	if len(aliceInbox) != 0 {
+		t.Fatalf("transferências recebidas não removidas: %v", aliceInbox)
+	}
+
+	// A conta de serviço sobrevive, sem dono
+	orphan, err := s.GetUserByID(ctx, svc.ID)
+	if err != nil {
+		t.Fatalf("conta de serviço removida junto com o dono: %v", err)
+	}
+	if orphan.OwnerID != nil {
+		t.Fatalf("OwnerID deveria ser nil após excluir o dono, obteve %v", orphan.OwnerID)
+	}
+}
+
+// --- Transfers ---
+
+func testTransfersNewestFirst(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	base := now()
+	// Inseridas fora de ordem cronológica
+	var ids []uuid.UUID
+	for _, offset := range []time.Duration{time.Minute, 0, 2 * time.Minute} {
+		tr := newTransfer(alice.ID, bob.ID, base.Add(offset))
+		if err := s.CreateTransfer(ctx, tr); err != nil {
+			t.Fatalf("CreateTransfer: %v", err)
+		}
+		ids = append(ids, tr.ID)
+	}
+
+	transfers, err := s.GetTransfersByDestUserID(ctx, bob.ID)
+	if err != nil {
+		t.Fatalf("GetTransfersByDestUserID: %v", err)
+	}
+	if len(transfers) != 3 {
+		t.Fatalf("esperava 3 transferências, obteve %d", len(transfers))
+	}
+	want := []uuid.UUID{ids[2], ids[0], ids[1]}
+	for i, tr := range transfers {
+		if tr.ID != want[i] {
+			t.Fatalf("posição %d: obteve %s, esperado %s (mais recentes primeiro)", i, tr.ID, want[i])
+		}
+	}
+
+	got := transfers[0]
+	if got.SourceUserID != alice.ID || got.DestUserID != bob.ID || got.SKB != "skb" || got.Sig != "sig" ||
+		got.Size != 100 || got.ChecksumSHA256 != "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hXuFU=" ||
+		!got.CreatedAt.Equal(base.Add(2*time.Minute)) {
+		t.Fatalf("campos da transferência divergentes: %+v", got)
+	}
+
+	dup := newTransfer(alice.ID, bob.ID, base)
+	dup.ID = ids[0]
+	assertKind(t, s.CreateTransfer(ctx, dup), apperr.ErrConflict, "CreateTransfer com ID duplicado")
+}
+
+func testTransfersEmptyList(t *testing.T, s repository.Store) {
+	transfers, err := s.GetTransfersByDestUserID(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("GetTransfersByDestUserID: %v", err)
+	}
+	if transfers == nil || len(transfers) != 0 {
+		t.Fatalf("esperava lista vazia não-nil, obteve %#v", transfers)
+	}
+}
+
+func testTransfersBySource(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	carol := mustCreateUser(t, s, "carol")
+
+	base := now()
+	older := newTransfer(alice.ID, bob.ID, base)
+	newer := newTransfer(alice.ID, carol.ID, base.Add(time.Minute))
+	other := newTransfer(bob.ID, carol.ID, base.Add(2*time.Minute))
+	for _, tr := range []*models.Transfer{older, newer, other} {
+		if err := s.CreateTransfer(ctx, tr); err != nil {
+			t.Fatalf("CreateTransfer: %v", err)
+		}
+	}
+
+	transfers, err := s.GetTransfersBySourceUserID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("GetTransfersBySourceUserID: %v", err)
+	}
+	if len(transfers) != 2 || transfers[0].ID != newer.ID || transfers[1].ID != older.ID {
+		t.Fatalf("esperava as 2 transferências de alice, mais recentes primeiro, obteve %+v", transfers)
+	}
+	if transfers[0].DestUserID != carol.ID {
+		t.Fatalf("destinatário divergente: %+v", transfers[0])
+	}
+
+	transfers, err = s.GetTransfersBySourceUserID(ctx, carol.ID)
+	if err != nil {
+		t.Fatalf("GetTransfersBySourceUserID: %v", err)
+	}
+	if transfers == nil || len(transfers) != 0 {
+		t.Fatalf("esperava lista vazia não-nil, obteve %#v", transfers)
+	}
+}
+
+func testTransferDeviceSKBs(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	laptop := mustCreateDevice(t, s, bob.ID, "laptop", now())
+	phone := mustCreateDevice(t, s, bob.ID, "phone", now())
+
+	tr := newTransfer(alice.ID, bob.ID, now())
+	tr.DeviceSKBs = map[uuid.UUID]string{laptop.ID: "skb-laptop", phone.ID: "skb-phone"}
+	if err := s.CreateTransfer(ctx, tr); err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	// Alterar o mapa depois de gravar não afeta o store
+	tr.DeviceSKBs[laptop.ID] = "alterado"
+
+	transfers, _ := s.GetTransfersByDestUserID(ctx, bob.ID)
+	if len(transfers) != 1 {
+		t.Fatalf("esperava 1 transferência, obteve %d", len(transfers))
+	}
+	skbs := transfers[0].DeviceSKBs
+	if len(skbs) != 2 || skbs[laptop.ID] != "skb-laptop" || skbs[phone.ID] != "skb-phone" {
+		t.Fatalf("SKBs por dispositivo divergentes: %v", skbs)
+	}
+}
+
+func testTransferUnknownReferences(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	assertKind(t, s.CreateTransfer(ctx, newTransfer(alice.ID, uuid.New(), now())), apperr.ErrNotFound, "CreateTransfer com destinatário inexistente")
+	assertKind(t, s.CreateTransfer(ctx, newTransfer(uuid.New(), bob.ID, now())), apperr.ErrNotFound, "CreateTransfer com remetente inexistente")
+
+	// Uma SKB para dispositivo inexistente invalida a transferência inteira
+	tr := newTransfer(alice.ID, bob.ID, now())
+	tr.DeviceSKBs = map[uuid.UUID]string{uuid.New(): "skb"}
+	assertKind(t, s.CreateTransfer(ctx, tr), apperr.ErrNotFound, "CreateTransfer com dispositivo inexistente")
+	transfers, _ := s.GetTransfersByDestUserID(ctx, bob.ID)
+	if len(transfers) != 0 {
+		t.Fatalf("transferência parcial gravada: %v", transfers)
+	}
+}
+
+// --- Devices ---
+
+func testDevicesOrdered(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "alice")
+	other := mustCreateUser(t, s, "bob")
+
+	base := now()
+	second := mustCreateDevice(t, s, user.ID, "second", base.Add(time.Minute))
+	first := mustCreateDevice(t, s, user.ID, "first", base)
+	mustCreateDevice(t, s, other.ID, "foreign", base)
+
+	signed := &models.Device{
+		ID:             uuid.New(),
+		UserID:         user.ID,
+		Name:           "third",
+		PublicKey:      "pk3",
+		PublicKeySign:  "pks3",
+		SignerDeviceID: &first.ID,
+		Signature:      "sig",
+		CreatedAt:      base.Add(2 * time.Minute),
+	}
+	if err := s.CreateDevice(ctx, signed); err != nil {
+		t.Fatalf("CreateDevice: %v", err)
+	}
+
+	got, err := s.GetDeviceByID(ctx, signed.ID)
+	if err != nil {
+		t.Fatalf("GetDeviceByID: %v", err)
+	}
+	if got.UserID != user.ID || got.Name != "third" || got.SignerDeviceID == nil || *got.SignerDeviceID != first.ID ||
+		got.Signature != "sig" || got.RevokedAt != nil || !got.CreatedAt.Equal(signed.CreatedAt) {
+		t.Fatalf("dispositivo divergente: %+v", got)
+	}
+
+	devices, err := s.GetDevicesByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetDevicesByUserID: %v", err)
+	}
+	if len(devices) != 3 || devices[0].ID != first.ID || devices[1].ID != second.ID || devices[2].ID != signed.ID {
+		t.Fatalf("dispositivos fora de ordem (mais antigo primeiro): %v", devices)
+	}
+
+	dup := *first
+	dup.Name = "dup"
+	assertKind(t, s.CreateDevice(ctx, &dup), apperr.ErrConflict, "CreateDevice com ID duplicado")
+	orphan := *first
+	orphan.ID = uuid.New()
+	orphan.UserID = uuid.New()
+	assertKind(t, s.CreateDevice(ctx, &orphan), apperr.ErrNotFound, "CreateDevice de usuário inexistente")
+}
+
+func testDeviceNotFound(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	_, err := s.GetDeviceByID(ctx, uuid.New())
+	assertKind(t, err, apperr.ErrNotFound, "GetDeviceByID")
+	assertKind(t, s.RevokeDevice(ctx, uuid.New(), now()), apperr.ErrNotFound, "RevokeDevice")
+	devices, err := s.GetDevicesByUserID(ctx, uuid.New())
+	if err != nil {
+		t.Fatalf("GetDevicesByUserID: %v", err)
+	}
+	if devices == nil || len(devices) != 0 {
+		t.Fatalf("esperava lista vazia não-nil, obteve %#v", devices)
+	}
+}
+
+func testLockUser(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	err := s.WithTx(ctx, func(tx repository.Store) error {
+		return tx.LockUser(ctx, uuid.New())
+	})
+	assertKind(t, err, apperr.ErrNotFound, "LockUser(inexistente)")
+
+	// Cada transação conta os dispositivos ativos e revoga um se houver mais
+	// de um; com o usuário travado, nunca ficam zero ativos
+	const devices = 4
+	const workers = 8
+	bob := mustCreateUser(t, s, "bob")
+	ids := make([]uuid.UUID, devices)
+	for i := range ids {
+		ids[i] = mustCreateDevice(t, s, bob.ID, fmt.Sprintf("d%d", i), now()).ID
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(target uuid.UUID) {
+			defer wg.Done()
+			err := s.WithTx(ctx, func(tx repository.Store) error {
+				if err := tx.LockUser(ctx, bob.ID); err != nil {
+					return err
+				}
+				all, err := tx.GetDevicesByUserID(ctx, bob.ID)
+				if err != nil {
+					return err
+				}
+				active := 0
+				for _, d := range all {
+					if d.RevokedAt == nil {
+						active++
+					}
+				}
+				if active <= 1 {
+					return nil
+				}
+				return tx.RevokeDevice(ctx, target, now())
+			})
+			if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+				t.Errorf("transação concorrente: %v", err)
+			}
+		}(ids[i%devices])
+	}
+	wg.Wait()
+
+	all, err := s.GetDevicesByUserID(ctx, bob.ID)
+	if err != nil {
+		t.Fatalf("GetDevicesByUserID: %v", err)
+	}
+	active := 0
+	for _, d := range all {
+		if d.RevokedAt == nil {
+			active++
+		}
+	}
+	if active != 1 {
+		t.Fatalf("esperava exatamente 1 dispositivo ativo, obteve %d", active)
+	}
+}
+
+func testRevokeDeviceDropsSKBs(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	laptop := mustCreateDevice(t, s, bob.ID, "laptop", now())
+	phone := mustCreateDevice(t, s, bob.ID, "phone", now())
+
+	tr := newTransfer(alice.ID, bob.ID, now())
+	tr.DeviceSKBs = map[uuid.UUID]string{laptop.ID: "skb-laptop", phone.ID: "skb-phone"}
+	if err := s.CreateTransfer(ctx, tr); err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	before, _ := s.GetTransfersByDestUserID(ctx, bob.ID)
+
+	revokedAt := now()
+	if err := s.RevokeDevice(ctx, phone.ID, revokedAt); err != nil {
+		t.Fatalf("RevokeDevice: %v", err)
+	}
+
+	got, err := s.GetDeviceByID(ctx, phone.ID)
+	if err != nil {
+		t.Fatalf("dispositivo revogado deveria continuar listável: %v", err)
+	}
+	if got.RevokedAt == nil || !got.RevokedAt.Equal(revokedAt) {
+		t.Fatalf("RevokedAt = %v, esperado %v", got.RevokedAt, revokedAt)
+	}
+
+	after, _ := s.GetTransfersByDestUserID(ctx, bob.ID)
+	if skbs := after[0].DeviceSKBs; len(skbs) != 1 || skbs[laptop.ID] != "skb-laptop" {
+		t.Fatalf("SKBs após revogação: %v", skbs)
+	}
+	// Resultados obtidos antes da revogação não mudam
+	if len(before[0].DeviceSKBs) != 2 {
+		t.Fatalf("revogação alterou resultado já entregue: %v", before[0].DeviceSKBs)
+	}
+
+	assertKind(t, s.RevokeDevice(ctx, phone.ID, now()), apperr.ErrNotFound, "RevokeDevice duas vezes")
+}
+
+// --- API keys ---
+
+func testAPIKeys(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	svc := mustCreateUser(t, s, "svc")
+
+	base := now()
+	expires := base.Add(24 * time.Hour)
+	older := newAPIKey(svc.ID, "aaaa1111", base)
+	older.AllowedCIDRs = []string{"10.0.0.0/8"}
+	older.ExpiresAt = &expires
+	newer := newAPIKey(svc.ID, "bbbb2222", base.Add(time.Minute))
+	for _, k := range []*models.APIKey{older, newer} {
+		if err := s.CreateAPIKey(ctx, k); err != nil {
+			t.Fatalf("CreateAPIKey: %v", err)
+		}
+	}
+
+	got, err := s.GetAPIKeyByPrefix(ctx, "aaaa1111")
+	if err != nil {
+		t.Fatalf("GetAPIKeyByPrefix: %v", err)
+	}
+	if got.ID != older.ID || got.UserID != svc.ID || got.Hash != older.Hash ||
+		fmt.Sprint(got.Scopes) != fmt.Sprint(older.Scopes) || fmt.Sprint(got.AllowedCIDRs) != "[10.0.0.0/8]" ||
+		got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) || got.RevokedAt != nil || got.LastUsedAt != nil {
+		t.Fatalf("API key divergente: %+v", got)
+	}
+	_, err = s.GetAPIKeyByPrefix(ctx, "zzzz9999")
+	assertKind(t, err, apperr.ErrNotFound, "GetAPIKeyByPrefix")
+
+	keys, err := s.GetAPIKeysByUserID(ctx, svc.ID)
+	if err != nil {
+		t.Fatalf("GetAPIKeysByUserID: %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != newer.ID || keys[1].ID != older.ID {
+		t.Fatalf("API keys fora de ordem (mais recentes primeiro): %v", keys)
+	}
+
+	assertKind(t, s.CreateAPIKey(ctx, newAPIKey(uuid.New(), "cccc3333", base)), apperr.ErrNotFound, "CreateAPIKey de usuário inexistente")
+}
+
+func testAPIKeyDuplicatePrefix(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	svc := mustCreateUser(t, s, "svc")
+
+	if err := s.CreateAPIKey(ctx, newAPIKey(svc.ID, "dddd4444", now())); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	assertKind(t, s.CreateAPIKey(ctx, newAPIKey(svc.ID, "dddd4444", now())), apperr.ErrConflict, "CreateAPIKey com prefixo duplicado")
+}
+
+func testRevokeAndTouchAPIKey(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	svc := mustCreateUser(t, s, "svc")
+	key := newAPIKey(svc.ID, "eeee5555", now())
+	if err := s.CreateAPIKey(ctx, key); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	usedAt := now()
+	if err := s.TouchAPIKey(ctx, key.ID, usedAt); err != nil {
+		t.Fatalf("TouchAPIKey: %v", err)
+	}
+	got, _ := s.GetAPIKeyByPrefix(ctx, key.Prefix)
+	if got.LastUsedAt == nil || !got.LastUsedAt.Equal(usedAt) {
+		t.Fatalf("LastUsedAt = %v, esperado %v", got.LastUsedAt, usedAt)
+	}
+
+	revokedAt := now()
+	if err := s.RevokeAPIKey(ctx, key.ID, revokedAt); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	got, _ = s.GetAPIKeyByPrefix(ctx, key.Prefix)
+	if got.RevokedAt == nil || !got.RevokedAt.Equal(revokedAt) {
+		t.Fatalf("RevokedAt = %v, esperado %v", got.RevokedAt, revokedAt)
+	}
+	assertKind(t, s.RevokeAPIKey(ctx, key.ID, now()), apperr.ErrNotFound, "RevokeAPIKey duas vezes")
+	assertKind(t, s.RevokeAPIKey(ctx, uuid.New(), now()), apperr.ErrNotFound, "RevokeAPIKey inexistente")
+}
+
+// --- Identities ---
+
+func testIdentities(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	identity := &models.Identity{Issuer: "https://idp.example", Subject: "sub-1", UserID: alice.ID, Email: "a@example.com", CreatedAt: now()}
+	if err := s.CreateIdentity(ctx, identity); err != nil {
+		t.Fatalf("CreateIdentity: %v", err)
+	}
+
+	got, err := s.GetUserByIdentity(ctx, "https://idp.example", "sub-1")
+	if err != nil {
+		t.Fatalf("GetUserByIdentity: %v", err)
+	}
+	assertSameUser(t, got, alice)
+
+	// O mesmo sub em outro emissor é outra identidade
+	_, err = s.GetUserByIdentity(ctx, "https://other.example", "sub-1")
+	assertKind(t, err, apperr.ErrNotFound, "GetUserByIdentity em outro emissor")
+	if err := s.CreateIdentity(ctx, &models.Identity{Issuer: "https://other.example", Subject: "sub-1", UserID: bob.ID, CreatedAt: now()}); err != nil {
+		t.Fatalf("CreateIdentity em outro emissor: %v", err)
+	}
+
+	dup := *identity
+	dup.UserID = bob.ID
+	assertKind(t, s.CreateIdentity(ctx, &dup), apperr.ErrConflict, "CreateIdentity já vinculada")
+	assertKind(t, s.CreateIdentity(ctx, &models.Identity{Issuer: "https://idp.example", Subject: "sub-2", UserID: uuid.New(), CreatedAt: now()}),
+		apperr.ErrNotFound, "CreateIdentity de usuário inexistente")
+}
+
+// --- Key backups ---
+
+func testKeyBackups(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "alice")
+
+	_, err := s.GetKeyBackup(ctx, user.ID)
+	assertKind(t, err, apperr.ErrNotFound, "GetKeyBackup sem backup")
+
+	backup := &models.KeyBackup{
+		UserID:         user.ID,
+		KDF:            "argon2id",
+		KDFSalt:        "salt",
+		KDFIterations:  3,
+		KDFMemoryKiB:   65536,
+		KDFParallelism: 4,
+		Cipher:         "aes-256-gcm",
+		Nonce:          "nonce",
+		Ciphertext:     "ct",
+		UpdatedAt:      now(),
+	}
+	if err := s.PutKeyBackup(ctx, backup); err != nil {
+		t.Fatalf("PutKeyBackup: %v", err)
+	}
+	got, err := s.GetKeyBackup(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetKeyBackup: %v", err)
+	}
+	// UpdatedAt é comparado à parte: o fuso do time.Time varia por backend
+	gotCmp, wantCmp := *got, *backup
+	gotCmp.UpdatedAt, wantCmp.UpdatedAt = time.Time{}, time.Time{}
+	if gotCmp != wantCmp || !got.UpdatedAt.Equal(backup.UpdatedAt) {
+		t.Fatalf("backup divergente:\n obtido:   %+v\n esperado: %+v", got, backup)
+	}
+
+	// Put substitui o backup existente
+	replacement := *backup
+	replacement.KDF = "pbkdf2-sha256"
+	replacement.KDFIterations = 600000
+	replacement.KDFMemoryKiB = 0
+	replacement.KDFParallelism = 0
+	replacement.Ciphertext = "ct2"
+	replacement.UpdatedAt = now().Add(time.Minute)
+	if err := s.PutKeyBackup(ctx, &replacement); err != nil {
+		t.Fatalf("PutKeyBackup (substituição): %v", err)
+	}
+	got, _ = s.GetKeyBackup(ctx, user.ID)
+	if got.KDF != "pbkdf2-sha256" || got.Ciphertext != "ct2" || got.KDFIterations != 600000 || !got.UpdatedAt.Equal(replacement.UpdatedAt) {
+		t.Fatalf("backup não substituído: %+v", got)
+	}
+
+	orphan := *backup
+	orphan.UserID = uuid.New()
+	assertKind(t, s.PutKeyBackup(ctx, &orphan), apperr.ErrNotFound, "PutKeyBackup de usuário inexistente")
+}
+
+// --- Transações ---
+
+func testTxCommit(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	var alice *models.User
+	err := s.WithTx(ctx, func(tx repository.Store) error {
+		alice = mustCreateUser(t, tx, "alice")
+		// Leituras dentro da transação enxergam as próprias escritas
+		if _, err := tx.GetUserByID(ctx, alice.ID); err != nil {
+			return err
+		}
+		return tx.EnqueueJob(ctx, newJob("teste", now()))
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	if _, err := s.GetUserByID(ctx, alice.ID); err != nil {
+		t.Fatalf("usuário criado na transação não foi persistido: %v", err)
+	}
+	jobs, err := s.ClaimJobs(ctx, now().Add(time.Second), 10, time.Minute)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("job da transação não foi persistido: %d jobs, err=%v", len(jobs), err)
+	}
+}
+
+func testTxRollback(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	bob := mustCreateUser(t, s, "bob")
+	failure := errors.New("falha proposital")
+
+	var alice *models.User
+	err := s.WithTx(ctx, func(tx repository.Store) error {
+		alice = mustCreateUser(t, tx, "alice")
+		if err := tx.DeleteUser(ctx, bob.ID); err != nil {
+			return err
+		}
+		if err := tx.EnqueueJob(ctx, newJob("teste", now())); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithTx deveria devolver o erro de fn, obteve %v", err)
+	}
+
+	_, err = s.GetUserByID(ctx, alice.ID)
+	assertKind(t, err, apperr.ErrNotFound, "GetUserByID(usuário da transação desfeita)")
+	if _, err := s.GetUserByID(ctx, bob.ID); err != nil {
+		t.Fatalf("remoção desfeita não foi revertida: %v", err)
+	}
+	jobs, err := s.ClaimJobs(ctx, now().Add(time.Second), 10, time.Minute)
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("job da transação desfeita foi persistido: %d jobs, err=%v", len(jobs), err)
+	}
+}
+
+func testTxNestedRollback(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	failure := errors.New("falha proposital")
+
+	var alice, bob *models.User
+	err := s.WithTx(ctx, func(tx repository.Store) error {
+		alice = mustCreateUser(t, tx, "alice")
+		innerErr := tx.WithTx(ctx, func(inner repository.Store) error {
+			bob = mustCreateUser(t, inner, "bob")
+			return failure
+		})
+		if !errors.Is(innerErr, failure) {
+			t.Errorf("WithTx aninhado deveria devolver o erro de fn, obteve %v", innerErr)
+		}
+		// A transação externa segue normalmente
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	if _, err := s.GetUserByID(ctx, alice.ID); err != nil {
+		t.Fatalf("escrita da transação externa perdida: %v", err)
+	}
+	_, err = s.GetUserByID(ctx, bob.ID)
+	assertKind(t, err, apperr.ErrNotFound, "GetUserByID(usuário do savepoint desfeito)")
+}
+
+// --- Jobs ---
+
+func testJobLifecycle(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+
+	first := newJob("teste", base.Add(-2*time.Minute))
+	second := newJob("teste", base.Add(-time.Minute))
+	future := newJob("teste", base.Add(time.Hour))
+	for _, job := range []*models.Job{second, future, first} {
+		if err := s.EnqueueJob(ctx, job); err != nil {
+			t.Fatalf("EnqueueJob: %v", err)
+		}
+	}
+	assertKind(t, s.EnqueueJob(ctx, first), apperr.ErrConflict, "EnqueueJob(ID duplicado)")
+
+	claimed, err := s.ClaimJobs(ctx, base, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimJobs: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != first.ID || claimed[1].ID != second.ID {
+		t.Fatalf("esperava os dois jobs vencidos, mais antigo primeiro; obteve %d", len(claimed))
+	}
+	got := claimed[0]
+	if got.Kind != "teste" || string(got.Payload) != `{"n":1}` || got.Attempts != 1 || got.FailedAt != nil {
+		t.Fatalf("job reservado inesperado: %+v", got)
+	}
+
+	if again, _ := s.ClaimJobs(ctx, base.Add(30*time.Second), 10, time.Minute); len(again) != 0 {
+		t.Fatalf("jobs reservados foram entregues de novo dentro do lease (%d)", len(again))
+	}
+
+	if err := s.CompleteJob(ctx, first.ID); err != nil {
+		t.Fatalf("CompleteJob: %v", err)
+	}
+	assertKind(t, s.CompleteJob(ctx, first.ID), apperr.ErrNotFound, "CompleteJob(já concluído)")
+
+	if err := s.RetryJob(ctx, second.ID, base.Add(5*time.Minute), "timeout"); err != nil {
+		t.Fatalf("RetryJob: %v", err)
+	}
+	assertKind(t, s.RetryJob(ctx, uuid.New(), base, "x"), apperr.ErrNotFound, "RetryJob(inexistente)")
+
+	if early, _ := s.ClaimJobs(ctx, base.Add(4*time.Minute), 10, time.Minute); len(early) != 0 {
+		t.Fatalf("job reagendado entregue antes da hora (%d)", len(early))
+	}
+	retried, err := s.ClaimJobs(ctx, base.Add(5*time.Minute), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimJobs: %v", err)
+	}
+	if len(retried) != 1 || retried[0].ID != second.ID || retried[0].Attempts != 2 || retried[0].LastError != "timeout" {
+		t.Fatalf("job reagendado inesperado: %+v", retried)
+	}
+}
+
+func testJobDeadLetter(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+
+	old := newJob("teste", base.Add(-time.Hour))
+	recent := newJob("teste", base.Add(-time.Hour))
+	for _, job := range []*models.Job{old, recent} {
+		if err := s.EnqueueJob(ctx, job); err != nil {
+			t.Fatalf("EnqueueJob: %v", err)
+		}
+	}
+	if err := s.FailJob(ctx, old.ID, base.Add(-48*time.Hour), "falhou 1"); err != nil {
+		t.Fatalf("FailJob: %v", err)
+	}
+	if err := s.FailJob(ctx, recent.ID, base, "falhou 2"); err != nil {
+		t.Fatalf("FailJob: %v", err)
+	}
+	assertKind(t, s.FailJob(ctx, recent.ID, base, "x"), apperr.ErrNotFound, "FailJob(já na dead letter)")
+	assertKind(t, s.RetryJob(ctx, recent.ID, base, "x"), apperr.ErrNotFound, "RetryJob(na dead letter)")
+
+	// Jobs mortos nunca são reservados
+	if claimed, _ := s.ClaimJobs(ctx, base.Add(time.Hour), 10, time.Minute); len(claimed) != 0 {
+		t.Fatalf("job da dead letter foi reservado (%d)", len(claimed))
+	}
+
+	dead, err := s.GetDeadJobs(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetDeadJobs: %v", err)
+	}
+	if len(dead) != 2 || dead[0].ID != recent.ID || dead[1].ID != old.ID {
+		t.Fatalf("esperava os dois jobs mortos, mais recente primeiro; obteve %d", len(dead))
+	}
+	if dead[0].FailedAt == nil || !dead[0].FailedAt.Equal(base) || dead[0].LastError != "falhou 2" {
+		t.Fatalf("job morto inesperado: %+v", dead[0])
+	}
+
+	pruned, err := s.PruneDeadJobs(ctx, base.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("PruneDeadJobs: %v", err)
+	}
+	if pruned != 1 {
+		t.Fatalf("esperava 1 job podado, obteve %d", pruned)
+	}
+	if dead, _ := s.GetDeadJobs(ctx, 10); len(dead) != 1 || dead[0].ID != recent.ID {
+		t.Fatalf("a poda removeu o job errado: %+v", dead)
+	}
+}
+
+func testJobCount(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+
+	if counts, err := s.CountJobs(ctx); err != nil || len(counts) != 0 {
+		t.Fatalf("CountJobs(vazio) = %v, %v", counts, err)
+	}
+	jobs := []*models.Job{newJob("b", base), newJob("b", base.Add(time.Hour)), newJob("b", base), newJob("a", base)}
+	for _, job := range jobs {
+		if err := s.EnqueueJob(ctx, job); err != nil {
+			t.Fatalf("EnqueueJob: %v", err)
+		}
+	}
+	if err := s.FailJob(ctx, jobs[2].ID, base, "falhou"); err != nil {
+		t.Fatalf("FailJob: %v", err)
+	}
+
+	counts, err := s.CountJobs(ctx)
+	if err != nil {
+		t.Fatalf("CountJobs: %v", err)
+	}
+	want := []models.JobCount{{Kind: "a", Queued: 1}, {Kind: "b", Queued: 2, Dead: 1}}
+	if len(counts) != len(want) || counts[0] != want[0] || counts[1] != want[1] {
+		t.Fatalf("CountJobs = %+v, esperado %+v", counts, want)
+	}
+}
+
+func testJobConcurrentClaims(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	const jobs = 40
+	const workers = 8
+
+	base := now()
+	for i := 0; i < jobs; i++ {
+		if err := s.EnqueueJob(ctx, newJob("teste", base.Add(-time.Duration(i)*time.Millisecond))); err != nil {
+			t.Fatalf("EnqueueJob: %v", err)
+		}
+	}
+
+	var mu sync.Mutex
+	seen := make(map[uuid.UUID]int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				claimed, err := s.ClaimJobs(ctx, base, 3, time.Hour)
+				if err != nil {
+					t.Errorf("ClaimJobs concorrente: %v", err)
+					return
+				}
+				if len(claimed) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, job := range claimed {
+					seen[job.ID]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != jobs {
+		t.Fatalf("esperava %d jobs reservados, obteve %d", jobs, len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Fatalf("job %s reservado %d vezes", id, n)
+		}
+	}
+}
+
+func testAdvanceSchedule(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+
+	// Primeira chamada só registra o próximo disparo
+	fired, err := s.AdvanceSchedule(ctx, "limpeza", base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("AdvanceSchedule: %v", err)
+	}
+	if fired {
+		t.Fatal("o primeiro registro não deveria disparar")
+	}
+
+	if fired, _ := s.AdvanceSchedule(ctx, "limpeza", base.Add(30*time.Minute), base.Add(2*time.Hour)); fired {
+		t.Fatal("disparou antes da hora")
+	}
+
+	// Várias réplicas no mesmo instante: só uma vence
+	var wins int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fired, err := s.AdvanceSchedule(ctx, "limpeza", base.Add(time.Hour), base.Add(2*time.Hour))
+			if err != nil {
+				t.Errorf("AdvanceSchedule concorrente: %v", err)
+				return
+			}
+			if fired {
+				mu.Lock()
+				wins++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if wins != 1 {
+		t.Fatalf("esperava exatamente 1 disparo, obteve %d", wins)
+	}
+
+	if fired, _ := s.AdvanceSchedule(ctx, "limpeza", base.Add(90*time.Minute), base.Add(3*time.Hour)); fired {
+		t.Fatal("disparou de novo antes do próximo horário")
+	}
+	// Outro nome é independente
+	if fired, _ := s.AdvanceSchedule(ctx, "outro", base.Add(time.Hour), base.Add(2*time.Hour)); fired {
+		t.Fatal("agendamento novo não deveria disparar")
+	}
+}
+
+// --- Reservas de upload ---
+
+func newUploadReservation(userID uuid.UUID, key string, createdAt time.Time, ttl time.Duration) *models.UploadReservation {
+	return &models.UploadReservation{
+		ObjectKey: key,
+		UserID:    userID,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(ttl),
+	}
+}
+
+func testUploadReferencedKeys(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	transfer := newTransfer(alice.ID, bob.ID, base)
+	if err := s.CreateTransfer(ctx, transfer); err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	live := newUploadReservation(alice.ID, "uploads/alice/live", base, time.Hour)
+	expired := newUploadReservation(alice.ID, "uploads/alice/expired", base.Add(-2*time.Hour), time.Hour)
+	for _, r := range []*models.UploadReservation{live, expired} {
+		if err := s.ReserveUpload(ctx, r); err != nil {
+			t.Fatalf("ReserveUpload: %v", err)
+		}
+	}
+	assertKind(t, s.ReserveUpload(ctx, live), apperr.ErrConflict, "ReserveUpload(chave duplicada)")
+	got, err := s.GetUploadReservation(ctx, live.ObjectKey)
+	if err != nil || got.UserID != alice.ID || got.Size != live.Size || got.ChecksumSHA256 != live.ChecksumSHA256 ||
+		!got.CreatedAt.Equal(live.CreatedAt) || !got.ExpiresAt.Equal(live.ExpiresAt) {
+		t.Fatalf("GetUploadReservation = %+v, %v; esperava %+v", got, err, live)
+	}
+	_, err = s.GetUploadReservation(ctx, "uploads/alice/orphan")
+	assertKind(t, err, apperr.ErrNotFound, "GetUploadReservation(inexistente)")
+	assertKind(t, s.ReserveUpload(ctx, newUploadReservation(uuid.New(), "uploads/x/y", base, time.Hour)),
+		apperr.ErrNotFound, "ReserveUpload(usuário inexistente)")
+
+	keys := []string{transfer.LinkToEncFile, live.ObjectKey, expired.ObjectKey, "uploads/alice/orphan"}
+	referenced, err := s.ReferencedKeys(ctx, keys, base)
+	if err != nil {
+		t.Fatalf("ReferencedKeys: %v", err)
+	}
+	if len(referenced) != 2 || !referenced[transfer.LinkToEncFile] || !referenced[live.ObjectKey] {
+		t.Fatalf("esperava a transferência e a reserva válida, obteve %v", referenced)
+	}
+
+	// Depois que a reserva expira, só a transferência segura o objeto
+	referenced, err = s.ReferencedKeys(ctx, keys, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("ReferencedKeys: %v", err)
+	}
+	if len(referenced) != 1 || !referenced[transfer.LinkToEncFile] {
+		t.Fatalf("esperava só a transferência, obteve %v", referenced)
+	}
+
+	if referenced, err := s.ReferencedKeys(ctx, []string{}, base); err != nil || len(referenced) != 0 {
+		t.Fatalf("ReferencedKeys(vazio) = %v, %v", referenced, err)
+	}
+}
+
+func testUploadCascadeAndPrune(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	reservations := []*models.UploadReservation{
+		newUploadReservation(alice.ID, "uploads/alice/a", base, time.Hour),
+		newUploadReservation(bob.ID, "uploads/bob/old", base.Add(-3*time.Hour), time.Hour),
+		newUploadReservation(bob.ID, "uploads/bob/new", base, time.Hour),
+	}
+	for _, r := range reservations {
+		if err := s.ReserveUpload(ctx, r); err != nil {
+			t.Fatalf("ReserveUpload: %v", err)
+		}
+	}
+
+	pruned, err := s.PruneUploadReservations(ctx, base)
+	if err != nil {
+		t.Fatalf("PruneUploadReservations: %v", err)
+	}
+	if pruned != 1 {
+		t.Fatalf("esperava 1 reserva podada, obteve %d", pruned)
+	}
+
+	if err := s.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	keys := []string{"uploads/alice/a", "uploads/bob/old", "uploads/bob/new"}
+	referenced, err := s.ReferencedKeys(ctx, keys, base)
+	if err != nil {
+		t.Fatalf("ReferencedKeys: %v", err)
+	}
+	if len(referenced) != 1 || !referenced["uploads/bob/new"] {
+		t.Fatalf("reservas do usuário removido deveriam sair em cascata: %v", referenced)
+	}
+}
+
+func testStorageUsage(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	carol := mustCreateUser(t, s, "carol")
+
+	// O mesmo objeto para dois destinatários ocupa espaço uma vez
+	shared := newTransfer(alice.ID, bob.ID, base)
+	shared.Size = 100
+	again := newTransfer(alice.ID, carol.ID, base)
+	again.LinkToEncFile, again.Size = shared.LinkToEncFile, 100
+	other := newTransfer(bob.ID, alice.ID, base)
+	other.Size = 40
+	for _, tr := range []*models.Transfer{shared, again, other} {
+		if err := s.CreateTransfer(ctx, tr); err != nil {
+			t.Fatalf("CreateTransfer: %v", err)
+		}
+	}
+	received, err := s.GetTransfersByDestUserID(ctx, bob.ID)
+	if err != nil || len(received) != 1 || received[0].Size != 100 {
+		t.Fatalf("tamanho da transferência não foi preservado: %+v, %v", received, err)
+	}
+
+	live := newUploadReservation(alice.ID, "uploads/alice/live", base, time.Hour)
+	live.Size = 30
+	expired := newUploadReservation(alice.ID, "uploads/alice/expired", base.Add(-2*time.Hour), time.Hour)
+	expired.Size = 1000
+	for _, r := range []*models.UploadReservation{live, expired} {
+		if err := s.ReserveUpload(ctx, r); err != nil {
+			t.Fatalf("ReserveUpload: %v", err)
+		}
+	}
+
+	usage, err := s.GetStorageUsage(ctx, []uuid.UUID{alice.ID}, base)
+	if err != nil {
+		t.Fatalf("GetStorageUsage: %v", err)
+	}
+	if usage.UsedBytes != 100 || usage.ReservedBytes != 30 {
+		t.Fatalf("uso de alice inesperado: %+v", usage)
+	}
+	usage, err = s.GetStorageUsage(ctx, []uuid.UUID{alice.ID, bob.ID}, base)
+	if err != nil {
+		t.Fatalf("GetStorageUsage: %v", err)
+	}
+	if usage.UsedBytes != 140 || usage.ReservedBytes != 30 {
+		t.Fatalf("uso de alice+bob inesperado: %+v", usage)
+	}
+
+	if err := s.ReleaseUpload(ctx, live.ObjectKey); err != nil {
+		t.Fatalf("ReleaseUpload: %v", err)
+	}
+	if err := s.ReleaseUpload(ctx, live.ObjectKey); err != nil {
+		t.Fatalf("ReleaseUpload(já liberada): %v", err)
+	}
+	usage, err = s.GetStorageUsage(ctx, []uuid.UUID{carol.ID}, base)
+	if err != nil {
+		t.Fatalf("GetStorageUsage: %v", err)
+	}
+	if usage.UsedBytes != 0 || usage.ReservedBytes != 0 {
+		t.Fatalf("carol não enviou nada: %+v", usage)
+	}
+	if usage, _ := s.GetStorageUsage(ctx, []uuid.UUID{alice.ID}, base); usage.ReservedBytes != 0 {
+		t.Fatalf("reserva liberada ainda conta: %+v", usage)
+	}
+}
+
+// --- Concorrência ---
+
+func newAuditEvent(eventType, actor, target string, createdAt time.Time) *models.AuditEvent {
+	return &models.AuditEvent{
+		Type:      eventType,
+		Actor:     actor,
+		IP:        "203.0.113.7",
+		UserAgent: "storetest",
+		Target:    target,
+		CreatedAt: createdAt,
+	}
+}
+
+// assertAuditChain confere Seq contíguo, PrevHash e Hash de events, que
+// devem ser a cadeia inteira
+func assertAuditChain(t *testing.T, events []*models.AuditEvent) {
+	t.Helper()
+	prevHash := ""
+	for i, e := range events {
+		if e.Seq != int64(i+1) || e.PrevHash != prevHash || e.Hash != e.ComputeHash() {
+			t.Fatalf("cadeia quebrada na posição %d: %+v", i, e)
+		}
+		prevHash = e.Hash
+	}
+}
+
+func testAuditChain(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+
+	first := newAuditEvent("auth.login.succeeded", "alice", "user:alice", now())
+	first.ActorID = &alice.ID
+	first.Details = map[string]string{"method": "password"}
+	if err := s.AppendAuditEvent(ctx, first); err != nil {
+		t.Fatalf("AppendAuditEvent: %v", err)
+	}
+	if first.Seq != 1 || first.PrevHash != "" || first.Hash == "" {
+		t.Fatalf("primeiro evento mal encadeado: %+v", first)
+	}
+	second := newAuditEvent("auth.login.failed", "", "user:bob", now())
+	if err := s.AppendAuditEvent(ctx, second); err != nil {
+		t.Fatalf("AppendAuditEvent: %v", err)
+	}
+	if second.Seq != 2 || second.PrevHash != first.Hash {
+		t.Fatalf("segundo evento mal encadeado: %+v", second)
+	}
+
+	// Os eventos sobrevivem à remoção do autor, com os mesmos campos
+	if err := s.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	events, err := s.ListAuditEvents(ctx, repository.AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListAuditEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("esperava 2 eventos, obteve %d", len(events))
+	}
+	assertAuditChain(t, events)
+	got := events[0]
+	if got.ActorID == nil || *got.ActorID != alice.ID || got.Actor != "alice" || got.IP != "203.0.113.7" ||
+		got.UserAgent != "storetest" || got.Details["method"] != "password" || !got.CreatedAt.Equal(first.CreatedAt) {
+		t.Fatalf("campos do evento divergentes: %+v", got)
+	}
+	if events[1].ActorID != nil || events[1].Details != nil {
+		t.Fatalf("evento anônimo não deveria ter autor nem detalhes: %+v", events[1])
+	}
+}
+
+func testAuditFilters(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+	for i, e := range []*models.AuditEvent{
+		newAuditEvent("auth.login.succeeded", "alice", "user:alice", base),
+		newAuditEvent("transfer.created", "alice", "transfer:1", base.Add(time.Minute)),
+		newAuditEvent("auth.login.succeeded", "bob", "user:bob", base.Add(2*time.Minute)),
+		newAuditEvent("transfer.created", "bob", "transfer:2", base.Add(3*time.Minute)),
+	} {
+		if err := s.AppendAuditEvent(ctx, e); err != nil {
+			t.Fatalf("AppendAuditEvent(%d): %v", i, err)
+		}
+	}
+
+	seqs := func(filter repository.AuditFilter) []int64 {
+		t.Helper()
+		events, err := s.ListAuditEvents(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListAuditEvents(%+v): %v", filter, err)
+		}
+		out := []int64{}
+		for _, e := range events {
+			out = append(out, e.Seq)
+		}
+		return out
+	}
+	cases := []struct {
+		filter repository.AuditFilter
+		want   []int64
+	}{
+		{repository.AuditFilter{Type: "transfer.created"}, []int64{2, 4}},
+		{repository.AuditFilter{Actor: "bob"}, []int64{3, 4}},
+		{repository.AuditFilter{Target: "user:alice"}, []int64{1}},
+		{repository.AuditFilter{Since: base.Add(time.Minute), Until: base.Add(3 * time.Minute)}, []int64{2, 3}},
+		{repository.AuditFilter{AfterSeq: 1, Limit: 2}, []int64{2, 3}},
+		{repository.AuditFilter{Descending: true, Limit: 3}, []int64{4, 3, 2}},
+		{repository.AuditFilter{Descending: true, BeforeSeq: 3}, []int64{2, 1}},
+		{repository.AuditFilter{Type: "auth.login.succeeded", Actor: "bob"}, []int64{3}},
+		{repository.AuditFilter{Actor: "carol"}, []int64{}},
+	}
+	for _, tc := range cases {
+		if got := seqs(tc.filter); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Fatalf("filtro %+v: obteve %v, esperado %v", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func testAuditConcurrentAppends(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	const workers, perWorker = 4, 5
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				errs <- s.AppendAuditEvent(ctx, newAuditEvent("test", fmt.Sprintf("worker-%d", w), "", now()))
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("AppendAuditEvent: %v", err)
+		}
+	}
+
+	events, err := s.ListAuditEvents(ctx, repository.AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListAuditEvents: %v", err)
+	}
+	if len(events) != workers*perWorker {
+		t.Fatalf("esperava %d eventos, obteve %d", workers*perWorker, len(events))
+	}
+	assertAuditChain(t, events)
+}
+
+func newInboxEvent(userID uuid.UUID, eventType string, createdAt time.Time) *models.InboxEvent {
+	return &models.InboxEvent{
+		UserID:    userID,
+		Type:      eventType,
+		Data:      json.RawMessage(`{"transferId":"` + uuid.NewString() + `"}`),
+		CreatedAt: createdAt,
+	}
+}
+
+func testEventsAppendAndList(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	latest, err := s.LatestEventID(ctx)
+	if err != nil || latest != 0 {
+		t.Fatalf("LatestEventID sem eventos: %d, err=%v", latest, err)
+	}
+
+	base := now()
+	var events []*models.InboxEvent
+	for i, userID := range []uuid.UUID{alice.ID, bob.ID, alice.ID} {
+		e := newInboxEvent(userID, "transfer.created", base.Add(time.Duration(i)*time.Second))
+		if err := s.AppendEvent(ctx, e); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+		if i > 0 && e.ID <= events[i-1].ID {
+			t.Fatalf("IDs devem crescer: %d depois de %d", e.ID, events[i-1].ID)
+		}
+		events = append(events, e)
+	}
+	assertKind(t, s.AppendEvent(ctx, newInboxEvent(uuid.New(), "transfer.created", base)), apperr.ErrNotFound, "AppendEvent para usuário inexistente")
+
+	latest, err = s.LatestEventID(ctx)
+	if err != nil || latest != events[2].ID {
+		t.Fatalf("LatestEventID: esperava %d, obteve %d (err=%v)", events[2].ID, latest, err)
+	}
+
+	got, err := s.GetEventsAfter(ctx, alice.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("GetEventsAfter: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != events[0].ID || got[1].ID != events[2].ID {
+		t.Fatalf("esperava os 2 eventos de alice em ordem, obteve %+v", got)
+	}
+	e := got[0]
+	var data, want map[string]string
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		t.Fatalf("data inválido: %v", err)
+	}
+	json.Unmarshal(events[0].Data, &want)
+	if e.UserID != alice.ID || e.Type != "transfer.created" || data["transferId"] != want["transferId"] ||
+		!e.CreatedAt.Equal(base) {
+		t.Fatalf("campos do evento divergentes: %+v", e)
+	}
+
+	got, err = s.GetEventsAfter(ctx, alice.ID, events[0].ID, 0)
+	if err != nil || len(got) != 1 || got[0].ID != events[2].ID {
+		t.Fatalf("retomada depois do primeiro evento: %+v, err=%v", got, err)
+	}
+	got, err = s.GetEventsAfter(ctx, uuid.Nil, 0, 2)
+	if err != nil || len(got) != 2 || got[0].ID != events[0].ID || got[1].ID != events[1].ID {
+		t.Fatalf("todos os usuários com limite 2: %+v, err=%v", got, err)
+	}
+	got, err = s.GetEventsAfter(ctx, bob.ID, events[2].ID, 0)
+	if err != nil || got == nil || len(got) != 0 {
+		t.Fatalf("esperava lista vazia não-nil, obteve %#v (err=%v)", got, err)
+	}
+}
+
+func testEventsRollback(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	failure := errors.New("falha proposital")
+
+	err := s.WithTx(ctx, func(tx repository.Store) error {
+		if err := tx.AppendEvent(ctx, newInboxEvent(alice.ID, "transfer.created", now())); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithTx deveria devolver o erro de fn, obteve %v", err)
+	}
+	events, err := s.GetEventsAfter(ctx, alice.ID, 0, 0)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("evento da transação desfeita foi persistido: %+v, err=%v", events, err)
+	}
+
+	committed := newInboxEvent(alice.ID, "transfer.created", now())
+	err = s.WithTx(ctx, func(tx repository.Store) error {
+		return tx.AppendEvent(ctx, committed)
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	events, err = s.GetEventsAfter(ctx, alice.ID, 0, 0)
+	if err != nil || len(events) != 1 || events[0].ID != committed.ID {
+		t.Fatalf("evento confirmado não encontrado: %+v, err=%v", events, err)
+	}
+}
+
+func testEventsPruneAndCascade(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	old := newInboxEvent(bob.ID, "transfer.created", base.Add(-2*time.Hour))
+	recent := newInboxEvent(bob.ID, "transfer.downloaded", base)
+	for _, e := range []*models.InboxEvent{old, recent, newInboxEvent(alice.ID, "transfer.created", base)} {
+		if err := s.AppendEvent(ctx, e); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+
+	pruned, err := s.PruneEvents(ctx, base.Add(-time.Hour))
+	if err != nil || pruned != 1 {
+		t.Fatalf("esperava 1 evento podado, obteve %d (err=%v)", pruned, err)
+	}
+	if err := s.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	events, err := s.GetEventsAfter(ctx, uuid.Nil, 0, 0)
+	if err != nil {
+		t.Fatalf("GetEventsAfter: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != recent.ID {
+		t.Fatalf("esperava só o evento recente de bob, obteve %+v", events)
+	}
+}
+
+func newWebhook(userID uuid.UUID, createdAt time.Time) *models.Webhook {
+	return &models.Webhook{
+		ID:         uuid.New(),
+		UserID:     userID,
+		URL:        "https://hooks.example.com/secureshare",
+		Secret:     "whsec_teste",
+		EventTypes: []string{"transfer.created", "transfer.revoked"},
+		CreatedAt:  createdAt,
+		CreatedBy:  "alice",
+	}
+}
+
+func newWebhookDelivery(webhookID uuid.UUID, createdAt time.Time) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:        uuid.New(),
+		WebhookID: webhookID,
+		EventType: "transfer.created",
+		Payload:   json.RawMessage(`{"type":"transfer.created"}`),
+		Status:    models.WebhookDeliveryPending,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+func testWebhooks(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	older := newWebhook(alice.ID, base)
+	newer := newWebhook(alice.ID, base.Add(time.Minute))
+	for _, w := range []*models.Webhook{older, newer, newWebhook(bob.ID, base)} {
+		if err := s.CreateWebhook(ctx, w); err != nil {
+			t.Fatalf("CreateWebhook: %v", err)
+		}
+	}
+	assertKind(t, s.CreateWebhook(ctx, newWebhook(uuid.New(), base)), apperr.ErrNotFound, "CreateWebhook para usuário inexistente")
+	dup := newWebhook(alice.ID, base)
+	dup.ID = older.ID
+	assertKind(t, s.CreateWebhook(ctx, dup), apperr.ErrConflict, "CreateWebhook com ID duplicado")
+
+	got, err := s.GetWebhook(ctx, older.ID)
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if got.UserID != alice.ID || got.URL != older.URL || got.Secret != older.Secret || got.CreatedBy != "alice" ||
+		len(got.EventTypes) != 2 || got.EventTypes[1] != "transfer.revoked" || !got.CreatedAt.Equal(base) {
+		t.Fatalf("campos do webhook divergentes: %+v", got)
+	}
+
+	webhooks, err := s.GetWebhooksByUserID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("GetWebhooksByUserID: %v", err)
+	}
+	if len(webhooks) != 2 || webhooks[0].ID != newer.ID || webhooks[1].ID != older.ID {
+		t.Fatalf("esperava os 2 webhooks de alice, mais recentes primeiro, obteve %+v", webhooks)
+	}
+
+	if err := s.DeleteWebhook(ctx, newer.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	assertKind(t, s.DeleteWebhook(ctx, newer.ID), apperr.ErrNotFound, "DeleteWebhook repetido")
+	_, err = s.GetWebhook(ctx, newer.ID)
+	assertKind(t, err, apperr.ErrNotFound, "GetWebhook(removido)")
+
+	// ON DELETE CASCADE
+	if err := s.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	_, err = s.GetWebhook(ctx, older.ID)
+	assertKind(t, err, apperr.ErrNotFound, "GetWebhook(usuário removido)")
+}
+
+func testWebhookDeliveries(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+	alice := mustCreateUser(t, s, "alice")
+	webhook := newWebhook(alice.ID, base)
+	if err := s.CreateWebhook(ctx, webhook); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+
+	first := newWebhookDelivery(webhook.ID, base)
+	second := newWebhookDelivery(webhook.ID, base.Add(time.Minute))
+	second.RedeliveryOf = &first.ID
+	for _, d := range []*models.WebhookDelivery{first, second} {
+		if err := s.CreateWebhookDelivery(ctx, d); err != nil {
+			t.Fatalf("CreateWebhookDelivery: %v", err)
+		}
+	}
+	assertKind(t, s.CreateWebhookDelivery(ctx, newWebhookDelivery(uuid.New(), base)), apperr.ErrNotFound, "CreateWebhookDelivery para webhook inexistente")
+
+	first.Status = models.WebhookDeliveryFailed
+	first.Attempts = 3
+	first.LastStatusCode = 502
+	first.LastError = "Bad Gateway"
+	first.UpdatedAt = base.Add(time.Hour)
+	if err := s.UpdateWebhookDelivery(ctx, first); err != nil {
+		t.Fatalf("UpdateWebhookDelivery: %v", err)
+	}
+	assertKind(t, s.UpdateWebhookDelivery(ctx, newWebhookDelivery(webhook.ID, base)), apperr.ErrNotFound, "UpdateWebhookDelivery inexistente")
+
+	got, err := s.GetWebhookDelivery(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("GetWebhookDelivery: %v", err)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(got.Payload, &payload); err != nil || payload["type"] != "transfer.created" {
+		t.Fatalf("payload divergente: %s (err=%v)", got.Payload, err)
+	}
+	if got.Status != models.WebhookDeliveryFailed || got.Attempts != 3 || got.LastStatusCode != 502 ||
+		got.LastError != "Bad Gateway" || !got.UpdatedAt.Equal(base.Add(time.Hour)) || !got.CreatedAt.Equal(base) ||
+		got.RedeliveryOf != nil {
+		t.Fatalf("campos da entrega divergentes: %+v", got)
+	}
+
+	deliveries, err := s.ListWebhookDeliveries(ctx, webhook.ID, 0)
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].ID != second.ID || deliveries[0].RedeliveryOf == nil || *deliveries[0].RedeliveryOf != first.ID {
+		t.Fatalf("esperava as 2 entregas, mais recentes primeiro, obteve %+v", deliveries)
+	}
+	deliveries, err = s.ListWebhookDeliveries(ctx, webhook.ID, 1)
+	if err != nil || len(deliveries) != 1 || deliveries[0].ID != second.ID {
+		t.Fatalf("ListWebhookDeliveries com limite 1: %+v, err=%v", deliveries, err)
+	}
+
+	// Remover o webhook apaga o log
+	if err := s.DeleteWebhook(ctx, webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	_, err = s.GetWebhookDelivery(ctx, first.ID)
+	assertKind(t, err, apperr.ErrNotFound, "GetWebhookDelivery(webhook removido)")
+}
+
+func newIdempotencyRecord(userID uuid.UUID, key string, createdAt time.Time) *models.IdempotencyRecord {
+	return &models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: "fp-" + key,
+		CreatedAt:   createdAt,
+		ExpiresAt:   createdAt.Add(24 * time.Hour),
+	}
+}
+
+func testIdempotencyLifecycle(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	record := newIdempotencyRecord(alice.ID, "k1", base)
+	if err := s.CreateIdempotencyRecord(ctx, record, base); err != nil {
+		t.Fatalf("CreateIdempotencyRecord: %v", err)
+	}
+	// A chave é por usuário
+	if err := s.CreateIdempotencyRecord(ctx, newIdempotencyRecord(bob.ID, "k1", base), base); err != nil {
+		t.Fatalf("CreateIdempotencyRecord(bob, mesma chave): %v", err)
+	}
+	other := newIdempotencyRecord(alice.ID, "k1", base)
+	other.Fingerprint = "outro"
+	assertKind(t, s.CreateIdempotencyRecord(ctx, other, base.Add(time.Hour)), apperr.ErrConflict, "CreateIdempotencyRecord duplicada")
+	assertKind(t, s.CreateIdempotencyRecord(ctx, newIdempotencyRecord(uuid.New(), "k1", base), base), apperr.ErrNotFound, "CreateIdempotencyRecord de usuário inexistente")
+
+	got, err := s.GetIdempotencyRecord(ctx, alice.ID, "k1")
+	if err != nil {
+		t.Fatalf("GetIdempotencyRecord: %v", err)
+	}
+	if got.Fingerprint != "fp-k1" || got.StatusCode != 0 || len(got.Body) != 0 ||
+		!got.CreatedAt.Equal(base) || !got.ExpiresAt.Equal(record.ExpiresAt) {
+		t.Fatalf("registro em andamento divergente: %+v", got)
+	}
+
+	record.StatusCode = 201
+	record.ContentType = "application/json"
+	record.Body = []byte(`{"transferId":"x"}`)
+	if err := s.CompleteIdempotencyRecord(ctx, record); err != nil {
+		t.Fatalf("CompleteIdempotencyRecord: %v", err)
+	}
+	assertKind(t, s.CompleteIdempotencyRecord(ctx, newIdempotencyRecord(alice.ID, "k2", base)), apperr.ErrNotFound, "CompleteIdempotencyRecord inexistente")
+
+	got, err = s.GetIdempotencyRecord(ctx, alice.ID, "k1")
+	if err != nil {
+		t.Fatalf("GetIdempotencyRecord: %v", err)
+	}
+	if got.StatusCode != 201 || got.ContentType != "application/json" || string(got.Body) != `{"transferId":"x"}` || got.Fingerprint != "fp-k1" {
+		t.Fatalf("resposta gravada divergente: %+v", got)
+	}
+
+	// Liberada, a chave pode ser reservada de novo
+	if err := s.DeleteIdempotencyRecord(ctx, record); err != nil {
+		t.Fatalf("DeleteIdempotencyRecord: %v", err)
+	}
+	if err := s.DeleteIdempotencyRecord(ctx, record); err != nil {
+		t.Fatalf("DeleteIdempotencyRecord repetido: %v", err)
+	}
+	_, err = s.GetIdempotencyRecord(ctx, alice.ID, "k1")
+	assertKind(t, err, apperr.ErrNotFound, "GetIdempotencyRecord(liberada)")
+	if err := s.CreateIdempotencyRecord(ctx, other, base); err != nil {
+		t.Fatalf("CreateIdempotencyRecord após liberar: %v", err)
+	}
+	if _, err := s.GetIdempotencyRecord(ctx, bob.ID, "k1"); err != nil {
+		t.Fatalf("a chave de bob não deveria ser afetada: %v", err)
+	}
+}
+
+func testIdempotencyLeaseTakeover(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+	alice := mustCreateUser(t, s, "alice")
+
+	// Reserva com prazo curto, abandonada (ex: o processo caiu)
+	stale := newIdempotencyRecord(alice.ID, "k1", base.Add(-2*time.Minute))
+	stale.ExpiresAt = base.Add(-time.Minute)
+	if err := s.CreateIdempotencyRecord(ctx, stale, stale.CreatedAt); err != nil {
+		t.Fatalf("CreateIdempotencyRecord: %v", err)
+	}
+	retry := newIdempotencyRecord(alice.ID, "k1", base)
+	retry.ExpiresAt = base.Add(time.Minute)
+	if err := s.CreateIdempotencyRecord(ctx, retry, base); err != nil {
+		t.Fatalf("CreateIdempotencyRecord sobre reserva vencida: %v", err)
+	}
+
+	// A requisição abandonada não grava nem libera a reserva nova
+	stale.StatusCode = 201
+	assertKind(t, s.CompleteIdempotencyRecord(ctx, stale), apperr.ErrNotFound, "CompleteIdempotencyRecord de reserva substituída")
+	if err := s.DeleteIdempotencyRecord(ctx, stale); err != nil {
+		t.Fatalf("DeleteIdempotencyRecord de reserva substituída: %v", err)
+	}
+	got, err := s.GetIdempotencyRecord(ctx, alice.ID, "k1")
+	if err != nil || got.StatusCode != 0 || !got.CreatedAt.Equal(base) {
+		t.Fatalf("a reserva nova deveria continuar em andamento: %+v (err=%v)", got, err)
+	}
+
+	// Completar troca o prazo da reserva pela validade da resposta
+	retry.StatusCode = 201
+	retry.ExpiresAt = base.Add(24 * time.Hour)
+	if err := s.CompleteIdempotencyRecord(ctx, retry); err != nil {
+		t.Fatalf("CompleteIdempotencyRecord: %v", err)
+	}
+	got, err = s.GetIdempotencyRecord(ctx, alice.ID, "k1")
+	if err != nil || got.StatusCode != 201 || !got.ExpiresAt.Equal(retry.ExpiresAt) {
+		t.Fatalf("resposta gravada divergente: %+v (err=%v)", got, err)
+	}
+	assertKind(t, s.CreateIdempotencyRecord(ctx, newIdempotencyRecord(alice.ID, "k1", base), base.Add(time.Hour)),
+		apperr.ErrConflict, "CreateIdempotencyRecord sobre resposta gravada")
+}
+
+func testIdempotencyExpiry(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	base := now()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+
+	old := newIdempotencyRecord(alice.ID, "velha", base.Add(-25*time.Hour))
+	old.StatusCode = 201
+	for _, r := range []*models.IdempotencyRecord{
+		old,
+		newIdempotencyRecord(bob.ID, "velha", base.Add(-25*time.Hour)),
+		newIdempotencyRecord(bob.ID, "nova", base),
+	} {
+		if err := s.CreateIdempotencyRecord(ctx, r, r.CreatedAt); err != nil {
+			t.Fatalf("CreateIdempotencyRecord(%s): %v", r.Key, err)
+		}
+	}
+
+	// Um registro expirado é substituído por uma nova reserva
+	replacement := newIdempotencyRecord(alice.ID, "velha", base)
+	replacement.Fingerprint = "outro"
+	if err := s.CreateIdempotencyRecord(ctx, replacement, base); err != nil {
+		t.Fatalf("CreateIdempotencyRecord sobre registro expirado: %v", err)
+	}
+	got, err := s.GetIdempotencyRecord(ctx, alice.ID, "velha")
+	if err != nil || got.Fingerprint != "outro" || got.StatusCode != 0 || !got.ExpiresAt.Equal(replacement.ExpiresAt) {
+		t.Fatalf("registro substituído divergente: %+v (err=%v)", got, err)
+	}
+
+	pruned, err := s.PruneIdempotencyRecords(ctx, base)
+	if err != nil {
+		t.Fatalf("PruneIdempotencyRecords: %v", err)
+	}
+	if pruned != 1 {
+		t.Fatalf("esperava 1 registro podado, obteve %d", pruned)
+	}
+	_, err = s.GetIdempotencyRecord(ctx, bob.ID, "velha")
+	assertKind(t, err, apperr.ErrNotFound, "GetIdempotencyRecord(podada)")
+	if _, err := s.GetIdempotencyRecord(ctx, bob.ID, "nova"); err != nil {
+		t.Fatalf("registro vivo não deveria ser podado: %v", err)
+	}
+
+	if err := s.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	_, err = s.GetIdempotencyRecord(ctx, alice.ID, "velha")
+	assertKind(t, err, apperr.ErrNotFound, "GetIdempotencyRecord(usuário removido)")
+}
+
+func testConcurrentSameUsername(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	const workers = 16
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.CreateUser(ctx, newUser("disputado"))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	ok := 0
+	for err := range errs {
+		if err == nil {
+			ok++
+		}
+	}
+	if ok != 1 {
+		t.Fatalf("%d criações concorrentes do mesmo username tiveram sucesso, esperado exatamente 1", ok)
+	}
+}
+
+func testConcurrentDistinctUsers(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	const workers = 32
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- s.CreateUser(ctx, newUser(fmt.Sprintf("user-%02d", i)))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("CreateUser concorrente: %v", err)
+		}
+	}
+	users, err := s.GetAllUsers(ctx)
+	if err != nil {
+		t.Fatalf("GetAllUsers: %v", err)
+	}
+	if len(users) != workers {
+		t.Fatalf("esperava %d usuários, obteve %d", workers, len(users))
+	}
+}
+
+func testConcurrentReadsAndWrites(t *testing.T, s repository.Store) {
+	ctx := context.Background()
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	device := mustCreateDevice(t, s, bob.ID, "laptop", now())
+	key := newAPIKey(alice.ID, "ffff6666", now())
+	if err := s.CreateAPIKey(ctx, key); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	const workers = 8
+	const perWorker = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker*3)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				tr := newTransfer(alice.ID, bob.ID, now())
+				tr.DeviceSKBs = map[uuid.UUID]string{device.ID: "skb"}
+				errs <- s.CreateTransfer(ctx, tr)
+				_, err := s.GetTransfersByDestUserID(ctx, bob.ID)
+				errs <- err
+				errs <- s.TouchAPIKey(ctx, key.ID, now())
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("operação concorrente falhou: %v", err)
+		}
+	}
+	transfers, err := s.GetTransfersByDestUserID(ctx, bob.ID)
+	if err != nil {
+		t.Fatalf("GetTransfersByDestUserID: %v", err)
+	}
+	if len(transfers) != workers*perWorker {
+		t.Fatalf("esperava %d transferências, obteve %d", workers*perWorker, len(transfers))
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"secureshare-backend/internal/jobs"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// AccountService orquestra operações de autoatendimento que envolvem
+// mais de um recurso (metadados no DB e blobs no S3)
+type AccountService struct {
+	store       repository.Store
+	userService *UserService
+}
+
+// NewAccountService cria um novo serviço de conta
+func NewAccountService(store repository.Store, userService *UserService) *AccountService {
+	return &AccountService{
+		store:       store,
+		userService: userService,
+	}
+}
+
+// DeleteAccount remove a conta do usuário, as contas de serviço dele e
+// todos os arquivos cifrados ligados a elas.
+//
+// Os metadados são removidos na mesma transação que enfileira a remoção dos
+// blobs (JobKindBlobDeletion): se a transação falhar, nada muda; se for
+// confirmada, a fila de jobs remove os objetos do S3 (com novas tentativas
+// até conseguir).
+func (s *AccountService) DeleteAccount(ctx context.Context, userID uuid.UUID, password string, sessionIssuedAt time.Time) error {
+	// 1. Reautenticar (operação destrutiva): senha ou, sem senha, login recente
+	if _, err := s.userService.Reauthenticate(ctx, userID, password, sessionIssuedAt); err != nil {
+		return err
+	}
+
+	err := s.store.WithTx(ctx, func(tx repository.Store) error {
+		user, err := tx.GetUserByID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("falha ao buscar usuário: %w", err)
+		}
+		// 2. As contas de serviço saem junto (com elas, as API keys); sem
+		// o dono, ninguém mais as administraria
+		accounts, err := tx.GetServiceAccountsByOwner(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("falha ao buscar contas de serviço: %w", err)
+		}
+		accounts = append(accounts, user)
+		removed := make(map[uuid.UUID]bool, len(accounts))
+		for _, account := range accounts {
+			removed[account.ID] = true
+		}
+
+		var blobs BlobDeletion
+		for _, account := range accounts {
+			if err := deleteAccountData(ctx, tx, account, removed, &blobs); err != nil {
+				return err
+			}
+		}
+
+		// 3. Agendar a remoção dos blobs
+		return jobs.Enqueue(ctx, tx, JobKindBlobDeletion, blobs)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao remover conta", "user_id", userID, "err", err)
+		return fmt.Errorf("erro interno ao remover conta")
+	}
+
+	return nil
+}
+
+// deleteAccountData remove account (as transferências saem via ON DELETE
+// CASCADE), acrescenta a blobs o que precisa sair do S3 e avisa os
+// destinatários que continuam existindo (fora de removed) de que as
+// transferências enviadas deixaram de existir
+func deleteAccountData(ctx context.Context, tx repository.Store, account *models.User, removed map[uuid.UUID]bool, blobs *BlobDeletion) error {
+	// Arquivos recebidos (estão sob o prefixo do remetente); precisam ser
+	// lidos antes do CASCADE
+	received, err := tx.GetTransfersByDestUserID(ctx, account.ID)
+	if err != nil {
+		return fmt.Errorf("falha ao buscar transferências recebidas: %w", err)
+	}
+	// Arquivos enviados: tudo sob uploads/<userID>/
+	blobs.Prefixes = append(blobs.Prefixes, fmt.Sprintf("%s%s/", UploadsPrefix, account.ID))
+	for _, t := range received {
+		blobs.Keys = append(blobs.Keys, t.LinkToEncFile)
+	}
+
+	sent, err := tx.GetTransfersBySourceUserID(ctx, account.ID)
+	if err != nil {
+		return fmt.Errorf("falha ao buscar transferências enviadas: %w", err)
+	}
+
+	if err := tx.DeleteUser(ctx, account.ID); err != nil {
+		return fmt.Errorf("falha ao remover usuário: %w", err)
+	}
+
+	for _, t := range sent {
+		if removed[t.DestUserID] {
+			continue // enviada para uma das contas removidas
+		}
+		err := publishEvent(ctx, tx, t.DestUserID, EventTransferRevoked, TransferEventData{
+			TransferID: t.ID,
+			SourceUser: account.Username,
+			Reason:     "account_deleted",
+		})
+		if err != nil {
+			return fmt.Errorf("falha ao gravar evento de revogação: %w", err)
+		}
+	}
+	return nil
+}
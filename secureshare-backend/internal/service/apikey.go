@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// apiKeyTouchInterval é a resolução de LastUsedAt: o uso só é gravado se o
+// último registro for mais antigo que isto, para que cada requisição
+// autenticada não custe uma escrita no banco
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyService lida com as API keys das contas de serviço
+type APIKeyService struct {
+	keys  repository.APIKeyStore
+	users repository.UserStore
+}
+
+// NewAPIKeyService cria um novo serviço de API keys
+func NewAPIKeyService(keys repository.APIKeyStore, users repository.UserStore) *APIKeyService {
+	return &APIKeyService{
+		keys:  keys,
+		users: users,
+	}
+}
+
+// CreateAPIKeyRequest define os parâmetros para criar uma API key
+type CreateAPIKeyRequest struct {
+	Name       string     `json:"name" validate:"required,max=100"`
+	Scopes     []string   `json:"scopes" validate:"required,min=1"`
+	AllowedIPs []string   `json:"allowedIps"` // IPs ou CIDRs; vazio = qualquer origem
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+// CreateAPIKey cria uma API key para a conta de serviço e retorna a chave
+// em texto claro, que não poderá ser recuperada depois
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, account *models.User, req CreateAPIKeyRequest) (string, *models.APIKey, error) {
+	for _, scope := range req.Scopes {
+		if !slices.Contains(auth.ValidScopes, scope) {
+			return "", nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidScope, "escopo inválido: '%s'", scope)
+		}
+	}
+
+	cidrs, err := normalizeCIDRs(req.AllowedIPs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return "", nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidAPIKeyExpiresAt, "expiresAt inválida: deve estar no futuro")
+	}
+
+	plaintext, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao gerar API key", "err", err)
+		return "", nil, fmt.Errorf("erro interno ao gerar API key")
+	}
+
+	key := &models.APIKey{
+		ID:           uuid.New(),
+		UserID:       account.ID,
+		Name:         req.Name,
+		Prefix:       prefix,
+		Hash:         hash,
+		Scopes:       slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		AllowedCIDRs: cidrs,
+		ExpiresAt:    req.ExpiresAt,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := s.keys.CreateAPIKey(ctx, key); err != nil {
+		logging.FromContext(ctx).Error("Erro ao salvar API key no store", "err", err)
+		return "", nil, fmt.Errorf("erro interno ao salvar API key")
+	}
+	return plaintext, key, nil
+}
+
+// ListAPIKeys lista as API keys da conta de serviço
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, accountID uuid.UUID) ([]*models.APIKey, error) {
+	keys, err := s.keys.GetAPIKeysByUserID(ctx, accountID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar API keys no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar API keys")
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revoga uma API key da conta de serviço
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, accountID, keyID uuid.UUID) error {
+	keys, err := s.ListAPIKeys(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	idx := slices.IndexFunc(keys, func(k *models.APIKey) bool {
+		return k.ID == keyID && k.RevokedAt == nil
+	})
+	if idx < 0 {
+		return apperr.New(apperr.ErrNotFound, apperr.CodeAPIKeyNotFound, "API key não encontrada")
+	}
+
+	if err := s.keys.RevokeAPIKey(ctx, keyID, time.Now()); err != nil {
+		logging.FromContext(ctx).Error("Erro ao revogar API key no store", "err", err)
+		return fmt.Errorf("erro interno ao revogar API key")
+	}
+	return nil
+}
+
+// Authenticate valida uma API key vinda de remoteIP e retorna a conta de
+// serviço e a chave (com seus escopos)
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string, remoteIP net.IP) (*models.User, *models.APIKey, error) {
+	prefix, err := auth.ParseAPIKey(rawKey)
+	if err != nil {
+		return nil, nil, apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidAPIKey, "API key inválida")
+	}
+
+	key, err := s.keys.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		logging.FromContext(ctx).Error("Erro ao buscar API key no store", "err", err)
+		return nil, nil, fmt.Errorf("erro interno ao validar API key")
+	}
+	if err != nil || !auth.VerifyAPIKey(rawKey, key.Hash) {
+		return nil, nil, apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidAPIKey, "API key inválida")
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, nil, apperr.New(apperr.ErrUnauthorized, apperr.CodeAPIKeyRevoked, "API key revogada")
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, nil, apperr.New(apperr.ErrUnauthorized, apperr.CodeAPIKeyExpired, "API key expirada")
+	}
+	if !ipAllowed(key.AllowedCIDRs, remoteIP) {
+		return nil, nil, apperr.New(apperr.ErrUnauthorized, apperr.CodeAPIKeyIPDenied, "API key não permitida para este IP")
+	}
+
+	user, err := s.users.GetUserByID(ctx, key.UserID)
+	// Contas de serviço sem dono (de contas removidas antes de
+	// DeleteAccount levar as contas de serviço junto) não autenticam
+	if err != nil || user.Kind != models.UserKindService || user.OwnerID == nil {
+		return nil, nil, apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidAPIKey, "API key inválida")
+	}
+
+	// Registro de uso é best-effort: não bloqueia a requisição
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.keys.TouchAPIKey(ctx, key.ID, now); err != nil {
+			logging.FromContext(ctx).Error("Erro ao registrar uso da API key", "api_key_prefix", key.Prefix, "err", err)
+		}
+	}
+	return user, key, nil
+}
+
+// normalizeCIDRs aceita IPs soltos ou CIDRs e devolve tudo em notação CIDR
+func normalizeCIDRs(entries []string) ([]string, error) {
+	cidrs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			cidrs = append(cidrs, (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}).String())
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidAllowedIP, "IP ou CIDR inválido: '%s'", entry)
+		}
+		cidrs = append(cidrs, network.String())
+	}
+	return cidrs, nil
+}
+
+func ipAllowed(cidrs []string, ip net.IP) bool {
+	if len(cidrs) == 0 {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
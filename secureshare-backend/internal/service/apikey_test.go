@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+)
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	users := newUserService(t, store)
+	keys := service.NewAPIKeyService(store, store)
+
+	alice, err := users.Register(ctx, "alice", "senha-forte", "pk", "pk-sign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot, err := users.CreateServiceAccount(ctx, alice, "alice-bot", "pk-bot", "pks-bot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := func(req service.CreateAPIKeyRequest) (string, *models.APIKey) {
+		t.Helper()
+		raw, key, err := keys.CreateAPIKey(ctx, bot, req)
+		if err != nil {
+			t.Fatalf("CreateAPIKey: %v", err)
+		}
+		return raw, key
+	}
+	ip := net.ParseIP("203.0.113.7")
+
+	raw, key := create(service.CreateAPIKeyRequest{Name: "ci", Scopes: []string{auth.ScopeTransfersRead}})
+	user, got, err := keys.Authenticate(ctx, raw, ip)
+	if err != nil || user.ID != bot.ID || got.ID != key.ID {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	// O uso é gravado no máximo uma vez por minuto
+	touched, _ := store.GetAPIKeyByPrefix(ctx, key.Prefix)
+	if touched.LastUsedAt == nil {
+		t.Fatal("uso da API key não registrado")
+	}
+	firstUse := *touched.LastUsedAt
+	if _, _, err := keys.Authenticate(ctx, raw, ip); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := store.GetAPIKeyByPrefix(ctx, key.Prefix); !again.LastUsedAt.Equal(firstUse) {
+		t.Fatal("uso regravado em menos de um minuto")
+	}
+
+	// Segredo adulterado, com prefixo válido
+	if _, _, err := keys.Authenticate(ctx, raw[:len(raw)-1]+"x", ip); apperr.CodeOf(err) != apperr.CodeInvalidAPIKey {
+		t.Fatalf("segredo errado: esperava %s, obteve %v", apperr.CodeInvalidAPIKey, err)
+	}
+	if _, _, err := keys.Authenticate(ctx, "ssk_lixo", ip); apperr.CodeOf(err) != apperr.CodeInvalidAPIKey {
+		t.Fatalf("chave malformada: esperava %s, obteve %v", apperr.CodeInvalidAPIKey, err)
+	}
+
+	// Lista de IPs: IP solto e CIDR
+	rawCIDR, _ := create(service.CreateAPIKeyRequest{Name: "rede", Scopes: []string{auth.ScopeTransfersRead}, AllowedIPs: []string{"198.51.100.0/24", "203.0.113.7"}})
+	for _, allowed := range []string{"198.51.100.42", "203.0.113.7"} {
+		if _, _, err := keys.Authenticate(ctx, rawCIDR, net.ParseIP(allowed)); err != nil {
+			t.Fatalf("IP %s permitido foi recusado: %v", allowed, err)
+		}
+	}
+	for _, denied := range []net.IP{net.ParseIP("198.51.101.1"), net.ParseIP("203.0.113.8"), nil} {
+		if _, _, err := keys.Authenticate(ctx, rawCIDR, denied); apperr.CodeOf(err) != apperr.CodeAPIKeyIPDenied {
+			t.Fatalf("IP %v fora da lista: esperava %s, obteve %v", denied, apperr.CodeAPIKeyIPDenied, err)
+		}
+	}
+	if _, _, err := keys.CreateAPIKey(ctx, bot, service.CreateAPIKeyRequest{Name: "x", Scopes: []string{auth.ScopeUsersRead}, AllowedIPs: []string{"10.0.0.0/33"}}); apperr.CodeOf(err) != apperr.CodeInvalidAllowedIP {
+		t.Fatalf("CIDR inválido: esperava %s, obteve %v", apperr.CodeInvalidAllowedIP, err)
+	}
+
+	// Revogada e expirada
+	if err := keys.RevokeAPIKey(ctx, bot.ID, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := keys.Authenticate(ctx, raw, ip); apperr.CodeOf(err) != apperr.CodeAPIKeyRevoked {
+		t.Fatalf("revogada: esperava %s, obteve %v", apperr.CodeAPIKeyRevoked, err)
+	}
+	// Expirada: gravada direto no store, já que CreateAPIKey recusa datas passadas
+	insertKey := func(owner *models.User, expiresAt *time.Time) string {
+		t.Helper()
+		raw, prefix, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.CreateAPIKey(ctx, &models.APIKey{
+			ID: uuid.New(), UserID: owner.ID, Name: "direta", Prefix: prefix, Hash: hash,
+			Scopes: []string{auth.ScopeUsersRead}, ExpiresAt: expiresAt, CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	past := time.Now().Add(-time.Minute)
+	if _, _, err := keys.Authenticate(ctx, insertKey(bot, &past), ip); apperr.CodeOf(err) != apperr.CodeAPIKeyExpired {
+		t.Fatalf("expirada: esperava %s, obteve %v", apperr.CodeAPIKeyExpired, err)
+	}
+
+	// Conta de serviço sem dono (removido antes desta correção) não autentica
+	orphan := &models.User{ID: uuid.New(), Username: "orfa", CreatedAt: time.Now(), Kind: models.UserKindService}
+	if err := store.CreateUser(ctx, orphan); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := keys.Authenticate(ctx, insertKey(orphan, nil), ip); apperr.CodeOf(err) != apperr.CodeInvalidAPIKey {
+		t.Fatalf("conta órfã: esperava %s, obteve %v", apperr.CodeInvalidAPIKey, err)
+	}
+
+	// Remover a conta do dono leva as contas de serviço e as API keys junto
+	accounts := service.NewAccountService(store, users)
+	if err := accounts.DeleteAccount(ctx, alice.ID, "senha-forte", time.Time{}); err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	if _, err := store.GetUserByID(ctx, bot.ID); err == nil {
+		t.Fatal("conta de serviço sobreviveu à remoção do dono")
+	}
+	if _, _, err := keys.Authenticate(ctx, rawCIDR, net.ParseIP("198.51.100.42")); apperr.CodeOf(err) != apperr.CodeInvalidAPIKey {
+		t.Fatalf("API key de conta removida: esperava %s, obteve %v", apperr.CodeInvalidAPIKey, err)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+)
+
+// Tipos de evento de auditoria
+const (
+	AuditUserRegistered    = "user.registered"
+	AuditLoginSucceeded    = "auth.login.succeeded"
+	AuditLoginFailed       = "auth.login.failed"
+	AuditKeyFetched        = "user.key.fetched"
+	AuditUploadURLIssued   = "transfer.upload_url.issued"
+	AuditTransferCreated   = "transfer.created"
+	AuditDownloadURLIssued = "transfer.download_url.issued"
+	AuditAccountDeleted    = "user.deleted"
+	AuditDeviceRevoked     = "device.revoked"
+	AuditAPIKeyRevoked     = "apikey.revoked"
+	AuditWebhookCreated    = "webhook.created"
+	AuditWebhookDeleted    = "webhook.deleted"
+)
+
+// MaxAuditPageSize é o maior número de eventos devolvido por consulta
+const MaxAuditPageSize = 1000
+
+// Limites padrão dos eventos anônimos (sem ActorID, ex: login que falhou)
+// por janela de anonymousAuditWindow: quem força senhas não consegue
+// inflar o log nem disputar o lock da cadeia com os demais eventos
+const (
+	anonymousAuditWindow = time.Minute
+	anonymousAuditPerIP  = 20
+	anonymousAuditGlobal = 600
+)
+
+// AuditService grava e consulta o log de auditoria
+type AuditService struct {
+	store repository.AuditStore
+
+	mu             sync.Mutex
+	anonPerIP      int
+	anonGlobal     int
+	windowStart    time.Time
+	anonCount      int
+	anonByIP       map[string]int
+	anonSuppressed int
+}
+
+// NewAuditService cria um novo serviço de auditoria
+func NewAuditService(store repository.AuditStore) *AuditService {
+	return &AuditService{
+		store:      store,
+		anonPerIP:  anonymousAuditPerIP,
+		anonGlobal: anonymousAuditGlobal,
+		anonByIP:   make(map[string]int),
+	}
+}
+
+// SetAnonymousLimit muda quantos eventos anônimos são gravados por minuto,
+// por IP e no total
+func (s *AuditService) SetAnonymousLimit(perIP, global int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.anonPerIP = perIP
+	s.anonGlobal = global
+}
+
+// Record grava o evento no fim da cadeia (CreatedAt vazio vira agora).
+// Falhas só são logadas: o log de auditoria não derruba a operação
+// auditada. Eventos anônimos acima do limite são descartados; a contagem
+// dos descartados vai no detalhe "suppressed" do próximo evento anônimo
+// gravado (e no log).
+func (s *AuditService) Record(ctx context.Context, event *models.AuditEvent) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if event.ActorID == nil {
+		suppressed, ok := s.allowAnonymous(event.IP, event.CreatedAt)
+		if !ok {
+			return
+		}
+		if suppressed > 0 {
+			logging.FromContext(ctx).Warn("Eventos anônimos de auditoria descartados pelo limite", "count", suppressed)
+			details := make(map[string]string, len(event.Details)+1)
+			for k, v := range event.Details {
+				details[k] = v
+			}
+			details["suppressed"] = fmt.Sprint(suppressed)
+			event.Details = details
+		}
+	}
+	if err := s.store.AppendAuditEvent(ctx, event); err != nil {
+		logging.FromContext(ctx).Error("Erro ao gravar evento de auditoria", "type", event.Type, "target", event.Target, "err", err)
+	}
+}
+
+// allowAnonymous conta o evento anônimo de ip na janela corrente. Se ele
+// couber nos limites, retorna quantos foram descartados desde o último
+// gravado (e zera a contagem).
+func (s *AuditService) allowAnonymous(ip string, now time.Time) (suppressed int, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.windowStart) >= anonymousAuditWindow || now.Before(s.windowStart) {
+		s.windowStart = now
+		s.anonCount = 0
+		clear(s.anonByIP)
+	}
+	if s.anonCount >= s.anonGlobal || s.anonByIP[ip] >= s.anonPerIP {
+		s.anonSuppressed++
+		return 0, false
+	}
+	s.anonCount++
+	s.anonByIP[ip]++
+
+	suppressed, s.anonSuppressed = s.anonSuppressed, 0
+	return suppressed, true
+}
+
+// List consulta os eventos; o limite padrão e máximo é MaxAuditPageSize
+func (s *AuditService) List(ctx context.Context, filter repository.AuditFilter) ([]*models.AuditEvent, error) {
+	if filter.Limit < 0 {
+		return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidParameter, "limit deve ser positivo")
+	}
+	if filter.Limit == 0 || filter.Limit > MaxAuditPageSize {
+		filter.Limit = MaxAuditPageSize
+	}
+	events, err := s.store.ListAuditEvents(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar eventos de auditoria", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar eventos de auditoria")
+	}
+	return events, nil
+}
+
+// AuditVerification é o resultado de Verify
+type AuditVerification struct {
+	Checked int64 `json:"checked"`
+	// Fim da cadeia: guarde-os fora do banco para detectar também a remoção
+	// dos últimos eventos, que a cadeia sozinha não acusa
+	LastSeq  int64  `json:"lastSeq"`
+	LastHash string `json:"lastHash"`
+	// BrokenAt é o Seq do primeiro evento inconsistente (0: cadeia íntegra)
+	BrokenAt int64  `json:"brokenAt,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+}
+
+// OK indica se a cadeia está íntegra
+func (v *AuditVerification) OK() bool {
+	return v.BrokenAt == 0
+}
+
+// Verify percorre a cadeia inteira recalculando os hashes e para no
+// primeiro evento inconsistente. O erro só indica falha de leitura; uma
+// cadeia quebrada é reportada no resultado.
+func (s *AuditService) Verify(ctx context.Context) (*AuditVerification, error) {
+	result := &AuditVerification{}
+	for {
+		page, err := s.store.ListAuditEvents(ctx, repository.AuditFilter{
+			AfterSeq: result.LastSeq,
+			Limit:    MaxAuditPageSize,
+		})
+		if err != nil {
+			return result, fmt.Errorf("falha ao ler o log de auditoria: %w", err)
+		}
+		for _, e := range page {
+			switch {
+			case e.Seq != result.LastSeq+1:
+				result.Problem = fmt.Sprintf("eventos %d a %d ausentes", result.LastSeq+1, e.Seq-1)
+			case e.PrevHash != result.LastHash:
+				result.Problem = "prevHash não corresponde ao hash do evento anterior"
+			case e.Hash != e.ComputeHash():
+				result.Problem = "hash não corresponde ao conteúdo do evento (evento alterado)"
+			}
+			if result.Problem != "" {
+				result.BrokenAt = e.Seq
+				return result, nil
+			}
+			result.Checked++
+			result.LastSeq = e.Seq
+			result.LastHash = e.Hash
+		}
+		if len(page) < MaxAuditPageSize {
+			return result, nil
+		}
+	}
+}
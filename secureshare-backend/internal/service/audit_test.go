@@ -0,0 +1,110 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// tamperedAuditStore simula alterações feitas direto no banco: tamper é
+// aplicado a cada evento lido
+type tamperedAuditStore struct {
+	*repository.InMemoryStore
+	tamper func(events []*models.AuditEvent) []*models.AuditEvent
+}
+
+func (s *tamperedAuditStore) ListAuditEvents(ctx context.Context, filter repository.AuditFilter) ([]*models.AuditEvent, error) {
+	events, err := s.InMemoryStore.ListAuditEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.tamper(events), nil
+}
+
+func TestAuditVerify(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	audit := service.NewAuditService(store)
+	for _, target := range []string{"user:alice", "user:bob", "user:carol"} {
+		audit.Record(ctx, &models.AuditEvent{Type: service.AuditLoginFailed, Target: target})
+	}
+
+	result, err := audit.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !result.OK() || result.Checked != 3 || result.LastSeq != 3 || result.LastHash == "" {
+		t.Fatalf("cadeia íntegra reportada como %+v", result)
+	}
+
+	cases := []struct {
+		name     string
+		tamper   func(events []*models.AuditEvent) []*models.AuditEvent
+		brokenAt int64
+		problem  string
+	}{
+		{"alterado", func(events []*models.AuditEvent) []*models.AuditEvent {
+			events[1].Target = "user:mallory"
+			return events
+		}, 2, "evento alterado"},
+		{"removido", func(events []*models.AuditEvent) []*models.AuditEvent {
+			return append(events[:1], events[2:]...)
+		}, 3, "ausentes"},
+		{"rehash sem reencadear", func(events []*models.AuditEvent) []*models.AuditEvent {
+			events[1].Target = "user:mallory"
+			events[1].Hash = events[1].ComputeHash()
+			return events
+		}, 3, "prevHash"}, // o evento 2 é consistente em si; o 3 aponta para o hash antigo
+	}
+	for _, tc := range cases {
+		tampered := service.NewAuditService(&tamperedAuditStore{InMemoryStore: store, tamper: tc.tamper})
+		result, err := tampered.Verify(ctx)
+		if err != nil {
+			t.Fatalf("%s: Verify: %v", tc.name, err)
+		}
+		if result.OK() || result.BrokenAt != tc.brokenAt || !strings.Contains(result.Problem, tc.problem) {
+			t.Fatalf("%s: esperava quebra em %d (%q), obteve %+v", tc.name, tc.brokenAt, tc.problem, result)
+		}
+	}
+}
+
+func TestAuditAnonymousLimit(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	audit := service.NewAuditService(store)
+	audit.SetAnonymousLimit(2, 3)
+
+	failed := func(ip string) {
+		audit.Record(ctx, &models.AuditEvent{Type: service.AuditLoginFailed, IP: ip, Target: "user:alice"})
+	}
+	for range 5 {
+		failed("203.0.113.7") // só os 2 primeiros cabem no limite por IP
+	}
+	failed("198.51.100.1")
+	failed("198.51.100.2") // acima do limite global
+	// Eventos com autor não entram no limite
+	aliceID := uuid.New()
+	audit.Record(ctx, &models.AuditEvent{Type: service.AuditLoginSucceeded, ActorID: &aliceID, Actor: "alice", IP: "203.0.113.7"})
+
+	events, err := store.ListAuditEvents(ctx, repository.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ips []string
+	for _, e := range events {
+		ips = append(ips, e.IP)
+	}
+	if len(events) != 4 || events[2].IP != "198.51.100.1" || events[3].ActorID == nil {
+		t.Fatalf("eventos gravados inesperados: %q", ips)
+	}
+	// O primeiro anônimo gravado depois dos descartes carrega a contagem
+	if got := events[2].Details["suppressed"]; got != "3" {
+		t.Fatalf("esperava suppressed=3, obteve %q", got)
+	}
+}
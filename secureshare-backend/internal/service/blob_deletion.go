@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"secureshare-backend/internal/jobs"
+)
+
+// JobKindBlobDeletion é o job da fila (internal/jobs) que remove objetos do
+// S3 (payload: BlobDeletion). Enfileire-o com o Store da transação que
+// remove os metadados, para que os blobs só saiam se ela for confirmada.
+const JobKindBlobDeletion = "blob.delete"
+
+// BlobDeletion é o payload de JobKindBlobDeletion
+type BlobDeletion struct {
+	Keys     []string `json:"keys,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+// BlobDeleter é o que o handler de JobKindBlobDeletion precisa do S3
+// (implementado por S3Service)
+type BlobDeleter interface {
+	DeleteObjects(ctx context.Context, objectKeys []string) error
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// BlobDeletionJob cria o handler de JobKindBlobDeletion. Remover um objeto
+// que já não existe não é erro, então repetir o job é seguro.
+func BlobDeletionJob(blobs BlobDeleter) func(ctx context.Context, payload []byte) error {
+	return func(ctx context.Context, payload []byte) error {
+		var req BlobDeletion
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return jobs.Permanent(fmt.Errorf("payload inválido: %w", err))
+		}
+		for _, prefix := range req.Prefixes {
+			if err := blobs.DeletePrefix(ctx, prefix); err != nil {
+				return err
+			}
+		}
+		return blobs.DeleteObjects(ctx, req.Keys)
+	}
+}
@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/jobs"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeBlobs registra as remoções pedidas e pode falhar sob demanda
+type fakeBlobs struct {
+	mu       sync.Mutex
+	fail     error
+	keys     []string
+	prefixes []string
+}
+
+func (f *fakeBlobs) DeleteObjects(ctx context.Context, keys []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail != nil {
+		return f.fail
+	}
+	f.keys = append(f.keys, keys...)
+	return nil
+}
+
+func (f *fakeBlobs) DeletePrefix(ctx context.Context, prefix string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail != nil {
+		return f.fail
+	}
+	f.prefixes = append(f.prefixes, prefix)
+	return nil
+}
+
+func TestDeleteAccountSchedulesBlobDeletion(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	tokens, err := auth.NewTokenService("segredo-de-teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := service.NewUserService(store, tokens)
+	accounts := service.NewAccountService(store, users)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("senha-forte"), bcrypt.MinCost)
+	alice := &models.User{ID: uuid.New(), Username: "alice", PasswordHash: string(hash), CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	bob := &models.User{ID: uuid.New(), Username: "bob", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	for _, u := range []*models.User{alice, bob} {
+		if err := store.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	received := &models.Transfer{
+		ID: uuid.New(), SourceUserID: bob.ID, DestUserID: alice.ID,
+		LinkToEncFile: "uploads/" + bob.ID.String() + "/arquivo", SKB: "skb", Sig: "sig", CreatedAt: time.Now(),
+	}
+	if err := store.CreateTransfer(ctx, received); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := accounts.DeleteAccount(ctx, alice.ID, "senha-errada", time.Time{}); err == nil {
+		t.Fatal("esperava erro com senha errada")
+	}
+	if err := accounts.DeleteAccount(ctx, alice.ID, "senha-forte", time.Time{}); err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	if _, err := store.GetUserByID(ctx, alice.ID); err == nil {
+		t.Fatal("conta não foi removida")
+	}
+
+	// Os blobs só saem quando a fila roda; uma falha do S3 é reagendada
+	blobs := &fakeBlobs{fail: errors.New("s3 fora do ar")}
+	runner := jobs.NewRunner(store)
+	runner.Register(service.JobKindBlobDeletion, service.BlobDeletionJob(blobs), jobs.Options{})
+
+	now := time.Now()
+	if n, err := runner.RunOnce(ctx, now); err != nil || n != 1 {
+		t.Fatalf("RunOnce: n=%d err=%v", n, err)
+	}
+	if n, _ := runner.RunOnce(ctx, now.Add(time.Second)); n != 0 {
+		t.Fatalf("job que falhou foi executado de novo antes do backoff (%d)", n)
+	}
+
+	blobs.fail = nil
+	if n, err := runner.RunOnce(ctx, now.Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("RunOnce após backoff: n=%d err=%v", n, err)
+	}
+	if len(blobs.prefixes) != 1 || blobs.prefixes[0] != "uploads/"+alice.ID.String()+"/" {
+		t.Fatalf("prefixos removidos = %v", blobs.prefixes)
+	}
+	if len(blobs.keys) != 1 || blobs.keys[0] != received.LinkToEncFile {
+		t.Fatalf("objetos removidos = %v", blobs.keys)
+	}
+
+	// Concluído, o job sai da fila
+	if dead, _ := store.GetDeadJobs(ctx, 10); len(dead) != 0 {
+		t.Fatalf("job concluído foi para a dead letter: %+v", dead)
+	}
+	if left, _ := store.ClaimJobs(ctx, now.Add(24*time.Hour), 10, time.Minute); len(left) != 0 {
+		t.Fatalf("job concluído continuou na fila: %+v", left)
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// UploadsPrefix é onde ficam os arquivos cifrados: uploads/<userID>/<uuid>
+const UploadsPrefix = "uploads/"
+
+// IsUploadKey diz se key tem o formato das chaves geradas por ReserveUpload
+func IsUploadKey(key string) bool {
+	rest, ok := strings.CutPrefix(key, UploadsPrefix)
+	if !ok {
+		return false
+	}
+	userID, objectID, ok := strings.Cut(rest, "/")
+	if !ok {
+		return false
+	}
+	// uuid.Parse aceita outras grafias; as chaves usam só a canônica
+	return len(userID) == 36 && len(objectID) == 36 && uuid.Validate(userID) == nil && uuid.Validate(objectID) == nil
+}
+
+// BlobObject descreve um objeto do backend de blobs
+type BlobObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	// ChecksumSHA256 (base64) só é preenchido por StatObject, e só se o
+	// objeto foi enviado com checksum
+	ChecksumSHA256 string `json:"checksumSha256,omitempty"`
+}
+
+// BlobLister lista os objetos sob um prefixo, chamando fn a cada página
+type BlobLister interface {
+	ListObjects(ctx context.Context, prefix string, fn func(page []BlobObject) error) error
+}
+
+// BlobBackend é o que o coletor de órfãos precisa de um backend de blobs
+// (implementado por S3Service)
+type BlobBackend interface {
+	BlobLister
+	BlobDeleter
+}
+
+// GCReport resume uma execução do coletor de órfãos
+type GCReport struct {
+	StartedAt   time.Time    `json:"startedAt"`
+	DryRun      bool         `json:"dryRun"`
+	Grace       string       `json:"grace"`
+	Scanned     int          `json:"scanned"`
+	Referenced  int          `json:"referenced"`
+	TooRecent   int          `json:"tooRecent"` // dentro do período de carência (nem verificados)
+	Orphans     []BlobObject `json:"orphans"`
+	OrphanBytes int64        `json:"orphanBytes"`
+	Deleted     int          `json:"deleted"`
+}
+
+// OrphanCollector remove do bucket os arquivos cifrados que nenhuma
+// transferência ou reserva de upload válida referencia: uploads que nunca
+// viraram transferência, ou transferências já apagadas.
+//
+// Objetos mais novos que o período de carência nunca são removidos, o que
+// cobre uploads em andamento. Uma transferência criada para um objeto cuja
+// reserva já expirou pode perder o arquivo; a reserva dura
+// UploadReservationTTL justamente para deixar essa janela longe do uso
+// normal.
+type OrphanCollector struct {
+	store  repository.UploadStore
+	blobs  BlobBackend
+	grace  time.Duration
+	prefix string
+}
+
+// NewOrphanCollector cria um coletor para o prefixo uploads/
+func NewOrphanCollector(store repository.UploadStore, blobs BlobBackend, grace time.Duration) *OrphanCollector {
+	return &OrphanCollector{
+		store:  store,
+		blobs:  blobs,
+		grace:  grace,
+		prefix: UploadsPrefix,
+	}
+}
+
+// Collect varre o prefixo e remove os órfãos mais velhos que o período de
+// carência. Com dryRun, só preenche o relatório. Também poda as reservas de
+// upload expiradas.
+func (c *OrphanCollector) Collect(ctx context.Context, now time.Time, dryRun bool) (*GCReport, error) {
+	report := &GCReport{
+		StartedAt: now,
+		DryRun:    dryRun,
+		Grace:     c.grace.String(),
+		Orphans:   []BlobObject{},
+	}
+	cutoff := now.Add(-c.grace)
+
+	err := c.blobs.ListObjects(ctx, c.prefix, func(page []BlobObject) error {
+		report.Scanned += len(page)
+
+		candidates := make([]BlobObject, 0, len(page))
+		keys := make([]string, 0, len(page))
+		for _, obj := range page {
+			if obj.LastModified.After(cutoff) {
+				report.TooRecent++
+				continue
+			}
+			candidates = append(candidates, obj)
+			keys = append(keys, obj.Key)
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+
+		referenced, err := c.store.ReferencedKeys(ctx, keys, now)
+		if err != nil {
+			return err
+		}
+
+		orphanKeys := make([]string, 0, len(candidates))
+		for _, obj := range candidates {
+			if referenced[obj.Key] {
+				report.Referenced++
+				continue
+			}
+			report.Orphans = append(report.Orphans, obj)
+			report.OrphanBytes += obj.Size
+			orphanKeys = append(orphanKeys, obj.Key)
+		}
+
+		if dryRun || len(orphanKeys) == 0 {
+			return nil
+		}
+		if err := c.blobs.DeleteObjects(ctx, orphanKeys); err != nil {
+			return err
+		}
+		report.Deleted += len(orphanKeys)
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("falha na coleta de órfãos: %w", err)
+	}
+
+	if !dryRun {
+		pruned, err := c.store.PruneUploadReservations(ctx, now)
+		if err != nil {
+			return report, err
+		}
+		if pruned > 0 {
+			logging.FromContext(ctx).Info("Reservas de upload expiradas podadas", "count", pruned)
+		}
+	}
+	return report, nil
+}
+
+// JobKindOrphanGC é o job da fila (internal/jobs) que roda o coletor
+const JobKindOrphanGC = "blobs.gc"
+
+// RunJob é o handler de JobKindOrphanGC. Pode ser interrompido no meio: os
+// órfãos já removidos não voltam, então a próxima execução continua o
+// trabalho.
+func (c *OrphanCollector) RunJob(ctx context.Context, _ []byte) error {
+	report, err := c.Collect(ctx, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("Coleta de órfãos concluída",
+		"scanned", report.Scanned, "deleted", report.Deleted, "orphan_bytes", report.OrphanBytes)
+	return nil
+}
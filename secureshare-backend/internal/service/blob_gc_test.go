@@ -0,0 +1,166 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// fakeBucket é um backend de blobs em memória, paginado de 2 em 2
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string]service.BlobObject
+}
+
+func newFakeBucket() *fakeBucket {
+	return &fakeBucket{objects: make(map[string]service.BlobObject)}
+}
+
+func (b *fakeBucket) put(key string, lastModified time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[key] = service.BlobObject{Key: key, Size: 100, LastModified: lastModified, ChecksumSHA256: testChecksum}
+}
+
+func (b *fakeBucket) StatObject(ctx context.Context, key string) (*service.BlobObject, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	obj, ok := b.objects[key]
+	if !ok {
+		return nil, apperr.NotFound("objeto '%s' não encontrado", key)
+	}
+	return &obj, nil
+}
+
+func (b *fakeBucket) has(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.objects[key]
+	return ok
+}
+
+func (b *fakeBucket) ListObjects(ctx context.Context, prefix string, fn func(page []service.BlobObject) error) error {
+	b.mu.Lock()
+	var all []service.BlobObject
+	for key, obj := range b.objects {
+		if strings.HasPrefix(key, prefix) {
+			all = append(all, obj)
+		}
+	}
+	b.mu.Unlock()
+
+	for start := 0; start < len(all); start += 2 {
+		if err := fn(all[start:min(start+2, len(all))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *fakeBucket) DeleteObjects(ctx context.Context, keys []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range keys {
+		delete(b.objects, key)
+	}
+	return nil
+}
+
+func (b *fakeBucket) DeletePrefix(ctx context.Context, prefix string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key := range b.objects {
+		if strings.HasPrefix(key, prefix) {
+			delete(b.objects, key)
+		}
+	}
+	return nil
+}
+
+func TestOrphanCollector(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	bucket := newFakeBucket()
+	transfers := service.NewTransferService(store, bucket, service.UploadLimits{})
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+
+	alice := &models.User{ID: uuid.New(), Username: "alice", CreatedAt: now, Kind: models.UserKindHuman}
+	bob := &models.User{ID: uuid.New(), Username: "bob", CreatedAt: now, Kind: models.UserKindHuman}
+	for _, u := range []*models.User{alice, bob} {
+		if err := store.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Referenciado por uma transferência
+	sent, err := transfers.ReserveUpload(ctx, alice, upload(100))
+	if err != nil {
+		t.Fatalf("ReserveUpload: %v", err)
+	}
+	bucket.put(sent, old)
+	if _, err := transfers.CreateTransfer(ctx, alice.ID, service.CreateTransferRequest{
+		DestUsername: "bob", LinkToEncFile: sent, SKB: "skb", Sig: "sig",
+	}); err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	// Reservado, ainda sem transferência
+	pending, err := transfers.ReserveUpload(ctx, alice, upload(100))
+	if err != nil {
+		t.Fatalf("ReserveUpload: %v", err)
+	}
+	orphan := "uploads/" + alice.ID.String() + "/" + uuid.NewString()
+	recent := "uploads/" + alice.ID.String() + "/" + uuid.NewString()
+	outside := "outro/prefixo"
+
+	for _, key := range []string{pending, orphan, outside} {
+		bucket.put(key, old)
+	}
+	bucket.put(recent, now.Add(-time.Hour))
+
+	collector := service.NewOrphanCollector(store, bucket, 24*time.Hour)
+
+	// Dry-run: relata sem remover
+	report, err := collector.Collect(ctx, now, true)
+	if err != nil {
+		t.Fatalf("Collect(dry-run): %v", err)
+	}
+	if report.Scanned != 4 || report.Referenced != 2 || report.TooRecent != 1 ||
+		len(report.Orphans) != 1 || report.Orphans[0].Key != orphan || report.OrphanBytes != 100 || report.Deleted != 0 {
+		t.Fatalf("relatório do dry-run inesperado: %+v", report)
+	}
+	if !bucket.has(orphan) {
+		t.Fatal("dry-run removeu o órfão")
+	}
+
+	report, err = collector.Collect(ctx, now, false)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if report.Deleted != 1 || bucket.has(orphan) {
+		t.Fatalf("esperava remover só o órfão: %+v", report)
+	}
+	for _, key := range []string{sent, pending, recent, outside} {
+		if !bucket.has(key) {
+			t.Fatalf("objeto %s não deveria ter sido removido", key)
+		}
+	}
+
+	// Depois que a reserva expira, o upload sem transferência vira órfão
+	report, err = collector.Collect(ctx, now.Add(service.UploadReservationTTL+time.Minute), false)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if bucket.has(pending) || !bucket.has(sent) {
+		t.Fatalf("esperava remover o upload abandonado e manter o transferido: %+v", report)
+	}
+}
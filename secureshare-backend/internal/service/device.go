@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/pkg/crosssign"
+
+	"github.com/google/uuid"
+)
+
+// DeviceService lida com a lógica de negócios de dispositivos
+type DeviceService struct {
+	store repository.Store
+}
+
+// NewDeviceService cria um novo serviço de dispositivos
+func NewDeviceService(store repository.Store) *DeviceService {
+	return &DeviceService{
+		store: store,
+	}
+}
+
+// AddDeviceRequest define os parâmetros para registrar um novo dispositivo
+type AddDeviceRequest struct {
+	Name          string `json:"name" validate:"required,max=100"`
+	PublicKey     string `json:"publicKey" validate:"required"`
+	PublicKeySign string `json:"publicKeySign" validate:"required"`
+	// SignerDeviceID e Signature só podem faltar no primeiro dispositivo de
+	// uma conta sem chaves (ex: criada via SSO)
+	SignerDeviceID *uuid.UUID `json:"signerDeviceId"`
+	Signature      string     `json:"signature" validate:"required_with=SignerDeviceID"`
+}
+
+// AddDevice registra um novo dispositivo para o usuário. As chaves do novo
+// dispositivo precisam estar assinadas por um dispositivo ativo do mesmo
+// usuário (ver pkg/crosssign), exceto quando a conta ainda não tem nenhum.
+func (s *DeviceService) AddDevice(ctx context.Context, user *models.User, req AddDeviceRequest) (*models.Device, error) {
+	if _, err := crosssign.ParsePublicKey(req.PublicKeySign); err != nil {
+		return nil, apperr.WrapCode(apperr.ErrValidation, apperr.CodeInvalidKeyFormat, err, "chave de assinatura inválida: %v", err)
+	}
+
+	active, err := s.GetActiveDevices(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	device := &models.Device{
+		ID:            uuid.New(),
+		UserID:        user.ID,
+		Name:          req.Name,
+		PublicKey:     req.PublicKey,
+		PublicKeySign: req.PublicKeySign,
+		CreatedAt:     time.Now(),
+	}
+
+	if len(active) == 0 {
+		// Primeiro dispositivo: não há quem assine; ele passa a ser a raiz
+		if req.SignerDeviceID != nil {
+			return nil, apperr.New(apperr.ErrNotFound, apperr.CodeSignerDeviceNotFound, "dispositivo signatário não encontrado")
+		}
+	} else {
+		if req.SignerDeviceID == nil {
+			return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidCrossSignature, "assinatura cruzada inválida: signerDeviceId é obrigatório")
+		}
+		signer, err := s.store.GetDeviceByID(ctx, *req.SignerDeviceID)
+		if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+			logging.FromContext(ctx).Error("Erro ao buscar dispositivo signatário no store", "err", err)
+			return nil, fmt.Errorf("erro interno ao salvar dispositivo")
+		}
+		if err != nil || signer.UserID != user.ID || signer.RevokedAt != nil {
+			return nil, apperr.New(apperr.ErrNotFound, apperr.CodeSignerDeviceNotFound, "dispositivo signatário não encontrado")
+		}
+
+		payload := crosssign.Payload(user.Username, req.PublicKey, req.PublicKeySign)
+		if err := crosssign.Verify(signer.PublicKeySign, payload, req.Signature); err != nil {
+			return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidCrossSignature, "assinatura cruzada inválida")
+		}
+
+		signerID := signer.ID
+		device.SignerDeviceID = &signerID
+		device.Signature = req.Signature
+	}
+
+	if err := s.store.CreateDevice(ctx, device); err != nil {
+		logging.FromContext(ctx).Error("Erro ao salvar dispositivo no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao salvar dispositivo")
+	}
+
+	if len(active) == 0 {
+		s.syncAccountKeys(ctx, user.ID, device)
+	}
+	return device, nil
+}
+
+// syncAccountKeys mantém as chaves "da conta" (users.public_key) iguais às
+// do dispositivo ativo mais antigo, para clientes sem suporte a dispositivos
+func (s *DeviceService) syncAccountKeys(ctx context.Context, userID uuid.UUID, oldest *models.Device) {
+	if err := s.store.UpdateUserPublicKeys(ctx, userID, oldest.PublicKey, oldest.PublicKeySign); err != nil {
+		logging.FromContext(ctx).Error("Erro ao sincronizar chaves da conta", "user_id", userID, "err", err)
+	}
+}
+
+// ListDevices lista todos os dispositivos do usuário, inclusive revogados
+func (s *DeviceService) ListDevices(ctx context.Context, userID uuid.UUID) ([]*models.Device, error) {
+	devices, err := s.store.GetDevicesByUserID(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar dispositivos no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar dispositivos")
+	}
+	return devices, nil
+}
+
+// GetActiveDevices lista os dispositivos não revogados, do mais antigo
+// para o mais novo
+func (s *DeviceService) GetActiveDevices(ctx context.Context, userID uuid.UUID) ([]*models.Device, error) {
+	devices, err := s.ListDevices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]*models.Device, 0, len(devices))
+	for _, d := range devices {
+		if d.RevokedAt == nil {
+			active = append(active, d)
+		}
+	}
+	return active, nil
+}
+
+// RevokeDevice revoga um dispositivo do usuário. Os demais dispositivos
+// continuam válidos; apenas as SKBs do revogado são descartadas.
+//
+// A contagem dos dispositivos ativos e a revogação rodam na mesma
+// transação, com o usuário travado (LockUser): duas revogações simultâneas
+// não conseguem deixar a conta sem nenhum dispositivo ativo.
+func (s *DeviceService) RevokeDevice(ctx context.Context, userID, deviceID uuid.UUID) error {
+	return s.store.WithTx(ctx, func(tx repository.Store) error {
+		if err := tx.LockUser(ctx, userID); err != nil {
+			logging.FromContext(ctx).Error("Erro ao travar usuário no store", "err", err)
+			return fmt.Errorf("erro interno ao revogar dispositivo")
+		}
+		devices, err := tx.GetDevicesByUserID(ctx, userID)
+		if err != nil {
+			logging.FromContext(ctx).Error("Erro ao buscar dispositivos no store", "err", err)
+			return fmt.Errorf("erro interno ao revogar dispositivo")
+		}
+
+		active := make([]*models.Device, 0, len(devices))
+		found := false
+		for _, d := range devices {
+			if d.RevokedAt == nil {
+				active = append(active, d)
+				found = found || d.ID == deviceID
+			}
+		}
+		if !found {
+			return apperr.New(apperr.ErrNotFound, apperr.CodeDeviceNotFound, "dispositivo não encontrado")
+		}
+		if len(active) == 1 {
+			return apperr.New(apperr.ErrConflict, apperr.CodeLastActiveDevice, "não é possível revogar o último dispositivo ativo")
+		}
+
+		if err := tx.RevokeDevice(ctx, deviceID, time.Now()); err != nil {
+			logging.FromContext(ctx).Error("Erro ao revogar dispositivo no store", "err", err)
+			return fmt.Errorf("erro interno ao revogar dispositivo")
+		}
+
+		// Se o revogado era o mais antigo, o próximo assume as chaves da conta
+		if active[0].ID == deviceID {
+			next := active[1]
+			if err := tx.UpdateUserPublicKeys(ctx, userID, next.PublicKey, next.PublicKeySign); err != nil {
+				logging.FromContext(ctx).Error("Erro ao sincronizar chaves da conta", "user_id", userID, "err", err)
+				return fmt.Errorf("erro interno ao revogar dispositivo")
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+)
+
+func TestRevokeDevice(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	devices := service.NewDeviceService(store)
+
+	bob := &models.User{ID: uuid.New(), Username: "bob", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	if err := store.CreateUser(ctx, bob); err != nil {
+		t.Fatal(err)
+	}
+	var ids []uuid.UUID
+	for i, name := range []string{"laptop", "phone", "tablet"} {
+		d := &models.Device{
+			ID: uuid.New(), UserID: bob.ID, Name: name, PublicKey: "pk-" + name, PublicKeySign: "pks-" + name,
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Second),
+		}
+		if err := store.CreateDevice(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, d.ID)
+	}
+
+	if err := devices.RevokeDevice(ctx, bob.ID, uuid.New()); apperr.CodeOf(err) != apperr.CodeDeviceNotFound {
+		t.Fatalf("dispositivo inexistente: esperava %s, obteve %v", apperr.CodeDeviceNotFound, err)
+	}
+
+	// Revogar o mais antigo passa as chaves da conta para o seguinte
+	if err := devices.RevokeDevice(ctx, bob.ID, ids[0]); err != nil {
+		t.Fatalf("RevokeDevice: %v", err)
+	}
+	if user, _ := store.GetUserByID(ctx, bob.ID); user.PublicKey != "pk-phone" || user.PublicKeySign != "pks-phone" {
+		t.Fatalf("chaves da conta não acompanharam o dispositivo mais antigo: %q", user.PublicKey)
+	}
+	if err := devices.RevokeDevice(ctx, bob.ID, ids[0]); apperr.CodeOf(err) != apperr.CodeDeviceNotFound {
+		t.Fatalf("dispositivo já revogado: esperava %s, obteve %v", apperr.CodeDeviceNotFound, err)
+	}
+
+	// Duas revogações simultâneas: só uma pode vencer
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, id := range ids[1:] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = devices.RevokeDevice(ctx, bob.ID, id)
+		}()
+	}
+	wg.Wait()
+	if (errs[0] == nil) == (errs[1] == nil) {
+		t.Fatalf("esperava exatamente uma revogação bem-sucedida: %v", errs)
+	}
+	for _, err := range errs {
+		if err != nil && apperr.CodeOf(err) != apperr.CodeLastActiveDevice {
+			t.Fatalf("esperava %s, obteve %v", apperr.CodeLastActiveDevice, err)
+		}
+	}
+	active, err := devices.GetActiveDevices(ctx, bob.ID)
+	if err != nil || len(active) != 1 {
+		t.Fatalf("esperava 1 dispositivo ativo, obteve %d (%v)", len(active), err)
+	}
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/i18n"
+	"secureshare-backend/internal/jobs"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/notify"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// JobKindEmailVerification envia o pedido de confirmação de um e-mail
+	// (payload: emailJob)
+	JobKindEmailVerification = "email.verification"
+	// JobKindEmailTransferReceived avisa o destinatário de uma nova
+	// transferência (payload: emailJob)
+	JobKindEmailTransferReceived = "email.transfer_received"
+
+	// EmailVerificationTTL é a validade do token de confirmação
+	EmailVerificationTTL = 48 * time.Hour
+
+	emailVerificationPurpose = "email_verification"
+)
+
+type emailJob struct {
+	UserID uuid.UUID `json:"userId"`
+	// Email é o endereço no momento do pedido: se o usuário trocar de
+	// e-mail antes do envio, o job é descartado
+	Email  string `json:"email"`
+	Sender string `json:"sender,omitempty"` // JobKindEmailTransferReceived
+}
+
+// EmailService cuida do e-mail dos usuários (cadastro e confirmação) e do
+// envio dos avisos, feito pelos jobs da fila
+type EmailService struct {
+	store     repository.Store
+	notifier  notify.Notifier
+	tokens    *auth.TokenService
+	verifyURL string
+}
+
+// NewEmailService cria o serviço. verifyURL é a página do frontend que
+// recebe o token em ?token=; se vazia, o e-mail traz só o token.
+func NewEmailService(store repository.Store, notifier notify.Notifier, tokens *auth.TokenService, verifyURL string) *EmailService {
+	return &EmailService{store: store, notifier: notifier, tokens: tokens, verifyURL: verifyURL}
+}
+
+// SetEmail cadastra (ou troca) o e-mail do usuário e pede a confirmação.
+// locale vazio mantém o idioma atual. O endereço só recebe avisos depois de
+// confirmado com VerifyEmail.
+func (s *EmailService) SetEmail(ctx context.Context, user *models.User, email, locale string) (*models.User, error) {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidEmail, "e-mail inválido: '%s'", email)
+	}
+	if locale != "" && !i18n.IsSupported(locale) {
+		return nil, apperr.New(apperr.ErrValidation, apperr.CodeUnsupportedLocale, "idioma não suportado: '%s' (aceitos: %s)", locale, strings.Join(i18n.SupportedLocales, ", "))
+	}
+
+	var updated *models.User
+	err = s.store.WithTx(ctx, func(tx repository.Store) error {
+		current, err := tx.GetUserByID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if locale != "" && locale != current.Locale {
+			if err := tx.UpdateUserLocale(ctx, user.ID, locale); err != nil {
+				return err
+			}
+		}
+		// O mesmo endereço já confirmado não precisa de nova confirmação
+		if email != current.Email || current.EmailVerifiedAt == nil {
+			if err := tx.UpdateUserEmail(ctx, user.ID, email, nil); err != nil {
+				return err
+			}
+			err := jobs.Enqueue(ctx, tx, JobKindEmailVerification, emailJob{UserID: user.ID, Email: email})
+			if err != nil {
+				return err
+			}
+		}
+		updated, err = tx.GetUserByID(ctx, user.ID)
+		return err
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao cadastrar e-mail", "user_id", user.ID, "err", err)
+		return nil, fmt.Errorf("erro interno ao cadastrar e-mail")
+	}
+	return updated, nil
+}
+
+// RemoveEmail apaga o e-mail do usuário; os avisos param de ser enviados
+func (s *EmailService) RemoveEmail(ctx context.Context, user *models.User) error {
+	if err := s.store.UpdateUserEmail(ctx, user.ID, "", nil); err != nil {
+		logging.FromContext(ctx).Error("Erro ao remover e-mail", "user_id", user.ID, "err", err)
+		return fmt.Errorf("erro interno ao remover e-mail")
+	}
+	return nil
+}
+
+// VerifyEmail confirma o endereço com o token enviado por e-mail. O token
+// identifica o usuário, então não exige sessão; ele deixa de valer se o
+// usuário trocar de e-mail.
+func (s *EmailService) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	invalid := apperr.New(apperr.ErrValidation, apperr.CodeInvalidVerificationToken, "token de confirmação inválido ou expirado")
+	values, err := s.tokens.ParseStateToken(token)
+	if err != nil || values["purpose"] != emailVerificationPurpose {
+		return nil, invalid
+	}
+	userID, err := uuid.Parse(values["user"])
+	if err != nil {
+		return nil, invalid
+	}
+
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, invalid
+		}
+		logging.FromContext(ctx).Error("Erro ao buscar usuário no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao confirmar e-mail")
+	}
+	if user.Email == "" || user.Email != values["email"] {
+		return nil, invalid
+	}
+	if user.EmailVerifiedAt != nil {
+		return user, nil
+	}
+
+	now := time.Now()
+	if err := s.store.UpdateUserEmail(ctx, user.ID, user.Email, &now); err != nil {
+		logging.FromContext(ctx).Error("Erro ao confirmar e-mail", "user_id", user.ID, "err", err)
+		return nil, fmt.Errorf("erro interno ao confirmar e-mail")
+	}
+	user.EmailVerifiedAt = &now
+	return user, nil
+}
+
+// enqueueTransferEmail agenda o aviso de nova transferência se o
+// destinatário tiver e-mail confirmado. Chame com o Store da transação que
+// cria a transferência.
+func enqueueTransferEmail(ctx context.Context, store repository.JobStore, dest *models.User, sender string) error {
+	if dest.Email == "" || dest.EmailVerifiedAt == nil {
+		return nil
+	}
+	return jobs.Enqueue(ctx, store, JobKindEmailTransferReceived, emailJob{UserID: dest.ID, Email: dest.Email, Sender: sender})
+}
+
+// RunVerificationJob é o handler de JobKindEmailVerification
+func (s *EmailService) RunVerificationJob(ctx context.Context, payload []byte) error {
+	job, user, err := s.loadJobUser(ctx, payload)
+	if err != nil || user == nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil // já confirmado
+	}
+
+	token, err := s.tokens.NewStateToken(map[string]string{
+		"purpose": emailVerificationPurpose,
+		"user":    user.ID.String(),
+		"email":   job.Email,
+	}, EmailVerificationTTL)
+	if err != nil {
+		return fmt.Errorf("falha ao gerar token de confirmação: %w", err)
+	}
+	data := notify.EmailVerificationData{
+		Recipient:  user.Username,
+		Email:      job.Email,
+		Token:      token,
+		ValidHours: int(EmailVerificationTTL / time.Hour),
+	}
+	if s.verifyURL != "" {
+		link, err := url.Parse(s.verifyURL)
+		if err != nil {
+			return jobs.Permanent(fmt.Errorf("URL de confirmação inválida: %w", err))
+		}
+		query := link.Query()
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+		data.Link = link.String()
+	}
+
+	msg, err := notify.EmailVerification(user.Locale, job.Email, data)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	return s.notifier.Send(ctx, msg)
+}
+
+// RunTransferReceivedJob é o handler de JobKindEmailTransferReceived. A
+// mensagem só leva os nomes de usuário: nada do arquivo nem das chaves.
+func (s *EmailService) RunTransferReceivedJob(ctx context.Context, payload []byte) error {
+	job, user, err := s.loadJobUser(ctx, payload)
+	if err != nil || user == nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil
+	}
+
+	msg, err := notify.TransferReceived(user.Locale, job.Email, notify.TransferReceivedData{
+		Recipient: user.Username,
+		Sender:    job.Sender,
+	})
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	return s.notifier.Send(ctx, msg)
+}
+
+// loadJobUser decodifica o payload e busca o usuário. user nil (sem erro)
+// indica um job obsoleto: usuário removido ou com outro e-mail.
+func (s *EmailService) loadJobUser(ctx context.Context, payload []byte) (*emailJob, *models.User, error) {
+	var job emailJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, nil, jobs.Permanent(fmt.Errorf("payload inválido: %w", err))
+	}
+	user, err := s.store.GetUserByID(ctx, job.UserID)
+	if errors.Is(err, apperr.ErrNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Email != job.Email {
+		return nil, nil, nil
+	}
+	return &job, user, nil
+}
@@ -0,0 +1,161 @@
+package service_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/i18n"
+	"secureshare-backend/internal/jobs"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/notify"
+	"secureshare-backend/internal/notify/notifytest"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// verificationToken extrai o token do link de confirmação do e-mail
+func verificationToken(t *testing.T, msg notifytest.Message) string {
+	t.Helper()
+	for _, line := range strings.Split(msg.Body, "\n") {
+		if strings.HasPrefix(line, "https://app.example.com/") {
+			link, err := url.Parse(line)
+			if err != nil {
+				t.Fatalf("link inválido: %v", err)
+			}
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("e-mail sem link de confirmação: %q", msg.Body)
+	return ""
+}
+
+func TestEmailNotifications(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	bucket := newFakeBucket()
+	transfers := service.NewTransferService(store, bucket, service.UploadLimits{})
+	tokens, err := auth.NewTokenService("segredo-de-teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := notifytest.NewServer()
+	defer server.Close()
+	notifier, err := notify.NewSMTPNotifier(notify.SMTPConfig{Host: server.Host(), Port: server.Port(), From: "no-reply@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	emails := service.NewEmailService(store, notifier, tokens, "https://app.example.com/verify-email")
+	runner := jobs.NewRunner(store)
+	runner.Register(service.JobKindEmailVerification, emails.RunVerificationJob, jobs.Options{})
+	runner.Register(service.JobKindEmailTransferReceived, emails.RunTransferReceivedJob, jobs.Options{})
+	runJobs := func() {
+		t.Helper()
+		if _, err := runner.RunOnce(ctx, time.Now()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+	}
+
+	alice := &models.User{ID: uuid.New(), Username: "alice", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	bob := &models.User{ID: uuid.New(), Username: "bob", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	for _, u := range []*models.User{alice, bob} {
+		if err := store.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sendTransfer := func() {
+		t.Helper()
+		key, err := transfers.ReserveUpload(ctx, alice, upload(100))
+		if err != nil {
+			t.Fatalf("ReserveUpload: %v", err)
+		}
+		bucket.put(key, time.Now())
+		_, err = transfers.CreateTransfer(ctx, alice.ID, service.CreateTransferRequest{
+			DestUsername: "bob", LinkToEncFile: key, SKB: "skb-secreta", Sig: "assinatura",
+		})
+		if err != nil {
+			t.Fatalf("CreateTransfer: %v", err)
+		}
+	}
+
+	if _, err := emails.SetEmail(ctx, bob, "bob arroba example", ""); err == nil {
+		t.Fatal("esperava erro com e-mail inválido")
+	}
+	if _, err := emails.SetEmail(ctx, bob, "bob@example.com", "fr"); err == nil {
+		t.Fatal("esperava erro com idioma não suportado")
+	}
+
+	// Cadastro: a confirmação é enviada, e até lá não há avisos
+	updated, err := emails.SetEmail(ctx, bob, "bob@example.com", i18n.LocaleEn)
+	if err != nil {
+		t.Fatalf("SetEmail: %v", err)
+	}
+	if updated.Email != "bob@example.com" || updated.EmailVerifiedAt != nil || updated.Locale != i18n.LocaleEn {
+		t.Fatalf("usuário depois do cadastro: %+v", updated)
+	}
+	sendTransfer()
+	runJobs()
+	msgs := server.Messages()
+	if len(msgs) != 1 || msgs[0].To[0] != "bob@example.com" || msgs[0].Subject != "Confirm your email on SecureShare" {
+		t.Fatalf("esperava só a confirmação, obteve %+v", msgs)
+	}
+	token := verificationToken(t, msgs[0])
+
+	if _, err := emails.VerifyEmail(ctx, "token-falso"); err == nil {
+		t.Fatal("token falso aceito")
+	}
+	verified, err := emails.VerifyEmail(ctx, token)
+	if err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if verified.EmailVerifiedAt == nil {
+		t.Fatal("e-mail deveria estar confirmado")
+	}
+
+	// Confirmado: nova transferência gera o aviso, sem SKB nem assinatura
+	sendTransfer()
+	runJobs()
+	msgs = server.Messages()
+	if len(msgs) != 2 {
+		t.Fatalf("esperava o aviso de transferência, obteve %d mensagem(ns)", len(msgs))
+	}
+	notice := msgs[1]
+	if notice.Subject != "New encrypted file from alice" || !strings.Contains(notice.Body, "Hi bob,") {
+		t.Fatalf("aviso inesperado: %q\n%s", notice.Subject, notice.Body)
+	}
+	for _, secret := range []string{"skb-secreta", "assinatura", "uploads/"} {
+		if strings.Contains(notice.Raw, secret) {
+			t.Fatalf("aviso contém %q:\n%s", secret, notice.Raw)
+		}
+	}
+
+	// Trocar de e-mail invalida o token antigo e exige nova confirmação
+	if _, err := emails.SetEmail(ctx, bob, "bob@work.example.com", ""); err != nil {
+		t.Fatalf("SetEmail: %v", err)
+	}
+	if _, err := emails.VerifyEmail(ctx, token); err == nil {
+		t.Fatal("token do e-mail anterior aceito")
+	}
+	sendTransfer()
+	runJobs()
+	msgs = server.Messages()
+	if len(msgs) != 3 || msgs[2].To[0] != "bob@work.example.com" || !strings.HasPrefix(msgs[2].Subject, "Confirm") {
+		t.Fatalf("esperava só a nova confirmação, obteve %d mensagem(ns)", len(msgs))
+	}
+
+	// Sem e-mail, nada é enviado
+	if err := emails.RemoveEmail(ctx, bob); err != nil {
+		t.Fatalf("RemoveEmail: %v", err)
+	}
+	sendTransfer()
+	runJobs()
+	if n := len(server.Messages()); n != 3 {
+		t.Fatalf("mensagem enviada sem e-mail cadastrado: %d", n)
+	}
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de evento da caixa de entrada (GET /events)
+const (
+	// EventTransferCreated vai para o destinatário de uma nova transferência
+	EventTransferCreated = "transfer.created"
+	// EventTransferRevoked vai para o destinatário quando a transferência
+	// deixa de existir (hoje, só quando o remetente remove a conta)
+	EventTransferRevoked = "transfer.revoked"
+	// EventTransferDownloaded vai para o remetente quando o destinatário
+	// pede a URL de download
+	EventTransferDownloaded = "transfer.downloaded"
+)
+
+// TransferEventData é o campo data dos eventos transfer.*
+type TransferEventData struct {
+	TransferID uuid.UUID `json:"transferId"`
+	SourceUser string    `json:"sourceUser,omitempty"`
+	DestUser   string    `json:"destUser,omitempty"`
+	Size       int64     `json:"size,omitempty"`
+	Reason     string    `json:"reason,omitempty"` // transfer.revoked
+}
+
+// publishEvent grava um evento para o usuário e enfileira as entregas dos
+// webhooks dele que assinam o tipo. Chame com o Store da transação (WithTx)
+// para que o evento só seja entregue se a mudança for confirmada.
+func publishEvent(ctx context.Context, store repository.Store, userID uuid.UUID, eventType string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("falha ao serializar evento '%s': %w", eventType, err)
+	}
+	event := &models.InboxEvent{
+		UserID:    userID,
+		Type:      eventType,
+		Data:      raw,
+		CreatedAt: time.Now(),
+	}
+	if err := store.AppendEvent(ctx, event); err != nil {
+		return err
+	}
+	return enqueueWebhookDeliveries(ctx, store, event)
+}
+
+// Subscription recebe os eventos de um usuário a partir do momento em que
+// foi criada. Events é fechado se o assinante ficar para trás (o buffer
+// encher) ou se o hub perder eventos (ex: queda do LISTEN): o cliente deve
+// reconectar e retomar do último ID com EventHub.Replay.
+type Subscription struct {
+	Events <-chan *models.InboxEvent
+
+	ch     chan *models.InboxEvent
+	hub    *EventHub
+	userID uuid.UUID
+}
+
+// Close cancela a assinatura; pode ser chamado mais de uma vez
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
+
+// EventHub distribui os eventos gravados para as conexões abertas nesta
+// réplica. Com o PostgreSQL, recebe os eventos de todas as réplicas via
+// LISTEN/NOTIFY; nos demais stores (um só processo), consulta os novos
+// eventos periodicamente.
+type EventHub struct {
+	store        repository.EventStore
+	pollInterval time.Duration
+	bufferSize   int
+	maxBackoff   time.Duration
+
+	mu      sync.Mutex
+	subs    map[uuid.UUID]map[*Subscription]struct{}
+	stopped bool // Run terminou: novas assinaturas já nascem fechadas
+}
+
+// NewEventHub cria o hub; os eventos só são distribuídos enquanto Run roda
+func NewEventHub(store repository.EventStore) *EventHub {
+	return &EventHub{
+		store:        store,
+		pollInterval: time.Second,
+		bufferSize:   64,
+		maxBackoff:   30 * time.Second,
+		subs:         make(map[uuid.UUID]map[*Subscription]struct{}),
+	}
+}
+
+// SetPollInterval muda o intervalo de consulta dos stores sem LISTEN
+func (h *EventHub) SetPollInterval(d time.Duration) {
+	h.pollInterval = d
+}
+
+// Subscribe passa a entregar os eventos do usuário. Assine antes de chamar
+// Replay, descartando os IDs repetidos, para não perder eventos gravados
+// entre as duas chamadas.
+func (h *EventHub) Subscribe(userID uuid.UUID) *Subscription {
+	ch := make(chan *models.InboxEvent, h.bufferSize)
+	sub := &Subscription{Events: ch, ch: ch, hub: h, userID: userID}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		close(ch)
+		return sub
+	}
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+// Replay chama fn, em ordem, para cada evento guardado do usuário com ID
+// maior que afterID (o Last-Event-ID do cliente)
+func (h *EventHub) Replay(ctx context.Context, userID uuid.UUID, afterID int64, fn func(event *models.InboxEvent) error) error {
+	const pageSize = 100
+	for {
+		events, err := h.store.GetEventsAfter(ctx, userID, afterID, pageSize)
+		if err != nil {
+			logging.FromContext(ctx).Error("Erro ao buscar eventos", "user_id", userID, "err", err)
+			return fmt.Errorf("erro interno ao buscar eventos")
+		}
+		for _, e := range events {
+			if err := fn(e); err != nil {
+				return err
+			}
+			afterID = e.ID
+		}
+		if len(events) < pageSize {
+			return nil
+		}
+	}
+}
+
+// Run distribui os eventos até ctx ser cancelado; ao sair, encerra todas
+// as assinaturas
+func (h *EventHub) Run(ctx context.Context) {
+	defer func() {
+		h.mu.Lock()
+		h.stopped = true
+		h.mu.Unlock()
+		h.dropAll()
+	}()
+
+	listener, ok := h.store.(repository.EventListener)
+	if !ok {
+		h.poll(ctx)
+		return
+	}
+
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := listener.ListenEvents(ctx, h.dispatch)
+		if ctx.Err() != nil {
+			return
+		}
+		// Eventos confirmados enquanto o LISTEN estava fora se perderam:
+		// derruba as assinaturas para que os clientes retomem do último ID
+		h.dropAll()
+		if time.Since(started) > h.maxBackoff {
+			backoff = time.Second
+		}
+		logging.FromContext(ctx).Warn("LISTEN de eventos interrompido; reconectando", "err", err, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, h.maxBackoff)
+	}
+}
+
+// poll consulta os eventos gravados depois do último visto. Só é usado com
+// stores de um processo só, em que os IDs seguem a ordem de commit.
+func (h *EventHub) poll(ctx context.Context) {
+	lastID, err := h.store.LatestEventID(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar o último evento", "err", err)
+	}
+
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		events, err := h.store.GetEventsAfter(ctx, uuid.Nil, lastID, 0)
+		if err != nil {
+			if ctx.Err() == nil {
+				logging.FromContext(ctx).Error("Erro ao buscar novos eventos", "err", err)
+			}
+			continue
+		}
+		for _, e := range events {
+			h.dispatch(e)
+			lastID = e.ID
+		}
+	}
+}
+
+// dispatch entrega o evento às assinaturas do destinatário sem bloquear:
+// quem está com o buffer cheio é desconectado
+func (h *EventHub) dispatch(event *models.InboxEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs[event.UserID] {
+		select {
+		case sub.ch <- event:
+		default:
+			slog.Warn("Assinante de eventos ficou para trás; desconectando", "user_id", event.UserID)
+			h.drop(sub)
+		}
+	}
+}
+
+// drop remove e fecha a assinatura; chame com h.mu travado
+func (h *EventHub) drop(sub *Subscription) {
+	subs := h.subs[sub.userID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subs, sub.userID)
+	}
+	close(sub.ch)
+}
+
+func (h *EventHub) dropAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subs := range h.subs {
+		for sub := range subs {
+			h.drop(sub)
+		}
+	}
+}
+
+// JobKindPruneEvents é o job da fila (internal/jobs) que apaga os eventos
+// mais antigos que a retenção
+const JobKindPruneEvents = "events.prune"
+
+// PruneEventsJob cria o handler de JobKindPruneEvents. Depois da retenção,
+// quem reconectar com um Last-Event-ID antigo só recebe os eventos ainda
+// guardados.
+func PruneEventsJob(store repository.EventStore, retention time.Duration) func(ctx context.Context, payload []byte) error {
+	return func(ctx context.Context, _ []byte) error {
+		pruned, err := store.PruneEvents(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		if pruned > 0 {
+			logging.FromContext(ctx).Info("Eventos da caixa de entrada podados", "count", pruned)
+		}
+		return nil
+	}
+}
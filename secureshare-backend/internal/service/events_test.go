@@ -0,0 +1,161 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// nextEvent espera o próximo evento da assinatura
+func nextEvent(t *testing.T, sub *service.Subscription) *models.InboxEvent {
+	t.Helper()
+	select {
+	case e, ok := <-sub.Events:
+		if !ok {
+			t.Fatal("assinatura fechada inesperadamente")
+		}
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("nenhum evento recebido")
+		return nil
+	}
+}
+
+func transferEventData(t *testing.T, e *models.InboxEvent) service.TransferEventData {
+	t.Helper()
+	var data service.TransferEventData
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		t.Fatalf("data inválido em %s: %v", e.Type, err)
+	}
+	return data
+}
+
+func TestTransferEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := repository.NewInMemoryStore()
+	bucket := newFakeBucket()
+	transfers := service.NewTransferService(store, bucket, service.UploadLimits{})
+	tokens, err := auth.NewTokenService("segredo-de-teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := service.NewAccountService(store, service.NewUserService(store, tokens))
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("senha-forte"), bcrypt.MinCost)
+	alice := &models.User{ID: uuid.New(), Username: "alice", PasswordHash: string(hash), CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	bob := &models.User{ID: uuid.New(), Username: "bob", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	for _, u := range []*models.User{alice, bob} {
+		if err := store.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hub := service.NewEventHub(store)
+	hub.SetPollInterval(10 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+	// Run lê o último ID ao começar; eventos anteriores só saem no Replay
+	time.Sleep(50 * time.Millisecond)
+	aliceSub := hub.Subscribe(alice.ID)
+	bobSub := hub.Subscribe(bob.ID)
+
+	// transfer.created vai para o destinatário
+	key, err := transfers.ReserveUpload(ctx, alice, upload(100))
+	if err != nil {
+		t.Fatalf("ReserveUpload: %v", err)
+	}
+	bucket.put(key, time.Now())
+	transfer, err := transfers.CreateTransfer(ctx, alice.ID, service.CreateTransferRequest{
+		DestUsername: "bob", LinkToEncFile: key, SKB: "skb", Sig: "sig",
+	})
+	if err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	created := nextEvent(t, bobSub)
+	data := transferEventData(t, created)
+	if created.Type != service.EventTransferCreated || data.TransferID != transfer.ID || data.SourceUser != "alice" || data.Size != 100 {
+		t.Fatalf("transfer.created inesperado: %s %+v", created.Type, data)
+	}
+
+	// transfer.downloaded vai para o remetente; o remetente baixando o
+	// próprio arquivo não gera evento
+	if err := transfers.RecordDownload(ctx, alice, key); err != nil {
+		t.Fatalf("RecordDownload(remetente): %v", err)
+	}
+	if err := transfers.RecordDownload(ctx, bob, key); err != nil {
+		t.Fatalf("RecordDownload: %v", err)
+	}
+	downloaded := nextEvent(t, aliceSub)
+	data = transferEventData(t, downloaded)
+	if downloaded.Type != service.EventTransferDownloaded || data.TransferID != transfer.ID || data.DestUser != "bob" {
+		t.Fatalf("transfer.downloaded inesperado: %s %+v", downloaded.Type, data)
+	}
+
+	// transfer.revoked vai para o destinatário quando o remetente sai
+	if err := accounts.DeleteAccount(ctx, alice.ID, "senha-forte", time.Time{}); err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	revoked := nextEvent(t, bobSub)
+	data = transferEventData(t, revoked)
+	if revoked.Type != service.EventTransferRevoked || data.TransferID != transfer.ID || data.Reason != "account_deleted" {
+		t.Fatalf("transfer.revoked inesperado: %s %+v", revoked.Type, data)
+	}
+
+	// A retomada devolve os eventos guardados depois do último visto
+	var replayed []int64
+	err = hub.Replay(ctx, bob.ID, created.ID, func(e *models.InboxEvent) error {
+		replayed = append(replayed, e.ID)
+		return nil
+	})
+	if err != nil || len(replayed) != 1 || replayed[0] != revoked.ID {
+		t.Fatalf("Replay depois de %d: %v, err=%v", created.ID, replayed, err)
+	}
+
+	// Ao parar, o hub fecha as assinaturas abertas e as novas
+	cancel()
+	<-done
+	if _, ok := <-bobSub.Events; ok {
+		t.Fatal("assinatura deveria ser fechada quando o hub para")
+	}
+	if _, ok := <-hub.Subscribe(bob.ID).Events; ok {
+		t.Fatal("assinatura depois do fim do hub deveria nascer fechada")
+	}
+}
+
+func TestPruneEventsJob(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	alice := &models.User{ID: uuid.New(), Username: "alice", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	if err := store.CreateUser(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	for _, age := range []time.Duration{2 * time.Hour, time.Minute} {
+		err := store.AppendEvent(ctx, &models.InboxEvent{
+			UserID: alice.ID, Type: service.EventTransferCreated, Data: json.RawMessage(`{}`), CreatedAt: time.Now().Add(-age),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := service.PruneEventsJob(store, time.Hour)(ctx, nil); err != nil {
+		t.Fatalf("PruneEventsJob: %v", err)
+	}
+	events, err := store.GetEventsAfter(ctx, alice.ID, 0, 0)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("esperava só o evento recente, obteve %d (err=%v)", len(events), err)
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// IdempotencyTTL é por quanto tempo a resposta de uma requisição com
+	// Idempotency-Key é guardada e repetida
+	IdempotencyTTL = 24 * time.Hour
+	// IdempotencyLease é o prazo de uma requisição em andamento: se o
+	// processo cair antes de Complete/Release, um reenvio depois disso
+	// assume a chave. Fica bem acima do WriteTimeout do servidor (10s).
+	IdempotencyLease = time.Minute
+	// MaxIdempotencyKeyLength é o tamanho máximo da chave (em bytes)
+	MaxIdempotencyKeyLength = 255
+	// JobKindPruneIdempotency é o job da fila (internal/jobs) que apaga as
+	// chaves expiradas
+	JobKindPruneIdempotency = "idempotency.prune"
+)
+
+// IdempotencyService guarda as respostas das requisições com
+// Idempotency-Key, para que um reenvio (ex: após um timeout no cliente) não
+// crie uma segunda transferência
+type IdempotencyService struct {
+	store repository.IdempotencyStore
+}
+
+// NewIdempotencyService cria um novo serviço de idempotência
+func NewIdempotencyService(store repository.IdempotencyStore) *IdempotencyService {
+	return &IdempotencyService{store: store}
+}
+
+// IdempotencyFingerprint identifica a requisição: o mesmo método, caminho e
+// corpo dão o mesmo valor
+func IdempotencyFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin reserva a chave do usuário para a requisição de fingerprint, por
+// IdempotencyLease. Se a chave for nova (ou a anterior tiver expirado),
+// retorna a reserva: a requisição deve ser executada e depois encerrada
+// com Complete ou Release. Se a chave já tiver uma resposta para a mesma
+// requisição, retorna-a em stored. Erros: ErrValidation (chave inválida),
+// ErrUnprocessable (a chave veio com outra requisição) e ErrConflict (a
+// original ainda está em andamento).
+func (s *IdempotencyService) Begin(ctx context.Context, userID uuid.UUID, key, fingerprint string) (reserved, stored *models.IdempotencyRecord, err error) {
+	if err := validateIdempotencyKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	// CreatedAt identifica a reserva em Complete e Release, na precisão
+	// que o PostgreSQL guarda
+	now := time.Now().Truncate(time.Microsecond)
+	reserved = &models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(IdempotencyLease),
+	}
+	err = s.store.CreateIdempotencyRecord(ctx, reserved, now)
+	if err == nil {
+		return reserved, nil, nil
+	}
+	if !errors.Is(err, apperr.ErrConflict) {
+		logging.FromContext(ctx).Error("Erro ao registrar chave de idempotência", "err", err)
+		return nil, nil, fmt.Errorf("erro interno ao registrar chave de idempotência")
+	}
+
+	existing, err := s.store.GetIdempotencyRecord(ctx, userID, key)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			// Liberada entre as duas consultas: a original falhou agora
+			return nil, nil, apperr.New(apperr.ErrConflict, apperr.CodeIdempotencyKeyInProgress, "requisição com esta Idempotency-Key em andamento; tente novamente")
+		}
+		logging.FromContext(ctx).Error("Erro ao buscar chave de idempotência", "err", err)
+		return nil, nil, fmt.Errorf("erro interno ao buscar chave de idempotência")
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, nil, apperr.New(apperr.ErrUnprocessable, apperr.CodeIdempotencyKeyReused, "Idempotency-Key já utilizada com outra requisição")
+	}
+	if existing.StatusCode == 0 {
+		return nil, nil, apperr.New(apperr.ErrConflict, apperr.CodeIdempotencyKeyInProgress, "requisição com esta Idempotency-Key em andamento; tente novamente")
+	}
+	return nil, existing, nil
+}
+
+// Complete grava a resposta da requisição reservada por Begin, que passa a
+// ser repetida por IdempotencyTTL
+func (s *IdempotencyService) Complete(ctx context.Context, reserved *models.IdempotencyRecord, statusCode int, contentType string, body []byte) {
+	completed := *reserved
+	completed.StatusCode = statusCode
+	completed.ContentType = contentType
+	completed.Body = body
+	completed.ExpiresAt = time.Now().Add(IdempotencyTTL)
+	if err := s.store.CompleteIdempotencyRecord(ctx, &completed); err != nil {
+		// A reserva expira em IdempotencyLease (ou já foi assumida por um
+		// reenvio); até lá o cliente vê 409 em vez de uma segunda execução
+		logging.FromContext(ctx).Error("Erro ao gravar resposta da chave de idempotência", "idempotency_key", reserved.Key, "err", err)
+	}
+}
+
+// Release libera a chave reservada por Begin sem guardar a resposta (erros
+// 5xx), para que o reenvio execute a requisição de novo
+func (s *IdempotencyService) Release(ctx context.Context, reserved *models.IdempotencyRecord) {
+	if err := s.store.DeleteIdempotencyRecord(ctx, reserved); err != nil {
+		logging.FromContext(ctx).Error("Erro ao liberar chave de idempotência", "idempotency_key", reserved.Key, "err", err)
+	}
+}
+
+// validateIdempotencyKey aceita de 1 a MaxIdempotencyKeyLength caracteres
+// ASCII visíveis (ex: um UUID)
+func validateIdempotencyKey(key string) error {
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return apperr.New(apperr.ErrValidation, apperr.CodeInvalidIdempotencyKey, "Idempotency-Key deve ter de 1 a %d caracteres", MaxIdempotencyKeyLength)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return apperr.New(apperr.ErrValidation, apperr.CodeInvalidIdempotencyKey, "Idempotency-Key deve conter apenas caracteres ASCII visíveis")
+		}
+	}
+	return nil
+}
+
+// PruneIdempotencyJob cria o handler de JobKindPruneIdempotency
+func PruneIdempotencyJob(store repository.IdempotencyStore) func(ctx context.Context, payload []byte) error {
+	return func(ctx context.Context, _ []byte) error {
+		pruned, err := store.PruneIdempotencyRecords(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		if pruned > 0 {
+			logging.FromContext(ctx).Info("Chaves de idempotência expiradas podadas", "count", pruned)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/pkg/keybackup"
+
+	"github.com/google/uuid"
+)
+
+// KeyBackupService lida com o escrow do backup cifrado de chaves privadas.
+// O servidor só valida o formato e os parâmetros do KDF; a cifragem e a
+// decifragem acontecem exclusivamente no cliente.
+type KeyBackupService struct {
+	store repository.KeyBackupStore
+}
+
+// NewKeyBackupService cria um novo serviço de backup de chaves
+func NewKeyBackupService(store repository.KeyBackupStore) *KeyBackupService {
+	return &KeyBackupService{
+		store: store,
+	}
+}
+
+// PutBackup cria ou substitui o backup do usuário
+func (s *KeyBackupService) PutBackup(ctx context.Context, userID uuid.UUID, req keybackup.Backup) (*models.KeyBackup, error) {
+	if err := req.Validate(); err != nil {
+		return nil, apperr.WrapCode(apperr.ErrValidation, apperr.CodeInvalidKeyBackup, err, "backup inválido: %v", err)
+	}
+
+	backup := &models.KeyBackup{
+		UserID:         userID,
+		KDF:            req.KDF,
+		KDFSalt:        req.KDFSalt,
+		KDFIterations:  req.KDFIterations,
+		KDFMemoryKiB:   req.KDFMemoryKiB,
+		KDFParallelism: req.KDFParallelism,
+		Cipher:         req.Cipher,
+		Nonce:          req.Nonce,
+		Ciphertext:     req.Ciphertext,
+		UpdatedAt:      time.Now(),
+	}
+
+	if err := s.store.PutKeyBackup(ctx, backup); err != nil {
+		logging.FromContext(ctx).Error("Erro ao salvar backup de chaves no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao salvar backup de chaves")
+	}
+	return backup, nil
+}
+
+// GetBackup busca o backup do usuário
+func (s *KeyBackupService) GetBackup(ctx context.Context, userID uuid.UUID) (*models.KeyBackup, error) {
+	backup, err := s.store.GetKeyBackup(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, apperr.New(apperr.ErrNotFound, apperr.CodeKeyBackupNotFound, "backup de chaves não encontrado")
+		}
+		logging.FromContext(ctx).Error("Erro ao buscar backup de chaves no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar backup de chaves")
+	}
+	return backup, nil
+}
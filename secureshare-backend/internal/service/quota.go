@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// UploadLimits limita o tamanho dos arquivos e o espaço ocupado. Zero
+// desabilita o limite correspondente.
+type UploadLimits struct {
+	MaxFileSize int64
+	UserQuota   int64
+	// OrgQuota vale para uma pessoa somada às contas de serviço dela (a
+	// "organização" de quem é dono das contas)
+	OrgQuota int64
+}
+
+// BlobStater lê os metadados de um objeto (implementado por S3Service)
+type BlobStater interface {
+	StatObject(ctx context.Context, objectKey string) (*BlobObject, error)
+}
+
+// QuotaUsage é o uso de um escopo (usuário ou organização) frente à cota
+type QuotaUsage struct {
+	models.StorageUsage
+	QuotaBytes int64 `json:"quotaBytes,omitempty"` // ausente: sem limite
+	// AvailableBytes só aparece quando há cota
+	AvailableBytes *int64 `json:"availableBytes,omitempty"`
+}
+
+// StorageUsageReport é a resposta de GET /users/me/usage
+type StorageUsageReport struct {
+	User        QuotaUsage `json:"user"`
+	Org         QuotaUsage `json:"org"`
+	MaxFileSize int64      `json:"maxFileSize,omitempty"` // ausente: sem limite
+}
+
+func newQuotaUsage(usage *models.StorageUsage, quota int64) QuotaUsage {
+	q := QuotaUsage{StorageUsage: *usage, QuotaBytes: quota}
+	if quota > 0 {
+		available := max(quota-usage.UsedBytes-usage.ReservedBytes, 0)
+		q.AvailableBytes = &available
+	}
+	return q
+}
+
+// GetUsage retorna o espaço ocupado pelo usuário e pela organização dele
+func (s *TransferService) GetUsage(ctx context.Context, user *models.User) (*StorageUsageReport, error) {
+	report, err := s.usage(ctx, s.store, user, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("erro interno ao calcular uso de armazenamento")
+	}
+	return report, nil
+}
+
+func (s *TransferService) usage(ctx context.Context, store repository.Store, user *models.User, now time.Time) (*StorageUsageReport, error) {
+	userUsage, err := store.GetStorageUsage(ctx, []uuid.UUID{user.ID}, now)
+	if err != nil {
+		return nil, err
+	}
+	members, err := orgMembers(ctx, store, user)
+	if err != nil {
+		return nil, err
+	}
+	orgUsage, err := store.GetStorageUsage(ctx, members, now)
+	if err != nil {
+		return nil, err
+	}
+	return &StorageUsageReport{
+		User:        newQuotaUsage(userUsage, s.limits.UserQuota),
+		Org:         newQuotaUsage(orgUsage, s.limits.OrgQuota),
+		MaxFileSize: s.limits.MaxFileSize,
+	}, nil
+}
+
+// checkQuota falha se reservar size bytes passar de alguma cota
+func (s *TransferService) checkQuota(ctx context.Context, store repository.Store, user *models.User, size int64, now time.Time) error {
+	if s.limits.MaxFileSize > 0 && size > s.limits.MaxFileSize {
+		return apperr.New(apperr.ErrTooLarge, apperr.CodeFileTooLarge, "o arquivo (%d bytes) excede o tamanho máximo de %d bytes", size, s.limits.MaxFileSize)
+	}
+	if s.limits.UserQuota <= 0 && s.limits.OrgQuota <= 0 {
+		return nil
+	}
+
+	report, err := s.usage(ctx, store, user, now)
+	if err != nil {
+		return fmt.Errorf("falha ao calcular uso de armazenamento: %w", err)
+	}
+	if avail := report.User.AvailableBytes; avail != nil && size > *avail {
+		return apperr.New(apperr.ErrQuotaExceeded, apperr.CodeUserQuotaExceeded, "o upload de %d bytes excede a cota do usuário (%d bytes livres de %d)",
+			size, *avail, report.User.QuotaBytes)
+	}
+	if avail := report.Org.AvailableBytes; avail != nil && size > *avail {
+		return apperr.New(apperr.ErrQuotaExceeded, apperr.CodeOrgQuotaExceeded, "o upload de %d bytes excede a cota da organização (%d bytes livres de %d)",
+			size, *avail, report.Org.QuotaBytes)
+	}
+	return nil
+}
+
+// orgOwnerID retorna a pessoa dona da organização do usuário. Contas de
+// serviço sem dono formam uma organização sozinhas.
+func orgOwnerID(user *models.User) uuid.UUID {
+	if user.Kind == models.UserKindService && user.OwnerID != nil {
+		return *user.OwnerID
+	}
+	return user.ID
+}
+
+// orgMembers retorna a pessoa dona da organização do usuário e as contas de
+// serviço dela
+func orgMembers(ctx context.Context, store repository.UserStore, user *models.User) ([]uuid.UUID, error) {
+	ownerID := orgOwnerID(user)
+	accounts, err := store.GetServiceAccountsByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]uuid.UUID, 0, len(accounts)+1)
+	members = append(members, ownerID)
+	for _, account := range accounts {
+		members = append(members, account.ID)
+	}
+	return members, nil
+}
@@ -0,0 +1,223 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// testChecksum é o SHA-256 (base64) que o fakeBucket atribui aos objetos
+var testChecksum = func() string {
+	sum := sha256.Sum256([]byte("conteúdo cifrado"))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}()
+
+func upload(size int64) service.UploadRequest {
+	return service.UploadRequest{Size: size, ChecksumSHA256: testChecksum}
+}
+
+func createQuotaUsers(t *testing.T, store repository.Store) (alice, bot, bob *models.User) {
+	t.Helper()
+	now := time.Now()
+	alice = &models.User{ID: uuid.New(), Username: "alice", CreatedAt: now, Kind: models.UserKindHuman}
+	bot = &models.User{ID: uuid.New(), Username: "alice-bot", CreatedAt: now, Kind: models.UserKindService, OwnerID: &alice.ID}
+	bob = &models.User{ID: uuid.New(), Username: "bob", CreatedAt: now, Kind: models.UserKindHuman}
+	for _, u := range []*models.User{alice, bot, bob} {
+		if err := store.CreateUser(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return alice, bot, bob
+}
+
+func TestReserveUploadLimits(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	alice, bot, bob := createQuotaUsers(t, store)
+	transfers := service.NewTransferService(store, newFakeBucket(), service.UploadLimits{
+		MaxFileSize: 500,
+		UserQuota:   800,
+		OrgQuota:    1000,
+	})
+
+	if _, err := transfers.ReserveUpload(ctx, alice, upload(0)); !errors.Is(err, apperr.ErrValidation) {
+		t.Fatalf("tamanho zero: esperava ErrValidation, obteve %v", err)
+	}
+	if _, err := transfers.ReserveUpload(ctx, alice, upload(501)); !errors.Is(err, apperr.ErrTooLarge) {
+		t.Fatalf("acima do máximo: esperava ErrTooLarge, obteve %v", err)
+	}
+
+	// Cota do usuário: 500 + 300 cabem em 800; mais 1 byte não
+	for _, size := range []int64{500, 300} {
+		if _, err := transfers.ReserveUpload(ctx, alice, upload(size)); err != nil {
+			t.Fatalf("ReserveUpload(%d): %v", size, err)
+		}
+	}
+	if _, err := transfers.ReserveUpload(ctx, alice, upload(1)); !errors.Is(err, apperr.ErrQuotaExceeded) {
+		t.Fatalf("cota do usuário: esperava ErrQuotaExceeded, obteve %v", err)
+	}
+
+	// Cota da organização: a conta de serviço divide os 1000 bytes com alice
+	if _, err := transfers.ReserveUpload(ctx, bot, upload(200)); err != nil {
+		t.Fatalf("ReserveUpload(bot): %v", err)
+	}
+	if _, err := transfers.ReserveUpload(ctx, bot, upload(1)); !errors.Is(err, apperr.ErrQuotaExceeded) {
+		t.Fatalf("cota da organização: esperava ErrQuotaExceeded, obteve %v", err)
+	}
+	// Outra organização não é afetada
+	if _, err := transfers.ReserveUpload(ctx, bob, upload(500)); err != nil {
+		t.Fatalf("ReserveUpload(bob): %v", err)
+	}
+
+	usage, err := transfers.GetUsage(ctx, bot)
+	if err != nil {
+		t.Fatalf("GetUsage: %v", err)
+	}
+	if usage.User.ReservedBytes != 200 || usage.Org.ReservedBytes != 1000 ||
+		usage.Org.AvailableBytes == nil || *usage.Org.AvailableBytes != 0 || usage.MaxFileSize != 500 {
+		t.Fatalf("uso inesperado: %+v", usage)
+	}
+}
+
+func TestReserveUploadConcurrentQuota(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	alice, bot, _ := createQuotaUsers(t, store)
+	transfers := service.NewTransferService(store, newFakeBucket(), service.UploadLimits{OrgQuota: 1000})
+
+	// 20 reservas de 100 bytes, divididas entre alice e a conta de serviço
+	// dela: só 10 cabem na cota da organização
+	var wg sync.WaitGroup
+	var reserved, exceeded atomic.Int32
+	for i := 0; i < 20; i++ {
+		user := alice
+		if i%2 == 1 {
+			user = bot
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := transfers.ReserveUpload(ctx, user, upload(100))
+			switch {
+			case err == nil:
+				reserved.Add(1)
+			case errors.Is(err, apperr.ErrQuotaExceeded):
+				exceeded.Add(1)
+			default:
+				t.Errorf("ReserveUpload: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reserved.Load() != 10 || exceeded.Load() != 10 {
+		t.Fatalf("esperava 10 reservas e 10 recusas, obteve %d e %d", reserved.Load(), exceeded.Load())
+	}
+	usage, err := transfers.GetUsage(ctx, alice)
+	if err != nil {
+		t.Fatalf("GetUsage: %v", err)
+	}
+	if usage.Org.ReservedBytes != 1000 {
+		t.Fatalf("a organização deveria ter exatamente a cota reservada: %+v", usage.Org)
+	}
+}
+
+func TestCreateTransferRecordsActualSize(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	alice, _, bob := createQuotaUsers(t, store)
+	bucket := newFakeBucket()
+	transfers := service.NewTransferService(store, bucket, service.UploadLimits{UserQuota: 1000})
+
+	key, err := transfers.ReserveUpload(ctx, alice, upload(100))
+	if err != nil {
+		t.Fatalf("ReserveUpload: %v", err)
+	}
+	req := service.CreateTransferRequest{DestUsername: "bob", LinkToEncFile: key, SKB: "skb", Sig: "sig"}
+
+	if _, err := transfers.CreateTransfer(ctx, alice.ID, req); !errors.Is(err, apperr.ErrValidation) {
+		t.Fatalf("objeto ainda não enviado: esperava ErrValidation, obteve %v", err)
+	}
+	if _, err := transfers.CreateTransfer(ctx, bob.ID, req); !errors.Is(err, apperr.ErrForbidden) {
+		t.Fatalf("upload de outro usuário: esperava ErrForbidden, obteve %v", err)
+	}
+
+	bucket.put(key, time.Now())
+	transfer, err := transfers.CreateTransfer(ctx, alice.ID, req)
+	if err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	if transfer.Size != 100 || transfer.ChecksumSHA256 != testChecksum {
+		t.Fatalf("esperava o tamanho e o checksum do objeto, obteve %d/%q", transfer.Size, transfer.ChecksumSHA256)
+	}
+
+	// O mesmo arquivo para outro destinatário não conta de novo; a reserva
+	// foi trocada pelo tamanho real
+	req.DestUsername = "alice"
+	if _, err := transfers.CreateTransfer(ctx, alice.ID, req); err != nil {
+		t.Fatalf("CreateTransfer(segundo destinatário): %v", err)
+	}
+	usage, err := transfers.GetUsage(ctx, alice)
+	if err != nil {
+		t.Fatalf("GetUsage: %v", err)
+	}
+	if usage.User.UsedBytes != 100 || usage.User.ReservedBytes != 0 || *usage.User.AvailableBytes != 900 {
+		t.Fatalf("uso inesperado: %+v", usage.User)
+	}
+	if usage.Org.QuotaBytes != 0 || usage.Org.AvailableBytes != nil {
+		t.Fatalf("organização sem cota não deveria ter limite: %+v", usage.Org)
+	}
+}
+
+func TestUploadChecksums(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	alice, _, bob := createQuotaUsers(t, store)
+	bucket := newFakeBucket()
+	transfers := service.NewTransferService(store, bucket, service.UploadLimits{})
+
+	for _, checksum := range []string{"", "não é base64", base64.StdEncoding.EncodeToString([]byte("curto"))} {
+		req := service.UploadRequest{Size: 100, ChecksumSHA256: checksum}
+		if _, err := transfers.ReserveUpload(ctx, alice, req); !errors.Is(err, apperr.ErrValidation) {
+			t.Fatalf("checksum %q: esperava ErrValidation, obteve %v", checksum, err)
+		}
+	}
+
+	key, err := transfers.ReserveUpload(ctx, alice, upload(100))
+	if err != nil {
+		t.Fatalf("ReserveUpload: %v", err)
+	}
+	bucket.put(key, time.Now())
+
+	// O checksum declarado na criação precisa bater com o do objeto
+	other := sha256.Sum256([]byte("outro arquivo"))
+	req := service.CreateTransferRequest{DestUsername: "bob", LinkToEncFile: key, SKB: "skb", Sig: "sig",
+		ChecksumSHA256: base64.StdEncoding.EncodeToString(other[:])}
+	if _, err := transfers.CreateTransfer(ctx, alice.ID, req); !errors.Is(err, apperr.ErrValidation) {
+		t.Fatalf("checksum divergente: esperava ErrValidation, obteve %v", err)
+	}
+	req.ChecksumSHA256 = testChecksum
+	if _, err := transfers.CreateTransfer(ctx, alice.ID, req); err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+
+	pending, err := transfers.GetPendingTransfers(ctx, bob.ID)
+	if err != nil {
+		t.Fatalf("GetPendingTransfers: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ChecksumSHA256 != testChecksum {
+		t.Fatalf("o destinatário deveria receber o checksum: %+v", pending)
+	}
+}
@@ -3,12 +3,18 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
 	"time"
 
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/metrics"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 // S3Service encapsula o cliente S3
@@ -28,24 +34,37 @@ func NewS3Service(s3Client *s3.Client, bucketName string) *S3Service {
 	}
 }
 
-// GeneratePresignedPutURL gera uma URL para o cliente fazer upload (PUT)
-func (s *S3Service) GeneratePresignedPutURL(ctx context.Context, objectKey string, lifetime time.Duration) (string, error) {
+// GeneratePresignedPutURL gera uma URL para o cliente fazer upload (PUT).
+// O Content-Length e o x-amz-checksum-sha256 entram na assinatura: o S3
+// recusa um corpo de tamanho ou SHA-256 diferente. Retorna também os
+// cabeçalhos assinados, que o cliente precisa enviar exatamente assim.
+func (s *S3Service) GeneratePresignedPutURL(ctx context.Context, objectKey string, size int64, checksumSHA256 string, lifetime time.Duration) (string, http.Header, error) {
 	if objectKey == "" {
-		return "", fmt.Errorf("objectKey não pode ser vazio")
+		return "", nil, fmt.Errorf("objectKey não pode ser vazio")
+	}
+	if size <= 0 || checksumSHA256 == "" {
+		return "", nil, fmt.Errorf("tamanho e checksum do objeto são obrigatórios")
 	}
 
 	// Cria a requisição para a operação PutObject
 	request, err := s.presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(s.bucketName),
-		Key:    aws.String(objectKey),
+		Bucket:            aws.String(s.bucketName),
+		Key:               aws.String(objectKey),
+		ContentLength:     aws.Int64(size),
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+		ChecksumSHA256:    aws.String(checksumSHA256),
 	}, s3.WithPresignExpires(lifetime)) // Define o tempo de expiração
 
 	if err != nil {
-		log.Printf("Erro ao gerar Presigned PUT URL para %s: %v", objectKey, err)
-		return "", fmt.Errorf("falha ao gerar URL de upload")
+		logging.FromContext(ctx).Error("Erro ao gerar Presigned PUT URL", "object", objectKey, "err", err)
+		return "", nil, fmt.Errorf("falha ao gerar URL de upload")
 	}
+	metrics.PresignedURLIssued("put")
 
-	return request.URL, nil
+	// O Host é definido pela própria URL
+	headers := request.SignedHeader.Clone()
+	headers.Del("Host")
+	return request.URL, headers, nil
 }
 
 func (s *S3Service) GeneratePresignedGetURL(ctx context.Context, objectKey string, lifetime time.Duration) (string, error) {
@@ -60,9 +79,124 @@ func (s *S3Service) GeneratePresignedGetURL(ctx context.Context, objectKey strin
 	}, s3.WithPresignExpires(lifetime))
 
 	if err != nil {
-		log.Printf("Erro ao gerar Presigned GET URL para %s: %v", objectKey, err)
+		logging.FromContext(ctx).Error("Erro ao gerar Presigned GET URL", "object", objectKey, "err", err)
 		return "", fmt.Errorf("falha ao gerar URL de download")
 	}
+	metrics.PresignedURLIssued("get")
 
 	return request.URL, nil
 }
+
+// StatObject lê o tamanho, a data e o checksum de um objeto (HEAD). Objeto inexistente
+// retorna apperr.ErrNotFound.
+func (s *S3Service) StatObject(ctx context.Context, objectKey string) (*BlobObject, error) {
+	start := time.Now()
+	out, err := s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s.bucketName),
+		Key:          aws.String(objectKey),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	var notFound *types.NotFound
+	// Objeto inexistente é uma resposta esperada, não uma falha do S3
+	isNotFound := errors.As(err, &notFound)
+	metrics.ObserveS3("head_object", start, err != nil && !isNotFound)
+	if err != nil {
+		if isNotFound {
+			return nil, apperr.NotFound("objeto '%s' não encontrado", objectKey)
+		}
+		logging.FromContext(ctx).Error("Erro ao ler metadados no S3", "object", objectKey, "err", err)
+		return nil, fmt.Errorf("falha ao ler metadados do objeto")
+	}
+	return &BlobObject{
+		Key:          objectKey,
+		Size:         aws.ToInt64(out.ContentLength),
+		LastModified: aws.ToTime(out.LastModified),
+		// Vazio para objetos enviados sem checksum
+		ChecksumSHA256: aws.ToString(out.ChecksumSHA256),
+	}, nil
+}
+
+// DeleteObjects remove uma lista de objetos do bucket.
+// Objetos inexistentes não são considerados erro pelo S3.
+func (s *S3Service) DeleteObjects(ctx context.Context, objectKeys []string) error {
+	// O DeleteObjects do S3 aceita no máximo 1000 chaves por chamada
+	const batchSize = 1000
+
+	for start := 0; start < len(objectKeys); start += batchSize {
+		end := min(start+batchSize, len(objectKeys))
+
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range objectKeys[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		begin := time.Now()
+		out, err := s.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.bucketName),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		metrics.ObserveS3("delete_objects", begin, err != nil || len(out.Errors) > 0)
+		if err != nil {
+			logging.FromContext(ctx).Error("Erro ao remover objetos do S3", "err", err)
+			return fmt.Errorf("falha ao remover objetos do S3")
+		}
+		if len(out.Errors) > 0 {
+			first := out.Errors[0]
+			logging.FromContext(ctx).Error("Erro ao remover objeto do S3", "object", aws.ToString(first.Key), "err", aws.ToString(first.Message))
+			return fmt.Errorf("falha ao remover %d objeto(s) do S3", len(out.Errors))
+		}
+	}
+
+	return nil
+}
+
+// DeletePrefix remove todos os objetos cuja chave começa com o prefixo
+func (s *S3Service) DeletePrefix(ctx context.Context, prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("prefixo não pode ser vazio")
+	}
+
+	var keys []string
+	err := s.ListObjects(ctx, prefix, func(page []BlobObject) error {
+		for _, obj := range page {
+			keys = append(keys, obj.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.DeleteObjects(ctx, keys)
+}
+
+// ListObjects lista os objetos sob o prefixo, uma página (até 1000
+// objetos) por chamada de fn
+func (s *S3Service) ListObjects(ctx context.Context, prefix string, fn func(page []BlobObject) error) error {
+	paginator := s3.NewListObjectsV2Paginator(s.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucketName),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		start := time.Now()
+		page, err := paginator.NextPage(ctx)
+		metrics.ObserveS3("list_objects", start, err != nil)
+		if err != nil {
+			logging.FromContext(ctx).Error("Erro ao listar objetos", "prefix", prefix, "err", err)
+			return fmt.Errorf("falha ao listar objetos do S3")
+		}
+
+		objects := make([]BlobObject, 0, len(page.Contents))
+		for _, obj := range page.Contents {
+			objects = append(objects, BlobObject{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
+		if err := fn(objects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/auth/oidc"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/metrics"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// SSOService lida com o login via OIDC: mapeia a identidade do IdP para um
+// models.User e emite o token de sessão do próprio SecureShare
+type SSOService struct {
+	provider      *oidc.Provider
+	store         repository.Store
+	tokenService  *auth.TokenService
+	usernameClaim string
+	autoProvision bool
+}
+
+// NewSSOService cria um novo serviço de SSO
+func NewSSOService(
+	provider *oidc.Provider,
+	store repository.Store,
+	tokenService *auth.TokenService,
+	usernameClaim string,
+	autoProvision bool,
+) *SSOService {
+	return &SSOService{
+		provider:      provider,
+		store:         store,
+		tokenService:  tokenService,
+		usernameClaim: usernameClaim,
+		autoProvision: autoProvision,
+	}
+}
+
+// SSOLoginResult é o resultado de um login SSO concluído
+type SSOLoginResult struct {
+	Token string
+	User  *models.User
+	// NeedsKeys indica uma conta sem chaves (recém-criada): o cliente deve
+	// gerar um par e registrá-lo como primeiro dispositivo (POST /devices)
+	NeedsKeys bool
+}
+
+// AuthCodeURL monta a URL de autorização do IdP
+func (s *SSOService) AuthCodeURL(state, nonce, codeVerifier string) string {
+	return s.provider.AuthCodeURL(state, nonce, codeVerifier)
+}
+
+// CompleteLogin troca o código de autorização, valida o ID token e emite o
+// token de sessão
+func (s *SSOService) CompleteLogin(ctx context.Context, code, codeVerifier, nonce string) (_ *SSOLoginResult, err error) {
+	defer func() { metrics.ObserveLogin(metrics.LoginSSO, err) }()
+
+	claims, err := s.provider.Exchange(ctx, code, codeVerifier, nonce)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro no login SSO", "err", err)
+		return nil, apperr.New(apperr.ErrUnauthorized, apperr.CodeSSOFailed, "falha na autenticação SSO")
+	}
+
+	user, err := s.store.GetUserByIdentity(ctx, claims.Issuer, claims.Subject)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		logging.FromContext(ctx).Error("Erro ao buscar identidade SSO no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar usuário")
+	}
+	if err != nil {
+		user, err = s.provision(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Kind == models.UserKindService {
+		return nil, apperr.New(apperr.ErrUnauthorized, apperr.CodeSSOFailed, "falha na autenticação SSO")
+	}
+
+	token, err := s.tokenService.NewToken(user.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao gerar token JWT", "err", err)
+		return nil, fmt.Errorf("erro interno ao gerar token")
+	}
+
+	return &SSOLoginResult{Token: token, User: user, NeedsKeys: user.PublicKey == ""}, nil
+}
+
+// provision cria a conta local no primeiro login de uma identidade
+func (s *SSOService) provision(ctx context.Context, claims *oidc.Claims) (*models.User, error) {
+	if !s.autoProvision {
+		return nil, apperr.New(apperr.ErrUnauthorized, apperr.CodeSSOFailed, "identidade SSO não vinculada a nenhuma conta")
+	}
+
+	username, err := s.usernameFromClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Não vinculamos automaticamente a uma conta existente com o mesmo nome:
+	// isso permitiria tomar a conta de quem se cadastrou com senha
+	if _, err := s.store.GetUserByUsername(ctx, username); err == nil {
+		return nil, apperr.New(apperr.ErrConflict, apperr.CodeUserExists, "usuário '%s' já existe", username)
+	}
+
+	// Sem senha (login por senha fica desabilitado) e sem chaves até o
+	// cliente registrar o primeiro dispositivo
+	user := &models.User{
+		ID:        uuid.New(),
+		Username:  username,
+		CreatedAt: time.Now(),
+		Kind:      models.UserKindHuman,
+	}
+	identity := &models.Identity{
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		UserID:    user.ID,
+		Email:     claims.Email,
+		CreatedAt: user.CreatedAt,
+	}
+	// Usuário e vínculo na mesma transação: um usuário sem identidade
+	// ocuparia o nome e todo login SSO seguinte daria conflito
+	err = s.store.WithTx(ctx, func(tx repository.Store) error {
+		if err := tx.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		return tx.CreateIdentity(ctx, identity)
+	})
+	if err != nil {
+		if errors.Is(err, apperr.ErrConflict) {
+			return nil, apperr.New(apperr.ErrConflict, apperr.CodeUserExists, "usuário '%s' já existe", username)
+		}
+		logging.FromContext(ctx).Error("Erro ao salvar usuário SSO no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao salvar usuário")
+	}
+
+	return user, nil
+}
+
+func (s *SSOService) usernameFromClaims(claims *oidc.Claims) (string, error) {
+	if s.usernameClaim == "email" {
+		// E-mail não verificado não pode virar identificador da conta
+		if claims.Email == "" || !claims.EmailVerified {
+			return "", apperr.New(apperr.ErrUnauthorized, apperr.CodeSSOFailed, "o IdP não forneceu um e-mail verificado")
+		}
+		return strings.ToLower(claims.Email), nil
+	}
+
+	username, _ := claims.Raw[s.usernameClaim].(string)
+	if username == "" {
+		return "", apperr.New(apperr.ErrUnauthorized, apperr.CodeSSOFailed, "o IdP não forneceu a claim '%s'", s.usernameClaim)
+	}
+	return username, nil
+}
@@ -0,0 +1,232 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/auth/oidc"
+	"secureshare-backend/internal/auth/oidc/oidctest"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ssoClientID    = "secureshare"
+	ssoRedirectURL = "http://localhost:8080/v1/auth/oidc/callback"
+)
+
+type ssoFixture struct {
+	issuer *oidctest.Issuer
+	store  *repository.InMemoryStore
+	tokens *auth.TokenService
+	sso    *service.SSOService
+}
+
+func newSSOFixture(t *testing.T, usernameClaim string, autoProvision bool) *ssoFixture {
+	t.Helper()
+	iss := oidctest.NewIssuer(ssoClientID)
+	t.Cleanup(iss.Close)
+
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:   iss.URL(),
+		ClientID:    ssoClientID,
+		RedirectURL: ssoRedirectURL,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	tokens, err := auth.NewTokenService("segredo-de-teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := repository.NewInMemoryStore()
+	return &ssoFixture{
+		issuer: iss,
+		store:  store,
+		tokens: tokens,
+		sso:    service.NewSSOService(provider, store, tokens, usernameClaim, autoProvision),
+	}
+}
+
+// login executa o fluxo completo (authorize + callback) com as claims dadas
+func (f *ssoFixture) login(t *testing.T, claims map[string]any) (*service.SSOLoginResult, error) {
+	t.Helper()
+	f.issuer.SetClaims(claims)
+
+	state, _ := oidc.RandomString()
+	nonce, _ := oidc.RandomString()
+	verifier, _ := oidc.RandomString()
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get(f.sso.AuthCodeURL(state, nonce, verifier))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize: Location inválida: %v", err)
+	}
+	if loc.Query().Get("state") != state {
+		t.Fatalf("state não preservado pelo IdP")
+	}
+
+	return f.sso.CompleteLogin(context.Background(), loc.Query().Get("code"), verifier, nonce)
+}
+
+func TestSSOProvisionsUserOnFirstLogin(t *testing.T) {
+	f := newSSOFixture(t, "email", true)
+
+	first, err := f.login(t, map[string]any{"sub": "emp-42", "email": "Alice@Corp.example", "email_verified": true})
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if first.User.Username != "alice@corp.example" {
+		t.Fatalf("username = %q, esperado e-mail em minúsculas", first.User.Username)
+	}
+	if !first.NeedsKeys {
+		t.Fatal("conta recém-criada deveria exigir registro de chaves")
+	}
+	if first.User.PasswordHash != "" {
+		t.Fatal("conta SSO não deveria ter senha")
+	}
+
+	token, err := f.tokens.ValidateToken(first.Token)
+	if err != nil {
+		t.Fatalf("token emitido inválido: %v", err)
+	}
+	if id, _ := f.tokens.GetUserIDFromToken(token); id != first.User.ID {
+		t.Fatalf("token aponta para %s, esperado %s", id, first.User.ID)
+	}
+
+	// O segundo login reutiliza a conta pelo par (iss, sub), mesmo que o
+	// e-mail tenha mudado no IdP
+	second, err := f.login(t, map[string]any{"sub": "emp-42", "email": "alice.smith@corp.example", "email_verified": true})
+	if err != nil {
+		t.Fatalf("segundo CompleteLogin: %v", err)
+	}
+	if second.User.ID != first.User.ID {
+		t.Fatalf("segundo login criou outra conta (%s != %s)", second.User.ID, first.User.ID)
+	}
+}
+
+func TestSSOUsesConfiguredUsernameClaim(t *testing.T) {
+	f := newSSOFixture(t, "preferred_username", true)
+
+	res, err := f.login(t, map[string]any{"sub": "emp-7", "preferred_username": "bob"})
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if res.User.Username != "bob" {
+		t.Fatalf("username = %q, esperado 'bob'", res.User.Username)
+	}
+
+	if _, err := f.login(t, map[string]any{"sub": "emp-8"}); err == nil {
+		t.Fatal("esperava erro sem a claim de username")
+	}
+}
+
+func TestSSORejectsUnverifiedEmail(t *testing.T) {
+	f := newSSOFixture(t, "email", true)
+
+	if _, err := f.login(t, map[string]any{"sub": "emp-1", "email": "eve@corp.example", "email_verified": false}); err == nil {
+		t.Fatal("esperava erro com e-mail não verificado")
+	}
+}
+
+func TestSSODoesNotTakeOverExistingAccount(t *testing.T) {
+	f := newSSOFixture(t, "email", true)
+
+	existing := &models.User{
+		ID:           uuid.New(),
+		Username:     "carol@corp.example",
+		PasswordHash: "hash",
+		CreatedAt:    time.Now(),
+		Kind:         models.UserKindHuman,
+	}
+	if err := f.store.CreateUser(context.Background(), existing); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := f.login(t, map[string]any{"sub": "emp-2", "email": "carol@corp.example", "email_verified": true})
+	if !errors.Is(err, apperr.ErrConflict) {
+		t.Fatalf("esperava conflito (username já pertence a uma conta com senha), obteve %v", err)
+	}
+}
+
+func TestSSOWithoutAutoProvision(t *testing.T) {
+	f := newSSOFixture(t, "email", false)
+
+	if _, err := f.login(t, map[string]any{"sub": "emp-3", "email": "dave@corp.example", "email_verified": true}); err == nil {
+		t.Fatal("esperava erro para identidade não vinculada")
+	}
+
+	// Com a identidade vinculada previamente, o login funciona
+	user := &models.User{ID: uuid.New(), Username: "dave", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	if err := f.store.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.store.CreateIdentity(context.Background(), &models.Identity{
+		Issuer: f.issuer.URL(), Subject: "emp-3", UserID: user.ID, CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := f.login(t, map[string]any{"sub": "emp-3", "email": "dave@corp.example", "email_verified": true})
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if res.User.ID != user.ID {
+		t.Fatalf("login resolveu para %s, esperado %s", res.User.ID, user.ID)
+	}
+}
+
+func TestSSOAccountReauthenticatesWithRecentLogin(t *testing.T) {
+	ctx := context.Background()
+	f := newSSOFixture(t, "email", true)
+	users := service.NewUserService(f.store, f.tokens)
+	accounts := service.NewAccountService(f.store, users)
+
+	res, err := f.login(t, map[string]any{"sub": "emp-5", "email": "erin@corp.example", "email_verified": true})
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	_, session, err := users.AuthenticateSession(ctx, res.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateSession: %v", err)
+	}
+
+	// Sem senha, só um login recente confirma operações sensíveis
+	stale := time.Now().Add(-service.ReauthMaxAge - time.Minute)
+	if err := accounts.DeleteAccount(ctx, res.User.ID, "", stale); apperr.CodeOf(err) != apperr.CodeReauthRequired {
+		t.Fatalf("login antigo: esperava %s, obteve %v", apperr.CodeReauthRequired, err)
+	}
+
+	// A primeira senha é definida sem senha atual
+	if _, err := users.ChangePassword(ctx, res.User.ID, "", "senha-nova", session.IssuedAt); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if _, err := users.Login(ctx, "erin@corp.example", "senha-nova"); err != nil {
+		t.Fatalf("login com a senha definida: %v", err)
+	}
+
+	// Com senha, o login recente não basta mais
+	if err := accounts.DeleteAccount(ctx, res.User.ID, "", time.Now()); apperr.CodeOf(err) != apperr.CodeWrongPassword {
+		t.Fatalf("sem a senha: esperava %s, obteve %v", apperr.CodeWrongPassword, err)
+	}
+	if err := accounts.DeleteAccount(ctx, res.User.ID, "senha-nova", time.Time{}); err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+}
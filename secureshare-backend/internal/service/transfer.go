@@ -2,10 +2,16 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"log"
+	"strings"
 	"time"
 
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/metrics"
 	"secureshare-backend/internal/models"
 	"secureshare-backend/internal/repository"
 
@@ -14,57 +20,299 @@ import (
 
 // TransferService lida com a lógica de negócios de transferências
 type TransferService struct {
-	store repository.Store // Precisa de UserStore e TransferStore
+	store  repository.Store // Precisa de UserStore e TransferStore
+	blobs  BlobStater
+	limits UploadLimits
 }
 
 // NewTransferService cria um novo serviço de transferência
-func NewTransferService(store repository.Store) *TransferService {
+func NewTransferService(store repository.Store, blobs BlobStater, limits UploadLimits) *TransferService {
 	return &TransferService{
-		store: store,
+		store:  store,
+		blobs:  blobs,
+		limits: limits,
 	}
 }
 
-// CreateTransferRequest define os parâmetros para criar uma transferência
+// UploadReservationTTL é o prazo para concluir o POST /transfers depois de
+// pedir a URL de upload; até lá o coletor de órfãos não remove o objeto
+const UploadReservationTTL = 24 * time.Hour
+
+// UploadRequest descreve o arquivo cifrado que o cliente vai enviar. Os
+// dois campos entram na assinatura da URL: o S3 recusa um corpo de outro
+// tamanho ou com outro SHA-256.
+type UploadRequest struct {
+	Size int64 `json:"size"`
+	// SHA-256 do arquivo cifrado, em base64 (formato do x-amz-checksum-sha256)
+	ChecksumSHA256 string `json:"checksumSha256"`
+}
+
+// validateChecksumSHA256 exige um SHA-256 em base64 padrão
+func validateChecksumSHA256(checksum string) error {
+	raw, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil || len(raw) != sha256.Size {
+		return apperr.New(apperr.ErrValidation, apperr.CodeInvalidChecksum, "checksumSha256 deve ser o SHA-256 do arquivo cifrado em base64 (%d bytes)", sha256.Size)
+	}
+	return nil
+}
+
+// ReserveUpload confere o arquivo declarado, o tamanho máximo e as cotas,
+// gera a chave do objeto de um novo upload (uploads/<userID>/<uuid>) e a
+// registra. A reserva conta na cota e impede que o coletor de órfãos
+// remova o arquivo antes de a transferência ser criada.
+//
+// A conferência da cota e a reserva rodam na mesma transação, com o dono
+// da organização travado (LockUser): uploads simultâneos da organização
+// não conseguem, juntos, passar da cota.
+func (s *TransferService) ReserveUpload(ctx context.Context, user *models.User, req UploadRequest) (string, error) {
+	size := req.Size
+	if size <= 0 {
+		return "", apperr.New(apperr.ErrValidation, apperr.CodeInvalidFileSize, "o tamanho do arquivo deve ser positivo")
+	}
+	if err := validateChecksumSHA256(req.ChecksumSHA256); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	objectKey := fmt.Sprintf("%s%s/%s", UploadsPrefix, user.ID, uuid.New())
+	err := s.store.WithTx(ctx, func(tx repository.Store) error {
+		if err := tx.LockUser(ctx, orgOwnerID(user)); err != nil {
+			return fmt.Errorf("falha ao travar a organização: %w", err)
+		}
+		if err := s.checkQuota(ctx, tx, user, size, now); err != nil {
+			return err
+		}
+		return tx.ReserveUpload(ctx, &models.UploadReservation{
+			ObjectKey:      objectKey,
+			UserID:         user.ID,
+			Size:           size,
+			ChecksumSHA256: req.ChecksumSHA256,
+			CreatedAt:      now,
+			ExpiresAt:      now.Add(UploadReservationTTL),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, apperr.ErrTooLarge) || errors.Is(err, apperr.ErrQuotaExceeded) || errors.Is(err, apperr.ErrValidation) {
+			return "", err
+		}
+		logging.FromContext(ctx).Error("Erro ao reservar upload", "user_id", user.ID, "err", err)
+		return "", fmt.Errorf("erro interno ao reservar upload")
+	}
+	return objectKey, nil
+}
+
+// GetUploadReservation busca a reserva ainda válida de um upload do
+// usuário, para assinar de novo a URL de upload no reenvio de um pedido
+// com Idempotency-Key. Reservas de outro usuário, expiradas ou já usadas
+// numa transferência retornam ErrNotFound.
+func (s *TransferService) GetUploadReservation(ctx context.Context, user *models.User, objectKey string) (*models.UploadReservation, error) {
+	reservation, err := s.store.GetUploadReservation(ctx, objectKey)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		logging.FromContext(ctx).Error("Erro ao buscar reserva de upload", "object", objectKey, "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar reserva de upload")
+	}
+	if err != nil || reservation.UserID != user.ID || !time.Now().Before(reservation.ExpiresAt) {
+		return nil, apperr.New(apperr.ErrNotFound, apperr.CodeUploadNotFound, "a reserva do upload '%s' não existe mais; peça uma nova URL com outra Idempotency-Key", objectKey)
+	}
+	return reservation, nil
+}
+
+// CreateTransferRequest define os parâmetros para criar uma transferência.
+// As tags validate são conferidas pela API REST (upload_key é registrada
+// pelo validador dela).
 type CreateTransferRequest struct {
-	DestUsername  string `json:"destUser"`
-	LinkToEncFile string `json:"linkToEncFile"`
-	SKB           string `json:"skb"`
-	Sig           string `json:"sig"`
+	DestUsername  string `json:"destUser" validate:"required"`
+	LinkToEncFile string `json:"linkToEncFile" validate:"required,upload_key"`
+	SKB           string `json:"skb" validate:"omitempty,base64"`
+	Sig           string `json:"sig" validate:"required,base64"`
+	// Opcional: se presente, precisa bater com o checksum do objeto no S3
+	ChecksumSHA256 string `json:"checksumSha256,omitempty" validate:"omitempty,base64"`
+	// SKBs mapeia deviceId -> SKB cifrada para aquele dispositivo
+	SKBs map[string]string `json:"skbs,omitempty" validate:"omitempty,dive,keys,uuid,endkeys,required,base64"`
 }
 
-// CreateTransfer registra os metadados de uma nova transferência
+// CreateTransfer registra os metadados de uma nova transferência. A busca
+// do destinatário, a validação dos dispositivos e a gravação rodam na mesma
+// transação.
 func (s *TransferService) CreateTransfer(ctx context.Context, sourceUserID uuid.UUID, req CreateTransferRequest) (*models.Transfer, error) {
-	// 1. Encontrar o usuário de destino
-	destUser, err := s.store.GetUserByUsername(ctx, req.DestUsername)
+	// O arquivo precisa ser um upload do próprio remetente, já concluído;
+	// o tamanho real é o que passa a contar na cota
+	ownPrefix := fmt.Sprintf("%s%s/", UploadsPrefix, sourceUserID)
+	if !strings.HasPrefix(req.LinkToEncFile, ownPrefix) {
+		return nil, apperr.New(apperr.ErrForbidden, apperr.CodeUploadNotOwned, "linkToEncFile não é um upload deste usuário")
+	}
+	object, err := s.blobs.StatObject(ctx, req.LinkToEncFile)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, apperr.New(apperr.ErrValidation, apperr.CodeUploadNotFound, "o arquivo '%s' ainda não foi enviado", req.LinkToEncFile)
+		}
+		logging.FromContext(ctx).Error("Erro ao ler metadados do upload", "object", req.LinkToEncFile, "err", err)
+		return nil, fmt.Errorf("erro interno ao salvar transferência")
+	}
+	if req.ChecksumSHA256 != "" && req.ChecksumSHA256 != object.ChecksumSHA256 {
+		return nil, apperr.New(apperr.ErrValidation, apperr.CodeChecksumMismatch, "o arquivo enviado não corresponde ao checksumSha256 informado")
+	}
+
+	var transfer *models.Transfer
+	err = s.store.WithTx(ctx, func(tx repository.Store) error {
+		// 1. Encontrar o usuário de destino
+		destUser, err := tx.GetUserByUsername(ctx, req.DestUsername)
+		if err != nil {
+			if !errors.Is(err, apperr.ErrNotFound) {
+				logging.FromContext(ctx).Error("Erro ao buscar usuário de destino no store", "err", err)
+				return fmt.Errorf("erro interno ao salvar transferência")
+			}
+			return apperr.New(apperr.ErrNotFound, apperr.CodeDestUserNotFound, "usuário de destino '%s' não encontrado", req.DestUsername)
+		}
+
+		// 2. Validar as SKBs por dispositivo: cada uma precisa ir para um
+		// dispositivo ativo do destinatário
+		deviceSKBs, err := resolveDeviceSKBs(ctx, tx, destUser.ID, req.SKBs)
+		if err != nil {
+			return err
+		}
+
+		// 3. Criar o modelo de transferência
+		transfer = &models.Transfer{
+			ID:            uuid.New(),
+			SourceUserID:  sourceUserID,
+			DestUserID:    destUser.ID,
+			LinkToEncFile: req.LinkToEncFile,
+			SKB:           req.SKB,
+			Sig:           req.Sig,
+			CreatedAt:     time.Now(),
+			Size:          object.Size,
+			// O checksum vem do S3, que o conferiu no upload
+			ChecksumSHA256: object.ChecksumSHA256,
+			DeviceSKBs:     deviceSKBs,
+		}
+
+		// 4. Salvar no repositório; a reserva do upload deixa de valer (o
+		// objeto passa a contar pelo tamanho real)
+		if err := tx.CreateTransfer(ctx, transfer); err != nil {
+			logging.FromContext(ctx).Error("Erro ao salvar transferência no store", "err", err)
+			return fmt.Errorf("erro interno ao salvar transferência")
+		}
+		if err := tx.ReleaseUpload(ctx, transfer.LinkToEncFile); err != nil {
+			logging.FromContext(ctx).Error("Erro ao liberar reserva de upload", "err", err)
+			return fmt.Errorf("erro interno ao salvar transferência")
+		}
+
+		// 5. Avisar o destinatário (evento e e-mail, entregues só após o commit)
+		sourceUser, err := tx.GetUserByID(ctx, sourceUserID)
+		if err != nil {
+			logging.FromContext(ctx).Error("Erro ao buscar remetente no store", "err", err)
+			return fmt.Errorf("erro interno ao salvar transferência")
+		}
+		err = publishEvent(ctx, tx, destUser.ID, EventTransferCreated, TransferEventData{
+			TransferID: transfer.ID,
+			SourceUser: sourceUser.Username,
+			Size:       transfer.Size,
+		})
+		if err != nil {
+			logging.FromContext(ctx).Error("Erro ao gravar evento de transferência", "err", err)
+			return fmt.Errorf("erro interno ao salvar transferência")
+		}
+		if err := enqueueTransferEmail(ctx, tx, destUser, sourceUser.Username); err != nil {
+			logging.FromContext(ctx).Error("Erro ao agendar aviso por e-mail", "err", err)
+			return fmt.Errorf("erro interno ao salvar transferência")
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("usuário de destino '%s' não encontrado", req.DestUsername)
+		return nil, err
 	}
 
-	// 2. Criar o modelo de transferência
-	transfer := &models.Transfer{
-		ID:            uuid.New(),
-		SourceUserID:  sourceUserID,
-		DestUserID:    destUser.ID,
-		LinkToEncFile: req.LinkToEncFile,
-		SKB:           req.SKB,
-		Sig:           req.Sig,
-		CreatedAt:     time.Now(),
+	metrics.TransferCreated()
+	return transfer, nil
+}
+
+// AuthorizeDownload confere se user é remetente ou destinatário de alguma
+// transferência do arquivo fileKey. Arquivos de terceiros são
+// indistinguíveis de arquivos inexistentes (ErrNotFound).
+func (s *TransferService) AuthorizeDownload(ctx context.Context, user *models.User, fileKey string) error {
+	received, err := s.store.GetTransfersByDestUserID(ctx, user.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar transferências no store", "err", err)
+		return fmt.Errorf("erro interno ao autorizar download")
+	}
+	sent, err := s.store.GetTransfersBySourceUserID(ctx, user.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar transferências no store", "err", err)
+		return fmt.Errorf("erro interno ao autorizar download")
+	}
+	for _, t := range append(received, sent...) {
+		if t.LinkToEncFile == fileKey {
+			return nil
+		}
 	}
+	return apperr.New(apperr.ErrNotFound, apperr.CodeFileNotFound, "arquivo '%s' não encontrado", fileKey)
+}
 
-	// 3. Salvar no repositório
-	if err := s.store.CreateTransfer(ctx, transfer); err != nil {
-		log.Printf("Erro ao salvar transferência no store: %v", err)
+// RecordDownload avisa o remetente de que o destinatário pediu a URL de
+// download de fileKey. Sem transferência de fileKey para o usuário (ex: o
+// remetente baixando o próprio arquivo), não faz nada.
+func (s *TransferService) RecordDownload(ctx context.Context, user *models.User, fileKey string) error {
+	received, err := s.store.GetTransfersByDestUserID(ctx, user.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar transferências no store", "err", err)
+		return fmt.Errorf("erro interno ao registrar download")
+	}
+	for _, t := range received {
+		if t.LinkToEncFile != fileKey {
+			continue
+		}
+		// Evento e entregas de webhook são gravados juntos
+		err := s.store.WithTx(ctx, func(tx repository.Store) error {
+			return publishEvent(ctx, tx, t.SourceUserID, EventTransferDownloaded, TransferEventData{
+				TransferID: t.ID,
+				DestUser:   user.Username,
+			})
+		})
+		if err != nil {
+			logging.FromContext(ctx).Error("Erro ao gravar evento de download", "err", err)
+			return fmt.Errorf("erro interno ao registrar download")
+		}
+	}
+	return nil
+}
+
+func resolveDeviceSKBs(ctx context.Context, devices repository.DeviceStore, destUserID uuid.UUID, skbs map[string]string) (map[uuid.UUID]string, error) {
+	if len(skbs) == 0 {
+		return nil, nil
+	}
+
+	destDevices, err := devices.GetDevicesByUserID(ctx, destUserID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar dispositivos no store", "err", err)
 		return nil, fmt.Errorf("erro interno ao salvar transferência")
 	}
+	active := make(map[uuid.UUID]bool, len(destDevices))
+	for _, d := range destDevices {
+		if d.RevokedAt == nil {
+			active[d.ID] = true
+		}
+	}
 
-	return transfer, nil
+	resolved := make(map[uuid.UUID]string, len(skbs))
+	for rawID, skb := range skbs {
+		deviceID, err := uuid.Parse(rawID)
+		if err != nil || !active[deviceID] {
+			return nil, apperr.New(apperr.ErrNotFound, apperr.CodeDestDeviceNotFound, "dispositivo de destino '%s' não encontrado", rawID)
+		}
+		if skb == "" {
+			return nil, apperr.New(apperr.ErrValidation, apperr.CodeEmptySKB, "SKB vazia para o dispositivo '%s'", rawID)
+		}
+		resolved[deviceID] = skb
+	}
+	return resolved, nil
 }
 
 // GetPendingTransfers lista todas as transferências para um usuário
 func (s *TransferService) GetPendingTransfers(ctx context.Context, destUserID uuid.UUID) ([]*models.Transfer, error) {
 	transfers, err := s.store.GetTransfersByDestUserID(ctx, destUserID)
 	if err != nil {
-		log.Printf("Erro ao buscar transferências no store: %v", err)
+		logging.FromContext(ctx).Error("Erro ao buscar transferências no store", "err", err)
 		return nil, fmt.Errorf("erro interno ao buscar transferências")
 	}
 	return transfers, nil
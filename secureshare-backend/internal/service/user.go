@@ -2,11 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
+	"secureshare-backend/internal/apperr"
 	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/metrics"
 	"secureshare-backend/internal/models"
 	"secureshare-backend/internal/repository"
 
@@ -16,12 +20,12 @@ import (
 
 // UserService lida com a lógica de negócios de usuários
 type UserService struct {
-	store        repository.UserStore
+	store        repository.Store
 	tokenService *auth.TokenService
 }
 
 // NewUserService cria um novo serviço de usuário
-func NewUserService(store repository.UserStore, tokenService *auth.TokenService) *UserService {
+func NewUserService(store repository.Store, tokenService *auth.TokenService) *UserService {
 	return &UserService{
 		store:        store,
 		tokenService: tokenService,
@@ -32,18 +36,18 @@ func NewUserService(store repository.UserStore, tokenService *auth.TokenService)
 func (s *UserService) Register(ctx context.Context, username, password, publicKey, publicKeySign string) (*models.User, error) {
 	// Validação (simples, pode ser melhorada com 'validator')
 	if username == "" || password == "" || publicKey == "" || publicKeySign == "" {
-		return nil, fmt.Errorf("username, password e publicKey são obrigatórios")
+		return nil, apperr.Validation("username, password e publicKey são obrigatórios")
 	}
 
 	// Verificar se usuário já existe
 	if _, err := s.store.GetUserByUsername(ctx, username); err == nil {
-		return nil, fmt.Errorf("usuário '%s' já existe", username)
+		return nil, apperr.New(apperr.ErrConflict, apperr.CodeUserExists, "usuário '%s' já existe", username)
 	}
 
 	// Gerar hash da senha (nunca armazene senha em texto plano)
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Printf("Erro ao gerar hash bcrypt: %v", err)
+		logging.FromContext(ctx).Error("Erro ao gerar hash bcrypt", "err", err)
 		return nil, fmt.Errorf("erro interno ao processar senha")
 	}
 
@@ -54,46 +58,182 @@ func (s *UserService) Register(ctx context.Context, username, password, publicKe
 		PublicKey:     publicKey,
 		PublicKeySign: publicKeySign,
 		CreatedAt:     time.Now(),
+		Kind:          models.UserKindHuman,
 	}
 
-	if err := s.store.CreateUser(ctx, user); err != nil {
-		log.Printf("Erro ao salvar usuário no store: %v", err)
-		return nil, fmt.Errorf("erro interno ao salvar usuário")
+	if err := s.createUserWithDevice(ctx, user); err != nil {
+		return nil, err
 	}
 
 	return user, nil
 }
 
+// CreateServiceAccount cria uma conta de serviço (sem senha) pertencente a
+// owner. Ela tem suas próprias chaves públicas, para assinar o que envia e
+// receber arquivos, e autentica apenas com API keys.
+func (s *UserService) CreateServiceAccount(ctx context.Context, owner *models.User, username, publicKey, publicKeySign string) (*models.User, error) {
+	if owner.Kind == models.UserKindService {
+		return nil, apperr.Forbidden("contas de serviço não podem criar outras contas de serviço")
+	}
+
+	if _, err := s.store.GetUserByUsername(ctx, username); err == nil {
+		return nil, apperr.New(apperr.ErrConflict, apperr.CodeUserExists, "usuário '%s' já existe", username)
+	}
+
+	ownerID := owner.ID
+	user := &models.User{
+		ID:            uuid.New(),
+		Username:      username,
+		PublicKey:     publicKey,
+		PublicKeySign: publicKeySign,
+		CreatedAt:     time.Now(),
+		Kind:          models.UserKindService,
+		OwnerID:       &ownerID,
+	}
+
+	if err := s.createUserWithDevice(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetServiceAccount busca uma conta de serviço de owner pelo nome
+func (s *UserService) GetServiceAccount(ctx context.Context, owner *models.User, username string) (*models.User, error) {
+	user, err := s.store.GetUserByUsername(ctx, username)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		logging.FromContext(ctx).Error("Erro ao buscar conta de serviço no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar conta de serviço")
+	}
+	// Contas de outros donos são indistinguíveis de contas inexistentes
+	if err != nil || user.Kind != models.UserKindService || user.OwnerID == nil || *user.OwnerID != owner.ID {
+		return nil, apperr.New(apperr.ErrNotFound, apperr.CodeServiceAccountNotFound, "conta de serviço não encontrada")
+	}
+	return user, nil
+}
+
+// ListServiceAccounts lista as contas de serviço de owner
+func (s *UserService) ListServiceAccounts(ctx context.Context, ownerID uuid.UUID) ([]*models.User, error) {
+	users, err := s.store.GetServiceAccountsByOwner(ctx, ownerID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar contas de serviço no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar contas de serviço")
+	}
+	return users, nil
+}
+
+func (s *UserService) createUserWithDevice(ctx context.Context, user *models.User) error {
+	// O par de chaves do cadastro vira o dispositivo inicial, que não
+	// precisa de assinatura cruzada e assina os próximos dispositivos
+	device := &models.Device{
+		ID:            uuid.New(),
+		UserID:        user.ID,
+		Name:          "primary",
+		PublicKey:     user.PublicKey,
+		PublicKeySign: user.PublicKeySign,
+		CreatedAt:     user.CreatedAt,
+	}
+	// Usuário e dispositivo na mesma transação: um usuário sem dispositivo
+	// ocuparia o nome sem ter como assinar os próximos dispositivos
+	err := s.store.WithTx(ctx, func(tx repository.Store) error {
+		if err := tx.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		return tx.CreateDevice(ctx, device)
+	})
+	if err != nil {
+		// Outro cadastro com o mesmo nome pode ter vencido a corrida
+		if errors.Is(err, apperr.ErrConflict) {
+			return apperr.New(apperr.ErrConflict, apperr.CodeUserExists, "usuário '%s' já existe", user.Username)
+		}
+		logging.FromContext(ctx).Error("Erro ao salvar usuário no store", "err", err)
+		return fmt.Errorf("erro interno ao salvar usuário")
+	}
+
+	return nil
+}
+
 // Login autentica um usuário e retorna um token JWT
-func (s *UserService) Login(ctx context.Context, username, password string) (string, error) {
+func (s *UserService) Login(ctx context.Context, username, password string) (_ string, err error) {
+	defer func() { metrics.ObserveLogin(metrics.LoginPassword, err) }()
+
 	user, err := s.store.GetUserByUsername(ctx, username)
 	if err != nil {
 		// Resposta genérica para evitar enumeração de usuários
-		return "", fmt.Errorf("credenciais inválidas")
+		return "", apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidCredentials, "credenciais inválidas")
+	}
+
+	// Contas de serviço não têm senha: autenticam apenas via API key
+	if user.Kind == models.UserKindService {
+		return "", apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidCredentials, "credenciais inválidas")
 	}
 
 	// Comparar a senha fornecida com o hash armazenado
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
 		// Senha não confere
-		return "", fmt.Errorf("credenciais inválidas")
+		return "", apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidCredentials, "credenciais inválidas")
 	}
 
 	// Gerar token JWT
 	token, err := s.tokenService.NewToken(user.ID)
 	if err != nil {
-		log.Printf("Erro ao gerar token JWT: %v", err)
+		logging.FromContext(ctx).Error("Erro ao gerar token JWT", "err", err)
 		return "", fmt.Errorf("erro interno ao gerar token")
 	}
 
 	return token, nil
 }
 
+// AuthenticateToken valida um JWT de sessão e retorna o usuário dono dele.
+// Tokens emitidos antes da última revogação de sessões (ex: troca de senha)
+// são recusados. Usado pela API REST e pela gRPC.
+func (s *UserService) AuthenticateToken(ctx context.Context, tokenString string) (*models.User, error) {
+	user, _, err := s.AuthenticateSession(ctx, tokenString)
+	return user, err
+}
+
+// Session descreve a sessão de login de um JWT autenticado
+type Session struct {
+	IssuedAt  time.Time // o login, usado na reautenticação
+	ExpiresAt time.Time // o exp do token, usado para encerrar streams
+}
+
+// AuthenticateSession é o AuthenticateToken que também retorna a sessão do
+// token
+func (s *UserService) AuthenticateSession(ctx context.Context, tokenString string) (*models.User, Session, error) {
+	token, err := s.tokenService.ValidateToken(tokenString)
+	if err != nil {
+		return nil, Session{}, apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidToken, "Token inválido")
+	}
+
+	userID, err := s.tokenService.GetUserIDFromToken(token)
+	if err != nil {
+		return nil, Session{}, apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidToken, "Token inválido (claims)")
+	}
+	expiresAt, err := s.tokenService.GetExpiresAtFromToken(token)
+	if err != nil {
+		return nil, Session{}, apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidToken, "Token inválido (claims)")
+	}
+
+	// O usuário pode ter sido removido depois da emissão do token
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, Session{}, apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidToken, "Usuário do token não encontrado")
+	}
+
+	issuedAt, err := s.tokenService.GetIssuedAtFromToken(token)
+	if err != nil || issuedAt.Before(user.SessionsValidAfter) {
+		return nil, Session{}, apperr.New(apperr.ErrUnauthorized, apperr.CodeSessionRevoked, "Sessão revogada")
+	}
+	return user, Session{IssuedAt: issuedAt, ExpiresAt: expiresAt}, nil
+}
+
 // GetUserPublicKey busca a chave pública de um usuário
 func (s *UserService) GetUserPublicKey(ctx context.Context, username string) (*models.User, error) {
 	user, err := s.store.GetUserByUsername(ctx, username)
 	if err != nil {
-		return nil, fmt.Errorf("usuário não encontrado")
+		return nil, userLookupError(err)
 	}
 	return user, nil
 }
@@ -102,15 +242,82 @@ func (s *UserService) GetUserPublicKey(ctx context.Context, username string) (*m
 func (s *UserService) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	user, err := s.store.GetUserByID(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("usuário não encontrado")
+		return nil, userLookupError(err)
 	}
 	return user, nil
 }
 
+// ReauthMaxAge é a idade máxima do login de uma sessão para que ela confirme,
+// sozinha, operações sensíveis de contas sem senha (criadas via SSO)
+const ReauthMaxAge = 5 * time.Minute
+
+// Reauthenticate confirma a identidade de um usuário já autenticado antes
+// de uma operação sensível: pela senha atual ou, em contas sem senha (SSO),
+// por um login recente (sessionIssuedAt há no máximo ReauthMaxAge)
+func (s *UserService) Reauthenticate(ctx context.Context, userID uuid.UUID, password string, sessionIssuedAt time.Time) (*models.User, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, userLookupError(err)
+	}
+
+	if user.PasswordHash == "" {
+		if time.Since(sessionIssuedAt) > ReauthMaxAge {
+			return nil, apperr.New(apperr.ErrForbidden, apperr.CodeReauthRequired, "faça login novamente pelo SSO para confirmar a operação")
+		}
+		return user, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, apperr.New(apperr.ErrForbidden, apperr.CodeWrongPassword, "senha atual incorreta")
+	}
+	return user, nil
+}
+
+// ChangePassword troca a senha após a reautenticação (ver Reauthenticate;
+// contas SSO definem assim a primeira senha), revoga todas as sessões
+// existentes e retorna um novo token para a sessão corrente
+func (s *UserService) ChangePassword(ctx context.Context, userID uuid.UUID, oldPassword, newPassword string, sessionIssuedAt time.Time) (string, error) {
+	if _, err := s.Reauthenticate(ctx, userID, oldPassword, sessionIssuedAt); err != nil {
+		return "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao gerar hash bcrypt", "err", err)
+		return "", fmt.Errorf("erro interno ao processar senha")
+	}
+
+	// O 'iat' dos tokens tem precisão de microssegundos (ver
+	// auth.NewToken): tokens anteriores, mesmo do mesmo segundo, deixam de
+	// valer, e o novo (emitido logo abaixo) não é rejeitado
+	revokedAt := time.Now().Truncate(time.Microsecond)
+	if err := s.store.UpdateUserPassword(ctx, userID, string(hash), revokedAt); err != nil {
+		logging.FromContext(ctx).Error("Erro ao atualizar senha no store", "err", err)
+		return "", fmt.Errorf("erro interno ao atualizar senha")
+	}
+
+	token, err := s.tokenService.NewToken(userID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao gerar token JWT", "err", err)
+		return "", fmt.Errorf("erro interno ao gerar token")
+	}
+	return token, nil
+}
+
+// userLookupError traduz a falha de busca de um usuário: "não encontrado"
+// para o cliente, falhas do store como erro interno
+func userLookupError(err error) error {
+	if errors.Is(err, apperr.ErrNotFound) {
+		return apperr.New(apperr.ErrNotFound, apperr.CodeUserNotFound, "usuário não encontrado")
+	}
+	slog.Error("Erro ao buscar usuário no store", "err", err)
+	return fmt.Errorf("erro interno ao buscar usuário")
+}
+
 func (s *UserService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 	users, err := s.store.GetAllUsers(ctx)
 	if err != nil {
-		log.Printf("Erro ao buscar usuários no store: %v", err)
+		logging.FromContext(ctx).Error("Erro ao buscar usuários no store", "err", err)
 		return nil, fmt.Errorf("erro interno ao buscar usuários")
 	}
 	return users, nil
@@ -0,0 +1,141 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/auth"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+)
+
+func newUserService(t *testing.T, store repository.Store) *service.UserService {
+	t.Helper()
+	tokens, err := auth.NewTokenService("segredo-de-teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service.NewUserService(store, tokens)
+}
+
+func TestChangePasswordRevokesSessions(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	users := newUserService(t, store)
+
+	alice, err := users.Register(ctx, "alice", "senha-antiga", "pk", "pk-sign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Emitido no mesmo segundo da troca: também precisa ser revogado
+	oldToken, err := users.Login(ctx, "alice", "senha-antiga")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := users.ChangePassword(ctx, alice.ID, "senha-errada", "senha-nova", time.Time{}); apperr.CodeOf(err) != apperr.CodeWrongPassword {
+		t.Fatalf("senha atual errada: esperava %s, obteve %v", apperr.CodeWrongPassword, err)
+	}
+	if _, err := users.AuthenticateToken(ctx, oldToken); err != nil {
+		t.Fatalf("tentativa recusada revogou a sessão: %v", err)
+	}
+
+	newToken, err := users.ChangePassword(ctx, alice.ID, "senha-antiga", "senha-nova", time.Time{})
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if _, err := users.AuthenticateToken(ctx, oldToken); apperr.CodeOf(err) != apperr.CodeSessionRevoked {
+		t.Fatalf("token anterior à troca: esperava %s, obteve %v", apperr.CodeSessionRevoked, err)
+	}
+	if user, err := users.AuthenticateToken(ctx, newToken); err != nil || user.ID != alice.ID {
+		t.Fatalf("token emitido na troca: %v", err)
+	}
+
+	if _, err := users.Login(ctx, "alice", "senha-antiga"); apperr.CodeOf(err) != apperr.CodeInvalidCredentials {
+		t.Fatalf("login com a senha antiga: esperava %s, obteve %v", apperr.CodeInvalidCredentials, err)
+	}
+	if _, err := users.Login(ctx, "alice", "senha-nova"); err != nil {
+		t.Fatalf("login com a senha nova: %v", err)
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	users := newUserService(t, store)
+	accounts := service.NewAccountService(store, users)
+
+	alice, err := users.Register(ctx, "alice", "senha-forte", "pk", "pk-sign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := users.Login(ctx, "alice", "senha-forte")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := accounts.DeleteAccount(ctx, alice.ID, "senha-errada", time.Time{}); apperr.CodeOf(err) != apperr.CodeWrongPassword {
+		t.Fatalf("senha errada: esperava %s, obteve %v", apperr.CodeWrongPassword, err)
+	}
+	if _, err := users.AuthenticateToken(ctx, token); err != nil {
+		t.Fatalf("tentativa recusada afetou a conta: %v", err)
+	}
+
+	if err := accounts.DeleteAccount(ctx, alice.ID, "senha-forte", time.Time{}); err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	if _, err := users.AuthenticateToken(ctx, token); apperr.CodeOf(err) != apperr.CodeInvalidToken {
+		t.Fatalf("token de conta removida: esperava %s, obteve %v", apperr.CodeInvalidToken, err)
+	}
+	if _, err := users.Login(ctx, "alice", "senha-forte"); err == nil {
+		t.Fatal("login em conta removida")
+	}
+	if err := accounts.DeleteAccount(ctx, alice.ID, "senha-forte", time.Time{}); err == nil {
+		t.Fatal("DeleteAccount repetido deveria falhar")
+	}
+}
+
+// failingDeviceStore faz CreateDevice falhar dentro das transações
+type failingDeviceStore struct {
+	*repository.InMemoryStore
+}
+
+func (s failingDeviceStore) WithTx(ctx context.Context, fn func(tx repository.Store) error) error {
+	return s.InMemoryStore.WithTx(ctx, func(tx repository.Store) error {
+		return fn(failingDeviceTx{tx})
+	})
+}
+
+type failingDeviceTx struct {
+	repository.Store
+}
+
+func (failingDeviceTx) CreateDevice(ctx context.Context, device *models.Device) error {
+	return errors.New("falha proposital")
+}
+
+func TestRegisterRollsBackUserWithoutDevice(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+
+	if _, err := newUserService(t, failingDeviceStore{store}).Register(ctx, "alice", "senha-forte", "pk", "pk-sign"); err == nil {
+		t.Fatal("Register deveria falhar sem o dispositivo inicial")
+	}
+	_, err := store.GetUserByUsername(ctx, "alice")
+	if !errors.Is(err, apperr.ErrNotFound) {
+		t.Fatalf("usuário sem dispositivo foi persistido: %v", err)
+	}
+
+	// O nome continua livre
+	alice, err := newUserService(t, store).Register(ctx, "alice", "senha-forte", "pk", "pk-sign")
+	if err != nil {
+		t.Fatalf("Register após a falha: %v", err)
+	}
+	if devices, err := store.GetDevicesByUserID(ctx, alice.ID); err != nil || len(devices) != 1 {
+		t.Fatalf("dispositivo inicial: %d dispositivos, err=%v", len(devices), err)
+	}
+}
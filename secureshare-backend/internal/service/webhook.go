@@ -0,0 +1,519 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/jobs"
+	"secureshare-backend/internal/logging"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEventTypes são os tipos de evento que um webhook pode assinar
+var WebhookEventTypes = []string{EventTransferCreated, EventTransferRevoked, EventTransferDownloaded}
+
+// Headers das entregas de webhook
+const (
+	// WebhookSignatureHeader leva "t=<unix>,v1=<hex>", em que v1 é o
+	// HMAC-SHA256 de "<t>.<corpo>" com o segredo do webhook
+	WebhookSignatureHeader = "X-SecureShare-Signature"
+	WebhookEventHeader     = "X-SecureShare-Event"
+	WebhookDeliveryHeader  = "X-SecureShare-Delivery"
+)
+
+const (
+	// JobKindWebhookDelivery é o job da fila (internal/jobs) que faz uma
+	// tentativa de entrega (payload: webhookDeliveryJob)
+	JobKindWebhookDelivery = "webhooks.deliver"
+	// WebhookMaxAttempts é o número de tentativas de cada entrega; com o
+	// backoff da fila, a última acontece cerca de 40 minutos depois da
+	// primeira
+	WebhookMaxAttempts = 8
+	// MaxWebhookDeliveriesPage é o maior número de entregas por consulta
+	MaxWebhookDeliveriesPage = 100
+	// maxWebhookErrorBody limita o trecho da resposta guardado em LastError
+	maxWebhookErrorBody = 512
+)
+
+// WebhookPayload é o corpo JSON das entregas. Carrega só metadados da
+// transferência (TransferEventData), nunca SKBs ou assinaturas.
+type WebhookPayload struct {
+	EventID   int64           `json:"eventId"`
+	Type      string          `json:"type"`
+	User      string          `json:"user"` // dono do webhook
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type webhookDeliveryJob struct {
+	DeliveryID uuid.UUID `json:"deliveryId"`
+}
+
+// CreateWebhookRequest define os parâmetros para registrar um webhook
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" validate:"required"`
+	EventTypes []string `json:"eventTypes" validate:"required,min=1"`
+}
+
+// WebhookService registra webhooks e faz as entregas
+type WebhookService struct {
+	store        repository.Store
+	client       *http.Client
+	resolver     *net.Resolver
+	allowPrivate bool
+}
+
+// NewWebhookService cria o serviço. client nil usa um cliente com timeout de
+// 10s que não segue redirecionamentos (um 3xx conta como falha), não usa
+// proxy e só conecta em endereços públicos: o IP é conferido na hora da
+// conexão, depois da resolução, para que um DNS que muda de resposta entre
+// o cadastro e a entrega não leve o servidor à rede interna.
+func NewWebhookService(store repository.Store, client *http.Client) *WebhookService {
+	s := &WebhookService{store: store, client: client, resolver: net.DefaultResolver}
+	if s.client == nil {
+		dialer := &net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				return s.checkDialAddress(address)
+			},
+		}
+		s.client = &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				ForceAttemptHTTP2:   true,
+				TLSHandshakeTimeout: 5 * time.Second,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	return s
+}
+
+// AllowPrivateNetworks aceita URLs http e destinos em loopback e redes
+// privadas (desenvolvimento e testes; ver WEBHOOK_ALLOW_PRIVATE)
+func (s *WebhookService) AllowPrivateNetworks() {
+	s.allowPrivate = true
+}
+
+// CreateWebhook registra um webhook para owner (o próprio usuário, uma conta
+// de serviço dele ou, para administradores, qualquer usuário). Retorna o
+// segredo do HMAC, que só é mostrado aqui.
+func (s *WebhookService) CreateWebhook(ctx context.Context, owner *models.User, createdBy string, req CreateWebhookRequest) (*models.Webhook, error) {
+	endpoint, err := s.parseWebhookURL(ctx, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	eventTypes := []string{}
+	for _, eventType := range req.EventTypes {
+		if !slices.Contains(WebhookEventTypes, eventType) {
+			return nil, apperr.New(apperr.ErrValidation, apperr.CodeUnknownEventType, "tipo de evento desconhecido: '%s' (aceitos: %s)", eventType, strings.Join(WebhookEventTypes, ", "))
+		}
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	if len(eventTypes) == 0 {
+		return nil, apperr.Validation("eventTypes deve ter ao menos um tipo de evento")
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao gerar segredo de webhook", "err", err)
+		return nil, fmt.Errorf("erro interno ao criar webhook")
+	}
+	webhook := &models.Webhook{
+		ID:         uuid.New(),
+		UserID:     owner.ID,
+		URL:        endpoint.String(),
+		Secret:     secret,
+		EventTypes: eventTypes,
+		CreatedAt:  time.Now(),
+		CreatedBy:  createdBy,
+	}
+	if err := s.store.CreateWebhook(ctx, webhook); err != nil {
+		logging.FromContext(ctx).Error("Erro ao salvar webhook no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao criar webhook")
+	}
+	return webhook, nil
+}
+
+// parseWebhookURL exige uma URL https absoluta cujo host resolva só para
+// endereços públicos. A checagem na conexão (checkDialAddress) continua
+// valendo: aqui o erro só é mais cedo e mais claro.
+func (s *WebhookService) parseWebhookURL(ctx context.Context, rawURL string) (*url.URL, error) {
+	endpoint, err := url.Parse(rawURL)
+	if err != nil || endpoint.Host == "" || endpoint.User != nil {
+		return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidWebhookURL, "url deve ser uma URL https absoluta")
+	}
+	if s.allowPrivate {
+		if endpoint.Scheme != "https" && endpoint.Scheme != "http" {
+			return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidWebhookURL, "url deve ser uma URL http(s) absoluta")
+		}
+		return endpoint, nil
+	}
+	if endpoint.Scheme != "https" {
+		return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidWebhookURL, "url deve ser uma URL https absoluta")
+	}
+
+	host := endpoint.Hostname()
+	addrs := []netip.Addr{}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = append(addrs, addr)
+	} else {
+		addrs, err = s.resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil || len(addrs) == 0 {
+			return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidWebhookURL, "não foi possível resolver o host '%s'", host)
+		}
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidWebhookURL, "url aponta para um endereço de rede privada ou local (%s)", addr.Unmap())
+		}
+	}
+	return endpoint, nil
+}
+
+// checkDialAddress recusa conexões a endereços não públicos; address é o
+// "ip:porta" já resolvido que o net.Dialer vai conectar
+func (s *WebhookService) checkDialAddress(address string) error {
+	if s.allowPrivate {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("endereço de destino inválido %q: %w", address, err)
+	}
+	if !isPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("destino do webhook em rede privada ou local: %s", addrPort.Addr().Unmap())
+	}
+	return nil
+}
+
+// nonPublicPrefixes são faixas não roteáveis que netip.Addr não classifica:
+// "esta rede", CGNAT (RFC 6598) e testes de desempenho (RFC 2544)
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// isPublicAddr indica se addr é roteável na internet: fora de loopback,
+// redes privadas, link-local (incluindo o 169.254.169.254 dos metadados
+// de nuvem), multicast e endereços não especificados
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func generateWebhookSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// ListWebhooks lista os webhooks de um usuário
+func (s *WebhookService) ListWebhooks(ctx context.Context, ownerID uuid.UUID) ([]*models.Webhook, error) {
+	webhooks, err := s.store.GetWebhooksByUserID(ctx, ownerID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar webhooks no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar webhooks")
+	}
+	return webhooks, nil
+}
+
+// GetWebhook busca um webhook que actor pode gerenciar: os próprios, os das
+// suas contas de serviço ou, se isAdmin, qualquer um. Os demais são
+// indistinguíveis de webhooks inexistentes.
+func (s *WebhookService) GetWebhook(ctx context.Context, actor *models.User, isAdmin bool, id uuid.UUID) (*models.Webhook, error) {
+	notFound := apperr.New(apperr.ErrNotFound, apperr.CodeWebhookNotFound, "webhook '%s' não encontrado", id)
+	webhook, err := s.store.GetWebhook(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, notFound
+		}
+		logging.FromContext(ctx).Error("Erro ao buscar webhook no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar webhook")
+	}
+	if webhook.UserID == actor.ID || isAdmin {
+		return webhook, nil
+	}
+	owner, err := s.store.GetUserByID(ctx, webhook.UserID)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, notFound
+		}
+		logging.FromContext(ctx).Error("Erro ao buscar dono do webhook no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar webhook")
+	}
+	if owner.Kind != models.UserKindService || owner.OwnerID == nil || *owner.OwnerID != actor.ID {
+		return nil, notFound
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook remove o webhook e o log das entregas; entregas pendentes
+// são descartadas
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	if err := s.store.DeleteWebhook(ctx, id); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return err
+		}
+		logging.FromContext(ctx).Error("Erro ao remover webhook do store", "err", err)
+		return fmt.Errorf("erro interno ao remover webhook")
+	}
+	return nil
+}
+
+// ListDeliveries lista as entregas do webhook, mais recentes primeiro; o
+// limite padrão e máximo é MaxWebhookDeliveriesPage
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*models.WebhookDelivery, error) {
+	if limit < 0 {
+		return nil, apperr.New(apperr.ErrValidation, apperr.CodeInvalidParameter, "limit deve ser positivo")
+	}
+	if limit == 0 || limit > MaxWebhookDeliveriesPage {
+		limit = MaxWebhookDeliveriesPage
+	}
+	deliveries, err := s.store.ListWebhookDeliveries(ctx, webhookID, limit)
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao buscar entregas no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao buscar entregas")
+	}
+	return deliveries, nil
+}
+
+// Redeliver reenvia o corpo de uma entrega anterior (de qualquer situação)
+// como uma nova entrega, com novas tentativas
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	original, err := s.store.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil || original.WebhookID != webhookID {
+		if err == nil || errors.Is(err, apperr.ErrNotFound) {
+			return nil, apperr.New(apperr.ErrNotFound, apperr.CodeDeliveryNotFound, "entrega '%s' não encontrada", deliveryID)
+		}
+		logging.FromContext(ctx).Error("Erro ao buscar entrega no store", "err", err)
+		return nil, fmt.Errorf("erro interno ao reenviar entrega")
+	}
+
+	var delivery *models.WebhookDelivery
+	err = s.store.WithTx(ctx, func(tx repository.Store) error {
+		delivery, err = enqueueWebhookDelivery(ctx, tx, webhookID, original.EventType, original.Payload)
+		if err != nil {
+			return err
+		}
+		delivery.RedeliveryOf = &original.ID
+		return tx.UpdateWebhookDelivery(ctx, delivery)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Erro ao reenviar entrega", "delivery_id", deliveryID, "err", err)
+		return nil, fmt.Errorf("erro interno ao reenviar entrega")
+	}
+	return delivery, nil
+}
+
+// enqueueWebhookDeliveries registra e enfileira uma entrega para cada
+// webhook do destinatário do evento que assina o tipo. Chame com o Store da
+// transação que grava o evento.
+func enqueueWebhookDeliveries(ctx context.Context, store repository.Store, event *models.InboxEvent) error {
+	webhooks, err := store.GetWebhooksByUserID(ctx, event.UserID)
+	if err != nil {
+		return fmt.Errorf("falha ao buscar webhooks: %w", err)
+	}
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !slices.Contains(webhook.EventTypes, event.Type) {
+			continue
+		}
+		if payload == nil {
+			owner, err := store.GetUserByID(ctx, event.UserID)
+			if err != nil {
+				return fmt.Errorf("falha ao buscar dono do webhook: %w", err)
+			}
+			payload, err = json.Marshal(WebhookPayload{
+				EventID:   event.ID,
+				Type:      event.Type,
+				User:      owner.Username,
+				CreatedAt: event.CreatedAt,
+				Data:      event.Data,
+			})
+			if err != nil {
+				return fmt.Errorf("falha ao serializar entrega: %w", err)
+			}
+		}
+		if _, err := enqueueWebhookDelivery(ctx, store, webhook.ID, event.Type, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func enqueueWebhookDelivery(ctx context.Context, store repository.Store, webhookID uuid.UUID, eventType string, payload []byte) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		ID:        uuid.New(),
+		WebhookID: webhookID,
+		EventType: eventType,
+		Payload:   payload,
+		Status:    models.WebhookDeliveryPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := store.CreateWebhookDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("falha ao registrar entrega: %w", err)
+	}
+	if err := jobs.Enqueue(ctx, store, JobKindWebhookDelivery, webhookDeliveryJob{DeliveryID: delivery.ID}); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// RunDeliveryJob é o handler de JobKindWebhookDelivery: uma tentativa de
+// entrega. Falhas voltam para a fila (com backoff) até WebhookMaxAttempts;
+// o resultado de cada tentativa fica no log da entrega.
+func (s *WebhookService) RunDeliveryJob(ctx context.Context, payload []byte) error {
+	var job webhookDeliveryJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(fmt.Errorf("payload inválido: %w", err))
+	}
+
+	delivery, err := s.store.GetWebhookDelivery(ctx, job.DeliveryID)
+	if errors.Is(err, apperr.ErrNotFound) {
+		return nil // webhook removido
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != models.WebhookDeliveryPending {
+		return nil // já concluída (job repetido)
+	}
+	webhook, err := s.store.GetWebhook(ctx, delivery.WebhookID)
+	if errors.Is(err, apperr.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	statusCode, sendErr := s.send(ctx, webhook, delivery)
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.UpdatedAt = time.Now()
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.LastError = ""
+	case delivery.Attempts >= WebhookMaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = sendErr.Error()
+	default:
+		delivery.LastError = sendErr.Error()
+	}
+	if err := s.store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		logging.FromContext(ctx).Error("Erro ao registrar tentativa da entrega", "delivery_id", delivery.ID, "err", err)
+	}
+
+	if sendErr != nil && delivery.Status == models.WebhookDeliveryFailed {
+		return jobs.Permanent(sendErr)
+	}
+	return sendErr
+}
+
+// send faz o POST assinado; só respostas 2xx contam como entregues
+func (s *WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("requisição inválida: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SecureShare-Webhooks/1")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, time.Now(), delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload calcula o valor de WebhookSignatureHeader
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + webhookHMAC(secret, t, body)
+}
+
+func webhookHMAC(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature confere o header de assinatura de uma entrega,
+// recusando carimbos de tempo mais distantes de now que tolerance (contra
+// reenvio de entregas capturadas). Para uso de quem recebe os webhooks.
+func VerifyWebhookSignature(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var t, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signature = value
+		}
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || signature == "" {
+		return fmt.Errorf("header de assinatura malformado")
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("assinatura fora da janela de tempo")
+	}
+	if !hmac.Equal([]byte(signature), []byte(webhookHMAC(secret, t, body))) {
+		return fmt.Errorf("assinatura inválida")
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"secureshare-backend/internal/apperr"
+	"secureshare-backend/internal/jobs"
+	"secureshare-backend/internal/models"
+	"secureshare-backend/internal/repository"
+	"secureshare-backend/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// webhookReceiver é um endpoint de teste que responde com os códigos de
+// status da fila (o último se repete) e guarda as requisições recebidas
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func (rc *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, receivedWebhook{header: r.Header.Clone(), body: body})
+	status := rc.statuses[0]
+	if len(rc.statuses) > 1 {
+		rc.statuses = rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *webhookReceiver) received() []receivedWebhook {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]receivedWebhook(nil), rc.requests...)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	bucket := newFakeBucket()
+	transfers := service.NewTransferService(store, bucket, service.UploadLimits{})
+	webhooks := service.NewWebhookService(store, nil)
+	webhooks.AllowPrivateNetworks() // o receptor do httptest escuta em 127.0.0.1
+	runner := jobs.NewRunner(store)
+	runner.Register(service.JobKindWebhookDelivery, webhooks.RunDeliveryJob, jobs.Options{MaxAttempts: service.WebhookMaxAttempts})
+
+	alice := &models.User{ID: uuid.New(), Username: "alice", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	bob := &models.User{ID: uuid.New(), Username: "bob", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	for _, u := range []*models.User{alice, bob} {
+		if err := store.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receiver := &webhookReceiver{statuses: []int{http.StatusInternalServerError, http.StatusNoContent}}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	if _, err := webhooks.CreateWebhook(ctx, bob, bob.Username, service.CreateWebhookRequest{URL: "ftp://example.com", EventTypes: []string{service.EventTransferCreated}}); err == nil {
+		t.Fatal("esperava erro para URL não http(s)")
+	}
+	if _, err := webhooks.CreateWebhook(ctx, bob, bob.Username, service.CreateWebhookRequest{URL: srv.URL, EventTypes: []string{"transfer.unknown"}}); err == nil {
+		t.Fatal("esperava erro para tipo de evento desconhecido")
+	}
+	webhook, err := webhooks.CreateWebhook(ctx, bob, bob.Username, service.CreateWebhookRequest{
+		URL: srv.URL, EventTypes: []string{service.EventTransferCreated, service.EventTransferCreated},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if !strings.HasPrefix(webhook.Secret, "whsec_") || len(webhook.EventTypes) != 1 {
+		t.Fatalf("webhook inesperado: %+v", webhook)
+	}
+
+	// Uma transferência para bob gera uma entrega; o download (tipo não
+	// assinado) não gera
+	key, err := transfers.ReserveUpload(ctx, alice, upload(100))
+	if err != nil {
+		t.Fatalf("ReserveUpload: %v", err)
+	}
+	bucket.put(key, time.Now())
+	transfer, err := transfers.CreateTransfer(ctx, alice.ID, service.CreateTransferRequest{
+		DestUsername: "bob", LinkToEncFile: key, SKB: "skb-secreta", Sig: "sig",
+	})
+	if err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	if err := transfers.RecordDownload(ctx, bob, key); err != nil {
+		t.Fatalf("RecordDownload: %v", err)
+	}
+
+	// Primeira tentativa falha (500) e fica pendente para nova tentativa
+	now := time.Now()
+	if n, err := runner.RunOnce(ctx, now); err != nil || n != 1 {
+		t.Fatalf("RunOnce: n=%d err=%v", n, err)
+	}
+	deliveries, err := webhooks.ListDeliveries(ctx, webhook.ID, 0)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("esperava 1 entrega, obteve %d (err=%v)", len(deliveries), err)
+	}
+	first := deliveries[0]
+	if first.Status != models.WebhookDeliveryPending || first.Attempts != 1 || first.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("entrega depois da falha: %+v", first)
+	}
+
+	// Antes do backoff nada roda; depois, a entrega é concluída
+	if n, _ := runner.RunOnce(ctx, now); n != 0 {
+		t.Fatalf("tentativa antes do backoff: %d job(s)", n)
+	}
+	if _, err := runner.RunOnce(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, _ = webhooks.ListDeliveries(ctx, webhook.ID, 0)
+	if d := deliveries[0]; d.Status != models.WebhookDeliverySucceeded || d.Attempts != 2 || d.LastStatusCode != http.StatusNoContent || d.LastError != "" {
+		t.Fatalf("entrega depois do sucesso: %+v", d)
+	}
+
+	// O corpo é assinado e só tem metadados
+	requests := receiver.received()
+	if len(requests) != 2 {
+		t.Fatalf("esperava 2 requisições, obteve %d", len(requests))
+	}
+	got := requests[1]
+	if err := service.VerifyWebhookSignature(webhook.Secret, got.header.Get(service.WebhookSignatureHeader), got.body, time.Now(), 5*time.Minute); err != nil {
+		t.Fatalf("assinatura: %v", err)
+	}
+	if err := service.VerifyWebhookSignature("whsec_outro", got.header.Get(service.WebhookSignatureHeader), got.body, time.Now(), 5*time.Minute); err == nil {
+		t.Fatal("assinatura aceita com outro segredo")
+	}
+	if got.header.Get(service.WebhookEventHeader) != service.EventTransferCreated || got.header.Get(service.WebhookDeliveryHeader) != first.ID.String() {
+		t.Fatalf("headers inesperados: %v", got.header)
+	}
+	if strings.Contains(string(got.body), "skb") {
+		t.Fatalf("corpo da entrega contém a SKB: %s", got.body)
+	}
+	var payload service.WebhookPayload
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatalf("corpo inválido: %v", err)
+	}
+	var data service.TransferEventData
+	if err := json.Unmarshal(payload.Data, &data); err != nil || payload.Type != service.EventTransferCreated || payload.User != "bob" || data.TransferID != transfer.ID || data.SourceUser != "alice" {
+		t.Fatalf("payload inesperado: %+v %+v (err=%v)", payload, data, err)
+	}
+
+	// Reenvio manual: nova entrega com o mesmo corpo
+	redelivery, err := webhooks.Redeliver(ctx, webhook.ID, first.ID)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if redelivery.RedeliveryOf == nil || *redelivery.RedeliveryOf != first.ID {
+		t.Fatalf("RedeliveryOf inesperado: %+v", redelivery)
+	}
+	if _, err := runner.RunOnce(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	requests = receiver.received()
+	if len(requests) != 3 || string(requests[2].body) != string(got.body) || requests[2].header.Get(service.WebhookDeliveryHeader) != redelivery.ID.String() {
+		t.Fatalf("reenvio inesperado: %d requisição(ões)", len(requests))
+	}
+	if _, err := webhooks.Redeliver(ctx, uuid.New(), first.ID); err == nil {
+		t.Fatal("reenvio aceito com o webhook errado")
+	}
+}
+
+func TestWebhookDeliveryGivesUp(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	webhooks := service.NewWebhookService(store, nil)
+	webhooks.AllowPrivateNetworks() // o receptor do httptest escuta em 127.0.0.1
+	runner := jobs.NewRunner(store)
+	runner.Register(service.JobKindWebhookDelivery, webhooks.RunDeliveryJob, jobs.Options{MaxAttempts: service.WebhookMaxAttempts})
+
+	alice := &models.User{ID: uuid.New(), Username: "alice", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	if err := store.CreateUser(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	receiver := &webhookReceiver{statuses: []int{http.StatusBadGateway}}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+	webhook, err := webhooks.CreateWebhook(ctx, alice, alice.Username, service.CreateWebhookRequest{
+		URL: srv.URL, EventTypes: []string{service.EventTransferDownloaded},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := webhooks.CreateWebhook(ctx, alice, alice.Username, service.CreateWebhookRequest{
+		URL: srv.URL, EventTypes: []string{service.EventTransferRevoked},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transfers := service.NewTransferService(store, newFakeBucket(), service.UploadLimits{})
+	bob := &models.User{ID: uuid.New(), Username: "bob", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	if err := store.CreateUser(ctx, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateTransfer(ctx, &models.Transfer{
+		ID: uuid.New(), SourceUserID: alice.ID, DestUserID: bob.ID, LinkToEncFile: "uploads/x", SKB: "skb", Sig: "sig", CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := transfers.RecordDownload(ctx, bob, "uploads/x"); err != nil {
+		t.Fatal(err)
+	}
+
+	// O backoff da fila chega a 1h; avançar 2h por rodada esgota as tentativas
+	now := time.Now()
+	for i := 0; i < service.WebhookMaxAttempts+2; i++ {
+		if _, err := runner.RunOnce(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(2 * time.Hour)
+	}
+
+	deliveries, err := webhooks.ListDeliveries(ctx, webhook.ID, 0)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("esperava 1 entrega, obteve %d (err=%v)", len(deliveries), err)
+	}
+	if d := deliveries[0]; d.Status != models.WebhookDeliveryFailed || d.Attempts != service.WebhookMaxAttempts || d.LastStatusCode != http.StatusBadGateway {
+		t.Fatalf("entrega depois de esgotar as tentativas: %+v", d)
+	}
+	if n := len(receiver.received()); n != service.WebhookMaxAttempts {
+		t.Fatalf("esperava %d tentativas, obteve %d", service.WebhookMaxAttempts, n)
+	}
+	if deliveries, _ := webhooks.ListDeliveries(ctx, other.ID, 0); len(deliveries) != 0 {
+		t.Fatalf("webhook sem o tipo assinado recebeu %d entrega(s)", len(deliveries))
+	}
+
+	// Remover o webhook apaga o log
+	if err := webhooks.DeleteWebhook(ctx, webhook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetWebhookDelivery(ctx, deliveries[0].ID); err == nil {
+		t.Fatal("entrega deveria ter sido removida com o webhook")
+	}
+}
+
+func TestWebhookRejectsPrivateDestinations(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryStore()
+	webhooks := service.NewWebhookService(store, nil)
+	runner := jobs.NewRunner(store)
+	runner.Register(service.JobKindWebhookDelivery, webhooks.RunDeliveryJob, jobs.Options{MaxAttempts: service.WebhookMaxAttempts})
+
+	alice := &models.User{ID: uuid.New(), Username: "alice", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	bob := &models.User{ID: uuid.New(), Username: "bob", CreatedAt: time.Now(), Kind: models.UserKindHuman}
+	for _, u := range []*models.User{alice, bob} {
+		if err := store.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, rawURL := range []string{
+		"http://example.com/hook",
+		"https://127.0.0.1/hook",
+		"https://localhost/hook",
+		"https://10.1.2.3/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/hook",
+		"https://[::ffff:192.168.0.1]/hook",
+		"https://100.64.0.1/hook",
+	} {
+		_, err := webhooks.CreateWebhook(ctx, alice, alice.Username, service.CreateWebhookRequest{URL: rawURL, EventTypes: []string{service.EventTransferDownloaded}})
+		if apperr.CodeOf(err) != apperr.CodeInvalidWebhookURL {
+			t.Fatalf("%s: esperava %s, obteve %v", rawURL, apperr.CodeInvalidWebhookURL, err)
+		}
+	}
+
+	// Um host que passou no cadastro e depois passou a resolver para a rede
+	// interna (DNS rebinding) é barrado na conexão
+	receiver := &webhookReceiver{statuses: []int{http.StatusNoContent}}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+	webhook := &models.Webhook{
+		ID: uuid.New(), UserID: alice.ID, URL: srv.URL, Secret: "whsec_teste",
+		EventTypes: []string{service.EventTransferDownloaded}, CreatedAt: time.Now(), CreatedBy: alice.Username,
+	}
+	if err := store.CreateWebhook(ctx, webhook); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateTransfer(ctx, &models.Transfer{
+		ID: uuid.New(), SourceUserID: alice.ID, DestUserID: bob.ID, LinkToEncFile: "uploads/x", SKB: "skb", Sig: "sig", CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	transfers := service.NewTransferService(store, newFakeBucket(), service.UploadLimits{})
+	if err := transfers.RecordDownload(ctx, bob, "uploads/x"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := runner.RunOnce(ctx, time.Now()); err != nil || n != 1 {
+		t.Fatalf("RunOnce: n=%d err=%v", n, err)
+	}
+
+	deliveries, err := webhooks.ListDeliveries(ctx, webhook.ID, 0)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("esperava 1 entrega, obteve %d (err=%v)", len(deliveries), err)
+	}
+	if d := deliveries[0]; d.Attempts != 1 || !strings.Contains(d.LastError, "rede privada") {
+		t.Fatalf("entrega para 127.0.0.1 não foi barrada: %+v", d)
+	}
+	if n := len(receiver.received()); n != 0 {
+		t.Fatalf("receptor na rede interna recebeu %d requisição(ões)", n)
+	}
+}
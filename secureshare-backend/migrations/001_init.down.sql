@@ -0,0 +1,5 @@
+/* migrations/001_init.down.sql */
+
+-- A extensão uuid-ossp é mantida: pode ser usada por outros schemas
+DROP TABLE IF EXISTS transfers;
+DROP TABLE IF EXISTS users;
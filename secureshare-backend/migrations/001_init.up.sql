@@ -1,4 +1,4 @@
-/* migrations/001_init.sql */
+/* migrations/001_init.up.sql */
 
 -- Habilita a extensão para o tipo UUID, se ainda não estiver habilitada
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
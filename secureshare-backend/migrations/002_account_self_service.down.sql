@@ -0,0 +1,4 @@
+/* migrations/002_account_self_service.down.sql */
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS sessions_valid_after;
@@ -0,0 +1,6 @@
+/* migrations/002_account_self_service.up.sql */
+
+-- Tokens emitidos antes deste instante são rejeitados pelo AuthMiddleware.
+-- Atualizado na troca de senha para revogar as sessões existentes.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS sessions_valid_after TIMESTAMPTZ NOT NULL DEFAULT 'epoch';
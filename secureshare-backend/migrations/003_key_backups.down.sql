@@ -0,0 +1,3 @@
+/* migrations/003_key_backups.down.sql */
+
+DROP TABLE IF EXISTS key_backups;
@@ -0,0 +1,21 @@
+/* migrations/003_key_backups.up.sql */
+
+-- Backup cifrado das chaves privadas (escrow). O servidor nunca vê as
+-- chaves em claro: guarda apenas o blob cifrado e os parâmetros do KDF.
+CREATE TABLE IF NOT EXISTS key_backups (
+    user_id          UUID PRIMARY KEY,
+    kdf              TEXT NOT NULL,
+    kdf_salt         TEXT NOT NULL, -- Base64
+    kdf_iterations   INTEGER NOT NULL,
+    kdf_memory_kib   INTEGER NOT NULL DEFAULT 0,
+    kdf_parallelism  INTEGER NOT NULL DEFAULT 0,
+    cipher           TEXT NOT NULL,
+    nonce            TEXT NOT NULL, -- Base64
+    ciphertext       TEXT NOT NULL, -- Base64
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT (NOW()),
+
+    CONSTRAINT fk_key_backup_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE -- O backup some junto com a conta
+);
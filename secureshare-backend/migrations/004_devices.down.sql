@@ -0,0 +1,6 @@
+/* migrations/004_devices.down.sql */
+
+-- As chaves da conta (users.public_key) continuam com as do dispositivo
+-- mais antigo, então nada se perde para clientes sem dispositivos
+DROP TABLE IF EXISTS transfer_device_keys;
+DROP TABLE IF EXISTS devices;
@@ -0,0 +1,51 @@
+/* migrations/004_devices.up.sql */
+
+-- Dispositivos: cada um tem seu próprio par de chaves (RSA-OAEP + ECDSA)
+CREATE TABLE IF NOT EXISTS devices (
+    id                UUID PRIMARY KEY,
+    user_id           UUID NOT NULL,
+    name              TEXT NOT NULL,
+    public_key        TEXT NOT NULL,
+    public_key_sign   TEXT NOT NULL,
+    signer_device_id  UUID NULL,              -- NULL para o dispositivo inicial
+    signature         TEXT NOT NULL DEFAULT '', -- Base64, assinatura cruzada
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT (NOW()),
+    revoked_at        TIMESTAMPTZ NULL,
+
+    CONSTRAINT fk_device_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_device_signer
+        FOREIGN KEY(signer_device_id)
+        REFERENCES devices(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_devices_user_id ON devices(user_id);
+
+-- Usuários já existentes ganham um dispositivo inicial com a chave da conta
+INSERT INTO devices (id, user_id, name, public_key, public_key_sign, created_at)
+SELECT uuid_generate_v4(), u.id, 'primary', u.public_key, u.public_key_sign, u.created_at
+FROM users u
+WHERE NOT EXISTS (SELECT 1 FROM devices d WHERE d.user_id = u.id);
+
+-- SKB por dispositivo do destinatário
+CREATE TABLE IF NOT EXISTS transfer_device_keys (
+    transfer_id  UUID NOT NULL,
+    device_id    UUID NOT NULL,
+    skb          TEXT NOT NULL, -- Armazena a string Base64
+
+    PRIMARY KEY (transfer_id, device_id),
+
+    CONSTRAINT fk_tdk_transfer
+        FOREIGN KEY(transfer_id)
+        REFERENCES transfers(id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_tdk_device
+        FOREIGN KEY(device_id)
+        REFERENCES devices(id)
+        ON DELETE CASCADE
+);
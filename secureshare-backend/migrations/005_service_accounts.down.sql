@@ -0,0 +1,8 @@
+/* migrations/005_service_accounts.down.sql */
+
+DROP TABLE IF EXISTS api_keys;
+
+-- Contas de serviço viram usuários comuns sem senha (não conseguem logar)
+ALTER TABLE users
+    DROP COLUMN IF EXISTS owner_id,
+    DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,28 @@
+/* migrations/005_service_accounts.up.sql */
+
+-- Contas de serviço: usuários sem senha que autenticam via API key
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'user',
+    ADD COLUMN IF NOT EXISTS owner_id UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+
+-- API keys: só o hash (SHA-256) é armazenado; o prefixo serve para busca
+CREATE TABLE IF NOT EXISTS api_keys (
+    id             UUID PRIMARY KEY,
+    user_id        UUID NOT NULL,
+    name           TEXT NOT NULL,
+    prefix         TEXT NOT NULL UNIQUE,
+    hash           TEXT NOT NULL,
+    scopes         TEXT[] NOT NULL,
+    allowed_cidrs  TEXT[] NOT NULL DEFAULT '{}',
+    expires_at     TIMESTAMPTZ NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT (NOW()),
+    last_used_at   TIMESTAMPTZ NULL,
+    revoked_at     TIMESTAMPTZ NULL,
+
+    CONSTRAINT fk_api_key_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
@@ -0,0 +1,3 @@
+/* migrations/006_user_identities.down.sql */
+
+DROP TABLE IF EXISTS user_identities;
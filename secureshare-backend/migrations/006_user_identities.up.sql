@@ -0,0 +1,19 @@
+/* migrations/006_user_identities.up.sql */
+
+-- Identidades externas (SSO/OIDC) vinculadas a usuários locais
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer      TEXT NOT NULL,
+    subject     TEXT NOT NULL,
+    user_id     UUID NOT NULL,
+    email       TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT (NOW()),
+
+    PRIMARY KEY (issuer, subject),
+
+    CONSTRAINT fk_identity_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
@@ -0,0 +1,3 @@
+/* migrations/007_outbox.down.sql */
+
+DROP TABLE IF EXISTS outbox;
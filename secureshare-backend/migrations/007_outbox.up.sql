@@ -0,0 +1,16 @@
+/* migrations/007_outbox.up.sql */
+
+-- Caixa de saída transacional: efeitos colaterais (remoção de blobs,
+-- notificações) gravados junto com a mudança de metadados e executados
+-- depois por um worker
+CREATE TABLE IF NOT EXISTS outbox (
+    id            UUID PRIMARY KEY,
+    kind          TEXT NOT NULL,
+    payload       JSONB NOT NULL,
+    attempts      INTEGER NOT NULL DEFAULT 0,
+    available_at  TIMESTAMPTZ NOT NULL DEFAULT (NOW()),
+    last_error    TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT (NOW())
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_available_at ON outbox(available_at);
@@ -0,0 +1,25 @@
+/* migrations/008_jobs.up.sql */
+
+-- Fila persistente de jobs (ver internal/jobs). Jobs concluídos são
+-- apagados; os que esgotam as tentativas ficam com failed_at preenchido
+-- (dead letter) até serem podados.
+CREATE TABLE IF NOT EXISTS jobs (
+    id          UUID PRIMARY KEY,
+    kind        TEXT NOT NULL,
+    payload     JSONB NOT NULL,
+    attempts    INTEGER NOT NULL DEFAULT 0,
+    run_at      TIMESTAMPTZ NOT NULL DEFAULT (NOW()),
+    last_error  TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT (NOW()),
+    failed_at   TIMESTAMPTZ NULL
+);
+
+-- Só os jobs vivos entram no índice usado pela reserva
+CREATE INDEX IF NOT EXISTS idx_jobs_run_at ON jobs(run_at) WHERE failed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_jobs_failed_at ON jobs(failed_at) WHERE failed_at IS NOT NULL;
+
+-- Próximo disparo de cada job agendado (cron)
+CREATE TABLE IF NOT EXISTS job_schedules (
+    name         TEXT PRIMARY KEY,
+    next_run_at  TIMESTAMPTZ NOT NULL
+);
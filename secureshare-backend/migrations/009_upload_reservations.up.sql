@@ -0,0 +1,15 @@
+/* migrations/009_upload_reservations.up.sql */
+
+-- Chaves entregues por GET /transfers/upload-url. O coletor de órfãos não
+-- remove objetos com reserva válida, mesmo sem transferência.
+CREATE TABLE IF NOT EXISTS upload_reservations (
+    object_key  TEXT PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT (NOW()),
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_reservations_expires_at ON upload_reservations(expires_at);
+
+-- O coletor procura transferências pela chave do objeto
+CREATE INDEX IF NOT EXISTS idx_transfers_link_to_enc_file ON transfers(link_to_enc_file);
@@ -0,0 +1,6 @@
+/* migrations/010_storage_quotas.down.sql */
+
+DROP INDEX IF EXISTS idx_upload_reservations_user_id;
+DROP INDEX IF EXISTS idx_transfers_source_user_id;
+ALTER TABLE upload_reservations DROP COLUMN IF EXISTS size_bytes;
+ALTER TABLE transfers DROP COLUMN IF EXISTS size_bytes;
@@ -0,0 +1,10 @@
+/* migrations/010_storage_quotas.up.sql */
+
+-- Tamanho real de cada objeto (lido do bucket ao criar a transferência) e
+-- tamanho declarado de cada reserva de upload, usados nas cotas
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS size_bytes BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE upload_reservations ADD COLUMN IF NOT EXISTS size_bytes BIGINT NOT NULL DEFAULT 0;
+
+-- O cálculo de uso soma as transferências por remetente
+CREATE INDEX IF NOT EXISTS idx_transfers_source_user_id ON transfers(source_user_id);
+CREATE INDEX IF NOT EXISTS idx_upload_reservations_user_id ON upload_reservations(user_id);
@@ -0,0 +1,4 @@
+/* migrations/011_transfer_checksums.down.sql */
+
+ALTER TABLE upload_reservations DROP COLUMN IF EXISTS checksum_sha256;
+ALTER TABLE transfers DROP COLUMN IF EXISTS checksum_sha256;
@@ -0,0 +1,6 @@
+/* migrations/011_transfer_checksums.up.sql */
+
+-- SHA-256 (base64) do arquivo cifrado, assinado na URL de upload e
+-- conferido pelo S3. Vazio em transferências anteriores.
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS checksum_sha256 TEXT NOT NULL DEFAULT '';
+ALTER TABLE upload_reservations ADD COLUMN IF NOT EXISTS checksum_sha256 TEXT NOT NULL DEFAULT '';
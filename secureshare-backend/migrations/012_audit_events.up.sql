@@ -0,0 +1,22 @@
+/* migrations/012_audit_events.up.sql */
+
+-- Log de auditoria encadeado por hash (ver models.AuditEvent). Sem chaves
+-- estrangeiras: remover um usuário não pode apagar nem alterar eventos.
+CREATE TABLE IF NOT EXISTS audit_events (
+    seq         BIGINT PRIMARY KEY,
+    type        TEXT NOT NULL,
+    actor_id    UUID NULL,
+    actor       TEXT NOT NULL DEFAULT '',
+    ip          TEXT NOT NULL DEFAULT '',
+    user_agent  TEXT NOT NULL DEFAULT '',
+    target      TEXT NOT NULL DEFAULT '',
+    details     JSONB NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    prev_hash   TEXT NOT NULL,
+    hash        TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_type ON audit_events(type, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
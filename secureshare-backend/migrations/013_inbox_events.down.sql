@@ -0,0 +1,3 @@
+/* migrations/013_inbox_events.down.sql */
+
+DROP TABLE IF EXISTS inbox_events;
@@ -0,0 +1,14 @@
+/* migrations/013_inbox_events.up.sql */
+
+-- Eventos entregues por GET /v1/events (SSE e WebSocket). Ficam guardados
+-- para a retomada com Last-Event-ID e são podados pela fila de jobs.
+CREATE TABLE IF NOT EXISTS inbox_events (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type        TEXT NOT NULL,
+    data        JSONB NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT (NOW())
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbox_events_user_id ON inbox_events(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_inbox_events_created_at ON inbox_events(created_at);
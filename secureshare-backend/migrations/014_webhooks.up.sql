@@ -0,0 +1,30 @@
+/* migrations/014_webhooks.up.sql */
+
+-- Webhooks de saída (ver models.Webhook) e o log das entregas
+CREATE TABLE IF NOT EXISTS webhooks (
+    id           UUID PRIMARY KEY,
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url          TEXT NOT NULL,
+    secret       TEXT NOT NULL,
+    event_types  TEXT[] NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT (NOW()),
+    created_by   TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id                UUID PRIMARY KEY,
+    webhook_id        UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type        TEXT NOT NULL,
+    payload           JSONB NOT NULL,
+    status            TEXT NOT NULL,
+    attempts          INTEGER NOT NULL DEFAULT 0,
+    last_status_code  INTEGER NOT NULL DEFAULT 0,
+    last_error        TEXT NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ NOT NULL,
+    updated_at        TIMESTAMPTZ NOT NULL,
+    redelivery_of     UUID NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at);
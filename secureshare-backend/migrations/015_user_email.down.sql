@@ -0,0 +1,6 @@
+/* migrations/015_user_email.down.sql */
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS email_verified_at,
+    DROP COLUMN IF EXISTS email;
@@ -0,0 +1,8 @@
+/* migrations/015_user_email.up.sql */
+
+-- E-mail opcional para avisos de novas transferências (só usado depois de
+-- verificado) e o idioma das mensagens (vazio: pt-BR)
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+/* migrations/016_idempotency_keys.down.sql */
+
+DROP TABLE IF EXISTS idempotency_keys;
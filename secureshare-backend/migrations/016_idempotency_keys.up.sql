@@ -0,0 +1,17 @@
+/* migrations/016_idempotency_keys.up.sql */
+
+-- Respostas das requisições com Idempotency-Key (ver models.IdempotencyRecord),
+-- repetidas nos reenvios até expires_at
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idem_key      TEXT NOT NULL,
+    fingerprint   TEXT NOT NULL,
+    status_code   INTEGER NOT NULL DEFAULT 0, -- 0: requisição em andamento
+    content_type  TEXT NOT NULL DEFAULT '',
+    body          BYTEA NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, idem_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,21 @@
+/* migrations/017_outbox_to_jobs.down.sql */
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id            UUID PRIMARY KEY,
+    kind          TEXT NOT NULL,
+    payload       JSONB NOT NULL,
+    attempts      INTEGER NOT NULL DEFAULT 0,
+    available_at  TIMESTAMPTZ NOT NULL DEFAULT (NOW()),
+    last_error    TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT (NOW())
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_available_at ON outbox(available_at);
+
+-- As remoções de blobs pendentes voltam para a caixa de saída
+INSERT INTO outbox (id, kind, payload, attempts, available_at, last_error, created_at)
+SELECT id, kind, payload, attempts, run_at, last_error, created_at
+FROM jobs
+WHERE kind = 'blob.delete' AND failed_at IS NULL;
+
+DELETE FROM jobs WHERE kind = 'blob.delete' AND failed_at IS NULL;
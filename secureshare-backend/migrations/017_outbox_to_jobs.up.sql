@@ -0,0 +1,10 @@
+/* migrations/017_outbox_to_jobs.up.sql */
+
+-- A caixa de saída virou um tipo de job (blob.delete): as mensagens
+-- pendentes passam para a fila de jobs e a tabela sai
+INSERT INTO jobs (id, kind, payload, attempts, run_at, last_error, created_at)
+SELECT id, kind, payload, attempts, available_at, last_error, created_at
+FROM outbox
+ON CONFLICT (id) DO NOTHING;
+
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,30 @@
+// Package migrations embute os scripts SQL do schema no binário.
+//
+// Cada versão tem um par NNN_nome.up.sql / NNN_nome.down.sql; a ordem é dada
+// pelo número. Os scripts são aplicados por internal/migrate. O PostgreSQL
+// usa os da raiz; o SQLite tem seu próprio conjunto em sqlite/.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS contém todos os scripts de migração do PostgreSQL
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLiteFS contém os scripts de migração do SQLite
+var SQLiteFS = mustSub(sqliteFS, "sqlite")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,9 @@
+/* migrations/sqlite/001_init.down.sql */
+
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS transfer_device_keys;
+DROP TABLE IF EXISTS devices;
+DROP TABLE IF EXISTS key_backups;
+DROP TABLE IF EXISTS transfers;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,92 @@
+/* migrations/sqlite/001_init.up.sql */
+
+-- Schema completo para SQLite (equivale às migrações 001-006 do PostgreSQL).
+-- UUIDs são TEXT; instantes são TEXT em UTC, formato RFC 3339 com largura
+-- fixa (ordenação lexical = cronológica); arrays são JSON.
+
+CREATE TABLE users (
+    id                   TEXT PRIMARY KEY,
+    username             TEXT NOT NULL UNIQUE,
+    password_hash        TEXT NOT NULL,
+    public_key           TEXT NOT NULL,
+    public_key_sign      TEXT NOT NULL,
+    created_at           TEXT NOT NULL,
+    sessions_valid_after TEXT NOT NULL DEFAULT '1970-01-01T00:00:00.000000000Z',
+    kind                 TEXT NOT NULL DEFAULT 'user',
+    owner_id             TEXT NULL REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE transfers (
+    id                 TEXT PRIMARY KEY,
+    source_user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    dest_user_id       TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    link_to_enc_file   TEXT NOT NULL,
+    skb                TEXT NOT NULL, -- Base64
+    sig                TEXT NOT NULL, -- Base64
+    created_at         TEXT NOT NULL
+);
+
+CREATE INDEX idx_transfers_dest_user_id ON transfers(dest_user_id);
+
+CREATE TABLE key_backups (
+    user_id          TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    kdf              TEXT NOT NULL,
+    kdf_salt         TEXT NOT NULL, -- Base64
+    kdf_iterations   INTEGER NOT NULL,
+    kdf_memory_kib   INTEGER NOT NULL DEFAULT 0,
+    kdf_parallelism  INTEGER NOT NULL DEFAULT 0,
+    cipher           TEXT NOT NULL,
+    nonce            TEXT NOT NULL, -- Base64
+    ciphertext       TEXT NOT NULL, -- Base64
+    updated_at       TEXT NOT NULL
+);
+
+CREATE TABLE devices (
+    id                TEXT PRIMARY KEY,
+    user_id           TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name              TEXT NOT NULL,
+    public_key        TEXT NOT NULL,
+    public_key_sign   TEXT NOT NULL,
+    signer_device_id  TEXT NULL REFERENCES devices(id) ON DELETE SET NULL,
+    signature         TEXT NOT NULL DEFAULT '',
+    created_at        TEXT NOT NULL,
+    revoked_at        TEXT NULL
+);
+
+CREATE INDEX idx_devices_user_id ON devices(user_id);
+
+CREATE TABLE transfer_device_keys (
+    transfer_id  TEXT NOT NULL REFERENCES transfers(id) ON DELETE CASCADE,
+    device_id    TEXT NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    skb          TEXT NOT NULL, -- Base64
+
+    PRIMARY KEY (transfer_id, device_id)
+);
+
+CREATE TABLE api_keys (
+    id             TEXT PRIMARY KEY,
+    user_id        TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name           TEXT NOT NULL,
+    prefix         TEXT NOT NULL UNIQUE,
+    hash           TEXT NOT NULL,
+    scopes         TEXT NOT NULL,              -- JSON
+    allowed_cidrs  TEXT NOT NULL DEFAULT '[]', -- JSON
+    expires_at     TEXT NULL,
+    created_at     TEXT NOT NULL,
+    last_used_at   TEXT NULL,
+    revoked_at     TEXT NULL
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+
+CREATE TABLE user_identities (
+    issuer      TEXT NOT NULL,
+    subject     TEXT NOT NULL,
+    user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email       TEXT NOT NULL DEFAULT '',
+    created_at  TEXT NOT NULL,
+
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
@@ -0,0 +1,3 @@
+/* migrations/sqlite/002_outbox.down.sql */
+
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,14 @@
+/* migrations/sqlite/002_outbox.up.sql */
+
+-- Caixa de saída transacional (ver migrations/007_outbox.up.sql)
+CREATE TABLE outbox (
+    id            TEXT PRIMARY KEY,
+    kind          TEXT NOT NULL,
+    payload       TEXT NOT NULL, -- JSON
+    attempts      INTEGER NOT NULL DEFAULT 0,
+    available_at  TEXT NOT NULL,
+    last_error    TEXT NOT NULL DEFAULT '',
+    created_at    TEXT NOT NULL
+);
+
+CREATE INDEX idx_outbox_available_at ON outbox(available_at);
@@ -0,0 +1,4 @@
+/* migrations/sqlite/003_jobs.down.sql */
+
+DROP TABLE IF EXISTS job_schedules;
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,21 @@
+/* migrations/sqlite/003_jobs.up.sql */
+
+-- Fila persistente de jobs (ver migrations/008_jobs.up.sql)
+CREATE TABLE jobs (
+    id          TEXT PRIMARY KEY,
+    kind        TEXT NOT NULL,
+    payload     TEXT NOT NULL, -- JSON
+    attempts    INTEGER NOT NULL DEFAULT 0,
+    run_at      TEXT NOT NULL,
+    last_error  TEXT NOT NULL DEFAULT '',
+    created_at  TEXT NOT NULL,
+    failed_at   TEXT NULL
+);
+
+CREATE INDEX idx_jobs_run_at ON jobs(run_at) WHERE failed_at IS NULL;
+CREATE INDEX idx_jobs_failed_at ON jobs(failed_at) WHERE failed_at IS NOT NULL;
+
+CREATE TABLE job_schedules (
+    name         TEXT PRIMARY KEY,
+    next_run_at  TEXT NOT NULL
+);
@@ -0,0 +1,4 @@
+/* migrations/sqlite/004_upload_reservations.down.sql */
+
+DROP INDEX IF EXISTS idx_transfers_link_to_enc_file;
+DROP TABLE IF EXISTS upload_reservations;
@@ -0,0 +1,12 @@
+/* migrations/sqlite/004_upload_reservations.up.sql */
+
+-- Reservas de upload (ver migrations/009_upload_reservations.up.sql)
+CREATE TABLE upload_reservations (
+    object_key  TEXT PRIMARY KEY,
+    user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at  TEXT NOT NULL,
+    expires_at  TEXT NOT NULL
+);
+
+CREATE INDEX idx_upload_reservations_expires_at ON upload_reservations(expires_at);
+CREATE INDEX idx_transfers_link_to_enc_file ON transfers(link_to_enc_file);
@@ -0,0 +1,8 @@
+/* migrations/sqlite/005_storage_quotas.up.sql */
+
+-- Cotas de armazenamento (ver migrations/010_storage_quotas.up.sql)
+ALTER TABLE transfers ADD COLUMN size_bytes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE upload_reservations ADD COLUMN size_bytes INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_transfers_source_user_id ON transfers(source_user_id);
+CREATE INDEX idx_upload_reservations_user_id ON upload_reservations(user_id);
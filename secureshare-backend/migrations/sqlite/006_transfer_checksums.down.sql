@@ -0,0 +1,4 @@
+/* migrations/sqlite/006_transfer_checksums.down.sql */
+
+ALTER TABLE upload_reservations DROP COLUMN checksum_sha256;
+ALTER TABLE transfers DROP COLUMN checksum_sha256;
@@ -0,0 +1,5 @@
+/* migrations/sqlite/006_transfer_checksums.up.sql */
+
+-- Checksums de upload (ver migrations/011_transfer_checksums.up.sql)
+ALTER TABLE transfers ADD COLUMN checksum_sha256 TEXT NOT NULL DEFAULT '';
+ALTER TABLE upload_reservations ADD COLUMN checksum_sha256 TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+/* migrations/sqlite/007_audit_events.down.sql */
+
+DROP TABLE audit_events;
@@ -0,0 +1,21 @@
+/* migrations/sqlite/007_audit_events.up.sql */
+
+-- Log de auditoria (ver migrations/012_audit_events.up.sql)
+CREATE TABLE audit_events (
+    seq         INTEGER PRIMARY KEY,
+    type        TEXT NOT NULL,
+    actor_id    TEXT NULL,
+    actor       TEXT NOT NULL DEFAULT '',
+    ip          TEXT NOT NULL DEFAULT '',
+    user_agent  TEXT NOT NULL DEFAULT '',
+    target      TEXT NOT NULL DEFAULT '',
+    details     TEXT NULL, -- JSON
+    created_at  TEXT NOT NULL,
+    prev_hash   TEXT NOT NULL,
+    hash        TEXT NOT NULL
+);
+
+CREATE INDEX idx_audit_events_type ON audit_events(type, seq);
+CREATE INDEX idx_audit_events_actor ON audit_events(actor, seq);
+CREATE INDEX idx_audit_events_target ON audit_events(target, seq);
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
@@ -0,0 +1,3 @@
+/* migrations/sqlite/008_inbox_events.down.sql */
+
+DROP TABLE inbox_events;
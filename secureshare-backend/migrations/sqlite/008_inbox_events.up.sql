@@ -0,0 +1,13 @@
+/* migrations/sqlite/008_inbox_events.up.sql */
+
+-- Eventos da caixa de entrada (ver migrations/013_inbox_events.up.sql)
+CREATE TABLE inbox_events (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type        TEXT NOT NULL,
+    data        TEXT NOT NULL, -- JSON
+    created_at  TEXT NOT NULL
+);
+
+CREATE INDEX idx_inbox_events_user_id ON inbox_events(user_id, id);
+CREATE INDEX idx_inbox_events_created_at ON inbox_events(created_at);
@@ -0,0 +1,4 @@
+/* migrations/sqlite/009_webhooks.down.sql */
+
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
@@ -0,0 +1,30 @@
+/* migrations/sqlite/009_webhooks.up.sql */
+
+-- Webhooks de saída (ver migrations/014_webhooks.up.sql)
+CREATE TABLE webhooks (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url          TEXT NOT NULL,
+    secret       TEXT NOT NULL,
+    event_types  TEXT NOT NULL, -- JSON
+    created_at   TEXT NOT NULL,
+    created_by   TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_webhooks_user_id ON webhooks(user_id);
+
+CREATE TABLE webhook_deliveries (
+    id                TEXT PRIMARY KEY,
+    webhook_id        TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type        TEXT NOT NULL,
+    payload           TEXT NOT NULL, -- JSON
+    status            TEXT NOT NULL,
+    attempts          INTEGER NOT NULL DEFAULT 0,
+    last_status_code  INTEGER NOT NULL DEFAULT 0,
+    last_error        TEXT NOT NULL DEFAULT '',
+    created_at        TEXT NOT NULL,
+    updated_at        TEXT NOT NULL,
+    redelivery_of     TEXT NULL
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at);
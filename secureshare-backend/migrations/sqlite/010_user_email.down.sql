@@ -0,0 +1,5 @@
+/* migrations/sqlite/010_user_email.down.sql */
+
+ALTER TABLE users DROP COLUMN locale;
+ALTER TABLE users DROP COLUMN email_verified_at;
+ALTER TABLE users DROP COLUMN email;
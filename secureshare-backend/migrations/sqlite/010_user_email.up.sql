@@ -0,0 +1,6 @@
+/* migrations/sqlite/010_user_email.up.sql */
+
+-- E-mail e idioma das mensagens (ver migrations/015_user_email.up.sql)
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN email_verified_at TEXT NULL;
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+/* migrations/sqlite/011_idempotency_keys.down.sql */
+
+DROP TABLE idempotency_keys;
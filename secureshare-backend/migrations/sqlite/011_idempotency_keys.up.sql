@@ -0,0 +1,17 @@
+/* migrations/sqlite/011_idempotency_keys.up.sql */
+
+-- Respostas das requisições com Idempotency-Key (ver
+-- migrations/016_idempotency_keys.up.sql)
+CREATE TABLE idempotency_keys (
+    user_id       TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idem_key      TEXT NOT NULL,
+    fingerprint   TEXT NOT NULL,
+    status_code   INTEGER NOT NULL DEFAULT 0, -- 0: requisição em andamento
+    content_type  TEXT NOT NULL DEFAULT '',
+    body          BLOB NULL,
+    created_at    TEXT NOT NULL,
+    expires_at    TEXT NOT NULL,
+    PRIMARY KEY (user_id, idem_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,20 @@
+/* migrations/sqlite/012_outbox_to_jobs.down.sql */
+
+CREATE TABLE outbox (
+    id            TEXT PRIMARY KEY,
+    kind          TEXT NOT NULL,
+    payload       TEXT NOT NULL, -- JSON
+    attempts      INTEGER NOT NULL DEFAULT 0,
+    available_at  TEXT NOT NULL,
+    last_error    TEXT NOT NULL DEFAULT '',
+    created_at    TEXT NOT NULL
+);
+
+CREATE INDEX idx_outbox_available_at ON outbox(available_at);
+
+INSERT INTO outbox (id, kind, payload, attempts, available_at, last_error, created_at)
+SELECT id, kind, payload, attempts, run_at, last_error, created_at
+FROM jobs
+WHERE kind = 'blob.delete' AND failed_at IS NULL;
+
+DELETE FROM jobs WHERE kind = 'blob.delete' AND failed_at IS NULL;
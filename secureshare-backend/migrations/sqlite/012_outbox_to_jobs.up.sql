@@ -0,0 +1,8 @@
+/* migrations/sqlite/012_outbox_to_jobs.up.sql */
+
+-- A caixa de saída virou um tipo de job (ver migrations/017_outbox_to_jobs.up.sql)
+INSERT OR IGNORE INTO jobs (id, kind, payload, attempts, run_at, last_error, created_at)
+SELECT id, kind, payload, attempts, available_at, last_error, created_at
+FROM outbox;
+
+DROP TABLE outbox;
@@ -0,0 +1,142 @@
+// Package client é um SDK Go mínimo para a API v1 do SecureShare.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client fala com a API v1 do SecureShare (ex: "http://localhost:8080/v1")
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+}
+
+// Option configura um Client
+type Option func(*Client)
+
+// WithHTTPClient substitui o http.Client padrão
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithToken usa um token já obtido em vez de chamar Login. Aceita também
+// a API key de uma conta de serviço (ssk_...), útil em pipelines de CI.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// New cria um novo cliente para a API em baseURL
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token retorna o token de sessão atual
+func (c *Client) Token() string {
+	return c.token
+}
+
+// APIError é um erro retornado pela API. Code é o identificador estável do
+// erro (ex: "USER_EXISTS"); RequestID identifica a requisição nos logs do
+// servidor.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("secureshare: %d: %s", e.StatusCode, e.Message)
+}
+
+// Login autentica e guarda o token para as próximas chamadas
+func (c *Client) Login(ctx context.Context, username, password string) error {
+	var resp struct {
+		Token string `json:"token"`
+	}
+	req := map[string]string{"username": username, "password": password}
+	if err := c.do(ctx, http.MethodPost, "/users/login", req, &resp); err != nil {
+		return err
+	}
+	c.token = resp.Token
+	return nil
+}
+
+// do envia in como JSON (se não for nil) e decodifica a resposta em out
+// (se não for nil)
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("falha ao serializar requisição: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return decodeAPIError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("falha ao decodificar resposta: %w", err)
+	}
+	return nil
+}
+
+func decodeAPIError(resp *http.Response) error {
+	var problem struct {
+		Title     string `json:"title"`
+		Detail    string `json:"detail"`
+		Code      string `json:"code"`
+		RequestID string `json:"requestId"`
+	}
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: resp.Status, RequestID: resp.Header.Get("X-Request-Id")}
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err == nil {
+		apiErr.Code = problem.Code
+		if problem.Detail != "" {
+			apiErr.Message = problem.Detail
+		} else if problem.Title != "" {
+			apiErr.Message = problem.Title
+		}
+		if problem.RequestID != "" {
+			apiErr.RequestID = problem.RequestID
+		}
+	}
+	return apiErr
+}
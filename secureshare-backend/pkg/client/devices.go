@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"net/http"
+	"net/url"
+	"time"
+
+	"secureshare-backend/pkg/crosssign"
+)
+
+// Device é um dispositivo do usuário autenticado
+type Device struct {
+	DeviceID       string     `json:"deviceId"`
+	Name           string     `json:"name"`
+	PublicKey      string     `json:"publicKey"`
+	PublicKeySign  string     `json:"publicKeySign"`
+	SignerDeviceID string     `json:"signerDeviceId,omitempty"`
+	Signature      string     `json:"signature,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	RevokedAt      *time.Time `json:"revokedAt,omitempty"`
+}
+
+// NewDevice são as chaves públicas de um dispositivo a registrar
+type NewDevice struct {
+	Name          string
+	PublicKey     string // RSA-OAEP, PEM SPKI
+	PublicKeySign string // ECDSA P-256, PEM SPKI
+}
+
+// ListDevices lista os dispositivos do usuário autenticado
+func (c *Client) ListDevices(ctx context.Context) ([]Device, error) {
+	var devices []Device
+	if err := c.do(ctx, http.MethodGet, "/devices", nil, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// RegisterDevice registra um novo dispositivo, assinando suas chaves com a
+// chave ECDSA privada de um dispositivo já registrado (signerDeviceID)
+func (c *Client) RegisterDevice(ctx context.Context, username string, device NewDevice, signerDeviceID string, signerKey *ecdsa.PrivateKey) (*Device, error) {
+	payload := crosssign.Payload(username, device.PublicKey, device.PublicKeySign)
+	signature, err := crosssign.Sign(signerKey, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req := map[string]string{
+		"name":           device.Name,
+		"publicKey":      device.PublicKey,
+		"publicKeySign":  device.PublicKeySign,
+		"signerDeviceId": signerDeviceID,
+		"signature":      signature,
+	}
+	var created Device
+	if err := c.do(ctx, http.MethodPost, "/devices", req, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// RevokeDevice revoga um dispositivo (ex: perdido ou roubado)
+func (c *Client) RevokeDevice(ctx context.Context, deviceID string) error {
+	return c.do(ctx, http.MethodDelete, "/devices/"+url.PathEscape(deviceID), nil, nil)
+}
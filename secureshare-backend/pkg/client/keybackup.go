@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"secureshare-backend/pkg/keybackup"
+)
+
+// PutKeyBackup envia um backup já cifrado (substitui o anterior)
+func (c *Client) PutKeyBackup(ctx context.Context, backup *keybackup.Backup) error {
+	return c.do(ctx, http.MethodPut, "/users/me/key-backup", backup, nil)
+}
+
+// GetKeyBackup baixa o backup cifrado do usuário autenticado
+func (c *Client) GetKeyBackup(ctx context.Context) (*keybackup.Backup, error) {
+	var backup keybackup.Backup
+	if err := c.do(ctx, http.MethodGet, "/users/me/key-backup", nil, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// CreateKeyBackup cifra as chaves localmente com passphrase (Argon2id por
+// padrão) e envia apenas o resultado cifrado ao servidor
+func (c *Client) CreateKeyBackup(ctx context.Context, keys keybackup.Keys, passphrase string, params ...keybackup.Params) error {
+	p := keybackup.DefaultArgon2id()
+	if len(params) > 0 {
+		p = params[0]
+	}
+
+	backup, err := keybackup.Seal(keys, []byte(passphrase), p)
+	if err != nil {
+		return err
+	}
+	return c.PutKeyBackup(ctx, backup)
+}
+
+// RestoreKeyBackup baixa o backup e o decifra localmente com passphrase
+func (c *Client) RestoreKeyBackup(ctx context.Context, passphrase string) (*keybackup.Keys, error) {
+	backup, err := c.GetKeyBackup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keybackup.Open(backup, []byte(passphrase))
+}
@@ -0,0 +1,86 @@
+// Package crosssign define a assinatura cruzada de dispositivos: um
+// dispositivo já confiável assina (ECDSA P-256 / SHA-256) as chaves
+// públicas de um novo dispositivo do mesmo usuário.
+//
+// O servidor verifica a assinatura ao registrar o dispositivo, mas os
+// clientes devem verificá-la também ao buscar chaves de terceiros, já que
+// o servidor não é considerado confiável para isso.
+package crosssign
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+const payloadPrefix = "secureshare-device-v1"
+
+// Payload monta a mensagem canônica assinada pelo dispositivo signatário
+func Payload(username, publicKey, publicKeySign string) []byte {
+	return []byte(payloadPrefix + "\n" + username + "\n" + publicKey + "\n" + publicKeySign)
+}
+
+// Sign assina payload e retorna a assinatura em Base64 (formato IEEE P1363,
+// r||s, o mesmo gerado pelo WebCrypto)
+func Sign(priv *ecdsa.PrivateKey, payload []byte) (string, error) {
+	digest := sha256.Sum256(payload)
+	r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("falha ao assinar: %w", err)
+	}
+
+	size := (priv.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// Verify confere signatureB64 sobre payload com a chave pública ECDSA
+// (PEM SPKI) do signatário. Aceita assinaturas em P1363 (WebCrypto) ou ASN.1.
+func Verify(signerPublicKeyPEM string, payload []byte, signatureB64 string) error {
+	pub, err := ParsePublicKey(signerPublicKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signatureB64)
+	if err != nil {
+		return fmt.Errorf("assinatura não é Base64 válido")
+	}
+
+	digest := sha256.Sum256(payload)
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	if len(sig) == 2*size {
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if ecdsa.Verify(pub, digest[:], r, s) {
+			return nil
+		}
+	} else if ecdsa.VerifyASN1(pub, digest[:], sig) {
+		return nil
+	}
+	return fmt.Errorf("assinatura cruzada inválida")
+}
+
+// ParsePublicKey lê uma chave pública ECDSA em PEM (SPKI)
+func ParsePublicKey(publicKeyPEM string) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("chave pública de assinatura não está em PEM")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("chave pública de assinatura inválida: %w", err)
+	}
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("chave pública de assinatura não é ECDSA")
+	}
+	return pub, nil
+}
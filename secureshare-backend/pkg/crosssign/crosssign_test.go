@@ -0,0 +1,92 @@
+package crosssign_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"secureshare-backend/pkg/crosssign"
+)
+
+func newKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestVerifyP1363(t *testing.T) {
+	priv, pubPEM := newKey(t)
+	payload := crosssign.Payload("alice", "pk-novo", "pks-novo")
+
+	sig, err := crosssign.Sign(priv, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := base64.StdEncoding.DecodeString(sig); len(raw) != 64 {
+		t.Fatalf("assinatura P1363 de P-256 deveria ter 64 bytes, tem %d", len(raw))
+	}
+	if err := crosssign.Verify(pubPEM, payload, sig); err != nil {
+		t.Fatalf("Verify(P1363): %v", err)
+	}
+}
+
+func TestVerifyASN1(t *testing.T) {
+	priv, pubPEM := newKey(t)
+	payload := crosssign.Payload("alice", "pk-novo", "pks-novo")
+
+	digest := sha256.Sum256(payload)
+	der, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crosssign.Verify(pubPEM, payload, base64.StdEncoding.EncodeToString(der)); err != nil {
+		t.Fatalf("Verify(ASN.1): %v", err)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	priv, pubPEM := newKey(t)
+	_, otherPEM := newKey(t)
+	payload := crosssign.Payload("alice", "pk-novo", "pks-novo")
+	sig, err := crosssign.Sign(priv, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(sig)
+	flipped := append([]byte(nil), raw...)
+	flipped[10] ^= 0x01
+
+	cases := map[string]struct {
+		pub     string
+		payload []byte
+		sig     string
+	}{
+		"payload com outro usuário": {pubPEM, crosssign.Payload("mallory", "pk-novo", "pks-novo"), sig},
+		"payload com outra chave":   {pubPEM, crosssign.Payload("alice", "pk-falsa", "pks-novo"), sig},
+		"assinatura alterada":       {pubPEM, payload, base64.StdEncoding.EncodeToString(flipped)},
+		"assinatura truncada":       {pubPEM, payload, base64.StdEncoding.EncodeToString(raw[:63])},
+		"signatário errado":         {otherPEM, payload, sig},
+		"assinatura não Base64":     {pubPEM, payload, "não é base64!"},
+		"chave não PEM":             {"pk", payload, sig},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := crosssign.Verify(c.pub, c.payload, c.sig); err == nil {
+				t.Fatal("Verify aceitou assinatura inválida")
+			}
+		})
+	}
+}
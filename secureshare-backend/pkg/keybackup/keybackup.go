@@ -0,0 +1,248 @@
+// Package keybackup implementa o formato do backup cifrado de chaves
+// privadas do SecureShare.
+//
+// As chaves privadas (PEM) são serializadas em JSON e cifradas com
+// AES-256-GCM usando uma chave derivada da senha de backup via Argon2id
+// ou PBKDF2-SHA256. O resultado (Backup) é opaco para o servidor: ele
+// guarda o blob e os parâmetros do KDF, mas nunca a senha ou as chaves.
+package keybackup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Algoritmos suportados
+const (
+	KDFArgon2id     = "argon2id"
+	KDFPBKDF2SHA256 = "pbkdf2-sha256"
+	CipherAES256GCM = "aes-256-gcm"
+)
+
+// Parâmetros mínimos aceitos (recomendações OWASP). Backups mais fracos
+// são rejeitados tanto pelo servidor quanto por Seal.
+const (
+	MinPBKDF2Iterations  = 600_000
+	MinArgon2Iterations  = 2
+	MinArgon2MemoryKiB   = 19 * 1024
+	MinArgon2Parallelism = 1
+
+	// MaxCiphertextLen limita o tamanho do blob (Base64) aceito
+	MaxCiphertextLen = 64 * 1024
+
+	saltLen    = 16
+	maxSaltLen = 64
+	keyLen     = 32
+)
+
+// Parâmetros máximos aceitos. Os parâmetros vêm do servidor e Open os
+// segue: sem teto, um servidor malicioso poderia esgotar a memória ou
+// travar o cliente na derivação da chave.
+const (
+	MaxPBKDF2Iterations  = 10_000_000
+	MaxArgon2Iterations  = 16
+	MaxArgon2MemoryKiB   = 1024 * 1024 // 1 GiB
+	MaxArgon2Parallelism = 16
+)
+
+// additionalData amarra o texto cifrado a este formato
+var additionalData = []byte("secureshare-key-backup-v1")
+
+// ErrWrongPassphrase indica que o backup não pôde ser decifrado com a senha
+// informada (ou que o blob foi adulterado)
+var ErrWrongPassphrase = errors.New("senha de backup incorreta ou backup corrompido")
+
+// Keys são as chaves privadas (PEM PKCS#8) protegidas pelo backup
+type Keys struct {
+	EncryptPrivateKey string `json:"encryptPrivateKey"` // RSA-OAEP
+	SignPrivateKey    string `json:"signPrivateKey"`    // ECDSA P-256
+}
+
+// Params são os parâmetros do KDF usados para derivar a chave de backup
+type Params struct {
+	KDF         string
+	Iterations  int
+	MemoryKiB   int
+	Parallelism int
+}
+
+// DefaultArgon2id retorna os parâmetros padrão para Argon2id
+func DefaultArgon2id() Params {
+	return Params{KDF: KDFArgon2id, Iterations: 3, MemoryKiB: 64 * 1024, Parallelism: 4}
+}
+
+// DefaultPBKDF2 retorna os parâmetros padrão para PBKDF2-SHA256, útil para
+// clientes que só dispõem de WebCrypto (que não implementa Argon2)
+func DefaultPBKDF2() Params {
+	return Params{KDF: KDFPBKDF2SHA256, Iterations: MinPBKDF2Iterations}
+}
+
+// Backup é a representação trafegada em GET/PUT /v1/users/me/key-backup
+type Backup struct {
+	KDF            string    `json:"kdf"`
+	KDFSalt        string    `json:"kdfSalt"`
+	KDFIterations  int       `json:"kdfIterations"`
+	KDFMemoryKiB   int       `json:"kdfMemoryKiB,omitempty"`
+	KDFParallelism int       `json:"kdfParallelism,omitempty"`
+	Cipher         string    `json:"cipher"`
+	Nonce          string    `json:"nonce"`
+	Ciphertext     string    `json:"ciphertext"`
+	UpdatedAt      time.Time `json:"updatedAt,omitempty"`
+}
+
+func (b *Backup) params() Params {
+	return Params{
+		KDF:         b.KDF,
+		Iterations:  b.KDFIterations,
+		MemoryKiB:   b.KDFMemoryKiB,
+		Parallelism: b.KDFParallelism,
+	}
+}
+
+// Validate confere os parâmetros e o formato dos campos sem decifrar nada
+func (p Params) Validate() error {
+	switch p.KDF {
+	case KDFArgon2id:
+		if p.Iterations < MinArgon2Iterations || p.Iterations > MaxArgon2Iterations {
+			return fmt.Errorf("argon2id: kdfIterations deve estar entre %d e %d", MinArgon2Iterations, MaxArgon2Iterations)
+		}
+		if p.MemoryKiB < MinArgon2MemoryKiB || p.MemoryKiB > MaxArgon2MemoryKiB {
+			return fmt.Errorf("argon2id: kdfMemoryKiB deve estar entre %d e %d", MinArgon2MemoryKiB, MaxArgon2MemoryKiB)
+		}
+		if p.Parallelism < MinArgon2Parallelism || p.Parallelism > MaxArgon2Parallelism {
+			return fmt.Errorf("argon2id: kdfParallelism deve estar entre %d e %d", MinArgon2Parallelism, MaxArgon2Parallelism)
+		}
+	case KDFPBKDF2SHA256:
+		if p.Iterations < MinPBKDF2Iterations || p.Iterations > MaxPBKDF2Iterations {
+			return fmt.Errorf("pbkdf2-sha256: kdfIterations deve estar entre %d e %d", MinPBKDF2Iterations, MaxPBKDF2Iterations)
+		}
+		if p.MemoryKiB != 0 || p.Parallelism != 0 {
+			return fmt.Errorf("pbkdf2-sha256 não aceita kdfMemoryKiB/kdfParallelism")
+		}
+	default:
+		return fmt.Errorf("kdf não suportado: '%s'", p.KDF)
+	}
+	return nil
+}
+
+// Validate confere o backup inteiro (parâmetros, algoritmo e codificação)
+func (b *Backup) Validate() error {
+	if err := b.params().Validate(); err != nil {
+		return err
+	}
+	if b.Cipher != CipherAES256GCM {
+		return fmt.Errorf("cipher não suportado: '%s'", b.Cipher)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(b.KDFSalt)
+	if err != nil || len(salt) < saltLen || len(salt) > maxSaltLen {
+		return fmt.Errorf("kdfSalt deve ser Base64 com %d a %d bytes", saltLen, maxSaltLen)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(b.Nonce)
+	if err != nil || len(nonce) != 12 {
+		return fmt.Errorf("nonce deve ser Base64 com 12 bytes")
+	}
+	if len(b.Ciphertext) == 0 || len(b.Ciphertext) > MaxCiphertextLen {
+		return fmt.Errorf("ciphertext deve ter entre 1 e %d caracteres", MaxCiphertextLen)
+	}
+	if _, err := base64.StdEncoding.DecodeString(b.Ciphertext); err != nil {
+		return fmt.Errorf("ciphertext deve ser Base64")
+	}
+	return nil
+}
+
+func deriveKey(passphrase []byte, salt []byte, p Params) []byte {
+	if p.KDF == KDFArgon2id {
+		return argon2.IDKey(passphrase, salt, uint32(p.Iterations), uint32(p.MemoryKiB), uint8(p.Parallelism), keyLen)
+	}
+	return pbkdf2.Key(passphrase, salt, p.Iterations, keyLen, sha256.New)
+}
+
+// Seal cifra as chaves com uma chave derivada de passphrase
+func Seal(keys Keys, passphrase []byte, p Params) (*Backup, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("senha de backup não pode ser vazia")
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(keys)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao serializar chaves: %w", err)
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("falha ao gerar salt: %w", err)
+	}
+
+	gcm, err := newGCM(deriveKey(passphrase, salt, p))
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("falha ao gerar nonce: %w", err)
+	}
+
+	return &Backup{
+		KDF:            p.KDF,
+		KDFSalt:        base64.StdEncoding.EncodeToString(salt),
+		KDFIterations:  p.Iterations,
+		KDFMemoryKiB:   p.MemoryKiB,
+		KDFParallelism: p.Parallelism,
+		Cipher:         CipherAES256GCM,
+		Nonce:          base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext:     base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, additionalData)),
+	}, nil
+}
+
+// Open decifra o backup com passphrase e retorna as chaves privadas
+func Open(b *Backup, passphrase []byte) (*Keys, error) {
+	if err := b.Validate(); err != nil {
+		return nil, fmt.Errorf("backup inválido: %w", err)
+	}
+
+	// Os campos já foram validados como Base64 acima
+	salt, _ := base64.StdEncoding.DecodeString(b.KDFSalt)
+	nonce, _ := base64.StdEncoding.DecodeString(b.Nonce)
+	ciphertext, _ := base64.StdEncoding.DecodeString(b.Ciphertext)
+
+	gcm, err := newGCM(deriveKey(passphrase, salt, b.params()))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var keys Keys
+	if err := json.Unmarshal(plaintext, &keys); err != nil {
+		return nil, fmt.Errorf("conteúdo do backup inválido: %w", err)
+	}
+	return &keys, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao iniciar AES: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao iniciar GCM: %w", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,6 @@
+// Package securesharev1 é o código gerado da API gRPC do SecureShare
+// (proto/secureshare/v1). Serviços em Go usam NewSecureShareClient; o
+// servidor fica em internal/grpcapi.
+package securesharev1
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=secureshare-backend --go-grpc_out=../.. --go-grpc_opt=module=secureshare-backend secureshare/v1/secureshare.proto
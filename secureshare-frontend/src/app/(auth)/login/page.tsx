@@ -52,7 +52,7 @@ export default function LoginPage() {
 
       const data = await res.json();
       if (!res.ok) {
-        throw new Error(data.error.message || 'Falha no login.');
+        throw new Error(data.detail || data.title || 'Falha no login.');
       }
       
       const jwt = data.token;
@@ -53,7 +53,7 @@ export default function RegisterPage() {
 
       if (!res.ok) {
         const errorData = await res.json();
-        throw new Error(errorData.error.message || 'Falha ao registrar.');
+        throw new Error(errorData.detail || errorData.title || 'Falha ao registrar.');
       }
 
       // 4. Sucesso!